@@ -10,6 +10,7 @@ import (
 	"gastroshop-api/internal/middleware"
 	"gastroshop-api/internal/repository"
 	"gastroshop-api/internal/services"
+	"gastroshop-api/internal/storage"
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
@@ -48,16 +49,100 @@ func main() {
 	paymentRepo := repository.NewPaymentRepository(db)
 	regionRepo := repository.NewRegionRepository(db)
 	eventRepo := repository.NewEventRepository(db)
+	deliveryRepo := repository.NewDeliveryRepository(db)
+	subscriptionRepo := repository.NewSubscriptionRepository(db)
+	cartRepo := repository.NewCartRepository(db)
+	categoryRepo := repository.NewCategoryRepository(db)
+	importJobRepo := repository.NewImportJobRepository(db)
+	attributeRepo := repository.NewAttributeRepository(db)
+	priceRepo := repository.NewPriceRepository(db)
+	pairingRepo := repository.NewPairingRepository(db)
+	inventoryRepo := repository.NewInventoryRepository(db)
+	locationRepo := repository.NewLocationRepository(db)
+	batchRepo := repository.NewBatchRepository(db)
+	alertRepo := repository.NewAlertRepository(db)
+	wishlistRepo := repository.NewWishlistRepository(db)
+	emailTemplateRepo := repository.NewEmailTemplateRepository(db)
 
 	// Initialize services
 	authService := services.NewAuthService(userRepo, tokenRepo, cfg.JWTSecret)
 	productService := services.NewProductService(productRepo)
-	orderService := services.NewOrderService(orderRepo, productRepo)
+	orderService := services.NewOrderService(orderRepo, productRepo, deliveryRepo)
 	regionService := services.NewRegionService(regionRepo, productRepo)
 	recommendationService := services.NewRecommendationService(productRepo)
 	paymentService := services.NewPaymentService(cfg, paymentRepo, orderRepo)
 	eventService := services.NewEventService(eventRepo)
 	aiService := services.NewAIService(cfg, productRepo)
+	deliveryService := services.NewDeliveryService(deliveryRepo)
+	emailService := services.NewEmailService(services.EmailConfig{
+		SMTPHost:     cfg.SMTPHost,
+		SMTPPort:     cfg.SMTPPort,
+		SMTPUser:     cfg.SMTPUser,
+		SMTPPassword: cfg.SMTPPassword,
+		SMTPFrom:     cfg.SMTPFrom,
+		BaseURL:      cfg.BaseURL,
+	})
+	emailService.SetLocalization(emailTemplateRepo, userRepo)
+	orderService.SetEmailService(emailService)
+	orderService.SetPricesIncludeTax(cfg.PricesIncludeTax)
+	paymentService.SetEmailService(emailService, userRepo)
+	subscriptionService := services.NewSubscriptionService(subscriptionRepo, productRepo, userRepo, orderService, paymentService, emailService)
+	orderEditService := services.NewOrderEditService(orderRepo, productRepo, paymentService)
+	cartService := services.NewCartService(cartRepo, productRepo)
+	categoryService := services.NewCategoryService(categoryRepo, productRepo)
+	productImportService := services.NewProductImportService(importJobRepo, productRepo, regionRepo)
+
+	// Initialize file storage
+	fileStorage, err := storage.New(storage.Config{
+		Backend:      cfg.StorageBackend,
+		LocalDir:     cfg.UploadDir,
+		LocalBaseURL: cfg.UploadBaseURL,
+		S3Endpoint:   cfg.S3Endpoint,
+		S3AccessKey:  cfg.S3AccessKey,
+		S3SecretKey:  cfg.S3SecretKey,
+		S3Bucket:     cfg.S3Bucket,
+		S3Region:     cfg.S3Region,
+		S3UseSSL:     cfg.S3UseSSL,
+		S3PublicURL:  cfg.S3PublicURL,
+	})
+	if err != nil {
+		log.Fatal("Failed to initialize storage:", err)
+	}
+	productImageService := services.NewProductImageService(productRepo, fileStorage, cfg.WebPEncoderPath)
+	productService.SetImageService(productImageService)
+	attributeService := services.NewAttributeService(attributeRepo, productRepo)
+	productService.SetAttributeService(attributeService)
+	saleService := services.NewSaleService(priceRepo, productRepo)
+	pairingService := services.NewPairingService(pairingRepo, productRepo)
+	recommendationService.SetPairingService(pairingService)
+	aiService.SetPairingService(pairingService)
+	bundleService := services.NewBundleService(productRepo)
+	inventoryService := services.NewInventoryService(inventoryRepo, productRepo, locationRepo)
+	locationService := services.NewLocationService(locationRepo, productRepo, orderRepo)
+	productService.SetLocationService(locationService)
+	orderService.SetLocationService(locationService)
+	batchService := services.NewBatchService(batchRepo, productRepo, inventoryService, saleService)
+	batchService.SetExpiryPolicy(services.ExpiryPolicy{
+		DiscountDays:    cfg.BatchDiscountDays,
+		DiscountPercent: cfg.BatchDiscountPercent,
+		WriteOff:        cfg.BatchAutoWriteOff,
+	})
+	alertService := services.NewAlertService(alertRepo, productRepo, userRepo, emailService)
+	alertService.SetDailyLimit(cfg.AlertDailyLimit)
+	wishlistService := services.NewWishlistService(wishlistRepo, productRepo, cartService, eventService)
+	emailTemplateService := services.NewEmailTemplateService(emailTemplateRepo)
+
+	// Imports running when the server stopped can't resume
+	if err := productImportService.FailInterruptedImports(); err != nil {
+		log.Printf("Failed to mark interrupted import jobs: %v", err)
+	}
+
+	// Start background jobs
+	subscriptionService.StartScheduler(cfg.SubscriptionCheckInterval)
+	productService.StartRankingRefresher(cfg.ProductRankingInterval)
+	saleService.StartScheduler(cfg.SaleCheckInterval)
+	batchService.StartScheduler(cfg.BatchCheckInterval)
+	alertService.StartScheduler(cfg.AlertCheckInterval)
 
 	// Initialize handlers
 	apiHandlers := handlers.NewHandlers(
@@ -69,6 +154,23 @@ func main() {
 		paymentService,
 		eventService,
 		aiService,
+		deliveryService,
+		subscriptionService,
+		orderEditService,
+		cartService,
+		categoryService,
+		productImportService,
+		productImageService,
+		attributeService,
+		saleService,
+		pairingService,
+		bundleService,
+		inventoryService,
+		locationService,
+		batchService,
+		alertService,
+		wishlistService,
+		emailTemplateService,
 	)
 
 	// Setup router
@@ -111,17 +213,42 @@ func setupRouter(h *handlers.Handlers, cfg *config.Config) *gin.Engine {
 	// Health check
 	router.GET("/health", h.Health)
 
+	// Uploaded files of the local storage backend
+	if cfg.StorageBackend == "" || cfg.StorageBackend == "local" {
+		router.Static("/uploads", cfg.UploadDir)
+	}
+
 	// API routes
 	api := router.Group("/api")
 	{
 		// Public routes
 		api.GET("/products", h.GetProducts)
 		api.GET("/products/:slug", h.GetProduct)
+		api.GET("/products/:slug/pairings", h.GetProductPairings)
+		api.POST("/products/:slug/alerts", middleware.OptionalAuthMiddleware(h.AuthService), h.CreateProductAlert)
+		api.GET("/alerts/unsubscribe/:token", h.GetProductAlertByToken)
+		api.POST("/alerts/unsubscribe/:token", h.UnsubscribeProductAlert)
+		api.POST("/alerts/confirm/:token", h.ConfirmProductAlert)
+		api.GET("/categories", h.GetCategories)
+		api.GET("/attributes", h.GetAttributes)
 		api.GET("/regions", h.GetRegions)
 		api.GET("/regions/:code/products", h.GetRegionProducts)
-		api.POST("/recommend", h.GetRecommendations)
+		api.POST("/recommend", middleware.OptionalAuthMiddleware(h.AuthService), h.GetRecommendations)
+		api.GET("/cheese-board", middleware.OptionalAuthMiddleware(h.AuthService), h.GetCheeseBoard)
 		api.POST("/events", h.TrackEvent)
-		api.POST("/ai/chat", h.AIChat)
+		api.POST("/ai/chat", middleware.OptionalAuthMiddleware(h.AuthService), h.AIChat)
+		api.GET("/delivery/slots", h.GetDeliverySlots)
+		api.GET("/subscription-boxes", h.GetSubscriptionBoxes)
+		api.GET("/wishlists/shared/:token", h.GetSharedWishlist)
+
+		// Guest checkout routes
+		guest := api.Group("/guest")
+		{
+			guest.POST("/orders", h.GuestCheckout)
+			guest.GET("/orders/:token", h.GetGuestOrder)
+			guest.POST("/orders/:token/payment", h.CreateGuestPayment)
+			guest.GET("/orders/:token/invoice", h.GetGuestOrderInvoice)
+		}
 
 		// Auth routes
 		auth := api.Group("/auth")
@@ -137,11 +264,33 @@ func setupRouter(h *handlers.Handlers, cfg *config.Config) *gin.Engine {
 		protected.Use(middleware.AuthMiddleware(h.AuthService))
 		{
 			protected.GET("/auth/me", h.GetMe)
+			protected.GET("/dietary-restrictions", h.GetDietaryRestrictions)
+			protected.PUT("/dietary-restrictions", h.UpdateDietaryRestrictions)
+			protected.PUT("/locale", h.UpdateLocale)
 			protected.GET("/cart", h.GetCart)
 			protected.POST("/cart", h.AddToCart)
 			protected.DELETE("/cart/:productId", h.RemoveFromCart)
+			protected.GET("/wishlists", h.GetWishlists)
+			protected.POST("/wishlists", h.CreateWishlist)
+			protected.GET("/wishlists/:id", h.GetWishlist)
+			protected.PUT("/wishlists/:id", h.UpdateWishlist)
+			protected.DELETE("/wishlists/:id", h.DeleteWishlist)
+			protected.POST("/wishlists/:id/items", h.AddWishlistItem)
+			protected.DELETE("/wishlists/:id/items/:itemId", h.RemoveWishlistItem)
+			protected.POST("/wishlists/:id/items/:itemId/cart", h.MoveWishlistItemToCart)
 			protected.GET("/orders", h.GetUserOrders)
 			protected.POST("/orders", h.CreateOrder)
+			protected.GET("/orders/:id/invoice", h.GetOrderInvoice)
+			protected.GET("/alerts", h.GetProductAlerts)
+			protected.DELETE("/alerts/:id", h.DeleteProductAlert)
+			protected.GET("/payment-methods", h.GetPaymentMethods)
+			protected.GET("/subscriptions", h.GetSubscriptions)
+			protected.POST("/subscriptions", h.CreateSubscription)
+			protected.PUT("/subscriptions/:id", h.UpdateSubscription)
+			protected.POST("/subscriptions/:id/pause", h.PauseSubscription)
+			protected.POST("/subscriptions/:id/resume", h.ResumeSubscription)
+			protected.POST("/subscriptions/:id/skip", h.SkipSubscriptionDelivery)
+			protected.POST("/subscriptions/:id/cancel", h.CancelSubscription)
 		}
 
 		// Admin routes
@@ -150,17 +299,94 @@ func setupRouter(h *handlers.Handlers, cfg *config.Config) *gin.Engine {
 		admin.Use(middleware.AdminMiddleware())
 		{
 			admin.GET("/products", h.AdminGetProducts)
+			admin.GET("/products/export", h.AdminExportProducts)
+			admin.POST("/products/import", h.AdminImportProducts)
+			admin.GET("/products/imports", h.AdminGetImportJobs)
+			admin.GET("/products/imports/:id", h.AdminGetImportJob)
 			admin.GET("/products/:id", h.AdminGetProduct)
 			admin.POST("/products", h.AdminCreateProduct)
 			admin.PUT("/products/:id", h.AdminUpdateProduct)
 			admin.PATCH("/products/:id/quantity", h.AdminUpdateProductQuantity)
 			admin.DELETE("/products/:id", h.AdminDeleteProduct)
+			admin.POST("/products/:id/restore", h.AdminRestoreProduct)
+			admin.DELETE("/products/:id/purge", h.AdminPurgeProduct)
+			admin.POST("/products/:id/variants", h.AdminCreateProductVariant)
+			admin.PUT("/products/:id/variants/:variantId", h.AdminUpdateProductVariant)
+			admin.DELETE("/products/:id/variants/:variantId", h.AdminDeleteProductVariant)
+			admin.GET("/products/:id/images", h.AdminGetProductImages)
+			admin.POST("/products/:id/images", h.AdminUploadProductImage)
+			admin.PUT("/products/:id/images/order", h.AdminReorderProductImages)
+			admin.DELETE("/products/:id/images/:imageId", h.AdminDeleteProductImage)
+			admin.GET("/products/:id/categories", h.AdminGetProductCategories)
+			admin.PUT("/products/:id/categories", h.AdminSetProductCategories)
+			admin.GET("/products/:id/attributes", h.AdminGetProductAttributes)
+			admin.PUT("/products/:id/attributes", h.AdminSetProductAttributes)
+			admin.GET("/products/:id/sales", h.AdminGetProductSales)
+			admin.POST("/products/:id/sales", h.AdminCreateProductSale)
+			admin.DELETE("/products/:id/sales/:saleId", h.AdminCancelProductSale)
+			admin.GET("/products/:id/price-history", h.AdminGetProductPriceHistory)
+			admin.GET("/products/:id/pairings", h.AdminGetProductPairings)
+			admin.POST("/products/:id/pairings", h.AdminCreateProductPairing)
+			admin.PUT("/products/:id/pairings/:pairingId", h.AdminUpdateProductPairing)
+			admin.DELETE("/products/:id/pairings/:pairingId", h.AdminDeleteProductPairing)
+			admin.GET("/products/:id/bundle", h.AdminGetProductBundle)
+			admin.PUT("/products/:id/bundle", h.AdminSetProductBundle)
+			admin.DELETE("/products/:id/bundle", h.AdminDeleteProductBundle)
+			admin.GET("/inventory/movements", h.AdminGetInventoryMovements)
+			admin.POST("/inventory/movements", h.AdminCreateInventoryMovement)
+			admin.GET("/inventory/stock", h.AdminGetLocationStock)
+			admin.GET("/inventory/transfers", h.AdminGetStockTransfers)
+			admin.POST("/inventory/transfers", h.AdminCreateStockTransfer)
+			admin.GET("/batches", h.AdminGetBatches)
+			admin.POST("/batches", h.AdminReceiveBatch)
+			admin.GET("/batches/expiring", h.AdminGetExpiringBatches)
+			admin.GET("/batches/:id", h.AdminGetBatch)
+			admin.GET("/batches/:id/orders", h.AdminGetBatchOrders)
+			admin.POST("/batches/:id/write-off", h.AdminWriteOffBatch)
+			admin.GET("/locations", h.AdminGetLocations)
+			admin.POST("/locations", h.AdminCreateLocation)
+			admin.PUT("/locations/:id", h.AdminUpdateLocation)
+			admin.GET("/stock-takes", h.AdminGetStockTakes)
+			admin.POST("/stock-takes", h.AdminCreateStockTake)
+			admin.GET("/stock-takes/:id", h.AdminGetStockTake)
+			admin.PUT("/stock-takes/:id/counts", h.AdminSetStockTakeCounts)
+			admin.POST("/stock-takes/:id/complete", h.AdminCompleteStockTake)
+			admin.DELETE("/stock-takes/:id", h.AdminCancelStockTake)
+			admin.GET("/categories", h.AdminGetCategories)
+			admin.POST("/categories", h.AdminCreateCategory)
+			admin.PUT("/categories/:id", h.AdminUpdateCategory)
+			admin.DELETE("/categories/:id", h.AdminDeleteCategory)
+			admin.GET("/regions", h.AdminGetRegions)
+			admin.PUT("/regions/:code", h.AdminUpdateRegion)
+			admin.GET("/email-templates", h.AdminGetEmailTemplates)
+			admin.PUT("/email-templates/:name/:locale", h.AdminSetEmailTemplate)
+			admin.DELETE("/email-templates/:name/:locale", h.AdminDeleteEmailTemplate)
+			admin.GET("/attributes", h.AdminGetAttributes)
+			admin.POST("/attributes", h.AdminCreateAttribute)
+			admin.PUT("/attributes/:id", h.AdminUpdateAttribute)
+			admin.DELETE("/attributes/:id", h.AdminDeleteAttribute)
 			admin.GET("/orders", h.AdminGetOrders)
 			admin.PATCH("/orders/:id/status", h.AdminUpdateOrderStatus)
+			admin.GET("/orders/:id/invoice", h.AdminGetOrderInvoice)
+			admin.GET("/orders/:id/history", h.AdminGetOrderHistory)
+			admin.PUT("/orders/:id/fulfilment-location", h.AdminSetOrderFulfilmentLocation)
+			admin.POST("/orders/:id/items", h.AdminAddOrderItem)
+			admin.PATCH("/orders/:id/items/:productId", h.AdminUpdateOrderItem)
+			admin.DELETE("/orders/:id/items/:productId", h.AdminRemoveOrderItem)
 			admin.GET("/users", h.AdminGetUsers)
 			admin.PATCH("/users/:id/role", h.AdminUpdateUserRole)
 			admin.PATCH("/users/:id/blocked", h.AdminUpdateUserBlocked)
 			admin.GET("/statistics", h.AdminGetStatistics)
+			admin.GET("/delivery/slots", h.AdminGetDeliverySlots)
+			admin.POST("/delivery/slots", h.AdminCreateDeliverySlot)
+			admin.PUT("/delivery/slots/:id", h.AdminUpdateDeliverySlot)
+			admin.DELETE("/delivery/slots/:id", h.AdminDeleteDeliverySlot)
+			admin.GET("/delivery/export", h.AdminExportDeliveries)
+			admin.GET("/subscriptions", h.AdminGetSubscriptions)
+			admin.GET("/subscription-boxes", h.AdminGetSubscriptionBoxes)
+			admin.POST("/subscription-boxes", h.AdminCreateSubscriptionBox)
+			admin.PUT("/subscription-boxes/:id", h.AdminUpdateSubscriptionBox)
+			admin.DELETE("/subscription-boxes/:id", h.AdminDeleteSubscriptionBox)
 		}
 
 		// Payment routes
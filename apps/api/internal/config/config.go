@@ -2,6 +2,8 @@ package config
 
 import (
 	"os"
+	"strconv"
+	"time"
 )
 
 type Config struct {
@@ -25,6 +27,35 @@ type Config struct {
 	SMTPPassword       string
 	SMTPFrom           string
 	BaseURL            string
+	// Prices entered for products already include VAT
+	PricesIncludeTax   bool
+	// Uploaded files: "local" (UploadDir served at /uploads) or "s3"
+	StorageBackend     string
+	UploadDir          string
+	UploadBaseURL      string
+	S3Endpoint         string
+	S3AccessKey        string
+	S3SecretKey        string
+	S3Bucket           string
+	S3Region           string
+	S3UseSSL           bool
+	S3PublicURL        string
+	// cwebp binary for WebP image variants; skipped when not installed
+	WebPEncoderPath    string
+	// Background jobs
+	SubscriptionCheckInterval time.Duration
+	ProductRankingInterval    time.Duration
+	SaleCheckInterval         time.Duration
+	BatchCheckInterval        time.Duration
+	// Batches are discounted by BatchDiscountPercent this many days before
+	// their best-before date (0 turns it off) and written off once expired
+	BatchDiscountDays         int
+	BatchDiscountPercent      int
+	BatchAutoWriteOff         bool
+	// Product alerts are emailed every AlertCheckInterval, at most
+	// AlertDailyLimit a day per email (0 doesn't limit)
+	AlertCheckInterval        time.Duration
+	AlertDailyLimit           int
 }
 
 func Load() *Config {
@@ -48,6 +79,27 @@ func Load() *Config {
 		SMTPPassword:       getEnv("SMTP_PASSWORD", ""),
 		SMTPFrom:           getEnv("SMTP_FROM", ""),
 		BaseURL:            getEnv("BASE_URL", "http://localhost:3001"),
+		PricesIncludeTax:   getEnvBool("PRICES_INCLUDE_TAX", true),
+		StorageBackend:     getEnv("STORAGE_BACKEND", "local"),
+		UploadDir:          getEnv("UPLOAD_DIR", "./uploads"),
+		UploadBaseURL:      getEnv("UPLOAD_BASE_URL", "http://localhost:8080/uploads"),
+		S3Endpoint:         getEnv("S3_ENDPOINT", ""),
+		S3AccessKey:        getEnv("S3_ACCESS_KEY", ""),
+		S3SecretKey:        getEnv("S3_SECRET_KEY", ""),
+		S3Bucket:           getEnv("S3_BUCKET", ""),
+		S3Region:           getEnv("S3_REGION", ""),
+		S3UseSSL:           getEnvBool("S3_USE_SSL", true),
+		S3PublicURL:        getEnv("S3_PUBLIC_URL", ""),
+		WebPEncoderPath:    getEnv("WEBP_ENCODER_PATH", "cwebp"),
+		SubscriptionCheckInterval: getEnvDuration("SUBSCRIPTION_CHECK_INTERVAL", 15*time.Minute),
+		ProductRankingInterval:    getEnvDuration("PRODUCT_RANKING_INTERVAL", 30*time.Minute),
+		SaleCheckInterval:         getEnvDuration("SALE_CHECK_INTERVAL", time.Minute),
+		BatchCheckInterval:        getEnvDuration("BATCH_CHECK_INTERVAL", time.Hour),
+		BatchDiscountDays:         getEnvInt("BATCH_DISCOUNT_DAYS", 3),
+		BatchDiscountPercent:      getEnvInt("BATCH_DISCOUNT_PERCENT", 30),
+		BatchAutoWriteOff:         getEnvBool("BATCH_AUTO_WRITE_OFF", true),
+		AlertCheckInterval:        getEnvDuration("ALERT_CHECK_INTERVAL", 5*time.Minute),
+		AlertDailyLimit:           getEnvInt("ALERT_DAILY_LIMIT", 5),
 	}
 }
 
@@ -64,3 +116,21 @@ func getEnvBool(key string, defaultValue bool) bool {
 	}
 	return defaultValue
 }
+
+func getEnvDuration(key string, defaultValue time.Duration) time.Duration {
+	if value := os.Getenv(key); value != "" {
+		if d, err := time.ParseDuration(value); err == nil && d > 0 {
+			return d
+		}
+	}
+	return defaultValue
+}
+
+func getEnvInt(key string, defaultValue int) int {
+	if value := os.Getenv(key); value != "" {
+		if i, err := strconv.Atoi(value); err == nil && i >= 0 {
+			return i
+		}
+	}
+	return defaultValue
+}
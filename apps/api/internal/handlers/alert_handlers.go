@@ -0,0 +1,108 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"gastroshop-api/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Product alert handlers
+
+// CreateProductAlert subscribes to a back-in-stock or price-drop alert on a
+// product. Signed-in customers are alerted at their account email, guests
+// at the email they give.
+func (h *Handlers) CreateProductAlert(c *gin.Context) {
+	var req models.CreateProductAlertRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid request"})
+		return
+	}
+
+	var userID *int
+	if id, exists := c.Get("user_id"); exists {
+		uid := id.(int)
+		userID = &uid
+	}
+
+	alert, err := h.AlertService.Subscribe(userID, c.Param("slug"), &req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, alert)
+}
+
+func (h *Handlers) GetProductAlerts(c *gin.Context) {
+	userID, ok := requireUserID(c)
+	if !ok {
+		return
+	}
+
+	alerts, err := h.AlertService.GetUserAlerts(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to get alerts"})
+		return
+	}
+
+	c.JSON(http.StatusOK, alerts)
+}
+
+func (h *Handlers) DeleteProductAlert(c *gin.Context) {
+	userID, ok := requireUserID(c)
+	if !ok {
+		return
+	}
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid alert ID"})
+		return
+	}
+
+	if err := h.AlertService.Unsubscribe(userID, id); err != nil {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{Error: "Alert not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Unsubscribed successfully"})
+}
+
+// GetProductAlertByToken shows the alert of an unsubscribe or confirmation
+// link before the customer acts on it
+func (h *Handlers) GetProductAlertByToken(c *gin.Context) {
+	alert, err := h.AlertService.GetAlertByToken(c.Param("token"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{Error: "Alert not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, alert)
+}
+
+// ConfirmProductAlert confirms the alert of a guest from the link emailed to
+// them
+func (h *Handlers) ConfirmProductAlert(c *gin.Context) {
+	alert, err := h.AlertService.ConfirmAlert(c.Param("token"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{Error: "Alert not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, alert)
+}
+
+// UnsubscribeProductAlert unsubscribes the alert of an alert email, from
+// the unsubscribe page or the one-click List-Unsubscribe POST; with all=true
+// it unsubscribes every alert of the email
+func (h *Handlers) UnsubscribeProductAlert(c *gin.Context) {
+	count, err := h.AlertService.UnsubscribeByToken(c.Param("token"), c.Query("all") == "true")
+	if err != nil {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{Error: "Alert not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Unsubscribed successfully", "unsubscribed": count})
+}
@@ -0,0 +1,195 @@
+package handlers
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gastroshop-api/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// attributeFilterPrefix starts the query keys of attribute filters
+const attributeFilterPrefix = "attr."
+
+// GetAttributes returns the product attributes for the catalog filters
+func (h *Handlers) GetAttributes(c *gin.Context) {
+	attributes, err := h.AttributeService.GetAttributes()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to get attributes"})
+		return
+	}
+
+	c.JSON(http.StatusOK, attributes)
+}
+
+// parseAttributeFilters reads attribute filters into the filters, responding
+// with 400 when they are invalid. Filters are given as attr.<code>=a,b and,
+// for numbers, as ranges with attr.<code>.min and attr.<code>.max.
+func (h *Handlers) parseAttributeFilters(c *gin.Context, filters map[string]interface{}) bool {
+	byCode := make(map[string]*models.AttributeFilter)
+	filterFor := func(code string) *models.AttributeFilter {
+		if byCode[code] == nil {
+			byCode[code] = &models.AttributeFilter{Code: code}
+		}
+		return byCode[code]
+	}
+
+	for key, values := range c.Request.URL.Query() {
+		if !strings.HasPrefix(key, attributeFilterPrefix) {
+			continue
+		}
+		code := strings.TrimPrefix(key, attributeFilterPrefix)
+
+		bound := ""
+		if strings.HasSuffix(code, ".min") || strings.HasSuffix(code, ".max") {
+			bound = code[len(code)-3:]
+			code = code[:len(code)-4]
+		}
+
+		if bound == "" {
+			f := filterFor(code)
+			f.Values = append(f.Values, splitQueryValues(values)...)
+			continue
+		}
+
+		number, err := strconv.ParseFloat(values[0], 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid attribute range " + key})
+			return false
+		}
+		if bound == "min" {
+			filterFor(code).Min = &number
+		} else {
+			filterFor(code).Max = &number
+		}
+	}
+
+	if len(byCode) == 0 {
+		return true
+	}
+
+	// Sorted by code so that the same filters build the same query
+	codes := make([]string, 0, len(byCode))
+	for code := range byCode {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+	attributeFilters := make([]models.AttributeFilter, len(codes))
+	for i, code := range codes {
+		attributeFilters[i] = *byCode[code]
+	}
+
+	if err := h.AttributeService.ResolveFilters(attributeFilters); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+		return false
+	}
+	filters["attributes"] = attributeFilters
+	return true
+}
+
+// Admin attribute handlers
+
+func (h *Handlers) AdminGetAttributes(c *gin.Context) {
+	attributes, err := h.AttributeService.GetAttributes()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to get attributes"})
+		return
+	}
+
+	c.JSON(http.StatusOK, attributes)
+}
+
+func (h *Handlers) AdminCreateAttribute(c *gin.Context) {
+	var req models.CreateProductAttributeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid request"})
+		return
+	}
+
+	attribute, err := h.AttributeService.CreateAttribute(&req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, attribute)
+}
+
+func (h *Handlers) AdminUpdateAttribute(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid attribute ID"})
+		return
+	}
+
+	var req models.UpdateProductAttributeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid request"})
+		return
+	}
+
+	attribute, err := h.AttributeService.UpdateAttribute(id, &req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, attribute)
+}
+
+func (h *Handlers) AdminDeleteAttribute(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid attribute ID"})
+		return
+	}
+
+	if err := h.AttributeService.DeleteAttribute(id); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Attribute deleted successfully"})
+}
+
+func (h *Handlers) AdminGetProductAttributes(c *gin.Context) {
+	productID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid product ID"})
+		return
+	}
+
+	values, err := h.AttributeService.GetProductAttributes(productID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, values)
+}
+
+// AdminSetProductAttributes replaces the attribute values of a product
+func (h *Handlers) AdminSetProductAttributes(c *gin.Context) {
+	productID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid product ID"})
+		return
+	}
+
+	var req models.SetProductAttributesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid request"})
+		return
+	}
+
+	values, err := h.AttributeService.SetProductAttributes(productID, req.Values)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, values)
+}
@@ -0,0 +1,199 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"gastroshop-api/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Admin stock batch handlers
+
+// batchFilters reads the location_id, product_id and lot_number filters and
+// the page of batch listings. It responds and returns false when a filter
+// is invalid.
+func batchFilters(c *gin.Context) (map[string]interface{}, bool) {
+	filters := make(map[string]interface{})
+
+	if locationID := c.Query("location_id"); locationID != "" {
+		id, err := strconv.Atoi(locationID)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid location ID"})
+			return nil, false
+		}
+		filters["location_id"] = id
+	}
+	if productID := c.Query("product_id"); productID != "" {
+		id, err := strconv.Atoi(productID)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid product ID"})
+			return nil, false
+		}
+		filters["product_id"] = id
+	}
+	if lotNumber := c.Query("lot_number"); lotNumber != "" {
+		filters["lot_number"] = lotNumber
+	}
+
+	page := 1
+	if p := c.Query("page"); p != "" {
+		if val, err := strconv.Atoi(p); err == nil && val > 0 {
+			page = val
+		}
+	}
+	filters["page"] = page
+
+	pageSize := 50
+	if ps := c.Query("limit"); ps != "" {
+		if val, err := strconv.Atoi(ps); err == nil && val > 0 && val <= 200 {
+			pageSize = val
+		}
+	}
+	filters["page_size"] = pageSize
+
+	return filters, true
+}
+
+// AdminGetBatches lists stock batches, expiring first, filtered by
+// location_id, product_id, lot_number and in_stock
+func (h *Handlers) AdminGetBatches(c *gin.Context) {
+	filters, ok := batchFilters(c)
+	if !ok {
+		return
+	}
+	if inStock := c.Query("in_stock"); inStock != "" {
+		filters["in_stock"] = inStock == "true"
+	}
+
+	batches, total, err := h.BatchService.GetBatches(filters)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to get batches"})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.PaginatedResponse{
+		Items:    batches,
+		Total:    total,
+		Page:     filters["page"].(int),
+		PageSize: filters["page_size"].(int),
+	})
+}
+
+// AdminGetExpiringBatches reports the batches with stock left that expire
+// within days (7 by default) or have expired, expiring first
+func (h *Handlers) AdminGetExpiringBatches(c *gin.Context) {
+	filters, ok := batchFilters(c)
+	if !ok {
+		return
+	}
+
+	days := 7
+	if d := c.Query("days"); d != "" {
+		val, err := strconv.Atoi(d)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid days"})
+			return
+		}
+		days = val
+	}
+
+	batches, total, err := h.BatchService.GetExpiringBatches(days, filters)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.PaginatedResponse{
+		Items:    batches,
+		Total:    total,
+		Page:     filters["page"].(int),
+		PageSize: filters["page_size"].(int),
+	})
+}
+
+func (h *Handlers) AdminGetBatch(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid batch ID"})
+		return
+	}
+
+	batch, err := h.BatchService.GetBatch(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, batch)
+}
+
+// AdminReceiveBatch receives a batch at a location, adding it to the stock
+func (h *Handlers) AdminReceiveBatch(c *gin.Context) {
+	adminID, ok := requireUserID(c)
+	if !ok {
+		return
+	}
+
+	var req models.CreateStockBatchRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid request"})
+		return
+	}
+
+	batch, err := h.BatchService.ReceiveBatch(adminID, &req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, batch)
+}
+
+// AdminWriteOffBatch writes off the stock left of a batch
+func (h *Handlers) AdminWriteOffBatch(c *gin.Context) {
+	adminID, ok := requireUserID(c)
+	if !ok {
+		return
+	}
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid batch ID"})
+		return
+	}
+
+	var req models.WriteOffStockBatchRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid request"})
+			return
+		}
+	}
+
+	movement, err := h.BatchService.WriteOffBatch(adminID, id, req.Note)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, movement)
+}
+
+// AdminGetBatchOrders lists the orders sold with stock of the lot of a
+// batch, for recalls
+func (h *Handlers) AdminGetBatchOrders(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid batch ID"})
+		return
+	}
+
+	orders, err := h.BatchService.GetLotOrders(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, orders)
+}
@@ -0,0 +1,67 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"gastroshop-api/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Admin bundle handlers
+
+func (h *Handlers) AdminGetProductBundle(c *gin.Context) {
+	productID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid product ID"})
+		return
+	}
+
+	bundle, err := h.BundleService.GetBundle(productID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, bundle)
+}
+
+// AdminSetProductBundle makes a product a bundle or replaces its components
+func (h *Handlers) AdminSetProductBundle(c *gin.Context) {
+	productID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid product ID"})
+		return
+	}
+
+	var req models.SetProductBundleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid request"})
+		return
+	}
+
+	bundle, err := h.BundleService.SetBundle(productID, &req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, bundle)
+}
+
+// AdminDeleteProductBundle turns a bundle back into a regular product
+func (h *Handlers) AdminDeleteProductBundle(c *gin.Context) {
+	productID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid product ID"})
+		return
+	}
+
+	if err := h.BundleService.DeleteBundle(productID); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Bundle deleted successfully"})
+}
@@ -0,0 +1,72 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"gastroshop-api/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Cart handlers
+
+func (h *Handlers) GetCart(c *gin.Context) {
+	userID, ok := requireUserID(c)
+	if !ok {
+		return
+	}
+
+	cart, err := h.CartService.GetCart(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to get cart"})
+		return
+	}
+
+	c.JSON(http.StatusOK, cart)
+}
+
+func (h *Handlers) AddToCart(c *gin.Context) {
+	userID, ok := requireUserID(c)
+	if !ok {
+		return
+	}
+
+	var req models.AddToCartRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid request"})
+		return
+	}
+
+	cart, err := h.CartService.AddItem(userID, &req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, cart)
+}
+
+func (h *Handlers) RemoveFromCart(c *gin.Context) {
+	userID, ok := requireUserID(c)
+	if !ok {
+		return
+	}
+	productID, err := strconv.Atoi(c.Param("productId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid product ID"})
+		return
+	}
+	variantID, ok := optionalVariantID(c)
+	if !ok {
+		return
+	}
+
+	cart, err := h.CartService.RemoveItem(userID, productID, variantID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, cart)
+}
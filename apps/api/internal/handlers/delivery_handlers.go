@@ -0,0 +1,176 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"gastroshop-api/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Delivery slot handlers
+
+func (h *Handlers) GetDeliverySlots(c *gin.Context) {
+	date, err := parseDeliveryDate(c.Query("date"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid date, expected YYYY-MM-DD"})
+		return
+	}
+
+	slots, err := h.DeliveryService.GetAvailableSlots(c.Query("zone"), date)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to get delivery slots"})
+		return
+	}
+
+	c.JSON(http.StatusOK, slots)
+}
+
+// Admin delivery handlers
+
+func (h *Handlers) AdminGetDeliverySlots(c *gin.Context) {
+	date, err := parseDeliveryDate(c.Query("date"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid date, expected YYYY-MM-DD"})
+		return
+	}
+
+	slots, err := h.DeliveryService.GetSlots(c.Query("zone"), date)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to get delivery slots"})
+		return
+	}
+
+	c.JSON(http.StatusOK, slots)
+}
+
+func (h *Handlers) AdminCreateDeliverySlot(c *gin.Context) {
+	var req models.CreateDeliverySlotRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid request"})
+		return
+	}
+
+	slot, err := h.DeliveryService.CreateSlot(&req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, slot)
+}
+
+func (h *Handlers) AdminUpdateDeliverySlot(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid slot ID"})
+		return
+	}
+
+	var req models.UpdateDeliverySlotRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid request"})
+		return
+	}
+
+	slot, err := h.DeliveryService.UpdateSlot(id, &req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, slot)
+}
+
+func (h *Handlers) AdminDeleteDeliverySlot(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid slot ID"})
+		return
+	}
+
+	if err := h.DeliveryService.DeleteSlot(id); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Delivery slot deleted successfully"})
+}
+
+// AdminExportDeliveries returns the courier run sheet for a day as CSV
+func (h *Handlers) AdminExportDeliveries(c *gin.Context) {
+	date, err := parseDeliveryDate(c.Query("date"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid date, expected YYYY-MM-DD"})
+		return
+	}
+	zone := c.Query("zone")
+
+	orders, err := h.OrderService.GetDeliveryOrders(date, zone)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to get delivery orders"})
+		return
+	}
+
+	filename := fmt.Sprintf("deliveries-%s.csv", date.Format("2006-01-02"))
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	c.Status(http.StatusOK)
+
+	w := csv.NewWriter(c.Writer)
+	w.Write([]string{
+		"order_id", "status", "zone", "slot_start", "slot_end",
+		"name", "phone", "address", "city", "notes", "items", "amount",
+	})
+	for _, order := range orders {
+		var slotZone, slotStart, slotEnd string
+		if order.DeliverySlot != nil {
+			slotZone = order.DeliverySlot.Zone
+			slotStart = order.DeliverySlot.StartsAt.Format("15:04")
+			slotEnd = order.DeliverySlot.EndsAt.Format("15:04")
+		}
+
+		itemCount := 0
+		for _, item := range order.Items {
+			itemCount += item.Quantity
+		}
+
+		addr := order.ShippingAddress
+		name := strings.TrimSpace(addressField(addr, "firstName") + " " + addressField(addr, "lastName"))
+		w.Write([]string{
+			strconv.Itoa(order.ID),
+			order.Status,
+			slotZone,
+			slotStart,
+			slotEnd,
+			name,
+			addressField(addr, "phone"),
+			addressField(addr, "address"),
+			addressField(addr, "city"),
+			addressField(addr, "notes"),
+			strconv.Itoa(itemCount),
+			fmt.Sprintf("%.2f", float64(order.AmountCents)/100),
+		})
+	}
+	w.Flush()
+}
+
+// parseDeliveryDate parses YYYY-MM-DD, defaulting to today
+func parseDeliveryDate(value string) (time.Time, error) {
+	if value == "" {
+		return time.Now().UTC(), nil
+	}
+	return time.ParseInLocation("2006-01-02", value, time.UTC)
+}
+
+func addressField(address map[string]interface{}, key string) string {
+	if value, ok := address[key]; ok && value != nil {
+		return fmt.Sprintf("%v", value)
+	}
+	return ""
+}
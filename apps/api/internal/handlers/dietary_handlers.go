@@ -0,0 +1,64 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+
+	"gastroshop-api/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetDietaryRestrictions returns the dietary exclusions saved by the user
+func (h *Handlers) GetDietaryRestrictions(c *gin.Context) {
+	userID, ok := requireUserID(c)
+	if !ok {
+		return
+	}
+
+	restrictions, err := h.AuthService.GetDietaryRestrictions(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to get dietary restrictions"})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.DietaryRestrictions{Restrictions: restrictions})
+}
+
+// UpdateDietaryRestrictions replaces the dietary exclusions of the user
+func (h *Handlers) UpdateDietaryRestrictions(c *gin.Context) {
+	userID, ok := requireUserID(c)
+	if !ok {
+		return
+	}
+
+	var req models.DietaryRestrictions
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid request"})
+		return
+	}
+
+	restrictions, err := h.AuthService.SetDietaryRestrictions(userID, req.Restrictions)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.DietaryRestrictions{Restrictions: restrictions})
+}
+
+// dietaryRestrictions returns the saved dietary exclusions of the signed-in
+// user, none for guests. Failures are logged so suggestions still work.
+func (h *Handlers) dietaryRestrictions(c *gin.Context) []string {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		return nil
+	}
+
+	restrictions, err := h.AuthService.GetDietaryRestrictions(userID.(int))
+	if err != nil {
+		log.Printf("Failed to get dietary restrictions of user %d: %v", userID, err)
+		return nil
+	}
+	return restrictions
+}
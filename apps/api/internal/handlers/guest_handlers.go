@@ -0,0 +1,87 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+
+	"gastroshop-api/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Guest checkout handlers
+
+// GuestCheckout creates an order and its payment without an account. The
+// returned access token is the only way for the guest to view the order.
+func (h *Handlers) GuestCheckout(c *gin.Context) {
+	var req models.GuestCheckoutRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid request"})
+		return
+	}
+
+	order, err := h.OrderService.CreateGuestOrder(req.Email, req.Phone, req.Items, req.ShippingAddress, req.DeliverySlotID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	response := models.GuestCheckoutResponse{
+		Order:       order,
+		AccessToken: order.AccessToken,
+	}
+
+	// The order stays valid without a payment: the guest can retry via the token
+	payment, err := h.PaymentService.CreatePayment(order)
+	if err != nil {
+		log.Printf("Failed to create payment for guest order %d: %v", order.ID, err)
+	} else {
+		response.PaymentID = payment.PaymentID
+		response.PaymentURL = payment.PaymentURL
+		order.PaymentID = payment.PaymentID
+	}
+
+	c.JSON(http.StatusCreated, response)
+}
+
+func (h *Handlers) GetGuestOrder(c *gin.Context) {
+	order, err := h.OrderService.GetOrderByAccessToken(c.Param("token"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to get order"})
+		return
+	}
+	if order == nil {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{Error: "Order not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, order)
+}
+
+// CreateGuestPayment issues a new payment link for an unpaid guest order
+func (h *Handlers) CreateGuestPayment(c *gin.Context) {
+	order, err := h.OrderService.GetOrderByAccessToken(c.Param("token"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to get order"})
+		return
+	}
+	if order == nil {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{Error: "Order not found"})
+		return
+	}
+	if order.Status != "pending" {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Order is not awaiting payment"})
+		return
+	}
+
+	payment, err := h.PaymentService.CreatePayment(order)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to create payment: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"payment_id":  payment.PaymentID,
+		"payment_url": payment.PaymentURL,
+	})
+}
@@ -1,10 +1,11 @@
 package handlers
 
 import (
-	"fmt"
 	"log"
 	"net/http"
+	"sort"
 	"strconv"
+	"strings"
 	"time"
 
 	"gastroshop-api/internal/models"
@@ -45,6 +46,23 @@ type Handlers struct {
 	PaymentService        *services.PaymentService
 	EventService          *services.EventService
 	AIService             *services.AIService
+	DeliveryService       *services.DeliveryService
+	SubscriptionService   *services.SubscriptionService
+	OrderEditService      *services.OrderEditService
+	CartService           *services.CartService
+	CategoryService       *services.CategoryService
+	ProductImportService  *services.ProductImportService
+	ProductImageService   *services.ProductImageService
+	AttributeService      *services.AttributeService
+	SaleService           *services.SaleService
+	PairingService        *services.PairingService
+	BundleService         *services.BundleService
+	InventoryService      *services.InventoryService
+	LocationService       *services.LocationService
+	BatchService          *services.BatchService
+	AlertService          *services.AlertService
+	WishlistService       *services.WishlistService
+	EmailTemplateService  *services.EmailTemplateService
 }
 
 func NewHandlers(
@@ -56,6 +74,23 @@ func NewHandlers(
 	paymentService *services.PaymentService,
 	eventService *services.EventService,
 	aiService *services.AIService,
+	deliveryService *services.DeliveryService,
+	subscriptionService *services.SubscriptionService,
+	orderEditService *services.OrderEditService,
+	cartService *services.CartService,
+	categoryService *services.CategoryService,
+	productImportService *services.ProductImportService,
+	productImageService *services.ProductImageService,
+	attributeService *services.AttributeService,
+	saleService *services.SaleService,
+	pairingService *services.PairingService,
+	bundleService *services.BundleService,
+	inventoryService *services.InventoryService,
+	locationService *services.LocationService,
+	batchService *services.BatchService,
+	alertService *services.AlertService,
+	wishlistService *services.WishlistService,
+	emailTemplateService *services.EmailTemplateService,
 ) *Handlers {
 	return &Handlers{
 		AuthService:           authService,
@@ -66,9 +101,60 @@ func NewHandlers(
 		PaymentService:        paymentService,
 		EventService:          eventService,
 		AIService:             aiService,
+		DeliveryService:       deliveryService,
+		SubscriptionService:   subscriptionService,
+		OrderEditService:      orderEditService,
+		CartService:           cartService,
+		CategoryService:       categoryService,
+		ProductImportService:  productImportService,
+		ProductImageService:   productImageService,
+		AttributeService:      attributeService,
+		SaleService:           saleService,
+		PairingService:        pairingService,
+		BundleService:         bundleService,
+		InventoryService:      inventoryService,
+		LocationService:       locationService,
+		BatchService:          batchService,
+		AlertService:          alertService,
+		WishlistService:       wishlistService,
+		EmailTemplateService:  emailTemplateService,
 	}
 }
 
+// splitQueryValues splits comma-separated query values, so that lists can
+// be given as a=x,y as well as a=x&a=y
+func splitQueryValues(values []string) []string {
+	var result []string
+	for _, value := range values {
+		for _, v := range strings.Split(value, ",") {
+			if v = strings.TrimSpace(v); v != "" {
+				result = append(result, v)
+			}
+		}
+	}
+	return result
+}
+
+// requireUserID returns the authenticated user's ID, responding with 401 when missing
+func requireUserID(c *gin.Context) (int, bool) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, models.ErrorResponse{Error: "User not authenticated"})
+		return 0, false
+	}
+	return userID.(int), true
+}
+
+// requestLocale returns the locale of the catalog texts of a request, from
+// the lang query parameter or the Accept-Language header, and labels the
+// response with it
+func requestLocale(c *gin.Context) string {
+	locale := services.NegotiateLocale(c.Query("lang"), c.GetHeader("Accept-Language"))
+	c.Header("Content-Language", locale)
+	c.Header("Vary", "Accept-Language")
+	return locale
+}
+
 func (h *Handlers) Health(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"status": "ok"})
 }
@@ -130,6 +216,17 @@ func (h *Handlers) GetProducts(c *gin.Context) {
 			filters["in_stock"] = val
 		}
 	}
+	// Stock at the locations delivering to the customer's zone
+	if zone := c.Query("zone"); zone != "" {
+		filters["zone"] = zone
+	}
+	if exclusions := splitQueryValues(c.QueryArray("exclude")); len(exclusions) > 0 {
+		if err := services.ValidateDietaryExclusions(exclusions); err != nil {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+			return
+		}
+		services.ApplyDietaryExclusions(filters, exclusions)
+	}
 
 	page := 1
 	if p := c.Query("page"); p != "" {
@@ -147,22 +244,72 @@ func (h *Handlers) GetProducts(c *gin.Context) {
 	}
 	filters["page_size"] = pageSize
 
-	products, total, err := h.ProductService.GetProducts(filters)
+	if !h.parseAttributeFilters(c, filters) {
+		return
+	}
+	if !parseProductOrder(c, filters) {
+		return
+	}
+
+	result, err := h.ProductService.GetProducts(filters)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to get products"})
 		return
 	}
+	services.LocalizeProducts(result.Products, requestLocale(c))
 
-	response := models.PaginatedResponse{
-		Items:    products,
-		Total:    total,
-		Page:     page,
-		PageSize: pageSize,
+	response := models.ProductListResponse{
+		PaginatedResponse: models.PaginatedResponse{
+			Items:      result.Products,
+			Total:      result.Total,
+			Page:       page,
+			PageSize:   pageSize,
+			NextCursor: services.EncodeProductCursor(result.Next),
+		},
+	}
+
+	if withFacets, _ := strconv.ParseBool(c.Query("facets")); withFacets {
+		facets, err := h.ProductService.GetProductFacets(filters)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to get product facets"})
+			return
+		}
+		response.Facets = facets
 	}
 
 	c.JSON(http.StatusOK, response)
 }
 
+// parseProductOrder reads the sort mode and the cursor of a product list
+// request into the filters, responding with 400 when they are invalid.
+// Search results are sorted by relevance by default; without a search query
+// relevance falls back to newest.
+func parseProductOrder(c *gin.Context, filters map[string]interface{}) bool {
+	_, searching := filters["query"]
+	sort := c.Query("sort")
+	if sort == "" || (sort == models.ProductSortRelevance && !searching) {
+		sort = models.ProductSortNewest
+		if searching {
+			sort = models.ProductSortRelevance
+		}
+	}
+	if !services.ValidProductSort(sort) {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid sort"})
+		return false
+	}
+	filters["sort"] = sort
+
+	if value := c.Query("cursor"); value != "" {
+		cursor, err := services.ParseProductCursor(value, sort)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+			return false
+		}
+		filters["cursor"] = cursor
+	}
+	return true
+}
+
 func (h *Handlers) GetProduct(c *gin.Context) {
 	slug := c.Param("slug")
 
@@ -171,10 +318,17 @@ func (h *Handlers) GetProduct(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to get product"})
 		return
 	}
-	if product == nil {
+	if product == nil || product.ArchivedAt != nil {
 		c.JSON(http.StatusNotFound, models.ErrorResponse{Error: "Product not found"})
 		return
 	}
+	if zone := c.Query("zone"); zone != "" {
+		if err := h.ProductService.ScopeStockToZone(zone, product); err != nil {
+			c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to get product"})
+			return
+		}
+	}
+	services.LocalizeProduct(product, requestLocale(c))
 
 	c.JSON(http.StatusOK, product)
 }
@@ -187,6 +341,7 @@ func (h *Handlers) GetRegions(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to get regions"})
 		return
 	}
+	services.LocalizeRegions(regions, requestLocale(c))
 	c.JSON(http.StatusOK, regions)
 }
 
@@ -198,6 +353,7 @@ func (h *Handlers) GetRegionProducts(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to get region products"})
 		return
 	}
+	services.LocalizeProducts(products, requestLocale(c))
 	c.JSON(http.StatusOK, products)
 }
 
@@ -220,6 +376,20 @@ func (h *Handlers) Register(c *gin.Context) {
 		return
 	}
 
+	// Emails are sent in the language the customer signed up in
+	if locale := services.NegotiateLocale(c.Query("lang"), c.GetHeader("Accept-Language")); locale != models.DefaultLocale {
+		if err := h.AuthService.SetLocale(user.ID, locale); err != nil {
+			log.Printf("Failed to set locale of user %d: %v", user.ID, err)
+		}
+	}
+
+	// Orders placed as a guest with this email now belong to the account
+	if attached, err := h.OrderService.AttachGuestOrders(user.Email, user.ID); err != nil {
+		log.Printf("Failed to attach guest orders to user %d: %v", user.ID, err)
+	} else if attached > 0 {
+		log.Printf("Attached %d guest orders to user %d", attached, user.ID)
+	}
+
 	// Generate tokens after registration
 	accessToken, err := h.AuthService.GenerateAccessToken(user.ID)
 	if err != nil {
@@ -336,7 +506,7 @@ func (h *Handlers) GetRecommendations(c *gin.Context) {
 		return
 	}
 
-	products, err := h.RecommendationService.GetRecommendations(req.Query, req.Tags)
+	products, err := h.RecommendationService.GetRecommendations(req.Query, req.Tags, h.dietaryRestrictions(c))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to get recommendations"})
 		return
@@ -345,20 +515,6 @@ func (h *Handlers) GetRecommendations(c *gin.Context) {
 	c.JSON(http.StatusOK, products)
 }
 
-// -------------------- Cart (упрощённо) --------------------
-
-func (h *Handlers) GetCart(c *gin.Context) {
-	c.JSON(http.StatusOK, gin.H{"items": []interface{}{}})
-}
-
-func (h *Handlers) AddToCart(c *gin.Context) {
-	c.JSON(http.StatusOK, gin.H{"message": "Item added to cart"})
-}
-
-func (h *Handlers) RemoveFromCart(c *gin.Context) {
-	c.JSON(http.StatusOK, gin.H{"message": "Item removed from cart"})
-}
-
 // -------------------- Orders --------------------
 
 func (h *Handlers) GetUserOrders(c *gin.Context) {
@@ -395,7 +551,7 @@ func (h *Handlers) CreateOrder(c *gin.Context) {
 	}
 
 	userIDInt := userID.(int)
-	order, err := h.OrderService.CreateOrder(&userIDInt, req.Items, req.ShippingAddress)
+	order, err := h.OrderService.CreateOrder(&userIDInt, req.Items, req.ShippingAddress, req.DeliverySlotID)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
 		return
@@ -504,6 +660,12 @@ func (h *Handlers) YooKassaWebhook(c *gin.Context) {
 		return
 	}
 
+	// Supplementary payments settle an order edit and don't change the order
+	if webhookData.Supplementary {
+		c.JSON(http.StatusOK, gin.H{"message": "Webhook processed successfully"})
+		return
+	}
+
 	// Update order status based on payment status
 	switch webhookData.Status {
 	case "succeeded":
@@ -571,7 +733,7 @@ func (h *Handlers) AIChat(c *gin.Context) {
 		conversationHistory = []services.ChatMessage{}
 	}
 
-	response, err := h.AIService.ChatWithAI(req.Message, conversationHistory)
+	response, err := h.AIService.ChatWithAI(req.Message, conversationHistory, h.dietaryRestrictions(c))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to process AI request"})
 		return
@@ -606,17 +768,30 @@ func (h *Handlers) AdminGetProducts(c *gin.Context) {
 	}
 	filters["page_size"] = pageSize
 
-	products, total, err := h.ProductService.GetProducts(filters)
+	// archived=true lists the archived products instead of the live ones
+	if archived, _ := strconv.ParseBool(c.Query("archived")); archived {
+		filters["archived"] = true
+	}
+
+	if !h.parseAttributeFilters(c, filters) {
+		return
+	}
+	if !parseProductOrder(c, filters) {
+		return
+	}
+
+	result, err := h.ProductService.GetProducts(filters)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to get products"})
 		return
 	}
 
 	response := models.PaginatedResponse{
-		Items:    products,
-		Total:    total,
-		Page:     page,
-		PageSize: pageSize,
+		Items:      result.Products,
+		Total:      result.Total,
+		Page:       page,
+		PageSize:   pageSize,
+		NextCursor: services.EncodeProductCursor(result.Next),
 	}
 
 	c.JSON(http.StatusOK, response)
@@ -644,6 +819,10 @@ func (h *Handlers) AdminGetProduct(c *gin.Context) {
 }
 
 func (h *Handlers) AdminCreateProduct(c *gin.Context) {
+	adminID, ok := requireUserID(c)
+	if !ok {
+		return
+	}
 	var req models.CreateProductRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid request"})
@@ -658,23 +837,58 @@ func (h *Handlers) AdminCreateProduct(c *gin.Context) {
 	}
 
 	product := &models.Product{
-		Slug:        req.Slug,
-		Title:       req.Title,
-		Description: req.Description,
-		PriceCents:  req.PriceCents,
-		Currency:    req.Currency,
-		Tags:        req.Tags,
-		RegionCode:  req.RegionCode,
-		Images:      req.Images,
-		Quantity:    req.Quantity,
-		InStock:     req.Quantity > 0,
+		Slug:         req.Slug,
+		Title:        req.Title,
+		Description:  req.Description,
+		PriceCents:   req.PriceCents,
+		Currency:     req.Currency,
+		Tags:         req.Tags,
+		RegionCode:   req.RegionCode,
+		Images:       req.Images,
+		Quantity:     req.Quantity,
+		InStock:      req.Quantity > 0,
+		TaxCategory:  req.TaxCategory,
+		Unit:         req.Unit,
+		MinQuantity:  req.MinQuantity,
+		QuantityStep: req.QuantityStep,
+		DietaryFlags: req.DietaryFlags,
+	}
+	translations, err := services.MergeTranslations(nil, req.Translations)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+		return
 	}
+	product.Translations = translations
 
 	if product.Currency == "" {
 		product.Currency = "RUB"
 	}
+	if product.TaxCategory == "" {
+		product.TaxCategory = models.TaxCategoryVAT20
+	}
+	if !services.ValidTaxCategory(product.TaxCategory) {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid tax category"})
+		return
+	}
+	if product.Unit == "" {
+		product.Unit = models.UnitPiece
+	}
+	if product.MinQuantity == 0 {
+		product.MinQuantity = 1
+	}
+	if product.QuantityStep == 0 {
+		product.QuantityStep = 1
+	}
+	if err := services.ValidateQuantityRules(product.Unit, product.MinQuantity, product.QuantityStep); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+	if err := services.ValidateDietaryFlags(product.DietaryFlags); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+		return
+	}
 
-	if err := h.ProductService.CreateProduct(product); err != nil {
+	if err := h.ProductService.CreateProduct(product, adminID); err != nil {
 		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to create product"})
 		return
 	}
@@ -683,6 +897,10 @@ func (h *Handlers) AdminCreateProduct(c *gin.Context) {
 }
 
 func (h *Handlers) AdminUpdateProduct(c *gin.Context) {
+	adminID, ok := requireUserID(c)
+	if !ok {
+		return
+	}
 	idStr := c.Param("id")
 	id, err := strconv.Atoi(idStr)
 	if err != nil {
@@ -715,6 +933,16 @@ func (h *Handlers) AdminUpdateProduct(c *gin.Context) {
 		existing.Description = *req.Description
 	}
 	if req.PriceCents != nil {
+		// The scheduler restores the regular price when the sale ends
+		if existing.CompareAtPriceCents != nil && *req.PriceCents != existing.PriceCents {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Price can't be changed while the product is on sale"})
+			return
+		}
+		// Discount bundles are priced from their components
+		if existing.Bundle != nil && existing.Bundle.Pricing == models.BundlePricingDiscount && *req.PriceCents != existing.PriceCents {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Price of a discount bundle follows its components"})
+			return
+		}
 		existing.PriceCents = *req.PriceCents
 	}
 	if req.Currency != nil {
@@ -729,6 +957,10 @@ func (h *Handlers) AdminUpdateProduct(c *gin.Context) {
 	if req.Images != nil {
 		existing.Images = req.Images
 	}
+	if existing.Bundle != nil && (req.InStock != nil || req.Quantity != nil) {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Bundle stock follows its components"})
+		return
+	}
 	if req.InStock != nil {
 		existing.InStock = *req.InStock
 	}
@@ -736,8 +968,45 @@ func (h *Handlers) AdminUpdateProduct(c *gin.Context) {
 		existing.Quantity = *req.Quantity
 		existing.InStock = existing.Quantity > 0
 	}
+	if req.TaxCategory != nil {
+		if !services.ValidTaxCategory(*req.TaxCategory) {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid tax category"})
+			return
+		}
+		existing.TaxCategory = *req.TaxCategory
+	}
+	if req.Unit != nil {
+		if existing.Bundle != nil && *req.Unit != models.UnitPiece {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Bundles must be sold by the piece"})
+			return
+		}
+		existing.Unit = *req.Unit
+	}
+	if req.MinQuantity != nil {
+		existing.MinQuantity = *req.MinQuantity
+	}
+	if req.QuantityStep != nil {
+		existing.QuantityStep = *req.QuantityStep
+	}
+	if req.DietaryFlags != nil {
+		if err := services.ValidateDietaryFlags(req.DietaryFlags); err != nil {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+			return
+		}
+		existing.DietaryFlags = req.DietaryFlags
+	}
+	if req.Translations != nil {
+		if existing.Translations, err = services.MergeTranslations(existing.Translations, req.Translations); err != nil {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+			return
+		}
+	}
+	if err := services.ValidateQuantityRules(existing.Unit, existing.MinQuantity, existing.QuantityStep); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+		return
+	}
 
-	if err := h.ProductService.UpdateProduct(id, existing); err != nil {
+	if err := h.ProductService.UpdateProduct(id, existing, adminID); err != nil {
 		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to update product"})
 		return
 	}
@@ -746,6 +1015,10 @@ func (h *Handlers) AdminUpdateProduct(c *gin.Context) {
 }
 
 func (h *Handlers) AdminUpdateProductQuantity(c *gin.Context) {
+	adminID, ok := requireUserID(c)
+	if !ok {
+		return
+	}
 	idStr := c.Param("id")
 	id, err := strconv.Atoi(idStr)
 	if err != nil {
@@ -759,12 +1032,9 @@ func (h *Handlers) AdminUpdateProductQuantity(c *gin.Context) {
 		return
 	}
 
-	fmt.Printf("DEBUG: AdminUpdateProductQuantity - id: %d, quantity: %d\n", id, req.Quantity)
-
 	// Check if product exists
 	existing, err := h.ProductService.GetProductByID(id)
 	if err != nil {
-		fmt.Printf("DEBUG: AdminUpdateProductQuantity - error checking product: %v\n", err)
 		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to check product: " + err.Error()})
 		return
 	}
@@ -772,10 +1042,17 @@ func (h *Handlers) AdminUpdateProductQuantity(c *gin.Context) {
 		c.JSON(http.StatusNotFound, models.ErrorResponse{Error: "Product not found"})
 		return
 	}
+	if len(existing.Variants) > 0 {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Product has variants, update the variant quantity instead"})
+		return
+	}
+	if existing.Bundle != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Product is a bundle, update the component quantities instead"})
+		return
+	}
 
 	// Update quantity
-	if err := h.ProductService.UpdateProductQuantity(id, req.Quantity); err != nil {
-		fmt.Printf("DEBUG: AdminUpdateProductQuantity error: %v\n", err)
+	if err := h.ProductService.UpdateProductQuantity(id, req.Quantity, adminID, req.Note); err != nil {
 		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to update product quantity: " + err.Error()})
 		return
 	}
@@ -783,15 +1060,15 @@ func (h *Handlers) AdminUpdateProductQuantity(c *gin.Context) {
 	// Get updated product
 	product, err := h.ProductService.GetProductByID(id)
 	if err != nil {
-		fmt.Printf("DEBUG: AdminUpdateProductQuantity - failed to get updated product: %v\n", err)
 		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to get updated product: " + err.Error()})
 		return
 	}
 
-	fmt.Printf("DEBUG: AdminUpdateProductQuantity - success, product quantity: %d\n", product.Quantity)
 	c.JSON(http.StatusOK, product)
 }
 
+// AdminDeleteProduct archives a product: it disappears from the storefront
+// but stays resolvable for orders, statistics and reviews
 func (h *Handlers) AdminDeleteProduct(c *gin.Context) {
 	idStr := c.Param("id")
 	id, err := strconv.Atoi(idStr)
@@ -800,12 +1077,62 @@ func (h *Handlers) AdminDeleteProduct(c *gin.Context) {
 		return
 	}
 
-	fmt.Printf("DEBUG: AdminDeleteProduct - id: %d\n", id)
+	// Check if product exists before archiving
+	existing, err := h.ProductService.GetProductByID(id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to check product: " + err.Error()})
+		return
+	}
+	if existing == nil {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{Error: "Product not found"})
+		return
+	}
+
+	if err := h.ProductService.ArchiveProduct(id); err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to archive product: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Product archived successfully"})
+}
+
+func (h *Handlers) AdminRestoreProduct(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid product ID"})
+		return
+	}
+
+	existing, err := h.ProductService.GetProductByID(id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to check product: " + err.Error()})
+		return
+	}
+	if existing == nil {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{Error: "Product not found"})
+		return
+	}
+
+	product, err := h.ProductService.RestoreProduct(id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to restore product: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, product)
+}
+
+// AdminPurgeProduct deletes a product for good, which is only allowed when
+// no order, review, subscription or box refers to it
+func (h *Handlers) AdminPurgeProduct(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid product ID"})
+		return
+	}
 
-	// Check if product exists before deletion
 	existing, err := h.ProductService.GetProductByID(id)
 	if err != nil {
-		fmt.Printf("DEBUG: AdminDeleteProduct - error checking product: %v\n", err)
 		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to check product: " + err.Error()})
 		return
 	}
@@ -814,15 +1141,20 @@ func (h *Handlers) AdminDeleteProduct(c *gin.Context) {
 		return
 	}
 
-	// Delete the product
-	if err := h.ProductService.DeleteProduct(id); err != nil {
-		fmt.Printf("DEBUG: AdminDeleteProduct error: %v\n", err)
-		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to delete product: " + err.Error()})
+	refs, err := h.ProductService.PurgeProduct(id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to purge product: " + err.Error()})
+		return
+	}
+	if refs != nil {
+		c.JSON(http.StatusConflict, gin.H{
+			"error":      "Product is referenced by " + services.ProductReferenceSummary(refs) + " and can only be archived",
+			"references": refs,
+		})
 		return
 	}
 
-	fmt.Printf("DEBUG: AdminDeleteProduct - success\n")
-	c.JSON(http.StatusOK, gin.H{"message": "Product deleted successfully"})
+	c.JSON(http.StatusOK, gin.H{"message": "Product purged successfully"})
 }
 
 func (h *Handlers) AdminGetOrders(c *gin.Context) {
@@ -955,6 +1287,13 @@ func (h *Handlers) AdminUpdateUserBlocked(c *gin.Context) {
 
 // -------------------- Admin Statistics --------------------
 
+// productSaleKey groups sales by product and variant; VariantID is 0 for
+// products without variants
+type productSaleKey struct {
+	ProductID int
+	VariantID int
+}
+
 func (h *Handlers) AdminGetStatistics(c *gin.Context) {
 	// Get date range from query params
 	startDate := c.Query("start_date")
@@ -985,16 +1324,34 @@ func (h *Handlers) AdminGetStatistics(c *gin.Context) {
 
 	// Calculate statistics
 	totalRevenue := 0
+	totalTax := 0
 	orderCount := 0
-	productSales := make(map[int]int) // product_id -> quantity sold
+	productSales := make(map[productSaleKey]int)      // product and variant -> quantity sold
+	taxByCategory := make(map[string]*models.TaxLine) // tax_category -> totals
 
 	for _, order := range filteredOrders {
 		if order.Status == "paid" || order.Status == "shipped" || order.Status == "delivered" {
 			totalRevenue += order.AmountCents
+			totalTax += order.TaxCents
 			orderCount++
 
+			for _, line := range order.TaxBreakdown {
+				total, ok := taxByCategory[line.TaxCategory]
+				if !ok {
+					total = &models.TaxLine{TaxCategory: line.TaxCategory, RatePercent: line.RatePercent}
+					taxByCategory[line.TaxCategory] = total
+				}
+				total.NetCents += line.NetCents
+				total.TaxCents += line.TaxCents
+				total.GrossCents += line.GrossCents
+			}
+
 			for _, item := range order.Items {
-				productSales[item.ProductID] += item.Quantity
+				key := productSaleKey{ProductID: item.ProductID}
+				if item.VariantID != nil {
+					key.VariantID = *item.VariantID
+				}
+				productSales[key] += item.Quantity
 			}
 		}
 	}
@@ -1008,20 +1365,33 @@ func (h *Handlers) AdminGetStatistics(c *gin.Context) {
 	// Get top products
 	type ProductSale struct {
 		ProductID int
+		VariantID *int `json:",omitempty"`
 		Quantity  int
 		Product   *models.Product
+		Variant   *models.ProductVariant `json:",omitempty"`
 	}
 
 	var topProducts []ProductSale
-	for productID, quantity := range productSales {
-		product, _ := h.ProductService.GetProductByID(productID)
-		if product != nil {
-			topProducts = append(topProducts, ProductSale{
-				ProductID: productID,
-				Quantity:  quantity,
-				Product:   product,
-			})
+	for key, quantity := range productSales {
+		product, _ := h.ProductService.GetProductByID(key.ProductID)
+		if product == nil {
+			continue
+		}
+		sale := ProductSale{
+			ProductID: key.ProductID,
+			Quantity:  quantity,
+			Product:   product,
 		}
+		if key.VariantID != 0 {
+			variantID := key.VariantID
+			sale.VariantID = &variantID
+			for i := range product.Variants {
+				if product.Variants[i].ID == variantID {
+					sale.Variant = &product.Variants[i]
+				}
+			}
+		}
+		topProducts = append(topProducts, sale)
 	}
 
 	// Sort by quantity
@@ -1068,8 +1438,23 @@ func (h *Handlers) AdminGetStatistics(c *gin.Context) {
 		}
 	}
 
+	// Tax totals by category, highest rate first
+	taxBreakdown := make([]models.TaxLine, 0, len(taxByCategory))
+	for _, line := range taxByCategory {
+		taxBreakdown = append(taxBreakdown, *line)
+	}
+	sort.Slice(taxBreakdown, func(i, j int) bool {
+		if taxBreakdown[i].RatePercent != taxBreakdown[j].RatePercent {
+			return taxBreakdown[i].RatePercent > taxBreakdown[j].RatePercent
+		}
+		return taxBreakdown[i].TaxCategory < taxBreakdown[j].TaxCategory
+	})
+
 	response := map[string]interface{}{
 		"total_revenue":   totalRevenue / 100,
+		"total_tax":       totalTax / 100,
+		"net_revenue":     (totalRevenue - totalTax) / 100,
+		"tax_breakdown":   taxBreakdown,
 		"order_count":     orderCount,
 		"avg_order_value": avgOrderValue / 100,
 		"top_products":    topProducts,
@@ -1083,6 +1468,8 @@ func (h *Handlers) AdminGetStatistics(c *gin.Context) {
 func (h *Handlers) MockCompletePayment(c *gin.Context) {
 	var req struct {
 		PaymentID string `json:"payment_id" binding:"required"`
+		// SavePaymentMethod saves a mock card for subscription charges
+		SavePaymentMethod bool `json:"save_payment_method"`
 	}
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid request"})
@@ -1117,6 +1504,25 @@ func (h *Handlers) MockCompletePayment(c *gin.Context) {
 		return
 	}
 
+	if req.SavePaymentMethod && order.UserID != nil {
+		if err := h.PaymentService.SavePaymentMethod(*order.UserID, "mock_pm_"+req.PaymentID, "Mock card"); err != nil {
+			log.Printf("Warning: failed to save payment method: %v", err)
+		}
+	}
+
+	// Доплата после редактирования заказа не меняет его статус и остатки
+	if payment.Kind == models.PaymentKindSupplementary {
+		if err := h.PaymentService.RecordSupplementaryPayment(payment, "paid"); err != nil {
+			log.Printf("Warning: failed to record supplementary payment: %v", err)
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"message":    "Payment completed successfully",
+			"payment_id": req.PaymentID,
+			"order_id":   payment.OrderID,
+		})
+		return
+	}
+
 	// Обновляем статус заказа
 	if err := h.OrderService.UpdateOrderStatus(payment.OrderID, "paid"); err != nil {
 		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to update order status"})
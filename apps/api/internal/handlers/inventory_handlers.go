@@ -0,0 +1,300 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"gastroshop-api/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Admin inventory handlers
+
+// AdminGetInventoryMovements lists the inventory ledger, newest first,
+// filtered by location_id, product_id, variant_id, reason, reference and the
+// from and to days
+func (h *Handlers) AdminGetInventoryMovements(c *gin.Context) {
+	filters := make(map[string]interface{})
+
+	if locationID := c.Query("location_id"); locationID != "" {
+		id, err := strconv.Atoi(locationID)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid location ID"})
+			return
+		}
+		filters["location_id"] = id
+	}
+	if productID := c.Query("product_id"); productID != "" {
+		id, err := strconv.Atoi(productID)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid product ID"})
+			return
+		}
+		filters["product_id"] = id
+	}
+	if variantID := c.Query("variant_id"); variantID != "" {
+		id, err := strconv.Atoi(variantID)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid variant ID"})
+			return
+		}
+		filters["variant_id"] = id
+	}
+	if reason := c.Query("reason"); reason != "" {
+		filters["reason"] = reason
+	}
+	if reference := c.Query("reference"); reference != "" {
+		filters["reference"] = reference
+	}
+	if from := c.Query("from"); from != "" {
+		day, err := time.Parse("2006-01-02", from)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid from, expected YYYY-MM-DD"})
+			return
+		}
+		filters["from"] = day
+	}
+	if to := c.Query("to"); to != "" {
+		day, err := time.Parse("2006-01-02", to)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid to, expected YYYY-MM-DD"})
+			return
+		}
+		// to includes the whole day
+		filters["to"] = day.AddDate(0, 0, 1)
+	}
+
+	page := 1
+	if p := c.Query("page"); p != "" {
+		if val, err := strconv.Atoi(p); err == nil && val > 0 {
+			page = val
+		}
+	}
+	filters["page"] = page
+
+	pageSize := 50
+	if ps := c.Query("limit"); ps != "" {
+		if val, err := strconv.Atoi(ps); err == nil && val > 0 && val <= 200 {
+			pageSize = val
+		}
+	}
+	filters["page_size"] = pageSize
+
+	movements, total, err := h.InventoryService.GetMovements(filters)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.PaginatedResponse{
+		Items:    movements,
+		Total:    total,
+		Page:     page,
+		PageSize: pageSize,
+	})
+}
+
+// AdminCreateInventoryMovement records stock received, written off,
+// returned, reserved or corrected
+func (h *Handlers) AdminCreateInventoryMovement(c *gin.Context) {
+	adminID, ok := requireUserID(c)
+	if !ok {
+		return
+	}
+
+	var req models.CreateInventoryMovementRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid request"})
+		return
+	}
+
+	movement, err := h.InventoryService.RecordMovement(adminID, &req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, movement)
+}
+
+// AdminGetStockTransfers lists stock transfers, newest first, filtered by
+// location_id, either end of the transfer, and product_id
+func (h *Handlers) AdminGetStockTransfers(c *gin.Context) {
+	filters := make(map[string]interface{})
+
+	if locationID := c.Query("location_id"); locationID != "" {
+		id, err := strconv.Atoi(locationID)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid location ID"})
+			return
+		}
+		filters["location_id"] = id
+	}
+	if productID := c.Query("product_id"); productID != "" {
+		id, err := strconv.Atoi(productID)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid product ID"})
+			return
+		}
+		filters["product_id"] = id
+	}
+
+	page := 1
+	if p := c.Query("page"); p != "" {
+		if val, err := strconv.Atoi(p); err == nil && val > 0 {
+			page = val
+		}
+	}
+	filters["page"] = page
+
+	pageSize := 50
+	if ps := c.Query("limit"); ps != "" {
+		if val, err := strconv.Atoi(ps); err == nil && val > 0 && val <= 200 {
+			pageSize = val
+		}
+	}
+	filters["page_size"] = pageSize
+
+	transfers, total, err := h.InventoryService.GetTransfers(filters)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to get stock transfers"})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.PaginatedResponse{
+		Items:    transfers,
+		Total:    total,
+		Page:     page,
+		PageSize: pageSize,
+	})
+}
+
+// AdminCreateStockTransfer moves stock from one location to another
+func (h *Handlers) AdminCreateStockTransfer(c *gin.Context) {
+	adminID, ok := requireUserID(c)
+	if !ok {
+		return
+	}
+
+	var req models.CreateStockTransferRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid request"})
+		return
+	}
+
+	transfer, err := h.InventoryService.CreateTransfer(adminID, &req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, transfer)
+}
+
+func (h *Handlers) AdminGetStockTakes(c *gin.Context) {
+	stockTakes, err := h.InventoryService.GetStockTakes()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to get stock takes"})
+		return
+	}
+
+	c.JSON(http.StatusOK, stockTakes)
+}
+
+func (h *Handlers) AdminGetStockTake(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid stock take ID"})
+		return
+	}
+
+	stockTake, err := h.InventoryService.GetStockTake(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, stockTake)
+}
+
+func (h *Handlers) AdminCreateStockTake(c *gin.Context) {
+	adminID, ok := requireUserID(c)
+	if !ok {
+		return
+	}
+
+	var req models.CreateStockTakeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid request"})
+		return
+	}
+
+	stockTake, err := h.InventoryService.CreateStockTake(adminID, &req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, stockTake)
+}
+
+// AdminSetStockTakeCounts records counted quantities of an open stock take
+func (h *Handlers) AdminSetStockTakeCounts(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid stock take ID"})
+		return
+	}
+
+	var req models.SetStockTakeCountsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid request"})
+		return
+	}
+
+	stockTake, err := h.InventoryService.SetStockTakeCounts(id, &req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, stockTake)
+}
+
+// AdminCompleteStockTake sets the stock to the counted quantities
+func (h *Handlers) AdminCompleteStockTake(c *gin.Context) {
+	adminID, ok := requireUserID(c)
+	if !ok {
+		return
+	}
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid stock take ID"})
+		return
+	}
+
+	stockTake, err := h.InventoryService.CompleteStockTake(adminID, id)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, stockTake)
+}
+
+func (h *Handlers) AdminCancelStockTake(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid stock take ID"})
+		return
+	}
+
+	if err := h.InventoryService.CancelStockTake(id); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Stock take canceled successfully"})
+}
@@ -0,0 +1,81 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"gastroshop-api/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Invoice handlers
+
+func (h *Handlers) GetOrderInvoice(c *gin.Context) {
+	userID, ok := requireUserID(c)
+	if !ok {
+		return
+	}
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid order ID"})
+		return
+	}
+
+	order, err := h.OrderService.GetOrderByID(id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to get order"})
+		return
+	}
+	if order == nil || order.UserID == nil || *order.UserID != userID {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{Error: "Order not found"})
+		return
+	}
+
+	h.writeInvoice(c, order)
+}
+
+func (h *Handlers) GetGuestOrderInvoice(c *gin.Context) {
+	order, err := h.OrderService.GetOrderByAccessToken(c.Param("token"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to get order"})
+		return
+	}
+	if order == nil {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{Error: "Order not found"})
+		return
+	}
+
+	h.writeInvoice(c, order)
+}
+
+func (h *Handlers) AdminGetOrderInvoice(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid order ID"})
+		return
+	}
+
+	order, err := h.OrderService.GetOrderByID(id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to get order"})
+		return
+	}
+	if order == nil {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{Error: "Order not found"})
+		return
+	}
+
+	h.writeInvoice(c, order)
+}
+
+func (h *Handlers) writeInvoice(c *gin.Context, order *models.Order) {
+	invoice, err := h.OrderService.BuildInvoice(order)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to build invoice"})
+		return
+	}
+
+	c.JSON(http.StatusOK, invoice)
+}
@@ -0,0 +1,106 @@
+package handlers
+
+import (
+	"net/http"
+
+	"gastroshop-api/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Localization handlers
+
+// UpdateLocale sets the locale emails are sent to the signed-in customer in
+func (h *Handlers) UpdateLocale(c *gin.Context) {
+	userID, ok := requireUserID(c)
+	if !ok {
+		return
+	}
+
+	var req models.UpdateLocaleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid request"})
+		return
+	}
+
+	if err := h.AuthService.SetLocale(userID, req.Locale); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"locale": req.Locale})
+}
+
+// AdminGetRegions lists the regions with their translations
+func (h *Handlers) AdminGetRegions(c *gin.Context) {
+	regions, err := h.RegionService.GetRegions()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to get regions"})
+		return
+	}
+
+	c.JSON(http.StatusOK, regions)
+}
+
+// AdminUpdateRegion renames a region and sets its translations
+func (h *Handlers) AdminUpdateRegion(c *gin.Context) {
+	var req models.UpdateRegionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid request"})
+		return
+	}
+
+	region, err := h.RegionService.UpdateRegion(c.Param("code"), &req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, region)
+}
+
+// AdminGetEmailTemplates lists the built-in emails with the admin versions
+// of each by locale
+func (h *Handlers) AdminGetEmailTemplates(c *gin.Context) {
+	templates, err := h.EmailTemplateService.GetTemplates()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to get email templates"})
+		return
+	}
+
+	c.JSON(http.StatusOK, templates)
+}
+
+// AdminSetEmailTemplate sets the subject and body of a built-in email in a
+// locale
+func (h *Handlers) AdminSetEmailTemplate(c *gin.Context) {
+	adminID, ok := requireUserID(c)
+	if !ok {
+		return
+	}
+
+	var req models.SetEmailTemplateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid request"})
+		return
+	}
+
+	tmpl, err := h.EmailTemplateService.SetTemplate(adminID, c.Param("name"), c.Param("locale"), &req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, tmpl)
+}
+
+// AdminDeleteEmailTemplate removes the admin version of an email in a
+// locale
+func (h *Handlers) AdminDeleteEmailTemplate(c *gin.Context) {
+	if err := h.EmailTemplateService.DeleteTemplate(c.Param("name"), c.Param("locale")); err != nil {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{Error: "Email template not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Email template deleted successfully"})
+}
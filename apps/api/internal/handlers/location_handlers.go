@@ -0,0 +1,139 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"gastroshop-api/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Admin location handlers
+
+func (h *Handlers) AdminGetLocations(c *gin.Context) {
+	locations, err := h.LocationService.GetLocations()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to get locations"})
+		return
+	}
+
+	c.JSON(http.StatusOK, locations)
+}
+
+func (h *Handlers) AdminCreateLocation(c *gin.Context) {
+	var req models.CreateLocationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid request"})
+		return
+	}
+
+	location, err := h.LocationService.CreateLocation(&req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, location)
+}
+
+func (h *Handlers) AdminUpdateLocation(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid location ID"})
+		return
+	}
+
+	var req models.UpdateLocationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid request"})
+		return
+	}
+
+	location, err := h.LocationService.UpdateLocation(id, &req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, location)
+}
+
+// AdminGetLocationStock lists the stock kept at locations, filtered by
+// location_id and product_id
+func (h *Handlers) AdminGetLocationStock(c *gin.Context) {
+	filters := make(map[string]interface{})
+
+	if locationID := c.Query("location_id"); locationID != "" {
+		id, err := strconv.Atoi(locationID)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid location ID"})
+			return
+		}
+		filters["location_id"] = id
+	}
+	if productID := c.Query("product_id"); productID != "" {
+		id, err := strconv.Atoi(productID)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid product ID"})
+			return
+		}
+		filters["product_id"] = id
+	}
+
+	page := 1
+	if p := c.Query("page"); p != "" {
+		if val, err := strconv.Atoi(p); err == nil && val > 0 {
+			page = val
+		}
+	}
+	filters["page"] = page
+
+	pageSize := 50
+	if ps := c.Query("limit"); ps != "" {
+		if val, err := strconv.Atoi(ps); err == nil && val > 0 && val <= 200 {
+			pageSize = val
+		}
+	}
+	filters["page_size"] = pageSize
+
+	stock, total, err := h.LocationService.GetStock(filters)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to get stock"})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.PaginatedResponse{
+		Items:    stock,
+		Total:    total,
+		Page:     page,
+		PageSize: pageSize,
+	})
+}
+
+// AdminSetOrderFulfilmentLocation overrides the location an order ships from
+func (h *Handlers) AdminSetOrderFulfilmentLocation(c *gin.Context) {
+	adminID, ok := requireUserID(c)
+	if !ok {
+		return
+	}
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid order ID"})
+		return
+	}
+
+	var req models.SetFulfilmentLocationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid request"})
+		return
+	}
+
+	order, err := h.LocationService.SetFulfilmentLocation(adminID, id, req.LocationID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, order)
+}
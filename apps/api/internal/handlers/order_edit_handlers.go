@@ -0,0 +1,133 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"gastroshop-api/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Admin order editing handlers
+
+func (h *Handlers) AdminAddOrderItem(c *gin.Context) {
+	adminID, ok := requireUserID(c)
+	if !ok {
+		return
+	}
+	orderID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid order ID"})
+		return
+	}
+
+	var req models.AddOrderItemRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid request"})
+		return
+	}
+
+	result, err := h.OrderEditService.AddItem(adminID, orderID, &req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+func (h *Handlers) AdminUpdateOrderItem(c *gin.Context) {
+	adminID, ok := requireUserID(c)
+	if !ok {
+		return
+	}
+	orderID, productID, variantID, ok := orderItemParams(c)
+	if !ok {
+		return
+	}
+
+	var req models.UpdateOrderItemRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid request"})
+		return
+	}
+
+	result, err := h.OrderEditService.UpdateItemQuantity(adminID, orderID, productID, variantID, &req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+func (h *Handlers) AdminRemoveOrderItem(c *gin.Context) {
+	adminID, ok := requireUserID(c)
+	if !ok {
+		return
+	}
+	orderID, productID, variantID, ok := orderItemParams(c)
+	if !ok {
+		return
+	}
+
+	result, err := h.OrderEditService.RemoveItem(adminID, orderID, productID, variantID, c.Query("note"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+func (h *Handlers) AdminGetOrderHistory(c *gin.Context) {
+	orderID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid order ID"})
+		return
+	}
+
+	history, err := h.OrderEditService.GetHistory(orderID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to get order history"})
+		return
+	}
+
+	c.JSON(http.StatusOK, history)
+}
+
+// orderItemParams parses the order and product IDs of an order line URL and
+// the optional variant_id query parameter
+func orderItemParams(c *gin.Context) (int, int, *int, bool) {
+	orderID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid order ID"})
+		return 0, 0, nil, false
+	}
+	productID, err := strconv.Atoi(c.Param("productId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid product ID"})
+		return 0, 0, nil, false
+	}
+	variantID, ok := optionalVariantID(c)
+	if !ok {
+		return 0, 0, nil, false
+	}
+	return orderID, productID, variantID, true
+}
+
+// optionalVariantID parses the variant_id query parameter, responding with 400
+// when it is malformed
+func optionalVariantID(c *gin.Context) (*int, bool) {
+	raw := c.Query("variant_id")
+	if raw == "" {
+		return nil, true
+	}
+	id, err := strconv.Atoi(raw)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid variant ID"})
+		return nil, false
+	}
+	return &id, true
+}
@@ -0,0 +1,141 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"gastroshop-api/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetProductPairings returns the products that pair well with a product
+func (h *Handlers) GetProductPairings(c *gin.Context) {
+	pairings, err := h.PairingService.GetProductPairings(c.Param("slug"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, pairings)
+}
+
+// GetCheeseBoard builds a cheese board of size cheeses, starting from the
+// cheese with the slug given as cheese if any, with accompaniments paired
+// with them. Signed-in users get boards that suit their dietary restrictions.
+func (h *Handlers) GetCheeseBoard(c *gin.Context) {
+	size := 3
+	if s := c.Query("size"); s != "" {
+		val, err := strconv.Atoi(s)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid size"})
+			return
+		}
+		size = val
+	}
+
+	accompaniments := 3
+	if a := c.Query("accompaniments"); a != "" {
+		val, err := strconv.Atoi(a)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid accompaniments"})
+			return
+		}
+		accompaniments = val
+	}
+
+	board, err := h.PairingService.BuildCheeseBoard(size, accompaniments, c.Query("cheese"), h.dietaryRestrictions(c))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, board)
+}
+
+// Admin pairing handlers
+
+func (h *Handlers) AdminGetProductPairings(c *gin.Context) {
+	productID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid product ID"})
+		return
+	}
+
+	pairings, err := h.PairingService.GetPairings(productID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, pairings)
+}
+
+func (h *Handlers) AdminCreateProductPairing(c *gin.Context) {
+	productID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid product ID"})
+		return
+	}
+
+	var req models.CreateProductPairingRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid request"})
+		return
+	}
+
+	pairing, err := h.PairingService.CreatePairing(productID, &req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, pairing)
+}
+
+func (h *Handlers) AdminUpdateProductPairing(c *gin.Context) {
+	productID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid product ID"})
+		return
+	}
+	pairingID, err := strconv.Atoi(c.Param("pairingId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid pairing ID"})
+		return
+	}
+
+	var req models.UpdateProductPairingRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid request"})
+		return
+	}
+
+	pairing, err := h.PairingService.UpdatePairing(productID, pairingID, &req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, pairing)
+}
+
+func (h *Handlers) AdminDeleteProductPairing(c *gin.Context) {
+	productID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid product ID"})
+		return
+	}
+	pairingID, err := strconv.Atoi(c.Param("pairingId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid pairing ID"})
+		return
+	}
+
+	if err := h.PairingService.DeletePairing(productID, pairingID); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Pairing deleted successfully"})
+}
@@ -0,0 +1,127 @@
+package handlers
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"gastroshop-api/internal/models"
+	"gastroshop-api/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxImportFileSize limits uploaded product spreadsheets
+const maxImportFileSize = 10 << 20
+
+// Admin product import and export handlers
+
+// AdminImportProducts starts a background import of an uploaded CSV or XLSX
+// file. Imports are dry runs unless dry_run=false is given.
+func (h *Handlers) AdminImportProducts(c *gin.Context) {
+	userID, ok := requireUserID(c)
+	if !ok {
+		return
+	}
+
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "File is required"})
+		return
+	}
+	if fileHeader.Size > maxImportFileSize {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "File is too large"})
+		return
+	}
+
+	dryRun := true
+	if value := c.Query("dry_run"); value != "" {
+		parsed, err := strconv.ParseBool(value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid dry_run"})
+			return
+		}
+		dryRun = parsed
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Failed to read file"})
+		return
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(io.LimitReader(file, maxImportFileSize))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Failed to read file"})
+		return
+	}
+
+	job, err := h.ProductImportService.StartImport(userID, fileHeader.Filename, data, dryRun)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, job)
+}
+
+func (h *Handlers) AdminGetImportJobs(c *gin.Context) {
+	jobs, err := h.ProductImportService.GetImportJobs()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to get import jobs"})
+		return
+	}
+
+	c.JSON(http.StatusOK, jobs)
+}
+
+func (h *Handlers) AdminGetImportJob(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid import job ID"})
+		return
+	}
+
+	job, err := h.ProductImportService.GetImportJob(id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to get import job"})
+		return
+	}
+	if job == nil {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{Error: "Import job not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, job)
+}
+
+// AdminExportProducts downloads the catalog as CSV (default) or XLSX in the
+// import format
+func (h *Handlers) AdminExportProducts(c *gin.Context) {
+	format := c.DefaultQuery("format", services.SheetFormatCSV)
+
+	contentType := "text/csv; charset=utf-8"
+	switch format {
+	case services.SheetFormatCSV:
+	case services.SheetFormatXLSX:
+		contentType = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	default:
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid format"})
+		return
+	}
+
+	// Build the file first so a failure can still be reported as JSON
+	var buf bytes.Buffer
+	if err := h.ProductImportService.ExportProducts(&buf, format); err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to export products"})
+		return
+	}
+
+	filename := fmt.Sprintf("products-%s.%s", time.Now().Format("2006-01-02"), format)
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	c.Data(http.StatusOK, contentType, buf.Bytes())
+}
@@ -0,0 +1,213 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"gastroshop-api/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Subscription handlers
+
+func (h *Handlers) GetSubscriptionBoxes(c *gin.Context) {
+	boxes, err := h.SubscriptionService.GetActiveBoxes()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to get subscription boxes"})
+		return
+	}
+
+	c.JSON(http.StatusOK, boxes)
+}
+
+func (h *Handlers) GetSubscriptions(c *gin.Context) {
+	userID, ok := requireUserID(c)
+	if !ok {
+		return
+	}
+
+	subs, err := h.SubscriptionService.GetUserSubscriptions(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to get subscriptions"})
+		return
+	}
+
+	c.JSON(http.StatusOK, subs)
+}
+
+// GetPaymentMethods lists the payment methods the customer saved, which
+// subscriptions can be charged with
+func (h *Handlers) GetPaymentMethods(c *gin.Context) {
+	userID, ok := requireUserID(c)
+	if !ok {
+		return
+	}
+
+	methods, err := h.PaymentService.GetPaymentMethods(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to get payment methods"})
+		return
+	}
+
+	c.JSON(http.StatusOK, methods)
+}
+
+func (h *Handlers) CreateSubscription(c *gin.Context) {
+	userID, ok := requireUserID(c)
+	if !ok {
+		return
+	}
+
+	var req models.CreateSubscriptionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid request"})
+		return
+	}
+
+	sub, err := h.SubscriptionService.CreateSubscription(userID, &req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, sub)
+}
+
+func (h *Handlers) UpdateSubscription(c *gin.Context) {
+	userID, ok := requireUserID(c)
+	if !ok {
+		return
+	}
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid subscription ID"})
+		return
+	}
+
+	var req models.UpdateSubscriptionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid request"})
+		return
+	}
+
+	sub, err := h.SubscriptionService.UpdateSubscription(userID, id, &req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, sub)
+}
+
+func (h *Handlers) PauseSubscription(c *gin.Context) {
+	h.changeSubscription(c, h.SubscriptionService.PauseSubscription)
+}
+
+func (h *Handlers) ResumeSubscription(c *gin.Context) {
+	h.changeSubscription(c, h.SubscriptionService.ResumeSubscription)
+}
+
+func (h *Handlers) SkipSubscriptionDelivery(c *gin.Context) {
+	h.changeSubscription(c, h.SubscriptionService.SkipNextDelivery)
+}
+
+func (h *Handlers) CancelSubscription(c *gin.Context) {
+	h.changeSubscription(c, h.SubscriptionService.CancelSubscription)
+}
+
+// changeSubscription applies a customer action to the subscription in the URL
+func (h *Handlers) changeSubscription(c *gin.Context, action func(userID, id int) (*models.ProductSubscription, error)) {
+	userID, ok := requireUserID(c)
+	if !ok {
+		return
+	}
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid subscription ID"})
+		return
+	}
+
+	sub, err := action(userID, id)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, sub)
+}
+
+// Admin subscription handlers
+
+func (h *Handlers) AdminGetSubscriptions(c *gin.Context) {
+	subs, err := h.SubscriptionService.GetAllSubscriptions(c.Query("status"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to get subscriptions"})
+		return
+	}
+
+	c.JSON(http.StatusOK, subs)
+}
+
+func (h *Handlers) AdminGetSubscriptionBoxes(c *gin.Context) {
+	boxes, err := h.SubscriptionService.GetBoxes()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to get subscription boxes"})
+		return
+	}
+
+	c.JSON(http.StatusOK, boxes)
+}
+
+func (h *Handlers) AdminCreateSubscriptionBox(c *gin.Context) {
+	var req models.CreateSubscriptionBoxRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid request"})
+		return
+	}
+
+	box, err := h.SubscriptionService.CreateBox(&req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, box)
+}
+
+func (h *Handlers) AdminUpdateSubscriptionBox(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid box ID"})
+		return
+	}
+
+	var req models.UpdateSubscriptionBoxRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid request"})
+		return
+	}
+
+	box, err := h.SubscriptionService.UpdateBox(id, &req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, box)
+}
+
+func (h *Handlers) AdminDeleteSubscriptionBox(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid box ID"})
+		return
+	}
+
+	if err := h.SubscriptionService.DeleteBox(id); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Subscription box deleted successfully"})
+}
@@ -0,0 +1,197 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"gastroshop-api/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Wishlist handlers
+
+// wishlistParams reads the user and the wishlist ID of a wishlist route. It
+// responds and returns false when either is missing or invalid.
+func wishlistParams(c *gin.Context) (int, int, bool) {
+	userID, ok := requireUserID(c)
+	if !ok {
+		return 0, 0, false
+	}
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid wishlist ID"})
+		return 0, 0, false
+	}
+	return userID, id, true
+}
+
+func (h *Handlers) GetWishlists(c *gin.Context) {
+	userID, ok := requireUserID(c)
+	if !ok {
+		return
+	}
+
+	wishlists, err := h.WishlistService.GetWishlists(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to get wishlists"})
+		return
+	}
+
+	c.JSON(http.StatusOK, wishlists)
+}
+
+func (h *Handlers) GetWishlist(c *gin.Context) {
+	userID, id, ok := wishlistParams(c)
+	if !ok {
+		return
+	}
+
+	wishlist, err := h.WishlistService.GetWishlist(userID, id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{Error: "Wishlist not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, wishlist)
+}
+
+// GetSharedWishlist shows the wishlist shared with a link, e.g. a gift list,
+// to anyone with the link
+func (h *Handlers) GetSharedWishlist(c *gin.Context) {
+	wishlist, err := h.WishlistService.GetSharedWishlist(c.Param("token"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{Error: "Wishlist not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, wishlist)
+}
+
+func (h *Handlers) CreateWishlist(c *gin.Context) {
+	userID, ok := requireUserID(c)
+	if !ok {
+		return
+	}
+
+	var req models.CreateWishlistRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid request"})
+		return
+	}
+
+	wishlist, err := h.WishlistService.CreateWishlist(userID, &req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, wishlist)
+}
+
+// UpdateWishlist renames a wishlist and turns its share link on or off
+func (h *Handlers) UpdateWishlist(c *gin.Context) {
+	userID, id, ok := wishlistParams(c)
+	if !ok {
+		return
+	}
+
+	var req models.UpdateWishlistRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid request"})
+		return
+	}
+
+	wishlist, err := h.WishlistService.UpdateWishlist(userID, id, &req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, wishlist)
+}
+
+func (h *Handlers) DeleteWishlist(c *gin.Context) {
+	userID, id, ok := wishlistParams(c)
+	if !ok {
+		return
+	}
+
+	if err := h.WishlistService.DeleteWishlist(userID, id); err != nil {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{Error: "Wishlist not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Wishlist deleted successfully"})
+}
+
+func (h *Handlers) AddWishlistItem(c *gin.Context) {
+	userID, id, ok := wishlistParams(c)
+	if !ok {
+		return
+	}
+
+	var req models.AddWishlistItemRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid request"})
+		return
+	}
+
+	wishlist, err := h.WishlistService.AddItem(userID, id, &req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, wishlist)
+}
+
+func (h *Handlers) RemoveWishlistItem(c *gin.Context) {
+	userID, id, ok := wishlistParams(c)
+	if !ok {
+		return
+	}
+	itemID, err := strconv.Atoi(c.Param("itemId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid item ID"})
+		return
+	}
+
+	wishlist, err := h.WishlistService.RemoveItem(userID, id, itemID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, wishlist)
+}
+
+// MoveWishlistItemToCart adds a wishlist item to the cart and removes it
+// from the wishlist unless keep is set. It returns the cart.
+func (h *Handlers) MoveWishlistItemToCart(c *gin.Context) {
+	userID, id, ok := wishlistParams(c)
+	if !ok {
+		return
+	}
+	itemID, err := strconv.Atoi(c.Param("itemId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid item ID"})
+		return
+	}
+
+	var req models.MoveWishlistItemToCartRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid request"})
+			return
+		}
+	}
+
+	cart, err := h.WishlistService.MoveToCart(userID, id, itemID, &req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, cart)
+}
@@ -56,6 +56,32 @@ func AuthMiddleware(authService *services.AuthService) gin.HandlerFunc {
 	}
 }
 
+// OptionalAuthMiddleware sets the user of a valid access token like
+// AuthMiddleware but lets requests without one through, for public
+// endpoints that personalize their response
+func OptionalAuthMiddleware(authService *services.AuthService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		tokenParts := strings.Split(c.GetHeader("Authorization"), " ")
+		if len(tokenParts) != 2 || tokenParts[0] != "Bearer" {
+			c.Next()
+			return
+		}
+
+		userID, err := authService.ValidateToken(tokenParts[1])
+		if err != nil {
+			c.Next()
+			return
+		}
+
+		user, err := authService.GetUserByID(userID)
+		if err == nil && user != nil && !user.Blocked {
+			c.Set("user_id", userID)
+			c.Set("user_role", user.Role)
+		}
+		c.Next()
+	}
+}
+
 func AdminMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		role, exists := c.Get("user_role")
@@ -5,24 +5,720 @@ import (
 )
 
 type Product struct {
+	ID           int              `json:"id" db:"id"`
+	Slug         string           `json:"slug" db:"slug"`
+	Title        string           `json:"title" db:"title"`
+	Description  string           `json:"description" db:"description"`
+	PriceCents   int              `json:"price_cents" db:"price_cents"`
+	Currency     string           `json:"currency" db:"currency"`
+	Tags         []string         `json:"tags" db:"tags"`
+	RegionCode   string           `json:"region_code" db:"region_code"`
+	Images       []string         `json:"images" db:"images"`
+	InStock      bool             `json:"in_stock" db:"in_stock"`
+	Quantity     int              `json:"quantity" db:"quantity"`
+	TaxCategory  string           `json:"tax_category" db:"tax_category"`
+	Unit         string           `json:"unit" db:"unit"`
+	MinQuantity  int              `json:"min_quantity" db:"min_quantity"`
+	QuantityStep int              `json:"quantity_step" db:"quantity_step"`
+	DietaryFlags []string         `json:"dietary_flags" db:"dietary_flags"`
+	CreatedAt    time.Time        `json:"created_at" db:"created_at"`
+	ArchivedAt   *time.Time       `json:"archived_at,omitempty" db:"archived_at"`
+	Variants     []ProductVariant `json:"variants,omitempty"`
+	PriceRange   *PriceRange      `json:"price_range,omitempty"`
+	Highlight    *SearchHighlight `json:"highlight,omitempty"`
+	Bundle       *ProductBundle   `json:"bundle,omitempty"`
+	// Resized versions of uploaded images keyed by image URL
+	ImageVariants map[string]map[string]string `json:"image_variants,omitempty"`
+	Attributes    []ProductAttributeValue      `json:"attributes,omitempty"`
+	// Regular price of a product on sale, shown struck through
+	CompareAtPriceCents *int `json:"compare_at_price_cents,omitempty" db:"compare_at_price_cents"`
+	// Title and description in other locales than the default one
+	Translations map[string]Translation `json:"translations,omitempty" db:"translations"`
+}
+
+// Locales of catalog texts and emails. Texts are written in the default
+// locale and translated to the others.
+const (
+	LocaleRU      = "ru"
+	LocaleEN      = "en"
+	DefaultLocale = LocaleRU
+)
+
+// Translation holds the texts of a product, category or region in one
+// locale. Empty texts fall back to the default locale.
+type Translation struct {
+	Title       string `json:"title,omitempty"`
+	Name        string `json:"name,omitempty"`
+	Description string `json:"description,omitempty"`
+}
+
+// SearchHighlight holds the title and a description snippet of a search
+// result with the matched words wrapped in <mark> tags
+type SearchHighlight struct {
+	Title       string `json:"title"`
+	Description string `json:"description"`
+}
+
+// ProductReferences counts the records that refer to a product. Only
+// products without references can be purged; others are archived.
+type ProductReferences struct {
+	Orders            int `json:"orders"`
+	Reviews           int `json:"reviews"`
+	Subscriptions     int `json:"subscriptions"`
+	SubscriptionBoxes int `json:"subscription_boxes"`
+	Bundles           int `json:"bundles"`
+	StockMovements    int `json:"stock_movements"`
+	Batches           int `json:"batches"`
+	PriceChanges      int `json:"price_changes"`
+}
+
+// Units of measure of products. Pieces are counted and priced per piece;
+// weighed goods are counted in grams and priced per gram or per kilogram.
+const (
+	UnitPiece    = "piece"
+	UnitGram     = "g"
+	UnitKilogram = "kg"
+)
+
+// ProductVariant is a purchasable version of a product, e.g. "200 г" or "1 кг".
+// For products with variants the product price, quantity and in_stock are
+// kept in sync with the cheapest variant and the total variant stock.
+type ProductVariant struct {
 	ID          int       `json:"id" db:"id"`
-	Slug        string    `json:"slug" db:"slug"`
+	ProductID   int       `json:"product_id" db:"product_id"`
+	SKU         string    `json:"sku" db:"sku"`
 	Title       string    `json:"title" db:"title"`
-	Description string    `json:"description" db:"description"`
+	WeightGrams *int      `json:"weight_grams" db:"weight_grams"`
+	VolumeML    *int      `json:"volume_ml" db:"volume_ml"`
 	PriceCents  int       `json:"price_cents" db:"price_cents"`
-	Currency    string    `json:"currency" db:"currency"`
-	Tags        []string  `json:"tags" db:"tags"`
-	RegionCode  string    `json:"region_code" db:"region_code"`
-	Images      []string  `json:"images" db:"images"`
-	InStock     bool      `json:"in_stock" db:"in_stock"`
 	Quantity    int       `json:"quantity" db:"quantity"`
+	InStock     bool      `json:"in_stock" db:"in_stock"`
+	Barcode     string    `json:"barcode,omitempty" db:"barcode"`
+	SortOrder   int       `json:"sort_order" db:"sort_order"`
 	CreatedAt   time.Time `json:"created_at" db:"created_at"`
 }
 
+// Pricing of bundles: their own fixed price or a discount off the sum of
+// the component prices
+const (
+	BundlePricingFixed    = "fixed"
+	BundlePricingDiscount = "discount"
+)
+
+// ProductBundle makes a product a bundle, e.g. a gift box, of other
+// products. The product quantity and in_stock follow the number of bundles
+// the component stock makes up, and with discount pricing so does the price.
+type ProductBundle struct {
+	Pricing         string            `json:"pricing" db:"pricing"`
+	DiscountPercent int               `json:"discount_percent" db:"discount_percent"`
+	Components      []BundleComponent `json:"components"`
+}
+
+// BundleComponent is a product, or a variant of it, in a bundle. Title, slug
+// and price are filled in from the catalog.
+type BundleComponent struct {
+	ProductID  int    `json:"product_id" db:"product_id"`
+	VariantID  *int   `json:"variant_id,omitempty" db:"variant_id"`
+	Quantity   int    `json:"quantity" db:"quantity"`
+	Title      string `json:"title,omitempty"`
+	Slug       string `json:"slug,omitempty"`
+	PriceCents int    `json:"price_cents,omitempty"`
+}
+
+type SetProductBundleRequest struct {
+	Pricing         string            `json:"pricing" binding:"required"`
+	DiscountPercent int               `json:"discount_percent"`
+	Components      []BundleComponent `json:"components" binding:"required"`
+}
+
+// PriceRange is the lowest and highest variant price of a product
+type PriceRange struct {
+	MinCents int `json:"min_cents"`
+	MaxCents int `json:"max_cents"`
+}
+
+// ProductImage is an image uploaded for a product. Its URL is also listed in
+// the product images, whose order is the display order.
+type ProductImage struct {
+	ID          int               `json:"id" db:"id"`
+	ProductID   int               `json:"product_id" db:"product_id"`
+	URL         string            `json:"url" db:"url"`
+	StorageKeys []string          `json:"-" db:"storage_keys"`
+	ContentType string            `json:"content_type" db:"content_type"`
+	Width       int               `json:"width" db:"width"`
+	Height      int               `json:"height" db:"height"`
+	SizeBytes   int               `json:"size_bytes" db:"size_bytes"`
+	Variants    map[string]string `json:"variants" db:"variants"`
+	CreatedAt   time.Time         `json:"created_at" db:"created_at"`
+}
+
+// Names of resized product image variants; the WebP version of a variant
+// is named with a "_webp" suffix, e.g. "thumb_webp"
+const (
+	ImageVariantThumb  = "thumb"
+	ImageVariantMedium = "medium"
+	ImageVariantWebP   = "webp"
+)
+
+// ReorderProductImagesRequest lists all image URLs of a product in the new order
+type ReorderProductImagesRequest struct {
+	URLs []string `json:"urls" binding:"required"`
+}
+
+// Reasons of price history entries
+const (
+	PriceChangeInitial   = "initial"
+	PriceChangeUpdate    = "update"
+	PriceChangeSaleStart = "sale_start"
+	PriceChangeSaleEnd   = "sale_end"
+)
+
+// PriceChange is an entry of the price history of a product or, with a
+// variant ID, of one of its variants
+type PriceChange struct {
+	ID                 int       `json:"id" db:"id"`
+	ProductID          int       `json:"product_id" db:"product_id"`
+	VariantID          *int      `json:"variant_id,omitempty" db:"variant_id"`
+	PriceCents         int       `json:"price_cents" db:"price_cents"`
+	PreviousPriceCents *int      `json:"previous_price_cents,omitempty" db:"previous_price_cents"`
+	Reason             string    `json:"reason" db:"reason"`
+	CreatedAt          time.Time `json:"created_at" db:"created_at"`
+}
+
+// Statuses of product sales
+const (
+	SaleStatusScheduled = "scheduled"
+	SaleStatusActive    = "active"
+	SaleStatusEnded     = "ended"
+	SaleStatusCanceled  = "canceled"
+)
+
+// ProductSale is a sale price of a product from StartsAt to EndsAt. While
+// the sale is active the product price is the sale price and its regular
+// price is shown as compare_at_price_cents.
+type ProductSale struct {
+	ID                int       `json:"id" db:"id"`
+	ProductID         int       `json:"product_id" db:"product_id"`
+	SalePriceCents    int       `json:"sale_price_cents" db:"sale_price_cents"`
+	RegularPriceCents *int      `json:"regular_price_cents,omitempty" db:"regular_price_cents"`
+	StartsAt          time.Time `json:"starts_at" db:"starts_at"`
+	EndsAt            time.Time `json:"ends_at" db:"ends_at"`
+	Status            string    `json:"status" db:"status"`
+	CreatedAt         time.Time `json:"created_at" db:"created_at"`
+}
+
+type CreateProductSaleRequest struct {
+	SalePriceCents int       `json:"sale_price_cents" binding:"required"`
+	StartsAt       time.Time `json:"starts_at" binding:"required"`
+	EndsAt         time.Time `json:"ends_at" binding:"required"`
+}
+
+// PriceReport is the price history and the sales of a product
+type PriceReport struct {
+	ProductID int           `json:"product_id"`
+	History   []PriceChange `json:"history"`
+	Sales     []ProductSale `json:"sales"`
+}
+
+// Reasons of inventory movements
+const (
+	MovementReceipt     = "receipt"
+	MovementSale        = "sale"
+	MovementReturn      = "return"
+	MovementWriteOff    = "write_off"
+	MovementCorrection  = "correction"
+	MovementReservation = "reservation"
+	MovementTransfer    = "transfer"
+)
+
+// InventoryMovement is an entry of the append-only stock ledger of a product
+// or, with a variant ID, of one of its variants at a location. QuantityAfter
+// is the stock at the location after the movement.
+type InventoryMovement struct {
+	ID             int       `json:"id" db:"id"`
+	LocationID     int       `json:"location_id" db:"location_id"`
+	ProductID      int       `json:"product_id" db:"product_id"`
+	VariantID      *int      `json:"variant_id,omitempty" db:"variant_id"`
+	QuantityChange int       `json:"quantity_change" db:"quantity_change"`
+	QuantityAfter  int       `json:"quantity_after" db:"quantity_after"`
+	Reason         string    `json:"reason" db:"reason"`
+	ActorID        *int      `json:"actor_id,omitempty" db:"actor_id"`
+	Reference      string    `json:"reference,omitempty" db:"reference"`
+	Note           string    `json:"note,omitempty" db:"note"`
+	CreatedAt      time.Time `json:"created_at" db:"created_at"`
+	// Title of the product and variant, when listed
+	Title string `json:"title,omitempty"`
+	// Batches the stock was taken from or added to, when recorded
+	Batches []BatchAllocation `json:"batches,omitempty"`
+}
+
+// StockChange says why stock changes, for the inventory movement recording
+// it. Reference names what caused the change, e.g. "order:42". The stock
+// changes at LocationID, or at the default location when it is zero.
+// Stock added goes to BatchID when it is set, and stock taken comes from it
+// first.
+type StockChange struct {
+	Reason     string
+	ActorID    *int
+	Reference  string
+	Note       string
+	LocationID int
+	BatchID    int
+}
+
+// LineStockChange is stock taken for an order line when a paid order is
+// edited, negative for stock returned. Stock taken records its batches on
+// the order line at index Line; returns of removed lines have no line (-1).
+type LineStockChange struct {
+	Line      int
+	ProductID int
+	VariantID *int
+	Quantity  int
+	Change    StockChange
+}
+
+// CreateInventoryMovementRequest records stock received, written off,
+// returned, reserved or corrected outside of orders
+type CreateInventoryMovementRequest struct {
+	LocationID     int    `json:"location_id"`
+	ProductID      int    `json:"product_id" binding:"required"`
+	VariantID      *int   `json:"variant_id"`
+	QuantityChange int    `json:"quantity_change" binding:"required"`
+	Reason         string `json:"reason" binding:"required"`
+	Reference      string `json:"reference"`
+	Note           string `json:"note"`
+}
+
+// Statuses of stock takes
+const (
+	StockTakeOpen      = "open"
+	StockTakeCompleted = "completed"
+	StockTakeCanceled  = "canceled"
+)
+
+// StockTake is a physical count of the stock at a location. Completing it
+// sets the stock to the counted quantities and records the differences as
+// corrections.
+type StockTake struct {
+	ID          int              `json:"id" db:"id"`
+	LocationID  int              `json:"location_id" db:"location_id"`
+	Status      string           `json:"status" db:"status"`
+	Note        string           `json:"note,omitempty" db:"note"`
+	CreatedBy   *int             `json:"created_by,omitempty" db:"created_by"`
+	CreatedAt   time.Time        `json:"created_at" db:"created_at"`
+	CompletedAt *time.Time       `json:"completed_at,omitempty" db:"completed_at"`
+	Counts      []StockTakeCount `json:"counts,omitempty"`
+}
+
+// StockTakeCount is the counted stock of a product or variant. Until the
+// stock take is completed ExpectedQuantity is the current stock.
+type StockTakeCount struct {
+	ProductID        int       `json:"product_id" db:"product_id"`
+	VariantID        *int      `json:"variant_id,omitempty" db:"variant_id"`
+	Title            string    `json:"title"`
+	CountedQuantity  int       `json:"counted_quantity" db:"counted_quantity"`
+	ExpectedQuantity int       `json:"expected_quantity" db:"expected_quantity"`
+	Difference       int       `json:"difference"`
+	CountedAt        time.Time `json:"counted_at" db:"counted_at"`
+}
+
+type CreateStockTakeRequest struct {
+	LocationID int    `json:"location_id"`
+	Note       string `json:"note"`
+}
+
+// StockTakeCountInput is a counted quantity sent by an admin
+type StockTakeCountInput struct {
+	ProductID       int  `json:"product_id" binding:"required"`
+	VariantID       *int `json:"variant_id"`
+	CountedQuantity int  `json:"counted_quantity"`
+}
+
+type SetStockTakeCountsRequest struct {
+	Counts []StockTakeCountInput `json:"counts" binding:"required"`
+}
+
+// Location is a warehouse or shop stock is kept at. Zones are the delivery
+// zones it ships to; among the locations serving a zone, orders are fulfilled
+// from the one with the lowest priority that has their stock.
+type Location struct {
+	ID        int       `json:"id" db:"id"`
+	Code      string    `json:"code" db:"code"`
+	Name      string    `json:"name" db:"name"`
+	City      string    `json:"city" db:"city"`
+	Address   string    `json:"address" db:"address"`
+	Zones     []string  `json:"zones" db:"zones"`
+	IsDefault bool      `json:"is_default" db:"is_default"`
+	Priority  int       `json:"priority" db:"priority"`
+	Active    bool      `json:"active" db:"active"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+}
+
+type CreateLocationRequest struct {
+	Code      string   `json:"code" binding:"required"`
+	Name      string   `json:"name" binding:"required"`
+	City      string   `json:"city"`
+	Address   string   `json:"address"`
+	Zones     []string `json:"zones"`
+	IsDefault bool     `json:"is_default"`
+	Priority  int      `json:"priority"`
+	Active    *bool    `json:"active"`
+}
+
+type UpdateLocationRequest struct {
+	Name      *string  `json:"name"`
+	City      *string  `json:"city"`
+	Address   *string  `json:"address"`
+	Zones     []string `json:"zones"`
+	IsDefault *bool    `json:"is_default"`
+	Priority  *int     `json:"priority"`
+	Active    *bool    `json:"active"`
+}
+
+// LocationStock is the stock of a product or variant at a location
+type LocationStock struct {
+	LocationID int    `json:"location_id" db:"location_id"`
+	ProductID  int    `json:"product_id" db:"product_id"`
+	VariantID  *int   `json:"variant_id,omitempty" db:"variant_id"`
+	Title      string `json:"title"`
+	Quantity   int    `json:"quantity" db:"quantity"`
+}
+
+// StockTransfer is stock moved from one location to another
+type StockTransfer struct {
+	ID             int       `json:"id" db:"id"`
+	FromLocationID int       `json:"from_location_id" db:"from_location_id"`
+	ToLocationID   int       `json:"to_location_id" db:"to_location_id"`
+	ProductID      int       `json:"product_id" db:"product_id"`
+	VariantID      *int      `json:"variant_id,omitempty" db:"variant_id"`
+	Quantity       int       `json:"quantity" db:"quantity"`
+	ActorID        *int      `json:"actor_id,omitempty" db:"actor_id"`
+	Note           string    `json:"note,omitempty" db:"note"`
+	CreatedAt      time.Time `json:"created_at" db:"created_at"`
+	// Title of the product and variant, when listed
+	Title string `json:"title,omitempty"`
+}
+
+type CreateStockTransferRequest struct {
+	FromLocationID int    `json:"from_location_id" binding:"required"`
+	ToLocationID   int    `json:"to_location_id" binding:"required"`
+	ProductID      int    `json:"product_id" binding:"required"`
+	VariantID      *int   `json:"variant_id"`
+	Quantity       int    `json:"quantity" binding:"required"`
+	Note           string `json:"note"`
+}
+
+// StockBatch is a lot of a product or variant received at a location.
+// Quantity is the stock left of it; stock is taken from the batches expiring
+// first. DiscountSaleID is the sale started when the batch neared its
+// best-before date.
+type StockBatch struct {
+	ID               int        `json:"id" db:"id"`
+	LocationID       int        `json:"location_id" db:"location_id"`
+	ProductID        int        `json:"product_id" db:"product_id"`
+	VariantID        *int       `json:"variant_id,omitempty" db:"variant_id"`
+	LotNumber        string     `json:"lot_number" db:"lot_number"`
+	ProducedOn       *time.Time `json:"produced_on,omitempty" db:"produced_on"`
+	BestBefore       *time.Time `json:"best_before,omitempty" db:"best_before"`
+	ReceivedQuantity int        `json:"received_quantity" db:"received_quantity"`
+	Quantity         int        `json:"quantity" db:"quantity"`
+	DiscountSaleID   *int       `json:"discount_sale_id,omitempty" db:"discount_sale_id"`
+	DiscountedAt     *time.Time `json:"discounted_at,omitempty" db:"discounted_at"`
+	WrittenOffAt     *time.Time `json:"written_off_at,omitempty" db:"written_off_at"`
+	CreatedAt        time.Time  `json:"created_at" db:"created_at"`
+	// Title of the product and variant, when listed
+	Title string `json:"title,omitempty"`
+}
+
+// BatchAllocation is the stock of a batch taken by, or added with, a stock
+// change, e.g. the lots an order line was sold from
+type BatchAllocation struct {
+	BatchID    int        `json:"batch_id"`
+	LotNumber  string     `json:"lot_number"`
+	BestBefore *time.Time `json:"best_before,omitempty"`
+	ProductID  int        `json:"product_id"`
+	VariantID  *int       `json:"variant_id,omitempty"`
+	Quantity   int        `json:"quantity"`
+}
+
+// CreateStockBatchRequest receives a batch at a location. Dates are given as
+// YYYY-MM-DD.
+type CreateStockBatchRequest struct {
+	LocationID int    `json:"location_id"`
+	ProductID  int    `json:"product_id" binding:"required"`
+	VariantID  *int   `json:"variant_id"`
+	LotNumber  string `json:"lot_number" binding:"required"`
+	ProducedOn string `json:"produced_on"`
+	BestBefore string `json:"best_before"`
+	Quantity   int    `json:"quantity" binding:"required"`
+	Note       string `json:"note"`
+}
+
+// WriteOffStockBatchRequest writes off the stock left of a batch
+type WriteOffStockBatchRequest struct {
+	Note string `json:"note"`
+}
+
+// BatchOrder is an order sold with stock of a batch, for recalls
+type BatchOrder struct {
+	OrderID   int       `json:"order_id"`
+	UserID    *int      `json:"user_id,omitempty"`
+	Email     string    `json:"email"`
+	Status    string    `json:"status"`
+	Quantity  int       `json:"quantity"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// SetFulfilmentLocationRequest overrides the location an order ships from
+type SetFulfilmentLocationRequest struct {
+	LocationID int `json:"location_id" binding:"required"`
+}
+
+// Types of product alerts
+const (
+	AlertBackInStock = "back_in_stock"
+	AlertPriceDrop   = "price_drop"
+)
+
+// Statuses of product alerts. Alerts of guests are pending until their email
+// is confirmed.
+const (
+	AlertStatusPending      = "pending"
+	AlertStatusActive       = "active"
+	AlertStatusSent         = "sent"
+	AlertStatusUnsubscribed = "unsubscribed"
+)
+
+// ProductAlert asks for an email once a product is back in stock, or once
+// its price is at or below TargetPriceCents. Alerts are sent once.
+type ProductAlert struct {
+	ID               int        `json:"id" db:"id"`
+	ProductID        int        `json:"product_id" db:"product_id"`
+	UserID           *int       `json:"user_id,omitempty" db:"user_id"`
+	Email            string     `json:"email" db:"email"`
+	Type             string     `json:"type" db:"type"`
+	TargetPriceCents *int       `json:"target_price_cents,omitempty" db:"target_price_cents"`
+	Status           string     `json:"status" db:"status"`
+	UnsubscribeToken string     `json:"-" db:"unsubscribe_token"`
+	SentAt           *time.Time `json:"sent_at,omitempty" db:"sent_at"`
+	CreatedAt        time.Time  `json:"created_at" db:"created_at"`
+	// Title, slug and current price of the product, when listed
+	Title      string `json:"title,omitempty"`
+	Slug       string `json:"slug,omitempty"`
+	PriceCents int    `json:"price_cents,omitempty"`
+}
+
+// CreateProductAlertRequest subscribes to an alert on a product. Guests give
+// their email; signed-in customers are alerted at their account email.
+type CreateProductAlertRequest struct {
+	Type             string `json:"type" binding:"required"`
+	TargetPriceCents *int   `json:"target_price_cents"`
+	Email            string `json:"email"`
+}
+
+// Types of product pairings
+const (
+	PairingClassic    = "classic"
+	PairingComplement = "complement"
+	PairingContrast   = "contrast"
+	PairingRegional   = "regional"
+)
+
+// ProductPairing is a curated match of two products. Pairings work both
+// ways and are returned from the side of ProductID, with the product on the
+// other side as PairedProduct.
+type ProductPairing struct {
+	ID              int       `json:"id" db:"id"`
+	ProductID       int       `json:"product_id" db:"product_id"`
+	PairedProductID int       `json:"paired_product_id" db:"paired_product_id"`
+	PairingType     string    `json:"pairing_type" db:"pairing_type"`
+	Note            string    `json:"note" db:"note"`
+	CreatedAt       time.Time `json:"created_at" db:"created_at"`
+	PairedProduct   *Product  `json:"paired_product,omitempty"`
+}
+
+type CreateProductPairingRequest struct {
+	PairedProductID int    `json:"paired_product_id" binding:"required"`
+	PairingType     string `json:"pairing_type" binding:"required"`
+	Note            string `json:"note"`
+}
+
+type UpdateProductPairingRequest struct {
+	PairingType *string `json:"pairing_type"`
+	Note        *string `json:"note"`
+}
+
+// CheeseBoard is a selection of cheeses with the products that pair with
+// them
+type CheeseBoard struct {
+	Cheeses        []Product                  `json:"cheeses"`
+	Accompaniments []CheeseBoardAccompaniment `json:"accompaniments"`
+}
+
+// CheeseBoardAccompaniment is a product on a cheese board with its pairings
+// to the cheeses of the board
+type CheeseBoardAccompaniment struct {
+	Product  Product          `json:"product"`
+	Pairings []ProductPairing `json:"pairings"`
+}
+
+// Tax categories of products
+const (
+	TaxCategoryVAT0  = "vat0"
+	TaxCategoryVAT10 = "vat10"
+	TaxCategoryVAT20 = "vat20"
+	TaxCategoryNoVAT = "no_vat"
+)
+
+// Allergen and dietary flags of products. Pasteurized and raw milk exclude
+// each other, as do gluten and gluten_free, and nuts and nut_free; products
+// with neither are unknown.
+const (
+	DietaryLactoseFree      = "lactose_free"
+	DietaryVegetarianRennet = "vegetarian_rennet"
+	DietaryPasteurized      = "pasteurized"
+	DietaryRawMilk          = "raw_milk"
+	DietaryGluten           = "gluten"
+	DietaryGlutenFree       = "gluten_free"
+	DietaryNuts             = "nuts"
+	DietaryNutFree          = "nut_free"
+)
+
+// Dietary exclusions, used as product list filters and as restrictions
+// saved by users
+const (
+	ExcludeLactose      = "lactose"
+	ExcludeAnimalRennet = "animal_rennet"
+	ExcludeRawMilk      = "raw_milk"
+	ExcludeGluten       = "gluten"
+	ExcludeNuts         = "nuts"
+)
+
+// DietaryRestrictions are the dietary exclusions saved by a user
+type DietaryRestrictions struct {
+	Restrictions []string `json:"restrictions"`
+}
+
 type Region struct {
-	Code           string `json:"code" db:"code"`
-	Name           string `json:"name" db:"name"`
-	GeoJSONFeature string `json:"geojson_feature" db:"geojson_feature"`
+	Code           string                 `json:"code" db:"code"`
+	Name           string                 `json:"name" db:"name"`
+	GeoJSONFeature string                 `json:"geojson_feature" db:"geojson_feature"`
+	Translations   map[string]Translation `json:"translations,omitempty" db:"translations"`
+}
+
+// UpdateRegionRequest renames a region; translations are merged by locale
+type UpdateRegionRequest struct {
+	Name         *string                `json:"name"`
+	Translations map[string]Translation `json:"translations"`
+}
+
+// Category is a node of the catalog tree. Filtering by a category also
+// returns the products of its descendants.
+type Category struct {
+	ID          int        `json:"id" db:"id"`
+	ParentID    *int       `json:"parent_id" db:"parent_id"`
+	Slug        string     `json:"slug" db:"slug"`
+	Name        string     `json:"name" db:"name"`
+	Description string     `json:"description,omitempty" db:"description"`
+	ImageURL    string     `json:"image_url,omitempty" db:"image_url"`
+	SortOrder   int        `json:"sort_order" db:"sort_order"`
+	CreatedAt   time.Time  `json:"created_at" db:"created_at"`
+	Children    []Category `json:"children,omitempty"`
+	// Name and description in other locales than the default one
+	Translations map[string]Translation `json:"translations,omitempty" db:"translations"`
+}
+
+// Types of product attributes
+const (
+	AttributeTypeEnum    = "enum"
+	AttributeTypeNumber  = "number"
+	AttributeTypeBoolean = "boolean"
+	AttributeTypeText    = "text"
+)
+
+// ProductAttribute is a typed characteristic of products, e.g. milk type
+// (enum) or aging in months (number with a unit). Its code names it in
+// product list filters.
+type ProductAttribute struct {
+	ID         int               `json:"id" db:"id"`
+	Code       string            `json:"code" db:"code"`
+	Name       string            `json:"name" db:"name"`
+	Type       string            `json:"type" db:"type"`
+	Unit       string            `json:"unit,omitempty" db:"unit"`
+	Options    []AttributeOption `json:"options,omitempty" db:"options"`
+	Filterable bool              `json:"filterable" db:"filterable"`
+	SortOrder  int               `json:"sort_order" db:"sort_order"`
+	CreatedAt  time.Time         `json:"created_at" db:"created_at"`
+}
+
+// AttributeOption is an allowed value of an enum attribute
+type AttributeOption struct {
+	Value string `json:"value"`
+	Label string `json:"label"`
+}
+
+// ProductAttributeValue is a product's value of an attribute. Value is a
+// string for enum and text attributes, a number or a boolean; enum values
+// come with the label of their option.
+type ProductAttributeValue struct {
+	ProductID   int         `json:"-"`
+	AttributeID int         `json:"-"`
+	Code        string      `json:"code"`
+	Name        string      `json:"name"`
+	Type        string      `json:"type"`
+	Unit        string      `json:"unit,omitempty"`
+	Value       interface{} `json:"value"`
+	Label       string      `json:"label,omitempty"`
+}
+
+// AttributeFilter narrows a product list to products whose attribute has
+// one of Values or, for numbers, lies between Min and Max. The handler fills
+// Code, Values, Min and Max; the rest is resolved from the attribute.
+type AttributeFilter struct {
+	Code        string
+	Values      []string
+	Min         *float64
+	Max         *float64
+	AttributeID int
+	Type        string
+	Numbers     []float64
+	Bool        *bool
+}
+
+// Statuses of product import jobs
+const (
+	ImportStatusQueued     = "queued"
+	ImportStatusValidating = "validating"
+	ImportStatusImporting  = "importing"
+	ImportStatusCompleted  = "completed"
+	ImportStatusFailed     = "failed"
+)
+
+// ProductImportJob is a background import of a product spreadsheet. A dry
+// run only validates the rows; otherwise the rows are written when all of
+// them are valid.
+type ProductImportJob struct {
+	ID            int              `json:"id" db:"id"`
+	UserID        *int             `json:"user_id,omitempty" db:"user_id"`
+	Filename      string           `json:"filename" db:"filename"`
+	DryRun        bool             `json:"dry_run" db:"dry_run"`
+	Status        string           `json:"status" db:"status"`
+	TotalRows     int              `json:"total_rows" db:"total_rows"`
+	ProcessedRows int              `json:"processed_rows" db:"processed_rows"`
+	CreatedCount  int              `json:"created_count" db:"created_count"`
+	UpdatedCount  int              `json:"updated_count" db:"updated_count"`
+	Errors        []ImportRowError `json:"errors" db:"errors"`
+	CreatedAt     time.Time        `json:"created_at" db:"created_at"`
+	FinishedAt    *time.Time       `json:"finished_at,omitempty" db:"finished_at"`
+}
+
+// ImportRowError is a problem with a spreadsheet row; Row is the 1-based
+// line number including the header, Row 0 is the file as a whole
+type ImportRowError struct {
+	Row     int    `json:"row"`
+	Column  string `json:"column,omitempty"`
+	Message string `json:"message"`
 }
 
 type User struct {
@@ -43,13 +739,228 @@ type Order struct {
 	Status          string                 `json:"status" db:"status"`
 	PaymentID       string                 `json:"payment_id" db:"payment_id"`
 	ShippingAddress map[string]interface{} `json:"shipping_address" db:"shipping_address"`
-	CreatedAt       time.Time              `json:"created_at" db:"created_at"`
+	DeliverySlotID  *int                   `json:"delivery_slot_id" db:"delivery_slot_id"`
+	DeliverySlot    *DeliverySlot          `json:"delivery_slot,omitempty"`
+	// FulfilmentLocationID is the location the order is packed and shipped from
+	FulfilmentLocationID *int      `json:"fulfilment_location_id,omitempty" db:"fulfilment_location_id"`
+	TaxCents             int       `json:"tax_cents" db:"tax_cents"`
+	TaxBreakdown         []TaxLine `json:"tax_breakdown" db:"tax_breakdown"`
+	PricesIncludeTax     bool      `json:"prices_include_tax" db:"prices_include_tax"`
+	GuestEmail           string    `json:"guest_email,omitempty" db:"guest_email"`
+	GuestPhone           string    `json:"guest_phone,omitempty" db:"guest_phone"`
+	AccessToken          string    `json:"-" db:"access_token"`
+	CreatedAt            time.Time `json:"created_at" db:"created_at"`
 }
 
 type OrderItem struct {
-	ProductID  int `json:"product_id"`
-	Quantity   int `json:"quantity"`
-	PriceCents int `json:"price_cents"`
+	ProductID   int    `json:"product_id"`
+	VariantID   *int   `json:"variant_id,omitempty"`
+	SKU         string `json:"sku,omitempty"`
+	Quantity    int    `json:"quantity"`
+	Unit        string `json:"unit,omitempty"`
+	PriceCents  int    `json:"price_cents"`
+	TaxCategory string `json:"tax_category,omitempty"`
+	TaxCents    int    `json:"tax_cents"`
+	// Batches the line was sold from, once the order is paid
+	Batches []BatchAllocation `json:"batches,omitempty"`
+}
+
+// CartItem is a product or product variant in a user's cart
+type CartItem struct {
+	ProductID  int             `json:"product_id" db:"product_id"`
+	VariantID  *int            `json:"variant_id" db:"variant_id"`
+	Quantity   int             `json:"quantity" db:"quantity"`
+	PriceCents int             `json:"price_cents"`
+	Product    *Product        `json:"product,omitempty"`
+	Variant    *ProductVariant `json:"variant,omitempty"`
+}
+
+type Cart struct {
+	Items      []CartItem `json:"items"`
+	TotalCents int        `json:"total_cents"`
+}
+
+// Wishlist is a named list of products a customer saves for later. A list
+// with a share token can be viewed by anyone with its link.
+type Wishlist struct {
+	ID         int            `json:"id" db:"id"`
+	UserID     int            `json:"-" db:"user_id"`
+	Name       string         `json:"name" db:"name"`
+	ShareToken *string        `json:"share_token,omitempty" db:"share_token"`
+	Items      []WishlistItem `json:"items"`
+	CreatedAt  time.Time      `json:"created_at" db:"created_at"`
+	UpdatedAt  time.Time      `json:"updated_at" db:"updated_at"`
+}
+
+// WishlistItem is a product or product variant on a wishlist
+type WishlistItem struct {
+	ID         int             `json:"id" db:"id"`
+	WishlistID int             `json:"-" db:"wishlist_id"`
+	ProductID  int             `json:"product_id" db:"product_id"`
+	VariantID  *int            `json:"variant_id,omitempty" db:"variant_id"`
+	Note       string          `json:"note,omitempty" db:"note"`
+	CreatedAt  time.Time       `json:"created_at" db:"created_at"`
+	Product    *Product        `json:"product,omitempty"`
+	Variant    *ProductVariant `json:"variant,omitempty"`
+}
+
+type CreateWishlistRequest struct {
+	Name string `json:"name" binding:"required"`
+}
+
+// UpdateWishlistRequest renames a wishlist and turns its share link on or
+// off; a new link is made each time sharing is turned on
+type UpdateWishlistRequest struct {
+	Name   *string `json:"name"`
+	Shared *bool   `json:"shared"`
+}
+
+type AddWishlistItemRequest struct {
+	ProductID int    `json:"product_id" binding:"required"`
+	VariantID *int   `json:"variant_id"`
+	Note      string `json:"note"`
+}
+
+// MoveWishlistItemToCartRequest moves a wishlist item to the cart, one
+// piece or the product's minimum quantity unless a quantity is given. Keep
+// leaves the item on the wishlist, e.g. on a shared gift list.
+type MoveWishlistItemToCartRequest struct {
+	Quantity int  `json:"quantity"`
+	Keep     bool `json:"keep"`
+}
+
+// EmailTemplate is an admin's version of a built-in email in one locale.
+// Subject and body are Go templates over the data of the email.
+type EmailTemplate struct {
+	Name      string    `json:"name" db:"name"`
+	Locale    string    `json:"locale" db:"locale"`
+	Subject   string    `json:"subject" db:"subject"`
+	Body      string    `json:"body" db:"body"`
+	UpdatedBy *int      `json:"updated_by,omitempty" db:"updated_by"`
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// EmailTemplateSet lists the admin versions of a built-in email by locale;
+// locales without one get the built-in email
+type EmailTemplateSet struct {
+	Name    string                   `json:"name"`
+	Locales map[string]EmailTemplate `json:"locales"`
+}
+
+type SetEmailTemplateRequest struct {
+	Subject string `json:"subject" binding:"required"`
+	Body    string `json:"body" binding:"required"`
+}
+
+// OrderHistoryEntry records a change made to an order
+type OrderHistoryEntry struct {
+	ID        int                    `json:"id" db:"id"`
+	OrderID   int                    `json:"order_id" db:"order_id"`
+	ActorID   *int                   `json:"actor_id" db:"actor_id"`
+	Action    string                 `json:"action" db:"action"`
+	Details   map[string]interface{} `json:"details" db:"details"`
+	CreatedAt time.Time              `json:"created_at" db:"created_at"`
+}
+
+// OrderEditResult is the outcome of an admin order edit, including how the
+// payment difference was settled
+type OrderEditResult struct {
+	Order           *Order `json:"order"`
+	AmountDiffCents int    `json:"amount_diff_cents"`
+	PaymentID       string `json:"payment_id,omitempty"`
+	PaymentURL      string `json:"payment_url,omitempty"`
+	RefundID        string `json:"refund_id,omitempty"`
+	PaymentError    string `json:"payment_error,omitempty"`
+}
+
+// TaxLine is the tax of an order, or of an order line, for one tax category
+type TaxLine struct {
+	TaxCategory string `json:"tax_category"`
+	RatePercent int    `json:"rate_percent"`
+	NetCents    int    `json:"net_cents"`
+	TaxCents    int    `json:"tax_cents"`
+	GrossCents  int    `json:"gross_cents"`
+}
+
+// Invoice is the printable breakdown of an order
+type Invoice struct {
+	Number           string        `json:"number"`
+	OrderID          int           `json:"order_id"`
+	IssuedAt         time.Time     `json:"issued_at"`
+	Status           string        `json:"status"`
+	Currency         string        `json:"currency"`
+	Lines            []InvoiceLine `json:"lines"`
+	TaxBreakdown     []TaxLine     `json:"tax_breakdown"`
+	NetCents         int           `json:"net_cents"`
+	TaxCents         int           `json:"tax_cents"`
+	TotalCents       int           `json:"total_cents"`
+	PricesIncludeTax bool          `json:"prices_include_tax"`
+}
+
+type InvoiceLine struct {
+	ProductID   int    `json:"product_id"`
+	VariantID   *int   `json:"variant_id,omitempty"`
+	SKU         string `json:"sku,omitempty"`
+	Title       string `json:"title"`
+	Quantity    int    `json:"quantity"`
+	Unit        string `json:"unit,omitempty"`
+	PriceCents  int    `json:"price_cents"`
+	TaxCategory string `json:"tax_category"`
+	RatePercent int    `json:"rate_percent"`
+	NetCents    int    `json:"net_cents"`
+	TaxCents    int    `json:"tax_cents"`
+	GrossCents  int    `json:"gross_cents"`
+}
+
+// DeliverySlot is a courier time window in a delivery zone with limited capacity
+type DeliverySlot struct {
+	ID        int       `json:"id" db:"id"`
+	Zone      string    `json:"zone" db:"zone"`
+	StartsAt  time.Time `json:"starts_at" db:"starts_at"`
+	EndsAt    time.Time `json:"ends_at" db:"ends_at"`
+	Capacity  int       `json:"capacity" db:"capacity"`
+	Reserved  int       `json:"reserved" db:"reserved"`
+	Available int       `json:"available"`
+	Active    bool      `json:"active" db:"active"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+}
+
+type SubscriptionBoxItem struct {
+	ProductID int  `json:"product_id"`
+	VariantID *int `json:"variant_id,omitempty"`
+	Quantity  int  `json:"quantity"`
+}
+
+// SubscriptionBox is a curated set of products sold as a recurring box
+type SubscriptionBox struct {
+	ID          int                   `json:"id" db:"id"`
+	Slug        string                `json:"slug" db:"slug"`
+	Title       string                `json:"title" db:"title"`
+	Description string                `json:"description" db:"description"`
+	Items       []SubscriptionBoxItem `json:"items" db:"items"`
+	Active      bool                  `json:"active" db:"active"`
+	CreatedAt   time.Time             `json:"created_at" db:"created_at"`
+}
+
+// ProductSubscription is a recurring order of a product or a box
+type ProductSubscription struct {
+	ID               int                    `json:"id" db:"id"`
+	UserID           int                    `json:"user_id" db:"user_id"`
+	ProductID        *int                   `json:"product_id" db:"product_id"`
+	VariantID        *int                   `json:"variant_id" db:"variant_id"`
+	BoxID            *int                   `json:"box_id" db:"box_id"`
+	Quantity         int                    `json:"quantity" db:"quantity"`
+	Frequency        string                 `json:"frequency" db:"frequency"`
+	Status           string                 `json:"status" db:"status"`
+	ShippingAddress  map[string]interface{} `json:"shipping_address" db:"shipping_address"`
+	PaymentMethodID  string                 `json:"-" db:"payment_method_id"`
+	HasPaymentMethod bool                   `json:"has_payment_method"`
+	NextRunAt        time.Time              `json:"next_run_at" db:"next_run_at"`
+	SkipNext         bool                   `json:"skip_next" db:"skip_next"`
+	FailedAttempts   int                    `json:"failed_attempts" db:"failed_attempts"`
+	NextRetryAt      *time.Time             `json:"next_retry_at" db:"next_retry_at"`
+	LastOrderID      *int                   `json:"last_order_id" db:"last_order_id"`
+	CreatedAt        time.Time              `json:"created_at" db:"created_at"`
 }
 
 type Event struct {
@@ -61,10 +972,70 @@ type Event struct {
 }
 
 type PaginatedResponse struct {
-	Items    interface{} `json:"items"`
-	Total    int         `json:"total"`
-	Page     int         `json:"page"`
-	PageSize int         `json:"page_size"`
+	Items      interface{} `json:"items"`
+	Total      int         `json:"total"`
+	Page       int         `json:"page"`
+	PageSize   int         `json:"page_size"`
+	NextCursor string      `json:"next_cursor,omitempty"`
+}
+
+// Sort modes of the product list
+const (
+	ProductSortNewest     = "newest"
+	ProductSortPriceAsc   = "price_asc"
+	ProductSortPriceDesc  = "price_desc"
+	ProductSortPopularity = "popularity"
+	ProductSortRating     = "rating"
+	ProductSortRelevance  = "relevance"
+)
+
+// ProductCursor marks the position after the last product of a page: the
+// value of the sort key in its database text form and the product ID, which
+// breaks ties
+type ProductCursor struct {
+	Sort  string `json:"s"`
+	Value string `json:"v"`
+	ID    int    `json:"id"`
+}
+
+// ProductPage is a page of products with the total of all matching products.
+// Next is set when more products follow.
+type ProductPage struct {
+	Products []Product
+	Total    int
+	Next     *ProductCursor
+}
+
+// ProductListResponse is the product list page, with facets when requested
+type ProductListResponse struct {
+	PaginatedResponse
+	Facets *ProductFacets `json:"facets,omitempty"`
+}
+
+// ProductFacets counts the products each filter value would return. Every
+// facet applies all current filters except its own, so the counts of
+// alternative values stay visible after one is selected.
+type ProductFacets struct {
+	Tags         []FacetCount       `json:"tags"`
+	Regions      []FacetCount       `json:"regions"`
+	Categories   []FacetCount       `json:"categories"`
+	PriceBuckets []PriceBucketCount `json:"price_buckets"`
+	InStock      int                `json:"in_stock"`
+	OutOfStock   int                `json:"out_of_stock"`
+}
+
+type FacetCount struct {
+	Value string `json:"value"`
+	Label string `json:"label,omitempty"`
+	Count int    `json:"count"`
+}
+
+// PriceBucketCount counts products priced from MinCents up to, but not
+// including, MaxCents; the last bucket has no upper bound
+type PriceBucketCount struct {
+	MinCents int  `json:"min_cents"`
+	MaxCents *int `json:"max_cents"`
+	Count    int  `json:"count"`
 }
 
 type ErrorResponse struct {
@@ -82,6 +1053,11 @@ type RegisterRequest struct {
 	Password string `json:"password" binding:"required,min=8"`
 }
 
+// UpdateLocaleRequest sets the locale emails are sent to a user in
+type UpdateLocaleRequest struct {
+	Locale string `json:"locale" binding:"required"`
+}
+
 type RefreshTokenRequest struct {
 	RefreshToken string `json:"refresh_token" binding:"required"`
 }
@@ -95,10 +1071,18 @@ type RecommendationRequest struct {
 	Tags  []string `json:"tags"`
 }
 
+// Payment kinds: the order's primary payment, or a supplementary payment of
+// an extra amount owed after an order edit, which doesn't change the order status
+const (
+	PaymentKindPrimary       = "primary"
+	PaymentKindSupplementary = "supplementary"
+)
+
 type Payment struct {
 	ID             int                    `json:"id" db:"id"`
 	PaymentID      string                 `json:"payment_id" db:"payment_id"`
 	OrderID        int                    `json:"order_id" db:"order_id"`
+	Kind           string                 `json:"kind" db:"kind"`
 	AmountCents    int                    `json:"amount_cents" db:"amount_cents"`
 	Currency       string                 `json:"currency" db:"currency"`
 	Status         string                 `json:"status" db:"status"`
@@ -110,9 +1094,54 @@ type Payment struct {
 	UpdatedAt      time.Time              `json:"updated_at" db:"updated_at"`
 }
 
+// PaymentMethod is a card or wallet a customer saved with the payment
+// provider; subscriptions charge it by its provider id
+type PaymentMethod struct {
+	ID               int       `json:"id" db:"id"`
+	UserID           int       `json:"-" db:"user_id"`
+	Provider         string    `json:"provider" db:"provider"`
+	ProviderMethodID string    `json:"payment_method_id" db:"provider_method_id"`
+	Title            string    `json:"title" db:"title"`
+	CreatedAt        time.Time `json:"created_at" db:"created_at"`
+}
+
 type CreateOrderRequest struct {
 	Items           []OrderItem            `json:"items" binding:"required"`
 	ShippingAddress map[string]interface{} `json:"shipping_address" binding:"required"`
+	DeliverySlotID  *int                   `json:"delivery_slot_id"`
+}
+
+type AddOrderItemRequest struct {
+	ProductID int    `json:"product_id" binding:"required"`
+	VariantID *int   `json:"variant_id"`
+	Quantity  int    `json:"quantity" binding:"required"`
+	Note      string `json:"note"`
+}
+
+type AddToCartRequest struct {
+	ProductID int  `json:"product_id" binding:"required"`
+	VariantID *int `json:"variant_id"`
+	Quantity  int  `json:"quantity" binding:"required"`
+}
+
+type UpdateOrderItemRequest struct {
+	Quantity int    `json:"quantity" binding:"required"`
+	Note     string `json:"note"`
+}
+
+type GuestCheckoutRequest struct {
+	Email           string                 `json:"email" binding:"required"`
+	Phone           string                 `json:"phone" binding:"required"`
+	Items           []OrderItem            `json:"items" binding:"required"`
+	ShippingAddress map[string]interface{} `json:"shipping_address" binding:"required"`
+	DeliverySlotID  *int                   `json:"delivery_slot_id"`
+}
+
+type GuestCheckoutResponse struct {
+	Order       *Order `json:"order"`
+	AccessToken string `json:"access_token"`
+	PaymentID   string `json:"payment_id,omitempty"`
+	PaymentURL  string `json:"payment_url,omitempty"`
 }
 
 type CreatePaymentRequest struct {
@@ -130,29 +1159,170 @@ type PaymentWebhookRequest struct {
 
 // Admin request models
 type CreateProductRequest struct {
-	Slug        string   `json:"slug" binding:"required"`
-	Title       string   `json:"title" binding:"required"`
-	Description string   `json:"description"`
-	PriceCents  int      `json:"price_cents" binding:"required"`
-	Currency    string   `json:"currency"`
-	Tags        []string `json:"tags"`
-	RegionCode  string   `json:"region_code"`
-	Images      []string `json:"images"`
-	Quantity    int      `json:"quantity"`
+	Slug         string   `json:"slug" binding:"required"`
+	Title        string   `json:"title" binding:"required"`
+	Description  string   `json:"description"`
+	PriceCents   int      `json:"price_cents" binding:"required"`
+	Currency     string   `json:"currency"`
+	Tags         []string `json:"tags"`
+	RegionCode   string   `json:"region_code"`
+	Images       []string `json:"images"`
+	Quantity     int      `json:"quantity"`
+	TaxCategory  string   `json:"tax_category"`
+	Unit         string   `json:"unit"`
+	MinQuantity  int      `json:"min_quantity"`
+	QuantityStep int      `json:"quantity_step"`
+	DietaryFlags []string `json:"dietary_flags"`
+	// Texts in other locales, e.g. {"en": {"title": "...", "description": "..."}}
+	Translations map[string]Translation `json:"translations"`
 }
 
+// UpdateProductRequest changes the given fields. Translations are merged by
+// locale; a locale given with empty texts is removed.
 type UpdateProductRequest struct {
-	Title       *string   `json:"title"`
-	Description *string   `json:"description"`
-	PriceCents  *int      `json:"price_cents"`
-	Currency    *string   `json:"currency"`
-	Tags        []string  `json:"tags"`
-	RegionCode  *string   `json:"region_code"`
-	Images      []string  `json:"images"`
-	InStock     *bool     `json:"in_stock"`
-	Quantity    *int      `json:"quantity"`
+	Title        *string                `json:"title"`
+	Description  *string                `json:"description"`
+	PriceCents   *int                   `json:"price_cents"`
+	Currency     *string                `json:"currency"`
+	Tags         []string               `json:"tags"`
+	RegionCode   *string                `json:"region_code"`
+	Images       []string               `json:"images"`
+	InStock      *bool                  `json:"in_stock"`
+	Quantity     *int                   `json:"quantity"`
+	TaxCategory  *string                `json:"tax_category"`
+	Unit         *string                `json:"unit"`
+	MinQuantity  *int                   `json:"min_quantity"`
+	QuantityStep *int                   `json:"quantity_step"`
+	DietaryFlags []string               `json:"dietary_flags"`
+	Translations map[string]Translation `json:"translations"`
+}
+
+type CreateProductVariantRequest struct {
+	SKU         string `json:"sku" binding:"required"`
+	Title       string `json:"title" binding:"required"`
+	WeightGrams *int   `json:"weight_grams"`
+	VolumeML    *int   `json:"volume_ml"`
+	PriceCents  int    `json:"price_cents" binding:"required"`
+	Quantity    int    `json:"quantity"`
+	Barcode     string `json:"barcode"`
+	SortOrder   int    `json:"sort_order"`
+}
+
+type UpdateProductVariantRequest struct {
+	SKU         *string `json:"sku"`
+	Title       *string `json:"title"`
+	WeightGrams *int    `json:"weight_grams"`
+	VolumeML    *int    `json:"volume_ml"`
+	PriceCents  *int    `json:"price_cents"`
+	Quantity    *int    `json:"quantity"`
+	Barcode     *string `json:"barcode"`
+	SortOrder   *int    `json:"sort_order"`
+}
+
+type CreateCategoryRequest struct {
+	ParentID     *int                   `json:"parent_id"`
+	Slug         string                 `json:"slug" binding:"required"`
+	Name         string                 `json:"name" binding:"required"`
+	Description  string                 `json:"description"`
+	ImageURL     string                 `json:"image_url"`
+	SortOrder    int                    `json:"sort_order"`
+	Translations map[string]Translation `json:"translations"`
+}
+
+// UpdateCategoryRequest changes the given fields. Setting parent_id to 0
+// moves the category to the top level. Translations are merged by locale.
+type UpdateCategoryRequest struct {
+	ParentID     *int                   `json:"parent_id"`
+	Slug         *string                `json:"slug"`
+	Name         *string                `json:"name"`
+	Description  *string                `json:"description"`
+	ImageURL     *string                `json:"image_url"`
+	SortOrder    *int                   `json:"sort_order"`
+	Translations map[string]Translation `json:"translations"`
+}
+
+type SetProductCategoriesRequest struct {
+	CategoryIDs []int `json:"category_ids"`
+}
+
+type CreateProductAttributeRequest struct {
+	Code       string            `json:"code" binding:"required"`
+	Name       string            `json:"name" binding:"required"`
+	Type       string            `json:"type" binding:"required"`
+	Unit       string            `json:"unit"`
+	Options    []AttributeOption `json:"options"`
+	Filterable *bool             `json:"filterable"`
+	SortOrder  int               `json:"sort_order"`
+}
+
+// UpdateProductAttributeRequest changes the given fields; the code and type
+// of an attribute are fixed as product values depend on them
+type UpdateProductAttributeRequest struct {
+	Name       *string            `json:"name"`
+	Unit       *string            `json:"unit"`
+	Options    *[]AttributeOption `json:"options"`
+	Filterable *bool              `json:"filterable"`
+	SortOrder  *int               `json:"sort_order"`
 }
 
+// SetProductAttributesRequest replaces the attribute values of a product,
+// keyed by attribute code
+type SetProductAttributesRequest struct {
+	Values map[string]interface{} `json:"values"`
+}
+
+// UpdateProductQuantityRequest sets the stock of a product, recorded as a
+// correction with the note
 type UpdateProductQuantityRequest struct {
-	Quantity int `json:"quantity" binding:"required"`
+	Quantity int    `json:"quantity" binding:"required"`
+	Note     string `json:"note"`
+}
+
+type CreateDeliverySlotRequest struct {
+	Zone     string    `json:"zone" binding:"required"`
+	StartsAt time.Time `json:"starts_at" binding:"required"`
+	EndsAt   time.Time `json:"ends_at" binding:"required"`
+	Capacity int       `json:"capacity" binding:"required"`
+	Active   *bool     `json:"active"`
+}
+
+type UpdateDeliverySlotRequest struct {
+	Zone     *string    `json:"zone"`
+	StartsAt *time.Time `json:"starts_at"`
+	EndsAt   *time.Time `json:"ends_at"`
+	Capacity *int       `json:"capacity"`
+	Active   *bool      `json:"active"`
+}
+
+type CreateSubscriptionRequest struct {
+	ProductID       *int                   `json:"product_id"`
+	VariantID       *int                   `json:"variant_id"`
+	BoxID           *int                   `json:"box_id"`
+	Quantity        int                    `json:"quantity"`
+	Frequency       string                 `json:"frequency" binding:"required"`
+	ShippingAddress map[string]interface{} `json:"shipping_address" binding:"required"`
+	PaymentMethodID string                 `json:"payment_method_id"`
+	StartAt         *time.Time             `json:"start_at"`
+}
+
+type UpdateSubscriptionRequest struct {
+	Quantity        *int                   `json:"quantity"`
+	Frequency       *string                `json:"frequency"`
+	ShippingAddress map[string]interface{} `json:"shipping_address"`
+	PaymentMethodID *string                `json:"payment_method_id"`
+}
+
+type CreateSubscriptionBoxRequest struct {
+	Slug        string                `json:"slug" binding:"required"`
+	Title       string                `json:"title" binding:"required"`
+	Description string                `json:"description"`
+	Items       []SubscriptionBoxItem `json:"items" binding:"required"`
+	Active      *bool                 `json:"active"`
+}
+
+type UpdateSubscriptionBoxRequest struct {
+	Title       *string               `json:"title"`
+	Description *string               `json:"description"`
+	Items       []SubscriptionBoxItem `json:"items"`
+	Active      *bool                 `json:"active"`
 }
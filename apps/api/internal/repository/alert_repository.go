@@ -0,0 +1,235 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"gastroshop-api/internal/models"
+)
+
+// AlertRepository stores the back-in-stock and price-drop alerts customers
+// subscribe to
+type AlertRepository struct {
+	db *sql.DB
+}
+
+func NewAlertRepository(db *sql.DB) *AlertRepository {
+	return &AlertRepository{db: db}
+}
+
+const alertColumns = `a.id, a.product_id, a.user_id, a.email, a.type, a.target_price_cents, a.status, a.unsubscribe_token,
+	a.sent_at, a.created_at, p.title, p.slug, p.price_cents`
+
+func scanAlert(row rowScanner) (*models.ProductAlert, error) {
+	var a models.ProductAlert
+	err := row.Scan(
+		&a.ID, &a.ProductID, &a.UserID, &a.Email, &a.Type, &a.TargetPriceCents, &a.Status, &a.UnsubscribeToken,
+		&a.SentAt, &a.CreatedAt, &a.Title, &a.Slug, &a.PriceCents,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &a, nil
+}
+
+func (r *AlertRepository) queryAlerts(query string, args ...interface{}) ([]models.ProductAlert, error) {
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	alerts := []models.ProductAlert{}
+	for rows.Next() {
+		a, err := scanAlert(rows)
+		if err != nil {
+			return nil, err
+		}
+		alerts = append(alerts, *a)
+	}
+
+	return alerts, rows.Err()
+}
+
+// CreateAlert subscribes to an alert, active or pending confirmation.
+// Subscribing again to a pending or active alert of the same type on the
+// product keeps its unsubscribe token; an active alert only changes when the
+// new subscription is active as well.
+func (r *AlertRepository) CreateAlert(a *models.ProductAlert) error {
+	query := `
+		INSERT INTO product_alerts (product_id, user_id, email, type, target_price_cents, status, unsubscribe_token)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (product_id, LOWER(email), type) WHERE status IN ('pending', 'active')
+		DO UPDATE SET
+			target_price_cents = CASE WHEN EXCLUDED.status = 'active' OR product_alerts.status = 'pending'
+				THEN EXCLUDED.target_price_cents ELSE product_alerts.target_price_cents END,
+			user_id = COALESCE(product_alerts.user_id, EXCLUDED.user_id),
+			status = CASE WHEN EXCLUDED.status = 'active' THEN 'active' ELSE product_alerts.status END
+		RETURNING id, user_id, target_price_cents, status, unsubscribe_token, created_at
+	`
+	return r.db.QueryRow(query, a.ProductID, a.UserID, a.Email, a.Type, a.TargetPriceCents, a.Status, a.UnsubscribeToken).
+		Scan(&a.ID, &a.UserID, &a.TargetPriceCents, &a.Status, &a.UnsubscribeToken, &a.CreatedAt)
+}
+
+// CountActiveAlerts returns the number of active and pending alerts of an
+// email
+func (r *AlertRepository) CountActiveAlerts(email string) (int, error) {
+	var count int
+	query := `SELECT COUNT(*) FROM product_alerts WHERE LOWER(email) = LOWER($1) AND status IN ('pending', 'active')`
+	err := r.db.QueryRow(query, email).Scan(&count)
+	return count, err
+}
+
+// GetAlertByToken returns the alert with an unsubscribe token, nil when
+// there is none
+func (r *AlertRepository) GetAlertByToken(token string) (*models.ProductAlert, error) {
+	query := `
+		SELECT ` + alertColumns + `
+		FROM product_alerts a
+		JOIN products p ON p.id = a.product_id
+		WHERE a.unsubscribe_token = $1
+	`
+	a, err := scanAlert(r.db.QueryRow(query, token))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// ConfirmAlert activates the pending alert with the token and returns the
+// status of the alert, empty when no alert has the token
+func (r *AlertRepository) ConfirmAlert(token string) (string, error) {
+	query := `
+		UPDATE product_alerts
+		SET status = CASE WHEN status = 'pending' THEN 'active' ELSE status END
+		WHERE unsubscribe_token = $1
+		RETURNING status
+	`
+	var status string
+	err := r.db.QueryRow(query, token).Scan(&status)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	return status, err
+}
+
+// GetUserAlerts returns the active alerts of a user, newest first
+func (r *AlertRepository) GetUserAlerts(userID int) ([]models.ProductAlert, error) {
+	query := `
+		SELECT ` + alertColumns + `
+		FROM product_alerts a
+		JOIN products p ON p.id = a.product_id
+		WHERE a.user_id = $1 AND a.status = 'active'
+		ORDER BY a.created_at DESC, a.id DESC
+	`
+	return r.queryAlerts(query, userID)
+}
+
+// UnsubscribeUserAlert unsubscribes an active alert of a user
+func (r *AlertRepository) UnsubscribeUserAlert(userID, id int) error {
+	query := `UPDATE product_alerts SET status = 'unsubscribed' WHERE id = $1 AND user_id = $2 AND status = 'active'`
+	result, err := r.db.Exec(query, id, userID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("alert with id %d not found", id)
+	}
+
+	return nil
+}
+
+// UnsubscribeByToken unsubscribes the active alert with the token, or with
+// all set every active alert of its email. It returns the number of alerts
+// unsubscribed and false when no alert has the token.
+func (r *AlertRepository) UnsubscribeByToken(token string, all bool) (int, bool, error) {
+	var email string
+	err := r.db.QueryRow(`SELECT email FROM product_alerts WHERE unsubscribe_token = $1`, token).Scan(&email)
+	if err == sql.ErrNoRows {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, err
+	}
+
+	query := `UPDATE product_alerts SET status = 'unsubscribed' WHERE unsubscribe_token = $1 AND status IN ('pending', 'active')`
+	args := []interface{}{token}
+	if all {
+		query = `UPDATE product_alerts SET status = 'unsubscribed' WHERE LOWER(email) = LOWER($1) AND status IN ('pending', 'active')`
+		args = []interface{}{email}
+	}
+	result, err := r.db.Exec(query, args...)
+	if err != nil {
+		return 0, true, err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return 0, true, err
+	}
+	return int(rowsAffected), true, nil
+}
+
+// GetDueAlerts returns up to limit active alerts whose product is back in
+// stock or at or below their target price, oldest first. With a daily limit
+// it leaves out the alerts of an email beyond what it may still get on top
+// of the alerts sent to it since a time, so held back alerts don't fill the
+// batch.
+func (r *AlertRepository) GetDueAlerts(dailyLimit int, since time.Time, limit int) ([]models.ProductAlert, error) {
+	query := `
+		WITH due AS (
+			SELECT a.id,
+				ROW_NUMBER() OVER (PARTITION BY LOWER(a.email) ORDER BY a.created_at, a.id) AS position,
+				(SELECT COUNT(*) FROM product_alerts s WHERE LOWER(s.email) = LOWER(a.email) AND s.sent_at >= $2) AS sent
+			FROM product_alerts a
+			JOIN products p ON p.id = a.product_id
+			WHERE a.status = 'active' AND p.archived_at IS NULL AND (
+				(a.type = 'back_in_stock' AND p.in_stock AND p.quantity > 0)
+				OR (a.type = 'price_drop' AND p.price_cents <= a.target_price_cents)
+			)
+		)
+		SELECT ` + alertColumns + `
+		FROM due
+		JOIN product_alerts a ON a.id = due.id
+		JOIN products p ON p.id = a.product_id
+		WHERE $1::int <= 0 OR due.sent + due.position <= $1::int
+		ORDER BY a.created_at, a.id
+		LIMIT $3
+	`
+	return r.queryAlerts(query, dailyLimit, since, limit)
+}
+
+// CountSentSince returns the number of alerts sent to an email since a time
+func (r *AlertRepository) CountSentSince(email string, since time.Time) (int, error) {
+	var count int
+	query := `SELECT COUNT(*) FROM product_alerts WHERE LOWER(email) = LOWER($1) AND sent_at >= $2`
+	err := r.db.QueryRow(query, email, since).Scan(&count)
+	return count, err
+}
+
+// MarkAlertSent marks an active alert sent
+func (r *AlertRepository) MarkAlertSent(id int, sentAt time.Time) error {
+	result, err := r.db.Exec(`UPDATE product_alerts SET status = 'sent', sent_at = $1 WHERE id = $2 AND status = 'active'`, sentAt, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("alert with id %d not found", id)
+	}
+
+	return nil
+}
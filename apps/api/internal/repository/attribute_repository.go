@@ -0,0 +1,252 @@
+package repository
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	"gastroshop-api/internal/models"
+
+	"github.com/lib/pq"
+)
+
+type AttributeRepository struct {
+	db *sql.DB
+}
+
+func NewAttributeRepository(db *sql.DB) *AttributeRepository {
+	return &AttributeRepository{db: db}
+}
+
+const attributeColumns = `id, code, name, type, COALESCE(unit, ''), options, filterable, sort_order, created_at`
+
+func scanAttribute(row rowScanner) (*models.ProductAttribute, error) {
+	var a models.ProductAttribute
+	var optionsJSON []byte
+	err := row.Scan(&a.ID, &a.Code, &a.Name, &a.Type, &a.Unit, &optionsJSON, &a.Filterable, &a.SortOrder, &a.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(optionsJSON, &a.Options); err != nil {
+		return nil, err
+	}
+	return &a, nil
+}
+
+// GetAttributes returns all attributes ordered for display
+func (r *AttributeRepository) GetAttributes() ([]models.ProductAttribute, error) {
+	query := `SELECT ` + attributeColumns + ` FROM product_attributes ORDER BY sort_order, name`
+
+	rows, err := r.db.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var attributes []models.ProductAttribute
+	for rows.Next() {
+		a, err := scanAttribute(rows)
+		if err != nil {
+			return nil, err
+		}
+		attributes = append(attributes, *a)
+	}
+
+	return attributes, nil
+}
+
+func (r *AttributeRepository) GetAttributeByID(id int) (*models.ProductAttribute, error) {
+	query := `SELECT ` + attributeColumns + ` FROM product_attributes WHERE id = $1`
+
+	a, err := scanAttribute(r.db.QueryRow(query, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return a, nil
+}
+
+func (r *AttributeRepository) GetAttributeByCode(code string) (*models.ProductAttribute, error) {
+	query := `SELECT ` + attributeColumns + ` FROM product_attributes WHERE code = $1`
+
+	a, err := scanAttribute(r.db.QueryRow(query, code))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return a, nil
+}
+
+func (r *AttributeRepository) CreateAttribute(a *models.ProductAttribute) error {
+	optionsJSON, err := attributeOptionsJSON(a.Options)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO product_attributes (code, name, type, unit, options, filterable, sort_order)
+		VALUES ($1, $2, $3, NULLIF($4, ''), $5, $6, $7)
+		RETURNING id, created_at
+	`
+	return r.db.QueryRow(
+		query,
+		a.Code,
+		a.Name,
+		a.Type,
+		a.Unit,
+		optionsJSON,
+		a.Filterable,
+		a.SortOrder,
+	).Scan(&a.ID, &a.CreatedAt)
+}
+
+func (r *AttributeRepository) UpdateAttribute(id int, a *models.ProductAttribute) error {
+	optionsJSON, err := attributeOptionsJSON(a.Options)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		UPDATE product_attributes
+		SET name = $1, unit = NULLIF($2, ''), options = $3, filterable = $4, sort_order = $5
+		WHERE id = $6
+	`
+	result, err := r.db.Exec(query, a.Name, a.Unit, optionsJSON, a.Filterable, a.SortOrder, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("attribute with id %d not found", id)
+	}
+
+	return nil
+}
+
+// DeleteAttribute deletes an attribute with the product values of it
+func (r *AttributeRepository) DeleteAttribute(id int) error {
+	result, err := r.db.Exec(`DELETE FROM product_attributes WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("attribute with id %d not found", id)
+	}
+
+	return nil
+}
+
+// RemoveAttributeOptionValues deletes product values of an enum attribute
+// that are no longer among its options
+func (r *AttributeRepository) RemoveAttributeOptionValues(id int, options []string) error {
+	query := `DELETE FROM product_attribute_values WHERE attribute_id = $1 AND NOT (text_value = ANY($2))`
+	_, err := r.db.Exec(query, id, pq.Array(options))
+	return err
+}
+
+// GetAttributeValuesByProductIDs returns the attribute values of the
+// products keyed by product ID, in attribute display order
+func (r *AttributeRepository) GetAttributeValuesByProductIDs(productIDs []int) (map[int][]models.ProductAttributeValue, error) {
+	values := make(map[int][]models.ProductAttributeValue)
+	if len(productIDs) == 0 {
+		return values, nil
+	}
+
+	query := `
+		SELECT v.product_id, a.id, a.code, a.name, a.type, COALESCE(a.unit, ''),
+			v.text_value, v.number_value, v.bool_value,
+			COALESCE((SELECT o->>'label' FROM jsonb_array_elements(a.options) o WHERE o->>'value' = v.text_value), '')
+		FROM product_attribute_values v
+		JOIN product_attributes a ON a.id = v.attribute_id
+		WHERE v.product_id = ANY($1)
+		ORDER BY a.sort_order, a.name
+	`
+	rows, err := r.db.Query(query, pq.Array(productIDs))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var v models.ProductAttributeValue
+		var textValue sql.NullString
+		var numberValue sql.NullFloat64
+		var boolValue sql.NullBool
+		err := rows.Scan(
+			&v.ProductID, &v.AttributeID, &v.Code, &v.Name, &v.Type, &v.Unit,
+			&textValue, &numberValue, &boolValue, &v.Label,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		switch v.Type {
+		case models.AttributeTypeNumber:
+			v.Value = numberValue.Float64
+		case models.AttributeTypeBoolean:
+			v.Value = boolValue.Bool
+		default:
+			v.Value = textValue.String
+		}
+		values[v.ProductID] = append(values[v.ProductID], v)
+	}
+
+	return values, rows.Err()
+}
+
+// SetProductAttributeValues replaces the attribute values of a product.
+// Values must match the type of their attribute.
+func (r *AttributeRepository) SetProductAttributeValues(productID int, values []models.ProductAttributeValue) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM product_attribute_values WHERE product_id = $1`, productID); err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO product_attribute_values (product_id, attribute_id, text_value, number_value, bool_value)
+		VALUES ($1, $2, $3, $4, $5)
+	`
+	for _, v := range values {
+		var textValue, numberValue, boolValue interface{}
+		switch v.Type {
+		case models.AttributeTypeNumber:
+			numberValue = v.Value
+		case models.AttributeTypeBoolean:
+			boolValue = v.Value
+		default:
+			textValue = v.Value
+		}
+		if _, err := tx.Exec(query, productID, v.AttributeID, textValue, numberValue, boolValue); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+func attributeOptionsJSON(options []models.AttributeOption) ([]byte, error) {
+	if options == nil {
+		options = []models.AttributeOption{}
+	}
+	return json.Marshal(options)
+}
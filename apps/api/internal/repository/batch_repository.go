@@ -0,0 +1,253 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"gastroshop-api/internal/models"
+)
+
+// BatchRepository stores the batches stock is received in. The stock of
+// batches is changed with the stock of their location by changeStock, which
+// records the part of each movement taken from or added to a batch.
+type BatchRepository struct {
+	db *sql.DB
+}
+
+func NewBatchRepository(db *sql.DB) *BatchRepository {
+	return &BatchRepository{db: db}
+}
+
+const batchColumns = `b.id, b.location_id, b.product_id, b.variant_id, b.lot_number, b.produced_on, b.best_before,
+	b.received_quantity, b.quantity, b.discount_sale_id, b.discounted_at, b.written_off_at, b.created_at,
+	CASE WHEN v.id IS NULL THEN p.title ELSE p.title || ', ' || v.title END`
+
+const batchTables = `stock_batches b
+	JOIN products p ON p.id = b.product_id
+	LEFT JOIN product_variants v ON v.id = b.variant_id`
+
+func scanBatch(row rowScanner) (*models.StockBatch, error) {
+	var b models.StockBatch
+	err := row.Scan(
+		&b.ID, &b.LocationID, &b.ProductID, &b.VariantID, &b.LotNumber, &b.ProducedOn, &b.BestBefore,
+		&b.ReceivedQuantity, &b.Quantity, &b.DiscountSaleID, &b.DiscountedAt, &b.WrittenOffAt, &b.CreatedAt, &b.Title,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &b, nil
+}
+
+// GetBatches returns a page of batches, expiring first, filtered by
+// location_id, product_id, lot_number, in_stock and best_before_until, the
+// last best-before date listed, with the total of matching batches
+func (r *BatchRepository) GetBatches(filters map[string]interface{}) ([]models.StockBatch, int, error) {
+	conditions := []string{"1=1"}
+	args := []interface{}{}
+	add := func(condition string, value interface{}) {
+		args = append(args, value)
+		conditions = append(conditions, fmt.Sprintf(condition, len(args)))
+	}
+
+	if locationID, ok := filters["location_id"].(int); ok {
+		add("b.location_id = $%d", locationID)
+	}
+	if productID, ok := filters["product_id"].(int); ok {
+		add("b.product_id = $%d", productID)
+	}
+	if lotNumber, ok := filters["lot_number"].(string); ok {
+		add("b.lot_number = $%d", lotNumber)
+	}
+	if until, ok := filters["best_before_until"].(time.Time); ok {
+		add("b.best_before <= $%d::DATE", until.Format("2006-01-02"))
+	}
+	if inStock, ok := filters["in_stock"].(bool); ok {
+		add("(b.quantity > 0) = $%d", inStock)
+	}
+	where := strings.Join(conditions, " AND ")
+
+	var total int
+	if err := r.db.QueryRow(`SELECT COUNT(*) FROM stock_batches b WHERE `+where, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	page, _ := filters["page"].(int)
+	pageSize, _ := filters["page_size"].(int)
+	query := `SELECT ` + batchColumns + ` FROM ` + batchTables + ` WHERE ` + where + fmt.Sprintf(`
+		ORDER BY b.best_before NULLS LAST, b.id
+		LIMIT $%d OFFSET $%d`, len(args)+1, len(args)+2)
+	args = append(args, pageSize, (page-1)*pageSize)
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	batches := []models.StockBatch{}
+	for rows.Next() {
+		b, err := scanBatch(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		batches = append(batches, *b)
+	}
+
+	return batches, total, rows.Err()
+}
+
+func (r *BatchRepository) GetBatchByID(id int) (*models.StockBatch, error) {
+	b, err := scanBatch(r.db.QueryRow(`SELECT `+batchColumns+` FROM `+batchTables+` WHERE b.id = $1`, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// GetExpiringBatches returns the batches with stock left whose best-before
+// date is on or before until, expiring first
+func (r *BatchRepository) GetExpiringBatches(until time.Time) ([]models.StockBatch, error) {
+	query := `SELECT ` + batchColumns + ` FROM ` + batchTables + `
+		WHERE b.quantity > 0 AND b.best_before <= $1::DATE
+		ORDER BY b.best_before, b.id`
+	rows, err := r.db.Query(query, until.Format("2006-01-02"))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	batches := []models.StockBatch{}
+	for rows.Next() {
+		b, err := scanBatch(rows)
+		if err != nil {
+			return nil, err
+		}
+		batches = append(batches, *b)
+	}
+
+	return batches, rows.Err()
+}
+
+// CreateBatch receives a batch at the location of the change, adding its
+// quantity to the stock there in one transaction. It returns the movement
+// recorded.
+func (r *BatchRepository) CreateBatch(b *models.StockBatch, change models.StockChange) (*models.InventoryMovement, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	query := `
+		INSERT INTO stock_batches (location_id, product_id, variant_id, lot_number, produced_on, best_before, received_quantity)
+		VALUES (` + locationOrDefault(1) + `, $2, $3, $4, $5, $6, $7)
+		RETURNING id, location_id, created_at
+	`
+	err = tx.QueryRow(query, change.LocationID, b.ProductID, b.VariantID, b.LotNumber, b.ProducedOn, b.BestBefore, b.ReceivedQuantity).
+		Scan(&b.ID, &b.LocationID, &b.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	change.LocationID = b.LocationID
+	change.BatchID = b.ID
+	change.Reference = fmt.Sprintf("batch:%d", b.ID)
+	movement, _, err := changeStock(tx, b.ProductID, b.VariantID, stockAdded, b.ReceivedQuantity, change)
+	if err != nil {
+		return nil, err
+	}
+	b.Quantity = b.ReceivedQuantity
+
+	return movement, tx.Commit()
+}
+
+// WriteOffBatch takes the stock left of a batch from the stock at its
+// location in one transaction and marks it written off. It returns the
+// movement recorded, nil when no stock was left.
+func (r *BatchRepository) WriteOffBatch(id int, change models.StockChange) (*models.InventoryMovement, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var productID, quantity int
+	var variantID *int
+	query := `SELECT location_id, product_id, variant_id, quantity FROM stock_batches WHERE id = $1 FOR UPDATE`
+	err = tx.QueryRow(query, id).Scan(&change.LocationID, &productID, &variantID, &quantity)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("batch with id %d not found", id)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	change.BatchID = id
+	movement, _, err := changeStock(tx, productID, variantID, stockAdded, -quantity, change)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := tx.Exec(`UPDATE stock_batches SET written_off_at = NOW() WHERE id = $1`, id); err != nil {
+		return nil, err
+	}
+
+	return movement, tx.Commit()
+}
+
+// SetBatchDiscounted marks a batch discounted with the sale started for it,
+// nil when no sale was started
+func (r *BatchRepository) SetBatchDiscounted(id int, saleID *int) error {
+	result, err := r.db.Exec(`UPDATE stock_batches SET discount_sale_id = $1, discounted_at = NOW() WHERE id = $2`, saleID, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("batch with id %d not found", id)
+	}
+
+	return nil
+}
+
+// GetLotOrders returns the orders sold with stock of the lot of a batch, at
+// any location it was moved to, with the quantity of each
+func (r *BatchRepository) GetLotOrders(id int) ([]models.BatchOrder, error) {
+	query := `
+		SELECT o.id, o.user_id, COALESCE(u.email, o.guest_email, ''), o.status, -SUM(bm.quantity_change), o.created_at
+		FROM stock_batches lot
+		JOIN stock_batches b ON b.product_id = lot.product_id AND b.lot_number = lot.lot_number
+		JOIN batch_movements bm ON bm.batch_id = b.id
+		JOIN inventory_movements m ON m.id = bm.movement_id
+		JOIN orders o ON m.reference = 'order:' || o.id
+		LEFT JOIN users u ON u.id = o.user_id
+		WHERE lot.id = $1 AND m.reason = 'sale'
+		GROUP BY o.id, u.email
+		ORDER BY o.created_at, o.id
+	`
+	rows, err := r.db.Query(query, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	orders := []models.BatchOrder{}
+	for rows.Next() {
+		var o models.BatchOrder
+		if err := rows.Scan(&o.OrderID, &o.UserID, &o.Email, &o.Status, &o.Quantity, &o.CreatedAt); err != nil {
+			return nil, err
+		}
+		orders = append(orders, o)
+	}
+
+	return orders, rows.Err()
+}
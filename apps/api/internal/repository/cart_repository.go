@@ -0,0 +1,92 @@
+package repository
+
+import (
+	"database/sql"
+
+	"gastroshop-api/internal/models"
+)
+
+type CartRepository struct {
+	db *sql.DB
+}
+
+func NewCartRepository(db *sql.DB) *CartRepository {
+	return &CartRepository{db: db}
+}
+
+func (r *CartRepository) GetCartItems(userID int) ([]models.CartItem, error) {
+	query := `
+		SELECT product_id, variant_id, quantity
+		FROM cart_items
+		WHERE user_id = $1
+		ORDER BY created_at, id
+	`
+	rows, err := r.db.Query(query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := make([]models.CartItem, 0)
+	for rows.Next() {
+		var item models.CartItem
+		var variantID sql.NullInt64
+		if err := rows.Scan(&item.ProductID, &variantID, &item.Quantity); err != nil {
+			return nil, err
+		}
+		if variantID.Valid {
+			id := int(variantID.Int64)
+			item.VariantID = &id
+		}
+		items = append(items, item)
+	}
+
+	return items, nil
+}
+
+// AddCartItem adds the quantity to the cart line of the product or variant
+// and returns the new quantity of the line
+func (r *CartRepository) AddCartItem(userID, productID int, variantID *int, quantity int) (int, error) {
+	query := `
+		INSERT INTO cart_items (user_id, product_id, variant_id, quantity)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (user_id, product_id, COALESCE(variant_id, 0))
+		DO UPDATE SET quantity = cart_items.quantity + EXCLUDED.quantity, updated_at = NOW()
+		RETURNING quantity
+	`
+	var total int
+	err := r.db.QueryRow(query, userID, productID, variantID, quantity).Scan(&total)
+	return total, err
+}
+
+// GetCartItemQuantity returns the quantity of a cart line, 0 when absent
+func (r *CartRepository) GetCartItemQuantity(userID, productID int, variantID *int) (int, error) {
+	query := `
+		SELECT quantity FROM cart_items
+		WHERE user_id = $1 AND product_id = $2 AND COALESCE(variant_id, 0) = COALESCE($3, 0)
+	`
+	var quantity int
+	err := r.db.QueryRow(query, userID, productID, variantID).Scan(&quantity)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	return quantity, err
+}
+
+// RemoveCartItem removes a cart line and reports whether it existed
+func (r *CartRepository) RemoveCartItem(userID, productID int, variantID *int) (bool, error) {
+	query := `
+		DELETE FROM cart_items
+		WHERE user_id = $1 AND product_id = $2 AND COALESCE(variant_id, 0) = COALESCE($3, 0)
+	`
+	result, err := r.db.Exec(query, userID, productID, variantID)
+	if err != nil {
+		return false, err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return rowsAffected > 0, nil
+}
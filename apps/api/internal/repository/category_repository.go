@@ -0,0 +1,231 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+
+	"gastroshop-api/internal/models"
+
+	"github.com/lib/pq"
+)
+
+type CategoryRepository struct {
+	db *sql.DB
+}
+
+func NewCategoryRepository(db *sql.DB) *CategoryRepository {
+	return &CategoryRepository{db: db}
+}
+
+// categoryTreeQuery selects the ids of the categories with the slugs in the
+// given array parameter and of all their descendants
+const categoryTreeQuery = `
+	WITH RECURSIVE category_tree AS (
+		SELECT id FROM categories WHERE slug = ANY($%d)
+		UNION
+		SELECT c.id FROM categories c JOIN category_tree t ON c.parent_id = t.id
+	)
+	SELECT id FROM category_tree`
+
+const categoryColumns = `id, parent_id, slug, name, COALESCE(description, ''), COALESCE(image_url, ''), sort_order, created_at,
+	translations`
+
+func scanCategory(row rowScanner) (*models.Category, error) {
+	var c models.Category
+	var translationsJSON []byte
+	err := row.Scan(&c.ID, &c.ParentID, &c.Slug, &c.Name, &c.Description, &c.ImageURL, &c.SortOrder, &c.CreatedAt, &translationsJSON)
+	if err != nil {
+		return nil, err
+	}
+	if c.Translations, err = scanTranslations(translationsJSON); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// GetCategories returns all categories as a flat list ordered for display
+func (r *CategoryRepository) GetCategories() ([]models.Category, error) {
+	query := `SELECT ` + categoryColumns + ` FROM categories ORDER BY sort_order, name`
+
+	rows, err := r.db.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var categories []models.Category
+	for rows.Next() {
+		c, err := scanCategory(rows)
+		if err != nil {
+			return nil, err
+		}
+		categories = append(categories, *c)
+	}
+
+	return categories, nil
+}
+
+func (r *CategoryRepository) GetCategoryByID(id int) (*models.Category, error) {
+	query := `SELECT ` + categoryColumns + ` FROM categories WHERE id = $1`
+
+	c, err := scanCategory(r.db.QueryRow(query, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+func (r *CategoryRepository) GetCategoryBySlug(slug string) (*models.Category, error) {
+	query := `SELECT ` + categoryColumns + ` FROM categories WHERE slug = $1`
+
+	c, err := scanCategory(r.db.QueryRow(query, slug))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+func (r *CategoryRepository) CreateCategory(c *models.Category) error {
+	translations, err := translationsJSON(c.Translations)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO categories (parent_id, slug, name, description, image_url, sort_order, translations)
+		VALUES ($1, $2, $3, NULLIF($4, ''), NULLIF($5, ''), $6, $7)
+		RETURNING id, created_at
+	`
+	return r.db.QueryRow(
+		query,
+		c.ParentID,
+		c.Slug,
+		c.Name,
+		c.Description,
+		c.ImageURL,
+		c.SortOrder,
+		translations,
+	).Scan(&c.ID, &c.CreatedAt)
+}
+
+func (r *CategoryRepository) UpdateCategory(id int, c *models.Category) error {
+	translations, err := translationsJSON(c.Translations)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		UPDATE categories
+		SET parent_id = $1, slug = $2, name = $3, description = NULLIF($4, ''), image_url = NULLIF($5, ''),
+			sort_order = $6, translations = $8, updated_at = NOW()
+		WHERE id = $7
+	`
+	result, err := r.db.Exec(
+		query,
+		c.ParentID,
+		c.Slug,
+		c.Name,
+		c.Description,
+		c.ImageURL,
+		c.SortOrder,
+		id,
+		translations,
+	)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("category with id %d not found", id)
+	}
+
+	return nil
+}
+
+func (r *CategoryRepository) DeleteCategory(id int) error {
+	result, err := r.db.Exec(`DELETE FROM categories WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("category with id %d not found", id)
+	}
+
+	return nil
+}
+
+func (r *CategoryRepository) HasChildren(id int) (bool, error) {
+	var exists bool
+	err := r.db.QueryRow(`SELECT EXISTS (SELECT 1 FROM categories WHERE parent_id = $1)`, id).Scan(&exists)
+	return exists, err
+}
+
+// GetProductCategories returns the categories a product is directly assigned to
+func (r *CategoryRepository) GetProductCategories(productID int) ([]models.Category, error) {
+	query := `
+		SELECT ` + categoryColumns + `
+		FROM categories
+		WHERE id IN (SELECT category_id FROM product_categories WHERE product_id = $1)
+		ORDER BY sort_order, name
+	`
+
+	rows, err := r.db.Query(query, productID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var categories []models.Category
+	for rows.Next() {
+		c, err := scanCategory(rows)
+		if err != nil {
+			return nil, err
+		}
+		categories = append(categories, *c)
+	}
+
+	return categories, nil
+}
+
+// SetProductCategories replaces the category assignments of a product
+func (r *CategoryRepository) SetProductCategories(productID int, categoryIDs []int) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM product_categories WHERE product_id = $1`, productID); err != nil {
+		return err
+	}
+
+	if len(categoryIDs) > 0 {
+		query := `
+			INSERT INTO product_categories (product_id, category_id)
+			SELECT $1, unnest($2::int[])
+			ON CONFLICT DO NOTHING
+		`
+		if _, err := tx.Exec(query, productID, pq.Array(categoryIDs)); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
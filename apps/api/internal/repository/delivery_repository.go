@@ -0,0 +1,206 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"gastroshop-api/internal/models"
+)
+
+type DeliveryRepository struct {
+	db *sql.DB
+}
+
+func NewDeliveryRepository(db *sql.DB) *DeliveryRepository {
+	return &DeliveryRepository{db: db}
+}
+
+const deliverySlotColumns = `id, zone, starts_at, ends_at, capacity, reserved, active, created_at`
+
+func scanDeliverySlot(row rowScanner) (*models.DeliverySlot, error) {
+	var slot models.DeliverySlot
+	err := row.Scan(
+		&slot.ID, &slot.Zone, &slot.StartsAt, &slot.EndsAt, &slot.Capacity,
+		&slot.Reserved, &slot.Active, &slot.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	slot.Available = slot.Capacity - slot.Reserved
+	if slot.Available < 0 {
+		slot.Available = 0
+	}
+	return &slot, nil
+}
+
+func (r *DeliveryRepository) CreateSlot(slot *models.DeliverySlot) error {
+	query := `
+		INSERT INTO delivery_slots (zone, starts_at, ends_at, capacity, active)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, reserved, created_at
+	`
+	err := r.db.QueryRow(query, slot.Zone, slot.StartsAt, slot.EndsAt, slot.Capacity, slot.Active).
+		Scan(&slot.ID, &slot.Reserved, &slot.CreatedAt)
+	if err != nil {
+		return err
+	}
+	slot.Available = slot.Capacity - slot.Reserved
+	return nil
+}
+
+func (r *DeliveryRepository) GetSlotByID(id int) (*models.DeliverySlot, error) {
+	query := `SELECT ` + deliverySlotColumns + ` FROM delivery_slots WHERE id = $1`
+
+	slot, err := scanDeliverySlot(r.db.QueryRow(query, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return slot, nil
+}
+
+// GetSlotsByIDs returns slots keyed by ID
+func (r *DeliveryRepository) GetSlotsByIDs(ids []int) (map[int]*models.DeliverySlot, error) {
+	slots := make(map[int]*models.DeliverySlot)
+	if len(ids) == 0 {
+		return slots, nil
+	}
+
+	placeholders := make([]string, len(ids))
+	args := make([]interface{}, len(ids))
+	for i, id := range ids {
+		placeholders[i] = fmt.Sprintf("$%d", i+1)
+		args[i] = id
+	}
+
+	query := fmt.Sprintf(`SELECT %s FROM delivery_slots WHERE id IN (%s)`, deliverySlotColumns, strings.Join(placeholders, ","))
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		slot, err := scanDeliverySlot(rows)
+		if err != nil {
+			return nil, err
+		}
+		slots[slot.ID] = slot
+	}
+
+	return slots, nil
+}
+
+// GetSlots returns slots starting on the given day. An empty zone matches all
+// zones. With onlyAvailable set, inactive, past and fully booked slots are skipped.
+func (r *DeliveryRepository) GetSlots(zone string, date time.Time, onlyAvailable bool) ([]models.DeliverySlot, error) {
+	query := `
+		SELECT ` + deliverySlotColumns + `
+		FROM delivery_slots
+		WHERE (starts_at AT TIME ZONE 'UTC')::date = $1::date
+		  AND ($2::text = '' OR zone = $2)
+	`
+	if onlyAvailable {
+		query += ` AND active = true AND starts_at > NOW() AND reserved < capacity`
+	}
+	query += ` ORDER BY zone, starts_at`
+
+	rows, err := r.db.Query(query, date.Format("2006-01-02"), zone)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	slots := make([]models.DeliverySlot, 0)
+	for rows.Next() {
+		slot, err := scanDeliverySlot(rows)
+		if err != nil {
+			return nil, err
+		}
+		slots = append(slots, *slot)
+	}
+
+	return slots, nil
+}
+
+// UpdateSlot updates a slot unless its capacity would drop below the places
+// already reserved, checked in the same statement so a concurrent booking
+// can't slip in between. Returns false when the capacity is too low.
+func (r *DeliveryRepository) UpdateSlot(id int, slot *models.DeliverySlot) (bool, error) {
+	query := `
+		UPDATE delivery_slots
+		SET zone = $1, starts_at = $2, ends_at = $3, capacity = $4, active = $5, updated_at = NOW()
+		WHERE id = $6 AND reserved <= $4
+		RETURNING reserved
+	`
+	err := r.db.QueryRow(query, slot.Zone, slot.StartsAt, slot.EndsAt, slot.Capacity, slot.Active, id).Scan(&slot.Reserved)
+	if err == sql.ErrNoRows {
+		var exists bool
+		if err := r.db.QueryRow(`SELECT EXISTS(SELECT 1 FROM delivery_slots WHERE id = $1)`, id).Scan(&exists); err != nil {
+			return false, err
+		}
+		if !exists {
+			return false, fmt.Errorf("delivery slot with id %d not found", id)
+		}
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// DeleteSlot removes a slot that has no reservations
+func (r *DeliveryRepository) DeleteSlot(id int) error {
+	result, err := r.db.Exec(`DELETE FROM delivery_slots WHERE id = $1 AND reserved = 0`, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("delivery slot with id %d not found or has reservations", id)
+	}
+
+	return nil
+}
+
+// ReserveSlot atomically takes one place in the slot. It returns false when the
+// slot is inactive, already started or fully booked.
+func (r *DeliveryRepository) ReserveSlot(id int) (bool, error) {
+	query := `
+		UPDATE delivery_slots
+		SET reserved = reserved + 1, updated_at = NOW()
+		WHERE id = $1 AND active = true AND starts_at > NOW() AND reserved < capacity
+	`
+	result, err := r.db.Exec(query, id)
+	if err != nil {
+		return false, err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return rowsAffected > 0, nil
+}
+
+// ReleaseSlot gives one place back to the slot
+func (r *DeliveryRepository) ReleaseSlot(id int) error {
+	query := `
+		UPDATE delivery_slots
+		SET reserved = GREATEST(0, reserved - 1), updated_at = NOW()
+		WHERE id = $1
+	`
+	_, err := r.db.Exec(query, id)
+	return err
+}
@@ -0,0 +1,98 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+
+	"gastroshop-api/internal/models"
+)
+
+// EmailTemplateRepository stores admins' versions of the built-in emails
+// per locale
+type EmailTemplateRepository struct {
+	db *sql.DB
+}
+
+func NewEmailTemplateRepository(db *sql.DB) *EmailTemplateRepository {
+	return &EmailTemplateRepository{db: db}
+}
+
+const emailTemplateColumns = `name, locale, subject, body, updated_by, updated_at`
+
+func scanEmailTemplate(row rowScanner) (*models.EmailTemplate, error) {
+	var t models.EmailTemplate
+	if err := row.Scan(&t.Name, &t.Locale, &t.Subject, &t.Body, &t.UpdatedBy, &t.UpdatedAt); err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// GetTemplates returns all email templates ordered by name and locale
+func (r *EmailTemplateRepository) GetTemplates() ([]models.EmailTemplate, error) {
+	rows, err := r.db.Query(`SELECT ` + emailTemplateColumns + ` FROM email_templates ORDER BY name, locale`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	templates := []models.EmailTemplate{}
+	for rows.Next() {
+		t, err := scanEmailTemplate(rows)
+		if err != nil {
+			return nil, err
+		}
+		templates = append(templates, *t)
+	}
+
+	return templates, rows.Err()
+}
+
+// GetTemplate returns the template of an email in the locale, else in the
+// fallback locale, nil when there is neither
+func (r *EmailTemplateRepository) GetTemplate(name, locale, fallback string) (*models.EmailTemplate, error) {
+	query := `
+		SELECT ` + emailTemplateColumns + `
+		FROM email_templates
+		WHERE name = $1 AND locale IN ($2, $3)
+		ORDER BY locale = $2 DESC
+		LIMIT 1
+	`
+	t, err := scanEmailTemplate(r.db.QueryRow(query, name, locale, fallback))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// SaveTemplate creates or replaces the template of an email in a locale
+func (r *EmailTemplateRepository) SaveTemplate(t *models.EmailTemplate) error {
+	query := `
+		INSERT INTO email_templates (name, locale, subject, body, updated_by)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (name, locale)
+		DO UPDATE SET subject = EXCLUDED.subject, body = EXCLUDED.body,
+			updated_by = EXCLUDED.updated_by, updated_at = NOW()
+		RETURNING updated_at
+	`
+	return r.db.QueryRow(query, t.Name, t.Locale, t.Subject, t.Body, t.UpdatedBy).Scan(&t.UpdatedAt)
+}
+
+func (r *EmailTemplateRepository) DeleteTemplate(name, locale string) error {
+	result, err := r.db.Exec(`DELETE FROM email_templates WHERE name = $1 AND locale = $2`, name, locale)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("email template %s in %s not found", name, locale)
+	}
+
+	return nil
+}
@@ -0,0 +1,122 @@
+package repository
+
+import (
+	"database/sql"
+	"encoding/json"
+
+	"gastroshop-api/internal/models"
+)
+
+type ImportJobRepository struct {
+	db *sql.DB
+}
+
+func NewImportJobRepository(db *sql.DB) *ImportJobRepository {
+	return &ImportJobRepository{db: db}
+}
+
+const importJobColumns = `id, user_id, filename, dry_run, status, total_rows, processed_rows, created_count, updated_count,
+		errors, created_at, finished_at`
+
+func scanImportJob(row rowScanner) (*models.ProductImportJob, error) {
+	var job models.ProductImportJob
+	var errorsJSON []byte
+	err := row.Scan(
+		&job.ID, &job.UserID, &job.Filename, &job.DryRun, &job.Status, &job.TotalRows, &job.ProcessedRows,
+		&job.CreatedCount, &job.UpdatedCount, &errorsJSON, &job.CreatedAt, &job.FinishedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(errorsJSON, &job.Errors); err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+func (r *ImportJobRepository) CreateImportJob(job *models.ProductImportJob) error {
+	query := `
+		INSERT INTO product_import_jobs (user_id, filename, dry_run, status, total_rows)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at
+	`
+	return r.db.QueryRow(query, job.UserID, job.Filename, job.DryRun, job.Status, job.TotalRows).
+		Scan(&job.ID, &job.CreatedAt)
+}
+
+func (r *ImportJobRepository) GetImportJobByID(id int) (*models.ProductImportJob, error) {
+	query := `SELECT ` + importJobColumns + ` FROM product_import_jobs WHERE id = $1`
+
+	job, err := scanImportJob(r.db.QueryRow(query, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return job, nil
+}
+
+// GetImportJobs returns the most recent import jobs first
+func (r *ImportJobRepository) GetImportJobs(limit int) ([]models.ProductImportJob, error) {
+	query := `SELECT ` + importJobColumns + ` FROM product_import_jobs ORDER BY created_at DESC LIMIT $1`
+
+	rows, err := r.db.Query(query, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var jobs []models.ProductImportJob
+	for rows.Next() {
+		job, err := scanImportJob(rows)
+		if err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, *job)
+	}
+
+	return jobs, nil
+}
+
+// UpdateImportJob saves the status, progress and errors of a job
+func (r *ImportJobRepository) UpdateImportJob(job *models.ProductImportJob) error {
+	errorsJSON, err := json.Marshal(job.Errors)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		UPDATE product_import_jobs
+		SET status = $1, processed_rows = $2, created_count = $3, updated_count = $4, errors = $5, finished_at = $6
+		WHERE id = $7
+	`
+	_, err = r.db.Exec(
+		query,
+		job.Status,
+		job.ProcessedRows,
+		job.CreatedCount,
+		job.UpdatedCount,
+		errorsJSON,
+		job.FinishedAt,
+		job.ID,
+	)
+	return err
+}
+
+// FailUnfinishedImportJobs marks jobs that are still queued, validating or
+// importing as failed with the message. It returns the number of jobs.
+func (r *ImportJobRepository) FailUnfinishedImportJobs(message string) (int64, error) {
+	query := `
+		UPDATE product_import_jobs
+		SET status = $1, finished_at = NOW(), errors = errors || jsonb_build_array(jsonb_build_object('row', 0, 'message', $2::TEXT))
+		WHERE status IN ($3, $4, $5)
+	`
+	result, err := r.db.Exec(query, models.ImportStatusFailed, message,
+		models.ImportStatusQueued, models.ImportStatusValidating, models.ImportStatusImporting)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
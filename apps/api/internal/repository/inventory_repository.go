@@ -0,0 +1,676 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"gastroshop-api/internal/models"
+)
+
+// locationOrDefault is the SQL of a location ID argument, falling back to
+// the default location when it is zero
+func locationOrDefault(arg int) string {
+	return fmt.Sprintf(`COALESCE(NULLIF($%d, 0), (SELECT id FROM locations WHERE is_default))`, arg)
+}
+
+// Stock expressions of changeStock: setting the stock at the location, setting
+// the total stock by changing the stock at the location by the difference,
+// and adding to the stock at the location
+const (
+	stockAtLocation = `$4`
+	stockTotal      = `old.quantity + $4 - old.total`
+	stockAdded      = `old.quantity + $4`
+)
+
+// changeStock sets the stock of a product, or of its variant when variantID
+// is set, at the location of the change to next, an SQL expression of the
+// stock at the location old.quantity, of the total stock over all locations
+// old.total and of value as $4. The difference is recorded in the inventory
+// ledger in the same statement. Stock doesn't go below zero. The total stock
+// of the product or variant is then summed up over the locations, and the
+// product price and stock synced with its variants and bundles with their
+// components. Stock taken comes from the batches at the location, the batch
+// of the change first and then the batches expiring first, and stock added
+// goes to the batch of the change; the sale started to clear a batch ends
+// when the batch runs out. It returns the movement, nil when the
+// stock didn't change, and the stock at the location before the change.
+func changeStock(db execQueryer, productID int, variantID *int, next string, value int, change models.StockChange) (*models.InventoryMovement, int, error) {
+	// Lock the product, and make sure the variant is one of its variants
+	query := `
+		SELECT COALESCE(v.quantity, p.quantity, 0)
+		FROM products p
+		LEFT JOIN product_variants v ON v.id = $2 AND v.product_id = p.id AND v.deleted_at IS NULL
+		WHERE p.id = $1 AND ($2::INTEGER IS NULL OR v.id IS NOT NULL)
+		FOR UPDATE OF p
+	`
+	var total int
+	err := db.QueryRow(query, productID, variantID).Scan(&total)
+	if err == sql.ErrNoRows {
+		if variantID != nil {
+			return nil, 0, fmt.Errorf("variant with id %d not found", *variantID)
+		}
+		return nil, 0, fmt.Errorf("product with id %d not found", productID)
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var locationID int
+	query = `SELECT id FROM locations WHERE id = ` + locationOrDefault(1)
+	err = db.QueryRow(query, change.LocationID).Scan(&locationID)
+	if err == sql.ErrNoRows {
+		return nil, 0, fmt.Errorf("location with id %d not found", change.LocationID)
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+
+	query = `
+		INSERT INTO location_stock (location_id, product_id, variant_id)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (location_id, product_id, COALESCE(variant_id, 0)) DO NOTHING
+	`
+	if _, err := db.Exec(query, locationID, productID, variantID); err != nil {
+		return nil, 0, err
+	}
+
+	query = `
+		WITH old AS (
+			SELECT id, quantity, $9::INTEGER AS total
+			FROM location_stock
+			WHERE location_id = $1 AND product_id = $2 AND COALESCE(variant_id, 0) = COALESCE($3::INTEGER, 0)
+			FOR UPDATE
+		), updated AS (
+			UPDATE location_stock t
+			SET quantity = GREATEST(0, ` + next + `)
+			FROM old
+			WHERE t.id = old.id
+			RETURNING old.quantity AS previous_quantity, t.quantity
+		), movement AS (
+			INSERT INTO inventory_movements (location_id, product_id, variant_id, quantity_change, quantity_after, reason, actor_id, reference, note)
+			SELECT $1, $2, $3, quantity - previous_quantity, quantity, $5, $6, $7, $8
+			FROM updated
+			WHERE quantity <> previous_quantity
+			RETURNING id, created_at
+		)
+		SELECT updated.previous_quantity, updated.quantity, movement.id, movement.created_at
+		FROM updated
+		LEFT JOIN movement ON true
+	`
+	var previous, quantity int
+	var movementID sql.NullInt64
+	var createdAt sql.NullTime
+	err = db.QueryRow(query, locationID, productID, variantID, value, change.Reason, change.ActorID, change.Reference, change.Note, total).
+		Scan(&previous, &quantity, &movementID, &createdAt)
+	if err != nil {
+		return nil, 0, err
+	}
+	if !movementID.Valid {
+		return nil, previous, nil
+	}
+
+	var batches []models.BatchAllocation
+	if quantity < previous {
+		batches, err = takeFromBatches(db, int(movementID.Int64), locationID, productID, variantID, previous-quantity, change.BatchID)
+		if err == nil && len(batches) > 0 {
+			err = endBatchSales(db, batches)
+		}
+	} else if change.BatchID != 0 {
+		batches, err = addToBatch(db, int(movementID.Int64), locationID, productID, variantID, quantity-previous, change.BatchID)
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+
+	// The total is the stock over all locations; in_stock follows it
+	table, set, id := "products", ``, productID
+	if variantID != nil {
+		table, set, id = "product_variants", `, updated_at = NOW()`, *variantID
+	}
+	query = `
+		WITH stock AS (
+			SELECT COALESCE(SUM(quantity), 0) AS quantity
+			FROM location_stock
+			WHERE product_id = $1 AND COALESCE(variant_id, 0) = COALESCE($2::INTEGER, 0)
+		)
+		UPDATE ` + table + ` t
+		SET quantity = stock.quantity,
+		    in_stock = CASE WHEN stock.quantity <> COALESCE(t.quantity, 0) THEN stock.quantity > 0 ELSE t.in_stock END` + set + `
+		FROM stock
+		WHERE t.id = $3
+	`
+	if _, err := db.Exec(query, productID, variantID, id); err != nil {
+		return nil, 0, err
+	}
+
+	if variantID != nil {
+		err = syncProductFromVariants(db, productID)
+	} else {
+		err = syncBundles(db, productID)
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return &models.InventoryMovement{
+		ID:             int(movementID.Int64),
+		LocationID:     locationID,
+		ProductID:      productID,
+		VariantID:      variantID,
+		QuantityChange: quantity - previous,
+		QuantityAfter:  quantity,
+		Reason:         change.Reason,
+		ActorID:        change.ActorID,
+		Reference:      change.Reference,
+		Note:           change.Note,
+		CreatedAt:      createdAt.Time,
+		Batches:        batches,
+	}, previous, nil
+}
+
+// takeFromBatches takes quantity from the batches of a product or variant at
+// a location: from batchID first, then from the batches expiring first and
+// the oldest batches. Stock the batches don't cover is stock received
+// without a batch. The parts taken are recorded with the movement.
+func takeFromBatches(db execQueryer, movementID, locationID, productID int, variantID *int, quantity, batchID int) ([]models.BatchAllocation, error) {
+	query := `
+		SELECT id, lot_number, best_before, quantity
+		FROM stock_batches
+		WHERE location_id = $1 AND product_id = $2 AND COALESCE(variant_id, 0) = COALESCE($3::INTEGER, 0) AND quantity > 0
+		ORDER BY id = $4 DESC, best_before NULLS LAST, produced_on NULLS LAST, id
+		FOR UPDATE
+	`
+	rows, err := db.Query(query, locationID, productID, variantID, batchID)
+	if err != nil {
+		return nil, err
+	}
+
+	var batches []models.BatchAllocation
+	for rows.Next() && quantity > 0 {
+		b := models.BatchAllocation{ProductID: productID, VariantID: variantID}
+		var available int
+		if err := rows.Scan(&b.BatchID, &b.LotNumber, &b.BestBefore, &available); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		b.Quantity = available
+		if b.Quantity > quantity {
+			b.Quantity = quantity
+		}
+		quantity -= b.Quantity
+		batches = append(batches, b)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for _, b := range batches {
+		if _, err := db.Exec(`UPDATE stock_batches SET quantity = quantity - $1 WHERE id = $2`, b.Quantity, b.BatchID); err != nil {
+			return nil, err
+		}
+		query := `INSERT INTO batch_movements (batch_id, movement_id, quantity_change) VALUES ($1, $2, $3)`
+		if _, err := db.Exec(query, b.BatchID, movementID, -b.Quantity); err != nil {
+			return nil, err
+		}
+	}
+	return batches, nil
+}
+
+// addToBatch adds quantity to a batch of the product or variant at the
+// location, recording the part added with the movement
+func addToBatch(db execQueryer, movementID, locationID, productID int, variantID *int, quantity, batchID int) ([]models.BatchAllocation, error) {
+	query := `
+		UPDATE stock_batches
+		SET quantity = quantity + $1
+		WHERE id = $2 AND location_id = $3 AND product_id = $4 AND COALESCE(variant_id, 0) = COALESCE($5::INTEGER, 0)
+		RETURNING lot_number, best_before
+	`
+	b := models.BatchAllocation{BatchID: batchID, ProductID: productID, VariantID: variantID, Quantity: quantity}
+	err := db.QueryRow(query, quantity, batchID, locationID, productID, variantID).Scan(&b.LotNumber, &b.BestBefore)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("batch with id %d not found", batchID)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	query = `INSERT INTO batch_movements (batch_id, movement_id, quantity_change) VALUES ($1, $2, $3)`
+	if _, err := db.Exec(query, batchID, movementID, quantity); err != nil {
+		return nil, err
+	}
+	return []models.BatchAllocation{b}, nil
+}
+
+// InventoryRepository stores the inventory ledger, stock transfers and stock
+// takes. Stock is otherwise changed through ProductRepository, which records
+// the movements.
+type InventoryRepository struct {
+	db *sql.DB
+}
+
+func NewInventoryRepository(db *sql.DB) *InventoryRepository {
+	return &InventoryRepository{db: db}
+}
+
+// movementFilters builds the WHERE clause of movement listings from the
+// filters location_id, product_id, variant_id, reason, reference, from and to
+func movementFilters(filters map[string]interface{}) (string, []interface{}) {
+	conditions := []string{"1=1"}
+	args := []interface{}{}
+	add := func(condition string, value interface{}) {
+		args = append(args, value)
+		conditions = append(conditions, fmt.Sprintf(condition, len(args)))
+	}
+
+	if locationID, ok := filters["location_id"].(int); ok {
+		add("m.location_id = $%d", locationID)
+	}
+	if productID, ok := filters["product_id"].(int); ok {
+		add("m.product_id = $%d", productID)
+	}
+	if variantID, ok := filters["variant_id"].(int); ok {
+		add("m.variant_id = $%d", variantID)
+	}
+	if reason, ok := filters["reason"].(string); ok {
+		add("m.reason = $%d", reason)
+	}
+	if reference, ok := filters["reference"].(string); ok {
+		add("m.reference = $%d", reference)
+	}
+	if from, ok := filters["from"].(time.Time); ok {
+		add("m.created_at >= $%d", from)
+	}
+	if to, ok := filters["to"].(time.Time); ok {
+		add("m.created_at < $%d", to)
+	}
+
+	return strings.Join(conditions, " AND "), args
+}
+
+// GetMovements returns a page of movements matching the filters, newest
+// first, with the total of all matching movements
+func (r *InventoryRepository) GetMovements(filters map[string]interface{}) ([]models.InventoryMovement, int, error) {
+	where, args := movementFilters(filters)
+
+	var total int
+	if err := r.db.QueryRow(`SELECT COUNT(*) FROM inventory_movements m WHERE `+where, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	page, _ := filters["page"].(int)
+	pageSize, _ := filters["page_size"].(int)
+	query := `
+		SELECT m.id, m.location_id, m.product_id, m.variant_id, m.quantity_change, m.quantity_after, m.reason, m.actor_id,
+			m.reference, m.note, m.created_at,
+			CASE WHEN v.id IS NULL THEN p.title ELSE p.title || ', ' || v.title END
+		FROM inventory_movements m
+		JOIN products p ON p.id = m.product_id
+		LEFT JOIN product_variants v ON v.id = m.variant_id
+		WHERE ` + where + fmt.Sprintf(`
+		ORDER BY m.created_at DESC, m.id DESC
+		LIMIT $%d OFFSET $%d`, len(args)+1, len(args)+2)
+	args = append(args, pageSize, (page-1)*pageSize)
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	movements := []models.InventoryMovement{}
+	for rows.Next() {
+		var m models.InventoryMovement
+		err := rows.Scan(&m.ID, &m.LocationID, &m.ProductID, &m.VariantID, &m.QuantityChange, &m.QuantityAfter, &m.Reason, &m.ActorID,
+			&m.Reference, &m.Note, &m.CreatedAt, &m.Title)
+		if err != nil {
+			return nil, 0, err
+		}
+		movements = append(movements, m)
+	}
+
+	return movements, total, rows.Err()
+}
+
+// GetLocationQuantity returns the stock of a product, or of its variant, at
+// a location, the default location when locationID is zero
+func (r *InventoryRepository) GetLocationQuantity(locationID, productID int, variantID *int) (int, error) {
+	query := `
+		SELECT COALESCE(SUM(quantity), 0)
+		FROM location_stock
+		WHERE location_id = ` + locationOrDefault(1) + ` AND product_id = $2 AND COALESCE(variant_id, 0) = COALESCE($3::INTEGER, 0)
+	`
+	var quantity int
+	err := r.db.QueryRow(query, locationID, productID, variantID).Scan(&quantity)
+	return quantity, err
+}
+
+// Stock transfers
+
+// GetTransfers returns a page of stock transfers, newest first, filtered by
+// location_id, either end of the transfer, and product_id, with the total of
+// matching transfers
+func (r *InventoryRepository) GetTransfers(filters map[string]interface{}) ([]models.StockTransfer, int, error) {
+	conditions := []string{"1=1"}
+	args := []interface{}{}
+	if locationID, ok := filters["location_id"].(int); ok {
+		args = append(args, locationID)
+		conditions = append(conditions, fmt.Sprintf("(t.from_location_id = $%[1]d OR t.to_location_id = $%[1]d)", len(args)))
+	}
+	if productID, ok := filters["product_id"].(int); ok {
+		args = append(args, productID)
+		conditions = append(conditions, fmt.Sprintf("t.product_id = $%d", len(args)))
+	}
+	where := strings.Join(conditions, " AND ")
+
+	var total int
+	if err := r.db.QueryRow(`SELECT COUNT(*) FROM stock_transfers t WHERE `+where, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	page, _ := filters["page"].(int)
+	pageSize, _ := filters["page_size"].(int)
+	query := `
+		SELECT t.id, t.from_location_id, t.to_location_id, t.product_id, t.variant_id, t.quantity, t.actor_id, t.note, t.created_at,
+			CASE WHEN v.id IS NULL THEN p.title ELSE p.title || ', ' || v.title END
+		FROM stock_transfers t
+		JOIN products p ON p.id = t.product_id
+		LEFT JOIN product_variants v ON v.id = t.variant_id
+		WHERE ` + where + fmt.Sprintf(`
+		ORDER BY t.created_at DESC, t.id DESC
+		LIMIT $%d OFFSET $%d`, len(args)+1, len(args)+2)
+	args = append(args, pageSize, (page-1)*pageSize)
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	transfers := []models.StockTransfer{}
+	for rows.Next() {
+		var t models.StockTransfer
+		err := rows.Scan(&t.ID, &t.FromLocationID, &t.ToLocationID, &t.ProductID, &t.VariantID, &t.Quantity, &t.ActorID,
+			&t.Note, &t.CreatedAt, &t.Title)
+		if err != nil {
+			return nil, 0, err
+		}
+		transfers = append(transfers, t)
+	}
+
+	return transfers, total, rows.Err()
+}
+
+// CreateTransfer moves stock from one location to another in one
+// transaction, recording a transfer movement at each end. Batches the stock
+// is taken from arrive as batches of the same lot. It fails when the
+// location moved from doesn't have the stock.
+func (r *InventoryRepository) CreateTransfer(t *models.StockTransfer) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `
+		INSERT INTO stock_transfers (from_location_id, to_location_id, product_id, variant_id, quantity, actor_id, note)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id, created_at
+	`
+	err = tx.QueryRow(query, t.FromLocationID, t.ToLocationID, t.ProductID, t.VariantID, t.Quantity, t.ActorID, t.Note).
+		Scan(&t.ID, &t.CreatedAt)
+	if err != nil {
+		return err
+	}
+
+	change := models.StockChange{
+		Reason:     models.MovementTransfer,
+		ActorID:    t.ActorID,
+		Reference:  fmt.Sprintf("transfer:%d", t.ID),
+		Note:       t.Note,
+		LocationID: t.FromLocationID,
+	}
+	movement, previous, err := changeStock(tx, t.ProductID, t.VariantID, stockAdded, -t.Quantity, change)
+	if err != nil {
+		return err
+	}
+	if previous < t.Quantity {
+		return fmt.Errorf("only %d in stock at location %d", previous, t.FromLocationID)
+	}
+
+	change.LocationID = t.ToLocationID
+	untracked := t.Quantity
+	for _, b := range movement.Batches {
+		query := `
+			INSERT INTO stock_batches (location_id, product_id, variant_id, lot_number, produced_on, best_before, received_quantity)
+			SELECT $1, product_id, variant_id, lot_number, produced_on, best_before, $2
+			FROM stock_batches
+			WHERE id = $3
+			RETURNING id
+		`
+		if err := tx.QueryRow(query, t.ToLocationID, b.Quantity, b.BatchID).Scan(&change.BatchID); err != nil {
+			return err
+		}
+		if _, _, err := changeStock(tx, t.ProductID, t.VariantID, stockAdded, b.Quantity, change); err != nil {
+			return err
+		}
+		untracked -= b.Quantity
+	}
+
+	change.BatchID = 0
+	if _, _, err := changeStock(tx, t.ProductID, t.VariantID, stockAdded, untracked, change); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Stock takes
+
+const stockTakeColumns = `id, location_id, status, note, created_by, created_at, completed_at`
+
+func scanStockTake(row rowScanner) (*models.StockTake, error) {
+	var st models.StockTake
+	err := row.Scan(&st.ID, &st.LocationID, &st.Status, &st.Note, &st.CreatedBy, &st.CreatedAt, &st.CompletedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &st, nil
+}
+
+// GetStockTakes returns the stock takes, newest first, without their counts
+func (r *InventoryRepository) GetStockTakes() ([]models.StockTake, error) {
+	rows, err := r.db.Query(`SELECT ` + stockTakeColumns + ` FROM stock_takes ORDER BY created_at DESC, id DESC`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	stockTakes := []models.StockTake{}
+	for rows.Next() {
+		st, err := scanStockTake(rows)
+		if err != nil {
+			return nil, err
+		}
+		stockTakes = append(stockTakes, *st)
+	}
+
+	return stockTakes, rows.Err()
+}
+
+// GetStockTakeByID returns a stock take with its counts. The expected
+// quantity of counts of an open stock take is the current stock at its
+// location.
+func (r *InventoryRepository) GetStockTakeByID(id int) (*models.StockTake, error) {
+	st, err := scanStockTake(r.db.QueryRow(`SELECT `+stockTakeColumns+` FROM stock_takes WHERE id = $1`, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	query := `
+		SELECT c.product_id, c.variant_id,
+			CASE WHEN v.id IS NULL THEN p.title ELSE p.title || ', ' || v.title END,
+			c.counted_quantity, COALESCE(c.expected_quantity, ls.quantity, 0), c.counted_at
+		FROM stock_take_counts c
+		JOIN products p ON p.id = c.product_id
+		LEFT JOIN product_variants v ON v.id = c.variant_id
+		LEFT JOIN location_stock ls ON ls.location_id = $2 AND ls.product_id = c.product_id
+			AND COALESCE(ls.variant_id, 0) = COALESCE(c.variant_id, 0)
+		WHERE c.stock_take_id = $1
+		ORDER BY p.title, v.sort_order, c.id
+	`
+	rows, err := r.db.Query(query, id, st.LocationID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	st.Counts = []models.StockTakeCount{}
+	for rows.Next() {
+		var c models.StockTakeCount
+		if err := rows.Scan(&c.ProductID, &c.VariantID, &c.Title, &c.CountedQuantity, &c.ExpectedQuantity, &c.CountedAt); err != nil {
+			return nil, err
+		}
+		c.Difference = c.CountedQuantity - c.ExpectedQuantity
+		st.Counts = append(st.Counts, c)
+	}
+
+	return st, rows.Err()
+}
+
+func (r *InventoryRepository) CreateStockTake(st *models.StockTake) error {
+	query := `
+		INSERT INTO stock_takes (location_id, note, created_by)
+		VALUES (` + locationOrDefault(1) + `, $2, $3)
+		RETURNING id, location_id, status, created_at
+	`
+	return r.db.QueryRow(query, st.LocationID, st.Note, st.CreatedBy).Scan(&st.ID, &st.LocationID, &st.Status, &st.CreatedAt)
+}
+
+// SetStockTakeCounts records counted quantities of an open stock take,
+// replacing earlier counts of the same products and variants
+func (r *InventoryRepository) SetStockTakeCounts(id int, counts []models.StockTakeCountInput) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var status string
+	err = tx.QueryRow(`SELECT status FROM stock_takes WHERE id = $1 FOR UPDATE`, id).Scan(&status)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("stock take with id %d not found", id)
+	}
+	if err != nil {
+		return err
+	}
+	if status != models.StockTakeOpen {
+		return fmt.Errorf("stock take with id %d is %s", id, status)
+	}
+
+	query := `
+		INSERT INTO stock_take_counts (stock_take_id, product_id, variant_id, counted_quantity)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (stock_take_id, product_id, COALESCE(variant_id, 0))
+		DO UPDATE SET counted_quantity = EXCLUDED.counted_quantity, counted_at = NOW()
+	`
+	for _, c := range counts {
+		if _, err := tx.Exec(query, id, c.ProductID, c.VariantID, c.CountedQuantity); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// CompleteStockTake sets the stock of the counted products and variants at
+// the location of the stock take to the counted quantities in one transaction, recording the differences as
+// corrections and keeping the stock they replaced as expected quantities
+func (r *InventoryRepository) CompleteStockTake(id int, actorID *int) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var status, note string
+	var locationID int
+	err = tx.QueryRow(`SELECT status, note, location_id FROM stock_takes WHERE id = $1 FOR UPDATE`, id).Scan(&status, &note, &locationID)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("stock take with id %d not found", id)
+	}
+	if err != nil {
+		return err
+	}
+	if status != models.StockTakeOpen {
+		return fmt.Errorf("stock take with id %d is %s", id, status)
+	}
+
+	type count struct {
+		id, productID, quantity int
+		variantID               *int
+	}
+	rows, err := tx.Query(`SELECT id, product_id, variant_id, counted_quantity FROM stock_take_counts WHERE stock_take_id = $1 ORDER BY id`, id)
+	if err != nil {
+		return err
+	}
+	var counts []count
+	for rows.Next() {
+		var c count
+		if err := rows.Scan(&c.id, &c.productID, &c.variantID, &c.quantity); err != nil {
+			rows.Close()
+			return err
+		}
+		counts = append(counts, c)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	change := models.StockChange{
+		Reason:     models.MovementCorrection,
+		ActorID:    actorID,
+		Reference:  fmt.Sprintf("stock_take:%d", id),
+		Note:       note,
+		LocationID: locationID,
+	}
+	for _, c := range counts {
+		_, previous, err := changeStock(tx, c.productID, c.variantID, stockAtLocation, c.quantity, change)
+		if err != nil {
+			return err
+		}
+		if _, err := tx.Exec(`UPDATE stock_take_counts SET expected_quantity = $1 WHERE id = $2`, previous, c.id); err != nil {
+			return err
+		}
+	}
+
+	if _, err := tx.Exec(`UPDATE stock_takes SET status = 'completed', completed_at = NOW() WHERE id = $1`, id); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// CancelStockTake cancels an open stock take, leaving stock unchanged
+func (r *InventoryRepository) CancelStockTake(id int) error {
+	result, err := r.db.Exec(`UPDATE stock_takes SET status = 'canceled' WHERE id = $1 AND status = 'open'`, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("open stock take with id %d not found", id)
+	}
+
+	return nil
+}
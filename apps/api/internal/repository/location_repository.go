@@ -0,0 +1,294 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"gastroshop-api/internal/models"
+
+	"github.com/lib/pq"
+)
+
+// zoneStock is the SQL of the stock of a product, with products in scope, at
+// the active locations serving the zone argument: its own stock and the
+// stock of its variants, and for bundles the whole bundles each location
+// makes up
+func zoneStock(arg int) string {
+	return fmt.Sprintf(`((
+		SELECT COALESCE(SUM(ls.quantity), 0)
+		FROM location_stock ls
+		JOIN locations l ON l.id = ls.location_id
+		WHERE ls.product_id = products.id AND l.active AND $%[1]d::TEXT = ANY(l.zones)
+	) + (
+		SELECT COALESCE(SUM(b.quantity), 0)
+		FROM (
+			SELECT MIN(COALESCE(ls.quantity, 0) / c.quantity) AS quantity
+			FROM locations l
+			CROSS JOIN bundle_components c
+			LEFT JOIN location_stock ls ON ls.location_id = l.id AND ls.product_id = c.product_id
+				AND COALESCE(ls.variant_id, 0) = COALESCE(c.variant_id, 0)
+			WHERE c.bundle_id = products.id AND l.active AND $%[1]d::TEXT = ANY(l.zones)
+			GROUP BY l.id
+		) b
+	))`, arg)
+}
+
+// LocationRepository stores the locations stock is kept at and the stock
+// at each of them. Stock is changed through ProductRepository and
+// InventoryRepository, which record the movements.
+type LocationRepository struct {
+	db *sql.DB
+}
+
+func NewLocationRepository(db *sql.DB) *LocationRepository {
+	return &LocationRepository{db: db}
+}
+
+const locationColumns = `id, code, name, city, address, zones, is_default, priority, active, created_at, updated_at`
+
+func scanLocation(row rowScanner) (*models.Location, error) {
+	var l models.Location
+	err := row.Scan(
+		&l.ID, &l.Code, &l.Name, &l.City, &l.Address, pq.Array(&l.Zones),
+		&l.IsDefault, &l.Priority, &l.Active, &l.CreatedAt, &l.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if l.Zones == nil {
+		l.Zones = []string{}
+	}
+	return &l, nil
+}
+
+// GetLocations returns the locations in the order orders are fulfilled from
+// them: by priority, then by ID
+func (r *LocationRepository) GetLocations() ([]models.Location, error) {
+	rows, err := r.db.Query(`SELECT ` + locationColumns + ` FROM locations ORDER BY priority, id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	locations := []models.Location{}
+	for rows.Next() {
+		l, err := scanLocation(rows)
+		if err != nil {
+			return nil, err
+		}
+		locations = append(locations, *l)
+	}
+
+	return locations, rows.Err()
+}
+
+// GetLocationByID returns a location, the default location when id is zero
+func (r *LocationRepository) GetLocationByID(id int) (*models.Location, error) {
+	query := `SELECT ` + locationColumns + ` FROM locations WHERE id = ` + locationOrDefault(1)
+
+	l, err := scanLocation(r.db.QueryRow(query, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// CreateLocation creates a location; a new default location replaces the
+// previous one
+func (r *LocationRepository) CreateLocation(l *models.Location) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if l.IsDefault {
+		if _, err := tx.Exec(`UPDATE locations SET is_default = false, updated_at = NOW() WHERE is_default`); err != nil {
+			return err
+		}
+	}
+
+	query := `
+		INSERT INTO locations (code, name, city, address, zones, is_default, priority, active)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING id, created_at, updated_at
+	`
+	err = tx.QueryRow(query, l.Code, l.Name, l.City, l.Address, pq.Array(l.Zones), l.IsDefault, l.Priority, l.Active).
+		Scan(&l.ID, &l.CreatedAt, &l.UpdatedAt)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// UpdateLocation updates a location; a new default location replaces the
+// previous one
+func (r *LocationRepository) UpdateLocation(id int, l *models.Location) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if l.IsDefault {
+		if _, err := tx.Exec(`UPDATE locations SET is_default = false, updated_at = NOW() WHERE is_default AND id <> $1`, id); err != nil {
+			return err
+		}
+	}
+
+	query := `
+		UPDATE locations
+		SET name = $1, city = $2, address = $3, zones = $4, is_default = $5, priority = $6, active = $7, updated_at = NOW()
+		WHERE id = $8
+	`
+	result, err := tx.Exec(query, l.Name, l.City, l.Address, pq.Array(l.Zones), l.IsDefault, l.Priority, l.Active, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("location with id %d not found", id)
+	}
+
+	return tx.Commit()
+}
+
+// GetStock returns a page of the stock kept at locations, filtered by
+// location_id and product_id, with the total of matching rows. Rows without
+// stock are left out unless a product is asked for.
+func (r *LocationRepository) GetStock(filters map[string]interface{}) ([]models.LocationStock, int, error) {
+	conditions := []string{"1=1"}
+	args := []interface{}{}
+	if locationID, ok := filters["location_id"].(int); ok {
+		args = append(args, locationID)
+		conditions = append(conditions, fmt.Sprintf("ls.location_id = $%d", len(args)))
+	}
+	if productID, ok := filters["product_id"].(int); ok {
+		args = append(args, productID)
+		conditions = append(conditions, fmt.Sprintf("ls.product_id = $%d", len(args)))
+	} else {
+		conditions = append(conditions, "ls.quantity > 0")
+	}
+	where := strings.Join(conditions, " AND ")
+
+	var total int
+	if err := r.db.QueryRow(`SELECT COUNT(*) FROM location_stock ls WHERE `+where, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	page, _ := filters["page"].(int)
+	pageSize, _ := filters["page_size"].(int)
+	query := `
+		SELECT ls.location_id, ls.product_id, ls.variant_id,
+			CASE WHEN v.id IS NULL THEN p.title ELSE p.title || ', ' || v.title END,
+			ls.quantity
+		FROM location_stock ls
+		JOIN products p ON p.id = ls.product_id
+		LEFT JOIN product_variants v ON v.id = ls.variant_id
+		WHERE ` + where + fmt.Sprintf(`
+		ORDER BY ls.location_id, p.title, v.sort_order, ls.id
+		LIMIT $%d OFFSET $%d`, len(args)+1, len(args)+2)
+	args = append(args, pageSize, (page-1)*pageSize)
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	stock := []models.LocationStock{}
+	for rows.Next() {
+		var s models.LocationStock
+		if err := rows.Scan(&s.LocationID, &s.ProductID, &s.VariantID, &s.Title, &s.Quantity); err != nil {
+			return nil, 0, err
+		}
+		stock = append(stock, s)
+	}
+
+	return stock, total, rows.Err()
+}
+
+// GetStockByProductIDs returns the stock of the products and their variants
+// at the locations that have any
+func (r *LocationRepository) GetStockByProductIDs(productIDs []int) ([]models.LocationStock, error) {
+	query := `
+		SELECT location_id, product_id, variant_id, quantity
+		FROM location_stock
+		WHERE product_id = ANY($1) AND quantity > 0
+	`
+	rows, err := r.db.Query(query, pq.Array(productIDs))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	stock := []models.LocationStock{}
+	for rows.Next() {
+		var s models.LocationStock
+		if err := rows.Scan(&s.LocationID, &s.ProductID, &s.VariantID, &s.Quantity); err != nil {
+			return nil, err
+		}
+		stock = append(stock, s)
+	}
+
+	return stock, rows.Err()
+}
+
+// GetZoneStock returns the stock of the products, and of their variants
+// keyed by variant ID, at the active locations serving the zone
+func (r *LocationRepository) GetZoneStock(zone string, productIDs []int) (map[int]int, map[int]int, error) {
+	products := make(map[int]int)
+	variants := make(map[int]int)
+	if len(productIDs) == 0 {
+		return products, variants, nil
+	}
+
+	rows, err := r.db.Query(`SELECT id, `+zoneStock(2)+` FROM products WHERE id = ANY($1)`, pq.Array(productIDs), zone)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id, quantity int
+		if err := rows.Scan(&id, &quantity); err != nil {
+			return nil, nil, err
+		}
+		products[id] = quantity
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	query := `
+		SELECT ls.variant_id, SUM(ls.quantity)
+		FROM location_stock ls
+		JOIN locations l ON l.id = ls.location_id
+		WHERE ls.product_id = ANY($1) AND ls.variant_id IS NOT NULL AND l.active AND $2 = ANY(l.zones)
+		GROUP BY ls.variant_id
+	`
+	variantRows, err := r.db.Query(query, pq.Array(productIDs), zone)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer variantRows.Close()
+
+	for variantRows.Next() {
+		var id, quantity int
+		if err := variantRows.Scan(&id, &quantity); err != nil {
+			return nil, nil, err
+		}
+		variants[id] = quantity
+	}
+
+	return products, variants, variantRows.Err()
+}
@@ -3,10 +3,19 @@ package repository
 import (
 	"database/sql"
 	"encoding/json"
+	"fmt"
+	"time"
 
 	"gastroshop-api/internal/models"
+
+	"github.com/lib/pq"
 )
 
+// rowScanner is implemented by both *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
 type OrderRepository struct {
 	db           *sql.DB
 	hasPaymentID *bool // Cache for payment_id column existence
@@ -25,8 +34,8 @@ func (r *OrderRepository) checkPaymentIDColumn() (bool, error) {
 	var exists bool
 	query := `
 		SELECT EXISTS (
-			SELECT 1 
-			FROM information_schema.columns 
+			SELECT 1
+			FROM information_schema.columns
 			WHERE table_name = 'orders' AND column_name = 'payment_id'
 		)
 	`
@@ -39,6 +48,74 @@ func (r *OrderRepository) checkPaymentIDColumn() (bool, error) {
 	return exists, nil
 }
 
+// orderColumns returns the select list for orders, matching scanOrder
+func orderColumns(hasPaymentID bool) string {
+	if hasPaymentID {
+		return `orders.id, orders.user_id, orders.items, orders.amount_cents, orders.currency, orders.status,
+			orders.payment_id, orders.shipping_address, orders.delivery_slot_id, orders.fulfilment_location_id,
+			orders.tax_cents, orders.tax_breakdown, orders.prices_include_tax,
+			orders.guest_email, orders.guest_phone, orders.access_token, orders.created_at`
+	}
+	return `orders.id, orders.user_id, orders.items, orders.amount_cents, orders.currency, orders.status,
+			orders.shipping_address, orders.delivery_slot_id, orders.fulfilment_location_id,
+			orders.tax_cents, orders.tax_breakdown, orders.prices_include_tax,
+			orders.guest_email, orders.guest_phone, orders.access_token, orders.created_at`
+}
+
+// scanOrder scans a row selected with orderColumns
+func scanOrder(row rowScanner, hasPaymentID bool) (*models.Order, error) {
+	var order models.Order
+	var itemsJSON, shippingJSON, taxJSON []byte
+	var paymentID, guestEmail, guestPhone, accessToken sql.NullString
+	var deliverySlotID sql.NullInt64
+
+	var err error
+	if hasPaymentID {
+		err = row.Scan(
+			&order.ID, &order.UserID, &itemsJSON, &order.AmountCents, &order.Currency,
+			&order.Status, &paymentID, &shippingJSON, &deliverySlotID, &order.FulfilmentLocationID,
+			&order.TaxCents, &taxJSON, &order.PricesIncludeTax,
+			&guestEmail, &guestPhone, &accessToken, &order.CreatedAt,
+		)
+	} else {
+		err = row.Scan(
+			&order.ID, &order.UserID, &itemsJSON, &order.AmountCents, &order.Currency,
+			&order.Status, &shippingJSON, &deliverySlotID, &order.FulfilmentLocationID,
+			&order.TaxCents, &taxJSON, &order.PricesIncludeTax,
+			&guestEmail, &guestPhone, &accessToken, &order.CreatedAt,
+		)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if paymentID.Valid {
+		order.PaymentID = paymentID.String
+	}
+	if deliverySlotID.Valid {
+		slotID := int(deliverySlotID.Int64)
+		order.DeliverySlotID = &slotID
+	}
+	order.GuestEmail = guestEmail.String
+	order.GuestPhone = guestPhone.String
+	order.AccessToken = accessToken.String
+
+	// Unmarshal JSON fields
+	if err := json.Unmarshal(itemsJSON, &order.Items); err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(shippingJSON, &order.ShippingAddress); err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(taxJSON, &order.TaxBreakdown); err != nil {
+		return nil, err
+	}
+
+	return &order, nil
+}
+
 func (r *OrderRepository) CreateOrder(order *models.Order) error {
 	itemsJSON, err := json.Marshal(order.Items)
 	if err != nil {
@@ -50,19 +127,39 @@ func (r *OrderRepository) CreateOrder(order *models.Order) error {
 		return err
 	}
 
+	taxJSON, err := json.Marshal(order.TaxBreakdown)
+	if err != nil {
+		return err
+	}
+
 	hasPaymentID, err := r.checkPaymentIDColumn()
 	if err != nil {
 		return err
 	}
 
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	productIDs := make([]int, len(order.Items))
+	for i, item := range order.Items {
+		productIDs[i] = item.ProductID
+	}
+	if err := lockProducts(tx, productIDs); err != nil {
+		return err
+	}
+
 	var query string
 	if hasPaymentID {
 		query = `
-			INSERT INTO orders (user_id, items, amount_cents, currency, status, payment_id, shipping_address)
-			VALUES ($1, $2, $3, $4, $5, $6, $7)
+			INSERT INTO orders (user_id, items, amount_cents, currency, status, payment_id, shipping_address, delivery_slot_id,
+				tax_cents, tax_breakdown, prices_include_tax, guest_email, guest_phone, access_token, fulfilment_location_id)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, NULLIF($12, ''), NULLIF($13, ''), NULLIF($14, ''), $15)
 			RETURNING id, created_at
 		`
-		return r.db.QueryRow(
+		err = tx.QueryRow(
 			query,
 			order.UserID,
 			itemsJSON,
@@ -71,14 +168,23 @@ func (r *OrderRepository) CreateOrder(order *models.Order) error {
 			order.Status,
 			order.PaymentID,
 			shippingJSON,
+			order.DeliverySlotID,
+			order.TaxCents,
+			taxJSON,
+			order.PricesIncludeTax,
+			order.GuestEmail,
+			order.GuestPhone,
+			order.AccessToken,
+			order.FulfilmentLocationID,
 		).Scan(&order.ID, &order.CreatedAt)
 	} else {
 		query = `
-			INSERT INTO orders (user_id, items, amount_cents, currency, status, shipping_address)
-			VALUES ($1, $2, $3, $4, $5, $6)
+			INSERT INTO orders (user_id, items, amount_cents, currency, status, shipping_address, delivery_slot_id,
+				tax_cents, tax_breakdown, prices_include_tax, guest_email, guest_phone, access_token, fulfilment_location_id)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, NULLIF($11, ''), NULLIF($12, ''), NULLIF($13, ''), $14)
 			RETURNING id, created_at
 		`
-		return r.db.QueryRow(
+		err = tx.QueryRow(
 			query,
 			order.UserID,
 			itemsJSON,
@@ -86,8 +192,41 @@ func (r *OrderRepository) CreateOrder(order *models.Order) error {
 			order.Currency,
 			order.Status,
 			shippingJSON,
+			order.DeliverySlotID,
+			order.TaxCents,
+			taxJSON,
+			order.PricesIncludeTax,
+			order.GuestEmail,
+			order.GuestPhone,
+			order.AccessToken,
+			order.FulfilmentLocationID,
 		).Scan(&order.ID, &order.CreatedAt)
 	}
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// lockProducts takes a share lock on the products until the transaction
+// ends, so they can't be purged while something starts referring to them by
+// id without a foreign key. It fails when one of them is gone.
+func lockProducts(db execQueryer, ids []int) error {
+	distinct := make(map[int]bool)
+	for _, id := range ids {
+		distinct[id] = true
+	}
+
+	var locked int
+	query := `SELECT COUNT(*) FROM (SELECT id FROM products WHERE id = ANY($1) FOR SHARE) p`
+	if err := db.QueryRow(query, pq.Array(ids)).Scan(&locked); err != nil {
+		return err
+	}
+	if locked != len(distinct) {
+		return fmt.Errorf("product not found")
+	}
+	return nil
 }
 
 func (r *OrderRepository) GetOrderByID(id int) (*models.Order, error) {
@@ -96,40 +235,37 @@ func (r *OrderRepository) GetOrderByID(id int) (*models.Order, error) {
 		return nil, err
 	}
 
-	var query string
-	if hasPaymentID {
-		query = `
-			SELECT id, user_id, items, amount_cents, currency, status, payment_id, shipping_address, created_at
-			FROM orders
-			WHERE id = $1
-		`
-	} else {
-		query = `
-			SELECT id, user_id, items, amount_cents, currency, status, shipping_address, created_at
-			FROM orders
-			WHERE id = $1
-		`
+	query := `
+		SELECT ` + orderColumns(hasPaymentID) + `
+		FROM orders
+		WHERE id = $1
+	`
+
+	order, err := scanOrder(r.db.QueryRow(query, id), hasPaymentID)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
 	}
 
-	var order models.Order
-	var itemsJSON, shippingJSON []byte
+	return order, nil
+}
 
-	if hasPaymentID {
-		var paymentID sql.NullString
-		err = r.db.QueryRow(query, id).Scan(
-			&order.ID, &order.UserID, &itemsJSON, &order.AmountCents, &order.Currency,
-			&order.Status, &paymentID, &shippingJSON, &order.CreatedAt,
-		)
-		if err == nil && paymentID.Valid {
-			order.PaymentID = paymentID.String
-		}
-	} else {
-		err = r.db.QueryRow(query, id).Scan(
-			&order.ID, &order.UserID, &itemsJSON, &order.AmountCents, &order.Currency,
-			&order.Status, &shippingJSON, &order.CreatedAt,
-		)
+// GetOrderByAccessToken returns the guest order the token was issued for
+func (r *OrderRepository) GetOrderByAccessToken(token string) (*models.Order, error) {
+	hasPaymentID, err := r.checkPaymentIDColumn()
+	if err != nil {
+		return nil, err
 	}
 
+	query := `
+		SELECT ` + orderColumns(hasPaymentID) + `
+		FROM orders
+		WHERE access_token = $1
+	`
+
+	order, err := scanOrder(r.db.QueryRow(query, token), hasPaymentID)
 	if err == sql.ErrNoRows {
 		return nil, nil
 	}
@@ -137,22 +273,383 @@ func (r *OrderRepository) GetOrderByID(id int) (*models.Order, error) {
 		return nil, err
 	}
 
-	// Unmarshal JSON fields
-	if err := json.Unmarshal(itemsJSON, &order.Items); err != nil {
-		return nil, err
+	return order, nil
+}
+
+// AttachGuestOrders assigns guest orders placed with the email to the user
+// and returns how many orders were attached
+func (r *OrderRepository) AttachGuestOrders(email string, userID int) (int64, error) {
+	query := `
+		UPDATE orders
+		SET user_id = $1
+		WHERE user_id IS NULL AND LOWER(guest_email) = LOWER($2)
+	`
+	result, err := r.db.Exec(query, userID, email)
+	if err != nil {
+		return 0, err
 	}
+	return result.RowsAffected()
+}
 
-	if err := json.Unmarshal(shippingJSON, &order.ShippingAddress); err != nil {
+// MoveFulfilment moves the fulfilment of an order to another location. For a
+// paid order, the stock its inventory movements took at the previous location
+// is returned there, to the batches it was taken from, and up to that much is
+// taken for the stock lines at the new location, storing the batches taken
+// with the order lines. It fails when the order status has changed.
+func (r *OrderRepository) MoveFulfilment(order *models.Order, previousLocationID, locationID int, returned models.StockChange, stock []models.LineStockChange) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var status string
+	err = tx.QueryRow(`SELECT status FROM orders WHERE id = $1 FOR UPDATE`, order.ID).Scan(&status)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("order with id %d not found", order.ID)
+	}
+	if err != nil {
+		return err
+	}
+	if status != order.Status {
+		return fmt.Errorf("order with id %d is %s and can no longer change location", order.ID, status)
+	}
+
+	if _, err := tx.Exec(`UPDATE orders SET fulfilment_location_id = $1 WHERE id = $2`, locationID, order.ID); err != nil {
+		return err
+	}
+
+	if len(stock) > 0 {
+		taken, err := stockTakenFor(tx, returned.Reference, previousLocationID)
+		if err != nil {
+			return err
+		}
+
+		left := make(map[stockKey]int, len(taken))
+		for _, t := range taken {
+			left[t.key] = t.quantity
+			remaining := t.quantity
+			for _, b := range t.batches {
+				if b.Quantity > remaining {
+					b.Quantity = remaining
+				}
+				if b.Quantity <= 0 {
+					continue
+				}
+				change := returned
+				change.BatchID = b.BatchID
+				if _, _, err := changeStock(tx, t.key.productID, t.key.variant(), stockAdded, b.Quantity, change); err != nil {
+					return err
+				}
+				remaining -= b.Quantity
+			}
+			if remaining > 0 {
+				if _, _, err := changeStock(tx, t.key.productID, t.key.variant(), stockAdded, remaining, returned); err != nil {
+					return err
+				}
+			}
+		}
+
+		for i := range order.Items {
+			order.Items[i].Batches = nil
+		}
+		for _, c := range stock {
+			key := newStockKey(c.ProductID, c.VariantID)
+			quantity := c.Quantity
+			if quantity > left[key] {
+				quantity = left[key]
+			}
+			if quantity <= 0 {
+				continue
+			}
+			left[key] -= quantity
+
+			movement, _, err := changeStock(tx, c.ProductID, c.VariantID, stockAdded, -quantity, c.Change)
+			if err != nil {
+				return err
+			}
+			if movement != nil && c.Line >= 0 {
+				order.Items[c.Line].Batches = append(order.Items[c.Line].Batches, movement.Batches...)
+			}
+		}
+
+		itemsJSON, err := json.Marshal(order.Items)
+		if err != nil {
+			return err
+		}
+		if _, err := tx.Exec(`UPDATE orders SET items = $1 WHERE id = $2`, itemsJSON, order.ID); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	order.FulfilmentLocationID = &locationID
+	return nil
+}
+
+// stockKey identifies the stock of a product or one of its variants
+type stockKey struct {
+	productID int
+	variantID int
+}
+
+func newStockKey(productID int, variantID *int) stockKey {
+	key := stockKey{productID: productID}
+	if variantID != nil {
+		key.variantID = *variantID
+	}
+	return key
+}
+
+func (k stockKey) variant() *int {
+	if k.variantID == 0 {
+		return nil
+	}
+	id := k.variantID
+	return &id
+}
+
+// takenStock is the stock of a product or variant the movements of a
+// reference took at a location, with the parts taken from batches
+type takenStock struct {
+	key      stockKey
+	quantity int
+	batches  []models.BatchAllocation
+}
+
+// stockTakenFor sums up the stock the movements of a reference took at a
+// location, net of the stock they returned, by product and variant and by
+// batch, ordered by product and variant
+func stockTakenFor(db execQueryer, reference string, locationID int) ([]takenStock, error) {
+	query := `
+		WITH movements AS (
+			SELECT id, product_id, variant_id, quantity_change
+			FROM inventory_movements
+			WHERE reference = $1 AND location_id = $2
+		), totals AS (
+			SELECT product_id, variant_id, -SUM(quantity_change) AS quantity
+			FROM movements
+			GROUP BY product_id, variant_id
+		), batches AS (
+			SELECT m.product_id, m.variant_id, bm.batch_id, -SUM(bm.quantity_change) AS quantity
+			FROM movements m
+			JOIN batch_movements bm ON bm.movement_id = m.id
+			GROUP BY m.product_id, m.variant_id, bm.batch_id
+		)
+		SELECT t.product_id, t.variant_id, t.quantity, COALESCE(b.batch_id, 0), COALESCE(b.quantity, 0)
+		FROM totals t
+		LEFT JOIN batches b ON b.product_id = t.product_id
+			AND COALESCE(b.variant_id, 0) = COALESCE(t.variant_id, 0) AND b.quantity > 0
+		WHERE t.quantity > 0
+		ORDER BY t.product_id, COALESCE(t.variant_id, 0), b.batch_id
+	`
+	rows, err := db.Query(query, reference, locationID)
+	if err != nil {
 		return nil, err
 	}
+	defer rows.Close()
 
-	return &order, nil
+	var taken []takenStock
+	for rows.Next() {
+		var productID, quantity, batchID, batchQuantity int
+		var variantID sql.NullInt64
+		if err := rows.Scan(&productID, &variantID, &quantity, &batchID, &batchQuantity); err != nil {
+			return nil, err
+		}
+		key := stockKey{productID: productID, variantID: int(variantID.Int64)}
+		if len(taken) == 0 || taken[len(taken)-1].key != key {
+			taken = append(taken, takenStock{key: key, quantity: quantity})
+		}
+		if batchID != 0 {
+			last := &taken[len(taken)-1]
+			last.batches = append(last.batches, models.BatchAllocation{BatchID: batchID, Quantity: batchQuantity})
+		}
+	}
+	return taken, rows.Err()
 }
 
+// SetOrderItemBatches stores the batches the items of an order were sold
+// from, keeping its amounts
+func (r *OrderRepository) SetOrderItemBatches(id int, items []models.OrderItem) error {
+	itemsJSON, err := json.Marshal(items)
+	if err != nil {
+		return err
+	}
+
+	result, err := r.db.Exec(`UPDATE orders SET items = $1 WHERE id = $2`, itemsJSON, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("order with id %d not found", id)
+	}
+
+	return nil
+}
+
+// UpdateOrderStatus changes the order status. When an order moves to
+// "canceled" its delivery slot reservation is released in the same transaction.
 func (r *OrderRepository) UpdateOrderStatus(id int, status string) error {
-	query := `UPDATE orders SET status = $1 WHERE id = $2`
-	_, err := r.db.Exec(query, status, id)
-	return err
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var previousStatus string
+	var deliverySlotID sql.NullInt64
+	err = tx.QueryRow(`SELECT status, delivery_slot_id FROM orders WHERE id = $1 FOR UPDATE`, id).
+		Scan(&previousStatus, &deliverySlotID)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec(`UPDATE orders SET status = $1 WHERE id = $2`, status, id); err != nil {
+		return err
+	}
+
+	if status == "canceled" && previousStatus != "canceled" && deliverySlotID.Valid {
+		query := `
+			UPDATE delivery_slots
+			SET reserved = GREATEST(0, reserved - 1), updated_at = NOW()
+			WHERE id = $1
+		`
+		if _, err := tx.Exec(query, deliverySlotID.Int64); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// UpdateOrderItems saves edited items and recomputed totals of an order,
+// takes or returns the stock of the changed lines and records the edit in
+// the order history in the same transaction. The order must still have the
+// status it was edited in.
+func (r *OrderRepository) UpdateOrderItems(order *models.Order, entry *models.OrderHistoryEntry, stock []models.LineStockChange) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var status string
+	err = tx.QueryRow(`SELECT status FROM orders WHERE id = $1 FOR UPDATE`, order.ID).Scan(&status)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("order with id %d not found", order.ID)
+	}
+	if err != nil {
+		return err
+	}
+	if status != order.Status {
+		return fmt.Errorf("order with id %d is %s and can no longer be edited", order.ID, status)
+	}
+
+	for _, c := range stock {
+		movement, _, err := changeStock(tx, c.ProductID, c.VariantID, stockAdded, -c.Quantity, c.Change)
+		if err != nil {
+			return err
+		}
+		if movement != nil && c.Quantity > 0 && c.Line >= 0 {
+			order.Items[c.Line].Batches = append(order.Items[c.Line].Batches, movement.Batches...)
+		}
+	}
+
+	itemsJSON, err := json.Marshal(order.Items)
+	if err != nil {
+		return err
+	}
+
+	taxJSON, err := json.Marshal(order.TaxBreakdown)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		UPDATE orders
+		SET items = $1, amount_cents = $2, tax_cents = $3, tax_breakdown = $4
+		WHERE id = $5
+	`
+	if _, err := tx.Exec(query, itemsJSON, order.AmountCents, order.TaxCents, taxJSON, order.ID); err != nil {
+		return err
+	}
+
+	if err := insertOrderHistory(tx, entry); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// AddOrderHistory appends an entry to the order history
+func (r *OrderRepository) AddOrderHistory(entry *models.OrderHistoryEntry) error {
+	return insertOrderHistory(r.db, entry)
+}
+
+// execQueryer is implemented by both *sql.DB and *sql.Tx
+type execQueryer interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+func insertOrderHistory(db execQueryer, entry *models.OrderHistoryEntry) error {
+	detailsJSON, err := json.Marshal(entry.Details)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO order_history (order_id, actor_id, action, details)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, created_at
+	`
+	return db.QueryRow(query, entry.OrderID, entry.ActorID, entry.Action, detailsJSON).
+		Scan(&entry.ID, &entry.CreatedAt)
+}
+
+func (r *OrderRepository) GetOrderHistory(orderID int) ([]models.OrderHistoryEntry, error) {
+	query := `
+		SELECT id, order_id, actor_id, action, details, created_at
+		FROM order_history
+		WHERE order_id = $1
+		ORDER BY created_at, id
+	`
+	rows, err := r.db.Query(query, orderID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := make([]models.OrderHistoryEntry, 0)
+	for rows.Next() {
+		var entry models.OrderHistoryEntry
+		var actorID sql.NullInt64
+		var detailsJSON []byte
+		if err := rows.Scan(&entry.ID, &entry.OrderID, &actorID, &entry.Action, &detailsJSON, &entry.CreatedAt); err != nil {
+			return nil, err
+		}
+		if actorID.Valid {
+			id := int(actorID.Int64)
+			entry.ActorID = &id
+		}
+		if err := json.Unmarshal(detailsJSON, &entry.Details); err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+
+	return entries, nil
 }
 
 func (r *OrderRepository) UpdateOrderPaymentID(id int, paymentID string) error {
@@ -184,93 +681,55 @@ func (r *OrderRepository) GetOrdersByUserID(userID int) ([]models.Order, error)
 		return []models.Order{}, err
 	}
 
-	var query string
-	if hasPaymentID {
-		query = `
-			SELECT id, user_id, items, amount_cents, currency, status, payment_id, shipping_address, created_at
-			FROM orders
-			WHERE user_id = $1
-			ORDER BY created_at DESC
-		`
-	} else {
-		query = `
-			SELECT id, user_id, items, amount_cents, currency, status, shipping_address, created_at
-			FROM orders
-			WHERE user_id = $1
-			ORDER BY created_at DESC
-		`
-	}
+	query := `
+		SELECT ` + orderColumns(hasPaymentID) + `
+		FROM orders
+		WHERE user_id = $1
+		ORDER BY created_at DESC
+	`
+
+	return r.queryOrders(hasPaymentID, query, userID)
+}
 
-	rows, err := r.db.Query(query, userID)
+func (r *OrderRepository) GetAllOrders() ([]models.Order, error) {
+	hasPaymentID, err := r.checkPaymentIDColumn()
 	if err != nil {
 		// Return empty array instead of nil to avoid null in JSON response
 		return []models.Order{}, err
 	}
-	defer rows.Close()
-
-	orders := make([]models.Order, 0)
-	for rows.Next() {
-		var order models.Order
-		var itemsJSON, shippingJSON []byte
-
-		var err error
-		if hasPaymentID {
-			var paymentID sql.NullString
-			err = rows.Scan(
-				&order.ID, &order.UserID, &itemsJSON, &order.AmountCents, &order.Currency,
-				&order.Status, &paymentID, &shippingJSON, &order.CreatedAt,
-			)
-			if err == nil && paymentID.Valid {
-				order.PaymentID = paymentID.String
-			}
-		} else {
-			err = rows.Scan(
-				&order.ID, &order.UserID, &itemsJSON, &order.AmountCents, &order.Currency,
-				&order.Status, &shippingJSON, &order.CreatedAt,
-			)
-		}
-		if err != nil {
-			return nil, err
-		}
-
-		// Unmarshal JSON fields
-		if err := json.Unmarshal(itemsJSON, &order.Items); err != nil {
-			return nil, err
-		}
 
-		if err := json.Unmarshal(shippingJSON, &order.ShippingAddress); err != nil {
-			return nil, err
-		}
-
-		orders = append(orders, order)
-	}
+	query := `
+		SELECT ` + orderColumns(hasPaymentID) + `
+		FROM orders
+		ORDER BY created_at DESC
+	`
 
-	return orders, nil
+	return r.queryOrders(hasPaymentID, query)
 }
 
-func (r *OrderRepository) GetAllOrders() ([]models.Order, error) {
+// GetOrdersByDeliveryDate returns non-canceled orders booked into slots
+// starting on the given day (UTC), optionally limited to one zone, in route order.
+func (r *OrderRepository) GetOrdersByDeliveryDate(date time.Time, zone string) ([]models.Order, error) {
 	hasPaymentID, err := r.checkPaymentIDColumn()
 	if err != nil {
-		// Return empty array instead of nil to avoid null in JSON response
 		return []models.Order{}, err
 	}
 
-	var query string
-	if hasPaymentID {
-		query = `
-			SELECT id, user_id, items, amount_cents, currency, status, payment_id, shipping_address, created_at
-			FROM orders
-			ORDER BY created_at DESC
-		`
-	} else {
-		query = `
-			SELECT id, user_id, items, amount_cents, currency, status, shipping_address, created_at
-			FROM orders
-			ORDER BY created_at DESC
-		`
-	}
+	query := `
+		SELECT ` + orderColumns(hasPaymentID) + `
+		FROM orders
+		JOIN delivery_slots ON delivery_slots.id = orders.delivery_slot_id
+		WHERE (delivery_slots.starts_at AT TIME ZONE 'UTC')::date = $1::date
+		  AND orders.status <> 'canceled'
+		  AND ($2::text = '' OR delivery_slots.zone = $2)
+		ORDER BY delivery_slots.zone, delivery_slots.starts_at, orders.id
+	`
+
+	return r.queryOrders(hasPaymentID, query, date.Format("2006-01-02"), zone)
+}
 
-	rows, err := r.db.Query(query)
+func (r *OrderRepository) queryOrders(hasPaymentID bool, query string, args ...interface{}) ([]models.Order, error) {
+	rows, err := r.db.Query(query, args...)
 	if err != nil {
 		// Return empty array instead of nil to avoid null in JSON response
 		return []models.Order{}, err
@@ -279,39 +738,11 @@ func (r *OrderRepository) GetAllOrders() ([]models.Order, error) {
 
 	orders := make([]models.Order, 0)
 	for rows.Next() {
-		var order models.Order
-		var itemsJSON, shippingJSON []byte
-
-		var err error
-		if hasPaymentID {
-			var paymentID sql.NullString
-			err = rows.Scan(
-				&order.ID, &order.UserID, &itemsJSON, &order.AmountCents, &order.Currency,
-				&order.Status, &paymentID, &shippingJSON, &order.CreatedAt,
-			)
-			if err == nil && paymentID.Valid {
-				order.PaymentID = paymentID.String
-			}
-		} else {
-			err = rows.Scan(
-				&order.ID, &order.UserID, &itemsJSON, &order.AmountCents, &order.Currency,
-				&order.Status, &shippingJSON, &order.CreatedAt,
-			)
-		}
+		order, err := scanOrder(rows, hasPaymentID)
 		if err != nil {
 			return nil, err
 		}
-
-		// Unmarshal JSON fields
-		if err := json.Unmarshal(itemsJSON, &order.Items); err != nil {
-			return nil, err
-		}
-
-		if err := json.Unmarshal(shippingJSON, &order.ShippingAddress); err != nil {
-			return nil, err
-		}
-
-		orders = append(orders, order)
+		orders = append(orders, *order)
 	}
 
 	return orders, nil
@@ -0,0 +1,142 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+
+	"gastroshop-api/internal/models"
+
+	"github.com/lib/pq"
+)
+
+type PairingRepository struct {
+	db *sql.DB
+}
+
+func NewPairingRepository(db *sql.DB) *PairingRepository {
+	return &PairingRepository{db: db}
+}
+
+// pairingSidesQuery selects the pairings of the products in $1 from the side
+// of those products, so a pairing stored as (a, b) is found for a and b
+const pairingSidesQuery = `
+	SELECT id AS pairing_id, product_id AS side_id, paired_product_id AS other_id,
+		pairing_type, note, created_at AS pairing_created_at
+	FROM product_pairings WHERE product_id = ANY($1)
+	UNION ALL
+	SELECT id, paired_product_id, product_id, pairing_type, note, created_at
+	FROM product_pairings WHERE paired_product_id = ANY($1)`
+
+// GetPairingsByProductIDs returns the pairings of the products keyed by
+// product ID, each with the product on its other side. With availableOnly
+// pairings with archived or out of stock products are left out.
+func (r *PairingRepository) GetPairingsByProductIDs(productIDs []int, availableOnly bool) (map[int][]models.ProductPairing, error) {
+	pairings := make(map[int][]models.ProductPairing)
+	if len(productIDs) == 0 {
+		return pairings, nil
+	}
+
+	query := `
+		SELECT ` + productColumns + `, pairing_id, side_id, pairing_type, note, pairing_created_at
+		FROM products
+		JOIN (` + pairingSidesQuery + `) sides ON sides.other_id = products.id
+	`
+	if availableOnly {
+		query += ` WHERE in_stock = true AND archived_at IS NULL`
+	}
+	query += ` ORDER BY side_id, pairing_id`
+
+	rows, err := r.db.Query(query, pq.Array(productIDs))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var pairing models.ProductPairing
+		p, err := scanProduct(rows, &pairing.ID, &pairing.ProductID, &pairing.PairingType, &pairing.Note, &pairing.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		pairing.PairedProductID = p.ID
+		pairing.PairedProduct = p
+		pairings[pairing.ProductID] = append(pairings[pairing.ProductID], pairing)
+	}
+
+	return pairings, rows.Err()
+}
+
+// GetPairingByID returns a pairing as stored, without the paired product
+func (r *PairingRepository) GetPairingByID(id int) (*models.ProductPairing, error) {
+	query := `
+		SELECT id, product_id, paired_product_id, pairing_type, note, created_at
+		FROM product_pairings
+		WHERE id = $1
+	`
+	var p models.ProductPairing
+	err := r.db.QueryRow(query, id).Scan(&p.ID, &p.ProductID, &p.PairedProductID, &p.PairingType, &p.Note, &p.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// PairingExists reports whether two products are already paired, in either
+// direction
+func (r *PairingRepository) PairingExists(productID, pairedProductID int) (bool, error) {
+	query := `
+		SELECT EXISTS (
+			SELECT 1 FROM product_pairings
+			WHERE (product_id = $1 AND paired_product_id = $2) OR (product_id = $2 AND paired_product_id = $1)
+		)
+	`
+	var exists bool
+	err := r.db.QueryRow(query, productID, pairedProductID).Scan(&exists)
+	return exists, err
+}
+
+func (r *PairingRepository) CreatePairing(p *models.ProductPairing) error {
+	query := `
+		INSERT INTO product_pairings (product_id, paired_product_id, pairing_type, note)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, created_at
+	`
+	return r.db.QueryRow(query, p.ProductID, p.PairedProductID, p.PairingType, p.Note).Scan(&p.ID, &p.CreatedAt)
+}
+
+func (r *PairingRepository) UpdatePairing(id int, pairingType, note string) error {
+	result, err := r.db.Exec(`UPDATE product_pairings SET pairing_type = $1, note = $2 WHERE id = $3`, pairingType, note, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("pairing with id %d not found", id)
+	}
+
+	return nil
+}
+
+func (r *PairingRepository) DeletePairing(id int) error {
+	result, err := r.db.Exec(`DELETE FROM product_pairings WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("pairing with id %d not found", id)
+	}
+
+	return nil
+}
@@ -18,15 +18,20 @@ func NewPaymentRepository(db *sql.DB) *PaymentRepository {
 
 func (r *PaymentRepository) CreatePayment(payment *models.Payment) error {
 	query := `
-		INSERT INTO payments (payment_id, order_id, amount_cents, currency, status, provider, checkout_url, metadata, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		INSERT INTO payments (payment_id, order_id, kind, amount_cents, currency, status, provider, checkout_url, metadata, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
 		RETURNING id`
 
+	if payment.Kind == "" {
+		payment.Kind = models.PaymentKindPrimary
+	}
+
 	now := time.Now()
 	err := r.db.QueryRow(
 		query,
 		payment.PaymentID,
 		payment.OrderID,
+		payment.Kind,
 		payment.AmountCents,
 		payment.Currency,
 		payment.Status,
@@ -48,7 +53,7 @@ func (r *PaymentRepository) CreatePayment(payment *models.Payment) error {
 
 func (r *PaymentRepository) GetPaymentByPaymentID(paymentID string) (*models.Payment, error) {
 	query := `
-		SELECT id, payment_id, order_id, amount_cents, currency, status, provider, checkout_url, webhook_event_id, metadata, created_at, updated_at
+		SELECT id, payment_id, order_id, kind, amount_cents, currency, status, provider, checkout_url, webhook_event_id, metadata, created_at, updated_at
 		FROM payments
 		WHERE payment_id = $1`
 
@@ -57,6 +62,7 @@ func (r *PaymentRepository) GetPaymentByPaymentID(paymentID string) (*models.Pay
 		&payment.ID,
 		&payment.PaymentID,
 		&payment.OrderID,
+		&payment.Kind,
 		&payment.AmountCents,
 		&payment.Currency,
 		&payment.Status,
@@ -98,7 +104,7 @@ func (r *PaymentRepository) UpdatePaymentWebhookEventID(paymentID string, eventI
 
 func (r *PaymentRepository) GetPaymentsByOrderID(orderID int) ([]models.Payment, error) {
 	query := `
-		SELECT id, payment_id, order_id, amount_cents, currency, status, provider, checkout_url, webhook_event_id, metadata, created_at, updated_at
+		SELECT id, payment_id, order_id, kind, amount_cents, currency, status, provider, checkout_url, webhook_event_id, metadata, created_at, updated_at
 		FROM payments
 		WHERE order_id = $1
 		ORDER BY created_at DESC`
@@ -116,6 +122,7 @@ func (r *PaymentRepository) GetPaymentsByOrderID(orderID int) ([]models.Payment,
 			&payment.ID,
 			&payment.PaymentID,
 			&payment.OrderID,
+			&payment.Kind,
 			&payment.AmountCents,
 			&payment.Currency,
 			&payment.Status,
@@ -137,7 +144,7 @@ func (r *PaymentRepository) GetPaymentsByOrderID(orderID int) ([]models.Payment,
 
 func (r *PaymentRepository) GetPaymentByWebhookEventID(eventID string) (*models.Payment, error) {
 	query := `
-		SELECT id, payment_id, order_id, amount_cents, currency, status, provider, checkout_url, webhook_event_id, metadata, created_at, updated_at
+		SELECT id, payment_id, order_id, kind, amount_cents, currency, status, provider, checkout_url, webhook_event_id, metadata, created_at, updated_at
 		FROM payments
 		WHERE webhook_event_id = $1`
 
@@ -146,6 +153,7 @@ func (r *PaymentRepository) GetPaymentByWebhookEventID(eventID string) (*models.
 		&payment.ID,
 		&payment.PaymentID,
 		&payment.OrderID,
+		&payment.Kind,
 		&payment.AmountCents,
 		&payment.Currency,
 		&payment.Status,
@@ -166,3 +174,63 @@ func (r *PaymentRepository) GetPaymentByWebhookEventID(eventID string) (*models.
 
 	return payment, nil
 }
+
+const paymentMethodColumns = `id, user_id, provider, provider_method_id, title, created_at`
+
+func scanPaymentMethod(row rowScanner) (*models.PaymentMethod, error) {
+	var m models.PaymentMethod
+	if err := row.Scan(&m.ID, &m.UserID, &m.Provider, &m.ProviderMethodID, &m.Title, &m.CreatedAt); err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+// SavePaymentMethod records a payment method saved with the provider for the
+// user; a method saved again keeps its owner
+func (r *PaymentRepository) SavePaymentMethod(m *models.PaymentMethod) error {
+	query := `
+		INSERT INTO payment_methods (user_id, provider, provider_method_id, title)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (provider, provider_method_id) DO UPDATE SET title = EXCLUDED.title
+		RETURNING ` + paymentMethodColumns
+	saved, err := scanPaymentMethod(r.db.QueryRow(query, m.UserID, m.Provider, m.ProviderMethodID, m.Title))
+	if err != nil {
+		return fmt.Errorf("failed to save payment method: %v", err)
+	}
+	*m = *saved
+	return nil
+}
+
+// GetPaymentMethod returns a saved payment method by its provider id, nil
+// when it isn't known
+func (r *PaymentRepository) GetPaymentMethod(provider, providerMethodID string) (*models.PaymentMethod, error) {
+	query := `SELECT ` + paymentMethodColumns + ` FROM payment_methods WHERE provider = $1 AND provider_method_id = $2`
+	m, err := scanPaymentMethod(r.db.QueryRow(query, provider, providerMethodID))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get payment method: %v", err)
+	}
+	return m, nil
+}
+
+func (r *PaymentRepository) GetPaymentMethodsByUserID(userID int) ([]models.PaymentMethod, error) {
+	query := `SELECT ` + paymentMethodColumns + ` FROM payment_methods WHERE user_id = $1 ORDER BY created_at DESC`
+	rows, err := r.db.Query(query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get payment methods: %v", err)
+	}
+	defer rows.Close()
+
+	methods := []models.PaymentMethod{}
+	for rows.Next() {
+		m, err := scanPaymentMethod(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan payment method: %v", err)
+		}
+		methods = append(methods, *m)
+	}
+
+	return methods, rows.Err()
+}
@@ -0,0 +1,304 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"gastroshop-api/internal/models"
+
+	"github.com/lib/pq"
+)
+
+// PriceRepository stores the price history of products and their scheduled
+// sales
+type PriceRepository struct {
+	db *sql.DB
+}
+
+func NewPriceRepository(db *sql.DB) *PriceRepository {
+	return &PriceRepository{db: db}
+}
+
+// GetPriceHistory returns the prices of a product and its variants, newest first
+func (r *PriceRepository) GetPriceHistory(productID int) ([]models.PriceChange, error) {
+	query := `
+		SELECT id, product_id, variant_id, price_cents, previous_price_cents, reason, created_at
+		FROM price_history
+		WHERE product_id = $1
+		ORDER BY created_at DESC, id DESC
+	`
+	rows, err := r.db.Query(query, productID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	history := []models.PriceChange{}
+	for rows.Next() {
+		var c models.PriceChange
+		err := rows.Scan(&c.ID, &c.ProductID, &c.VariantID, &c.PriceCents, &c.PreviousPriceCents, &c.Reason, &c.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		history = append(history, c)
+	}
+
+	return history, rows.Err()
+}
+
+const saleColumns = `id, product_id, sale_price_cents, regular_price_cents, starts_at, ends_at, status, created_at`
+
+func scanSale(row rowScanner) (*models.ProductSale, error) {
+	var s models.ProductSale
+	err := row.Scan(&s.ID, &s.ProductID, &s.SalePriceCents, &s.RegularPriceCents, &s.StartsAt, &s.EndsAt, &s.Status, &s.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+func (r *PriceRepository) querySales(query string, args ...interface{}) ([]models.ProductSale, error) {
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	sales := []models.ProductSale{}
+	for rows.Next() {
+		s, err := scanSale(rows)
+		if err != nil {
+			return nil, err
+		}
+		sales = append(sales, *s)
+	}
+
+	return sales, rows.Err()
+}
+
+func (r *PriceRepository) GetSaleByID(id int) (*models.ProductSale, error) {
+	s, err := scanSale(r.db.QueryRow(`SELECT `+saleColumns+` FROM product_sales WHERE id = $1`, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// GetSalesByProductID returns the sales of a product, latest start first
+func (r *PriceRepository) GetSalesByProductID(productID int) ([]models.ProductSale, error) {
+	return r.querySales(`SELECT `+saleColumns+` FROM product_sales WHERE product_id = $1 ORDER BY starts_at DESC`, productID)
+}
+
+// GetSalesToStart returns scheduled sales whose start has come
+func (r *PriceRepository) GetSalesToStart(now time.Time) ([]models.ProductSale, error) {
+	return r.querySales(`SELECT `+saleColumns+` FROM product_sales WHERE status = 'scheduled' AND starts_at <= $1 ORDER BY starts_at`, now)
+}
+
+// GetSalesToEnd returns active sales whose end has come
+func (r *PriceRepository) GetSalesToEnd(now time.Time) ([]models.ProductSale, error) {
+	return r.querySales(`SELECT `+saleColumns+` FROM product_sales WHERE status = 'active' AND ends_at <= $1 ORDER BY ends_at`, now)
+}
+
+// HasOverlappingSale reports whether a scheduled or active sale of the
+// product overlaps the period
+func (r *PriceRepository) HasOverlappingSale(productID int, startsAt, endsAt time.Time) (bool, error) {
+	query := `
+		SELECT EXISTS (
+			SELECT 1 FROM product_sales
+			WHERE product_id = $1 AND status IN ('scheduled', 'active')
+			  AND starts_at < $3 AND ends_at > $2
+		)
+	`
+	var exists bool
+	err := r.db.QueryRow(query, productID, startsAt, endsAt).Scan(&exists)
+	return exists, err
+}
+
+func (r *PriceRepository) CreateSale(sale *models.ProductSale) error {
+	query := `
+		INSERT INTO product_sales (product_id, sale_price_cents, starts_at, ends_at, status)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at
+	`
+	return r.db.QueryRow(query, sale.ProductID, sale.SalePriceCents, sale.StartsAt, sale.EndsAt, sale.Status).
+		Scan(&sale.ID, &sale.CreatedAt)
+}
+
+// SetSaleStatus changes the status of a sale that doesn't affect the
+// product price, e.g. when a scheduled sale is canceled
+func (r *PriceRepository) SetSaleStatus(id int, status string) error {
+	_, err := r.db.Exec(`UPDATE product_sales SET status = $1 WHERE id = $2`, status, id)
+	return err
+}
+
+// ActivateSale claims a scheduled sale and sets the product price to the
+// sale price, keeping the current price as the regular price shown struck
+// through. A sale that is no longer scheduled is left as it is, and a sale
+// whose price isn't below the current price any more is canceled; the sale
+// status tells which happened.
+func (r *PriceRepository) ActivateSale(sale *models.ProductSale) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(`UPDATE product_sales SET status = 'active' WHERE id = $1 AND status = 'scheduled'`, sale.ID)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return nil
+	}
+
+	var regularPrice int
+	err = tx.QueryRow(`SELECT price_cents FROM products WHERE id = $1 FOR UPDATE`, sale.ProductID).Scan(&regularPrice)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("product with id %d not found", sale.ProductID)
+	}
+	if err != nil {
+		return err
+	}
+
+	if sale.SalePriceCents >= regularPrice {
+		if _, err := tx.Exec(`UPDATE product_sales SET status = 'canceled' WHERE id = $1`, sale.ID); err != nil {
+			return err
+		}
+		if err := tx.Commit(); err != nil {
+			return err
+		}
+		sale.Status = models.SaleStatusCanceled
+		return nil
+	}
+
+	_, err = tx.Exec(`UPDATE products SET price_cents = $1, compare_at_price_cents = $2 WHERE id = $3`,
+		sale.SalePriceCents, regularPrice, sale.ProductID)
+	if err != nil {
+		return err
+	}
+	if err := recordPriceChange(tx, sale.ProductID, sale.SalePriceCents, regularPrice, models.PriceChangeSaleStart); err != nil {
+		return err
+	}
+	if err := syncBundles(tx, sale.ProductID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`UPDATE product_sales SET regular_price_cents = $1 WHERE id = $2`, regularPrice, sale.ID); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	sale.Status = models.SaleStatusActive
+	sale.RegularPriceCents = &regularPrice
+	return nil
+}
+
+// EndSale returns the product of an active sale to its regular price and
+// sets the sale status, ended or canceled
+func (r *PriceRepository) EndSale(sale *models.ProductSale, status string) error {
+	if sale.RegularPriceCents == nil {
+		return fmt.Errorf("sale with id %d is not active", sale.ID)
+	}
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := endSale(tx, sale, status); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	sale.Status = status
+	return nil
+}
+
+func endSale(db execQueryer, sale *models.ProductSale, status string) error {
+	var salePrice int
+	err := db.QueryRow(`SELECT price_cents FROM products WHERE id = $1 FOR UPDATE`, sale.ProductID).Scan(&salePrice)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("product with id %d not found", sale.ProductID)
+	}
+	if err != nil {
+		return err
+	}
+
+	_, err = db.Exec(`UPDATE products SET price_cents = $1, compare_at_price_cents = NULL WHERE id = $2`,
+		*sale.RegularPriceCents, sale.ProductID)
+	if err != nil {
+		return err
+	}
+	if err := recordPriceChange(db, sale.ProductID, *sale.RegularPriceCents, salePrice, models.PriceChangeSaleEnd); err != nil {
+		return err
+	}
+	if err := syncBundles(db, sale.ProductID); err != nil {
+		return err
+	}
+	_, err = db.Exec(`UPDATE product_sales SET status = $1 WHERE id = $2`, status, sale.ID)
+	return err
+}
+
+// endBatchSales ends the active sales started to clear batches, once one of
+// the batches has no stock left
+func endBatchSales(db execQueryer, batches []models.BatchAllocation) error {
+	batchIDs := make([]int, len(batches))
+	for i, b := range batches {
+		batchIDs[i] = b.BatchID
+	}
+
+	query := `
+		SELECT ` + saleColumns + `
+		FROM product_sales
+		WHERE status = 'active'
+		  AND id IN (SELECT discount_sale_id FROM stock_batches WHERE id = ANY($1) AND quantity = 0)
+		ORDER BY id
+	`
+	rows, err := db.Query(query, pq.Array(batchIDs))
+	if err != nil {
+		return err
+	}
+	var sales []models.ProductSale
+	for rows.Next() {
+		sale, err := scanSale(rows)
+		if err != nil {
+			rows.Close()
+			return err
+		}
+		sales = append(sales, *sale)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for i := range sales {
+		if sales[i].RegularPriceCents == nil {
+			continue
+		}
+		if err := endSale(db, &sales[i], models.SaleStatusEnded); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func recordPriceChange(db execQueryer, productID, priceCents, previousPriceCents int, reason string) error {
+	query := `
+		INSERT INTO price_history (product_id, price_cents, previous_price_cents, reason)
+		VALUES ($1, $2, $3, $4)
+	`
+	_, err := db.Exec(query, productID, priceCents, previousPriceCents, reason)
+	return err
+}
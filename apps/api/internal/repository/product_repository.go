@@ -2,7 +2,9 @@ package repository
 
 import (
 	"database/sql"
+	"encoding/json"
 	"fmt"
+	"strconv"
 	"strings"
 
 	"gastroshop-api/internal/models"
@@ -18,116 +20,133 @@ func NewProductRepository(db *sql.DB) *ProductRepository {
 	return &ProductRepository{db: db}
 }
 
-func (r *ProductRepository) GetProducts(filters map[string]interface{}) ([]models.Product, error) {
-	fmt.Printf("DEBUG: GetProducts called with filters: %+v\n", filters)
-	fmt.Printf("DEBUG: Database connection: %+v\n", r.db)
+// productColumns is the select list for products, matching scanProduct
+const productColumns = `id, slug, title, description, price_cents, currency, tags, region_code, images, in_stock, quantity,
+		tax_category, unit, min_quantity, quantity_step, dietary_flags, created_at, archived_at, compare_at_price_cents, translations`
 
-	query := `
-		SELECT id, slug, title, description, price_cents, currency, tags, region_code, images, in_stock, quantity, created_at
-		FROM products
-		WHERE 1=1
-	`
-	args := []interface{}{}
-	argIndex := 1
+// scanProduct scans productColumns followed by any extra selected columns
+func scanProduct(row rowScanner, extra ...interface{}) (*models.Product, error) {
+	var p models.Product
+	var translationsJSON []byte
+	dest := []interface{}{
+		&p.ID, &p.Slug, &p.Title, &p.Description, &p.PriceCents, &p.Currency,
+		pq.Array(&p.Tags), &p.RegionCode, pq.Array(&p.Images), &p.InStock, &p.Quantity,
+		&p.TaxCategory, &p.Unit, &p.MinQuantity, &p.QuantityStep, pq.Array(&p.DietaryFlags), &p.CreatedAt, &p.ArchivedAt,
+		&p.CompareAtPriceCents, &translationsJSON,
+	}
+	err := row.Scan(append(dest, extra...)...)
+	if err != nil {
+		return nil, err
+	}
+	if p.Translations, err = scanTranslations(translationsJSON); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
 
-	// Add filters
-	if queryStr, ok := filters["query"].(string); ok && queryStr != "" {
-		query += fmt.Sprintf(" AND (title ILIKE $%d OR description ILIKE $%d)", argIndex, argIndex)
-		args = append(args, "%"+queryStr+"%")
-		argIndex++
+// scanTranslations decodes a translations column, nil when there are none
+func scanTranslations(data []byte) (map[string]models.Translation, error) {
+	var translations map[string]models.Translation
+	if err := json.Unmarshal(data, &translations); err != nil {
+		return nil, err
+	}
+	if len(translations) == 0 {
+		return nil, nil
 	}
+	return translations, nil
+}
 
-	// Обработка фильтрации по категориям и тегам
-	categoryTagMap := map[string][]string{
-		"cheese":      {"cheese"},
-		"deli":        {"ham", "cured"},
-		"soft-cheese": {"cheese", "soft"},
-		"hard-cheese": {"cheese", "hard"},
-		"blue-cheese": {"cheese", "blue"},
-		"aged-cheese": {"cheese", "aged"},
-	}
-
-	if categories, hasCategories := filters["categories"].([]string); hasCategories && len(categories) > 0 {
-		// Фильтрация по категориям - каждая категория требует все свои теги
-		var categoryConditions []string
-		for _, cat := range categories {
-			if catTags, exists := categoryTagMap[cat]; exists && len(catTags) > 0 {
-				var catPlaceholders []string
-				for _, tag := range catTags {
-					catPlaceholders = append(catPlaceholders, fmt.Sprintf("$%d", argIndex))
-					args = append(args, tag)
-					argIndex++
-				}
-				// Продукт должен содержать все теги категории (используем @> для проверки "содержит все")
-				catCondition := fmt.Sprintf("tags @> ARRAY[%s]", strings.Join(catPlaceholders, ","))
-				categoryConditions = append(categoryConditions, catCondition)
-			}
-		}
+func translationsJSON(translations map[string]models.Translation) ([]byte, error) {
+	if translations == nil {
+		translations = map[string]models.Translation{}
+	}
+	return json.Marshal(translations)
+}
 
-		if len(categoryConditions) > 0 {
-			// Категории объединяются через OR - продукт может соответствовать любой категории
-			query += fmt.Sprintf(" AND (%s)", strings.Join(categoryConditions, " OR "))
-		}
+func (r *ProductRepository) GetProducts(filters map[string]interface{}) ([]models.Product, error) {
+	page, err := r.GetProductPage(filters)
+	if err != nil {
+		return nil, err
 	}
+	return page.Products, nil
+}
 
-	// Обычная фильтрация по тегам (если есть теги, не относящиеся к категориям)
-	if tags, ok := filters["tags"].([]string); ok && len(tags) > 0 {
-		// Исключаем теги, которые уже использованы в категориях
-		var regularTags []string
-		if categories, hasCategories := filters["categories"].([]string); hasCategories {
-			usedInCategories := make(map[string]bool)
-			for _, cat := range categories {
-				if catTags, exists := categoryTagMap[cat]; exists {
-					for _, tag := range catTags {
-						usedInCategories[tag] = true
-					}
-				}
-			}
-			for _, tag := range tags {
-				if !usedInCategories[tag] {
-					regularTags = append(regularTags, tag)
-				}
-			}
-		} else {
-			regularTags = tags
-		}
+// productSort is a sort mode of the product list: a single key expression,
+// its direction and the type to cast cursor values to
+type productSort struct {
+	key  string
+	desc bool
+	cast string
+}
 
-		if len(regularTags) > 0 {
-			placeholders := make([]string, len(regularTags))
-			for i, tag := range regularTags {
-				placeholders[i] = fmt.Sprintf("$%d", argIndex)
-				args = append(args, tag)
-				argIndex++
-			}
-			query += fmt.Sprintf(" AND tags && ARRAY[%s]", strings.Join(placeholders, ","))
+var productSorts = map[string]productSort{
+	models.ProductSortNewest:     {key: "products.created_at", desc: true, cast: "timestamp"},
+	models.ProductSortPriceAsc:   {key: "products.price_cents", desc: false, cast: "integer"},
+	models.ProductSortPriceDesc:  {key: "products.price_cents", desc: true, cast: "integer"},
+	models.ProductSortPopularity: {key: "COALESCE(pr.popularity, 0)", desc: true, cast: "bigint"},
+	models.ProductSortRating:     {key: "COALESCE(pr.rating, 0)", desc: true, cast: "numeric"},
+	models.ProductSortRelevance:  {key: searchRankExpr, desc: true, cast: "float8"}, // formatted with the search argument
+}
+
+// GetProductPage returns a page of the filtered products. Pages are selected
+// by filters["cursor"] (keyset) or by page and page_size (offset).
+func (r *ProductRepository) GetProductPage(filters map[string]interface{}) (*models.ProductPage, error) {
+	where, args := productFilterClause(filters)
+	argIndex := len(args) + 1
+
+	queryStr, _ := filters["query"].(string)
+	searching := queryStr != ""
+
+	sortName, _ := filters["sort"].(string)
+	if sortName == "" || (sortName == models.ProductSortRelevance && !searching) {
+		sortName = models.ProductSortNewest
+		if searching {
+			sortName = models.ProductSortRelevance
 		}
 	}
-
-	if region, ok := filters["region"].(string); ok && region != "" {
-		query += fmt.Sprintf(" AND region_code = $%d", argIndex)
-		args = append(args, region)
-		argIndex++
+	sort, ok := productSorts[sortName]
+	if !ok {
+		return nil, fmt.Errorf("unknown sort %q", sortName)
 	}
 
-	if priceMin, ok := filters["price_min"].(int); ok {
-		query += fmt.Sprintf(" AND price_cents >= $%d", argIndex)
-		args = append(args, priceMin)
-		argIndex++
+	// Search results carry highlighted fragments
+	selectList := productColumns
+	outerList := productColumns
+	if searching {
+		selectList += fmt.Sprintf(`,
+		ts_headline('russian', title, websearch_to_tsquery('russian', $%[1]d), '%[2]s, HighlightAll=true') AS highlight_title,
+		ts_headline('russian', COALESCE(description, ''), websearch_to_tsquery('russian', $%[1]d), '%[2]s, MaxWords=30, MinWords=10') AS highlight_description`,
+			argIndex, searchHeadlineOptions)
+		outerList += ", highlight_title, highlight_description"
 	}
-
-	if priceMax, ok := filters["price_max"].(int); ok {
-		query += fmt.Sprintf(" AND price_cents <= $%d", argIndex)
-		args = append(args, priceMax)
+	sortKey := sort.key
+	if sortName == models.ProductSortRelevance {
+		sortKey = fmt.Sprintf(searchRankExpr, argIndex)
+	}
+	if searching {
+		args = append(args, queryStr)
 		argIndex++
 	}
 
-	if inStock, ok := filters["in_stock"].(bool); ok {
-		query += fmt.Sprintf(" AND in_stock = $%d", argIndex)
-		args = append(args, inStock)
-		argIndex++
+	// The window count is taken before the keyset condition and the limit,
+	// so it is the total of all matching products
+	query := `
+		SELECT ` + outerList + `, sort_key::text, total
+		FROM (
+			SELECT ` + selectList + `,
+				` + sortKey + ` AS sort_key,
+				COUNT(*) OVER () AS total
+			FROM products
+			LEFT JOIN product_rankings pr ON pr.product_id = products.id
+			WHERE 1=1` + where + `
+		) p
+		WHERE 1=1`
+
+	direction, comparison := "ASC", ">"
+	if sort.desc {
+		direction, comparison = "DESC", "<"
 	}
 
-	// Add pagination
 	page := 1
 	pageSize := 20
 	if p, ok := filters["page"].(int); ok && p > 0 {
@@ -136,51 +155,75 @@ func (r *ProductRepository) GetProducts(filters map[string]interface{}) ([]model
 	if ps, ok := filters["page_size"].(int); ok && ps > 0 {
 		pageSize = ps
 	}
-
 	offset := (page - 1) * pageSize
-	query += fmt.Sprintf(" ORDER BY created_at DESC LIMIT $%d OFFSET $%d", argIndex, argIndex+1)
-	args = append(args, pageSize, offset)
 
-	fmt.Printf("DEBUG: Executing query: %s\n", query)
-	fmt.Printf("DEBUG: With args: %+v\n", args)
+	cursor, _ := filters["cursor"].(*models.ProductCursor)
+	if cursor != nil {
+		query += fmt.Sprintf(" AND (sort_key, id) %s ($%d::%s, $%d)", comparison, argIndex, sort.cast, argIndex+1)
+		args = append(args, cursor.Value, cursor.ID)
+		argIndex += 2
+		offset = 0
+	}
+
+	// One extra row tells whether another page follows
+	query += fmt.Sprintf(" ORDER BY sort_key %[1]s, id %[1]s LIMIT $%[2]d OFFSET $%[3]d", direction, argIndex, argIndex+1)
+	args = append(args, pageSize+1, offset)
 
 	rows, err := r.db.Query(query, args...)
 	if err != nil {
-		fmt.Printf("DEBUG: Query error: %v\n", err)
 		return nil, err
 	}
 	defer rows.Close()
 
-	var products []models.Product
+	result := &models.ProductPage{}
+	var lastSortKey string
 	for rows.Next() {
-		var p models.Product
-		err := rows.Scan(
-			&p.ID, &p.Slug, &p.Title, &p.Description, &p.PriceCents, &p.Currency,
-			pq.Array(&p.Tags), &p.RegionCode, pq.Array(&p.Images), &p.InStock, &p.Quantity, &p.CreatedAt,
-		)
+		var sortValue string
+		extra := []interface{}{}
+		var highlight models.SearchHighlight
+		if searching {
+			extra = append(extra, &highlight.Title, &highlight.Description)
+		}
+		extra = append(extra, &sortValue, &result.Total)
+
+		p, err := scanProduct(rows, extra...)
 		if err != nil {
 			return nil, err
 		}
-		products = append(products, p)
+		if searching {
+			p.Highlight = &highlight
+		}
+
+		if len(result.Products) == pageSize {
+			result.Next = &models.ProductCursor{Sort: sortName, Value: lastSortKey, ID: result.Products[pageSize-1].ID}
+			break
+		}
+		result.Products = append(result.Products, *p)
+		lastSortKey = sortValue
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// Past the last page there is no row to carry the total
+	if len(result.Products) == 0 && (offset > 0 || cursor != nil) {
+		if result.Total, err = r.CountProducts(filters); err != nil {
+			return nil, err
+		}
 	}
 
-	return products, nil
+	return result, nil
 }
 
 func (r *ProductRepository) GetProductBySlug(slug string) (*models.Product, error) {
 	// Try exact match first
 	query := `
-		SELECT id, slug, title, description, price_cents, currency, tags, region_code, images, in_stock, quantity, created_at
+		SELECT ` + productColumns + `
 		FROM products
 		WHERE LOWER(slug) = LOWER($1)
 	`
 
-	var p models.Product
-	err := r.db.QueryRow(query, slug).Scan(
-		&p.ID, &p.Slug, &p.Title, &p.Description, &p.PriceCents, &p.Currency,
-		pq.Array(&p.Tags), &p.RegionCode, pq.Array(&p.Images), &p.InStock, &p.Quantity, &p.CreatedAt,
-	)
-
+	p, err := scanProduct(r.db.QueryRow(query, slug))
 	if err == sql.ErrNoRows {
 		return nil, nil
 	}
@@ -188,14 +231,14 @@ func (r *ProductRepository) GetProductBySlug(slug string) (*models.Product, erro
 		return nil, err
 	}
 
-	return &p, nil
+	return p, nil
 }
 
 func (r *ProductRepository) GetProductsByRegion(regionCode string) ([]models.Product, error) {
 	query := `
-		SELECT id, slug, title, description, price_cents, currency, tags, region_code, images, in_stock, quantity, created_at
+		SELECT ` + productColumns + `
 		FROM products
-		WHERE region_code = $1 AND in_stock = true
+		WHERE region_code = $1 AND in_stock = true AND archived_at IS NULL
 		ORDER BY created_at DESC
 	`
 
@@ -207,15 +250,11 @@ func (r *ProductRepository) GetProductsByRegion(regionCode string) ([]models.Pro
 
 	var products []models.Product
 	for rows.Next() {
-		var p models.Product
-		err := rows.Scan(
-			&p.ID, &p.Slug, &p.Title, &p.Description, &p.PriceCents, &p.Currency,
-			pq.Array(&p.Tags), &p.RegionCode, pq.Array(&p.Images), &p.InStock, &p.Quantity, &p.CreatedAt,
-		)
+		p, err := scanProduct(rows)
 		if err != nil {
 			return nil, err
 		}
-		products = append(products, p)
+		products = append(products, *p)
 	}
 
 	return products, nil
@@ -234,9 +273,9 @@ func (r *ProductRepository) GetProductsByTags(tags []string) ([]models.Product,
 	}
 
 	query := fmt.Sprintf(`
-		SELECT id, slug, title, description, price_cents, currency, tags, region_code, images, in_stock, quantity, created_at
+		SELECT `+productColumns+`
 		FROM products
-		WHERE tags && ARRAY[%s] AND in_stock = true
+		WHERE tags && ARRAY[%s] AND in_stock = true AND archived_at IS NULL
 		ORDER BY created_at DESC
 	`, strings.Join(placeholders, ","))
 
@@ -248,15 +287,11 @@ func (r *ProductRepository) GetProductsByTags(tags []string) ([]models.Product,
 
 	var products []models.Product
 	for rows.Next() {
-		var p models.Product
-		err := rows.Scan(
-			&p.ID, &p.Slug, &p.Title, &p.Description, &p.PriceCents, &p.Currency,
-			pq.Array(&p.Tags), &p.RegionCode, pq.Array(&p.Images), &p.InStock, &p.Quantity, &p.CreatedAt,
-		)
+		p, err := scanProduct(rows)
 		if err != nil {
 			return nil, err
 		}
-		products = append(products, p)
+		products = append(products, *p)
 	}
 
 	return products, nil
@@ -281,9 +316,9 @@ func (r *ProductRepository) GetProductsByTagsRandom(tags []string, limit int, ex
 
 	argIndex := len(tags) + 1
 	query := fmt.Sprintf(`
-		SELECT id, slug, title, description, price_cents, currency, tags, region_code, images, in_stock, quantity, created_at
+		SELECT `+productColumns+`
 		FROM products
-		WHERE tags && ARRAY[%s] AND in_stock = true
+		WHERE tags && ARRAY[%s] AND in_stock = true AND archived_at IS NULL
 	`, strings.Join(placeholders, ","))
 
 	// Add exclusion of product IDs if provided
@@ -309,135 +344,330 @@ func (r *ProductRepository) GetProductsByTagsRandom(tags []string, limit int, ex
 
 	var products []models.Product
 	for rows.Next() {
-		var p models.Product
-		err := rows.Scan(
-			&p.ID, &p.Slug, &p.Title, &p.Description, &p.PriceCents, &p.Currency,
-			pq.Array(&p.Tags), &p.RegionCode, pq.Array(&p.Images), &p.InStock, &p.Quantity, &p.CreatedAt,
-		)
+		p, err := scanProduct(rows)
 		if err != nil {
 			return nil, err
 		}
-		products = append(products, p)
+		products = append(products, *p)
 	}
 
 	return products, nil
 }
 
 func (r *ProductRepository) CountProducts(filters map[string]interface{}) (int, error) {
-	query := "SELECT COUNT(*) FROM products WHERE 1=1"
+	where, args := productFilterClause(filters)
+	query := "SELECT COUNT(*) FROM products WHERE 1=1" + where
+
+	var count int
+	err := r.db.QueryRow(query, args...).Scan(&count)
+	return count, err
+}
+
+// RefreshProductRankings recomputes the popularity and rating used for sorting
+func (r *ProductRepository) RefreshProductRankings() error {
+	_, err := r.db.Exec(`REFRESH MATERIALIZED VIEW CONCURRENTLY product_rankings`)
+	return err
+}
+
+// Facets
+
+// CountProductsByTag counts the filtered products per tag
+func (r *ProductRepository) CountProductsByTag(filters map[string]interface{}) ([]models.FacetCount, error) {
+	where, args := productFilterClause(filters)
+	query := `
+		SELECT tag, '', COUNT(*)
+		FROM (SELECT tags FROM products WHERE 1=1` + where + `) p, unnest(p.tags) AS tag
+		GROUP BY tag
+		ORDER BY COUNT(*) DESC, tag
+	`
+	return r.queryFacetCounts(query, args)
+}
+
+// CountProductsByRegion counts the filtered products per region
+func (r *ProductRepository) CountProductsByRegion(filters map[string]interface{}) ([]models.FacetCount, error) {
+	where, args := productFilterClause(filters)
+	query := `
+		SELECT p.region_code, COALESCE(rg.name, ''), COUNT(*)
+		FROM (SELECT region_code FROM products WHERE region_code IS NOT NULL` + where + `) p
+		LEFT JOIN regions rg ON rg.code = p.region_code
+		GROUP BY p.region_code, rg.name
+		ORDER BY COUNT(*) DESC, p.region_code
+	`
+	return r.queryFacetCounts(query, args)
+}
+
+// CountProductsByCategory counts the filtered products per category. Like
+// the category filter, a category counts the products of its subcategories.
+func (r *ProductRepository) CountProductsByCategory(filters map[string]interface{}) ([]models.FacetCount, error) {
+	where, args := productFilterClause(filters)
+	query := `
+		WITH RECURSIVE category_paths AS (
+			SELECT id, id AS ancestor_id FROM categories
+			UNION
+			SELECT c.id, cp.ancestor_id FROM categories c JOIN category_paths cp ON c.parent_id = cp.id
+		)
+		SELECT cat.slug, cat.name, COUNT(DISTINCT pc.product_id)
+		FROM category_paths cp
+		JOIN product_categories pc ON pc.category_id = cp.id
+		JOIN categories cat ON cat.id = cp.ancestor_id
+		WHERE pc.product_id IN (SELECT id FROM products WHERE 1=1` + where + `)
+		GROUP BY cat.id, cat.slug, cat.name, cat.sort_order
+		ORDER BY cat.sort_order, cat.name
+	`
+	return r.queryFacetCounts(query, args)
+}
+
+// CountProductsByPriceBucket counts the filtered products per price bucket.
+// Bucket i holds prices from bounds[i-1] up to bounds[i]; bucket 0 is below
+// the first bound and bucket len(bounds) is from the last bound up.
+func (r *ProductRepository) CountProductsByPriceBucket(filters map[string]interface{}, bounds []int) (map[int]int, error) {
+	where, args := productFilterClause(filters)
+	query := fmt.Sprintf(`
+		SELECT width_bucket(price_cents, $%d::int[]), COUNT(*)
+		FROM products
+		WHERE 1=1`+where+`
+		GROUP BY 1
+	`, len(args)+1)
+	args = append(args, pq.Array(bounds))
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := make(map[int]int)
+	for rows.Next() {
+		var bucket, count int
+		if err := rows.Scan(&bucket, &count); err != nil {
+			return nil, err
+		}
+		counts[bucket] = count
+	}
+
+	return counts, rows.Err()
+}
+
+// CountProductsByStock counts the filtered products in and out of stock, in
+// the delivery zone when filters["zone"] is set
+func (r *ProductRepository) CountProductsByStock(filters map[string]interface{}) (int, int, error) {
+	where, args := productFilterClause(filters)
+	stocked := "in_stock"
+	if zone, _ := filters["zone"].(string); zone != "" {
+		args = append(args, zone)
+		stocked = "(in_stock AND " + zoneStock(len(args)) + " > 0)"
+	}
+	query := `
+		SELECT COUNT(*) FILTER (WHERE ` + stocked + `), COUNT(*) FILTER (WHERE NOT ` + stocked + `)
+		FROM products
+		WHERE 1=1` + where
+
+	var inStock, outOfStock int
+	err := r.db.QueryRow(query, args...).Scan(&inStock, &outOfStock)
+	return inStock, outOfStock, err
+}
+
+// queryFacetCounts runs a facet query selecting a value, a label and a count
+func (r *ProductRepository) queryFacetCounts(query string, args []interface{}) ([]models.FacetCount, error) {
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := []models.FacetCount{}
+	for rows.Next() {
+		var fc models.FacetCount
+		if err := rows.Scan(&fc.Value, &fc.Label, &fc.Count); err != nil {
+			return nil, err
+		}
+		counts = append(counts, fc)
+	}
+
+	return counts, rows.Err()
+}
+
+// searchRankExpr scores search results: full-text matches score above 1 by
+// ts_rank_cd, which weighs title over tags over description, so they come
+// before typo matches, which score by trigram word similarity of the title
+const searchRankExpr = `(CASE
+				WHEN search_vector @@ websearch_to_tsquery('russian', $%[1]d)
+				THEN 1 + ts_rank_cd(search_vector, websearch_to_tsquery('russian', $%[1]d))
+				ELSE word_similarity($%[1]d, title)
+			END)::float8`
+
+// searchHeadlineOptions marks matched words in search snippets
+const searchHeadlineOptions = `StartSel=<mark>, StopSel=</mark>`
+
+// productFilterClause builds the AND conditions shared by GetProducts and
+// CountProducts. Placeholders are numbered from $1 in the order of args.
+func productFilterClause(filters map[string]interface{}) (string, []interface{}) {
+	var clause strings.Builder
 	args := []interface{}{}
 	argIndex := 1
 
-	// Add same filters as GetProducts
+	// Archived products are listed only when asked for
+	if archived, _ := filters["archived"].(bool); archived {
+		clause.WriteString(" AND archived_at IS NOT NULL")
+	} else {
+		clause.WriteString(" AND archived_at IS NULL")
+	}
+
+	// Полнотекстовый поиск с учётом морфологии; триграммы находят названия с опечатками
 	if queryStr, ok := filters["query"].(string); ok && queryStr != "" {
-		query += fmt.Sprintf(" AND (title ILIKE $%d OR description ILIKE $%d)", argIndex, argIndex)
-		args = append(args, "%"+queryStr+"%")
+		clause.WriteString(fmt.Sprintf(" AND (search_vector @@ websearch_to_tsquery('russian', $%[1]d) OR $%[1]d <%% title)", argIndex))
+		args = append(args, queryStr)
 		argIndex++
 	}
 
-	// Обработка фильтрации по категориям и тегам (та же логика, что в GetProducts)
-	categoryTagMap := map[string][]string{
-		"cheese":      {"cheese"},
-		"deli":        {"ham", "cured"},
-		"soft-cheese": {"cheese", "soft"},
-		"hard-cheese": {"cheese", "hard"},
-		"blue-cheese": {"cheese", "blue"},
-		"aged-cheese": {"cheese", "aged"},
-	}
-
-	if categories, hasCategories := filters["categories"].([]string); hasCategories && len(categories) > 0 {
-		var categoryConditions []string
-		for _, cat := range categories {
-			if catTags, exists := categoryTagMap[cat]; exists && len(catTags) > 0 {
-				var catPlaceholders []string
-				for _, tag := range catTags {
-					catPlaceholders = append(catPlaceholders, fmt.Sprintf("$%d", argIndex))
-					args = append(args, tag)
-					argIndex++
-				}
-				catCondition := fmt.Sprintf("tags @> ARRAY[%s]", strings.Join(catPlaceholders, ","))
-				categoryConditions = append(categoryConditions, catCondition)
-			}
-		}
-		if len(categoryConditions) > 0 {
-			query += fmt.Sprintf(" AND (%s)", strings.Join(categoryConditions, " OR "))
-		}
+	// Категория включает товары всех своих подкатегорий
+	if categories, ok := filters["categories"].([]string); ok && len(categories) > 0 {
+		clause.WriteString(fmt.Sprintf(` AND id IN (
+			SELECT pc.product_id FROM product_categories pc
+			WHERE pc.category_id IN (`+categoryTreeQuery+`)
+		)`, argIndex))
+		args = append(args, pq.Array(categories))
+		argIndex++
 	}
 
-	if tags, ok := filters["tags"].([]string); ok && len(tags) > 0 {
-		var regularTags []string
-		if categories, hasCategories := filters["categories"].([]string); hasCategories {
-			usedInCategories := make(map[string]bool)
-			for _, cat := range categories {
-				if catTags, exists := categoryTagMap[cat]; exists {
-					for _, tag := range catTags {
-						usedInCategories[tag] = true
-					}
+	// Фильтры по характеристикам, например выдержка от 12 месяцев или козье молоко
+	if attributeFilters, ok := filters["attributes"].([]models.AttributeFilter); ok {
+		for _, f := range attributeFilters {
+			var conditions []string
+			args = append(args, f.AttributeID)
+			attributeArg := argIndex
+			argIndex++
+
+			switch f.Type {
+			case models.AttributeTypeEnum:
+				conditions = append(conditions, fmt.Sprintf("text_value = ANY($%d)", argIndex))
+				args = append(args, pq.Array(f.Values))
+				argIndex++
+			case models.AttributeTypeText:
+				conditions = append(conditions, fmt.Sprintf("LOWER(text_value) = ANY($%d)", argIndex))
+				lowered := make([]string, len(f.Values))
+				for i, v := range f.Values {
+					lowered[i] = strings.ToLower(v)
 				}
-			}
-			for _, tag := range tags {
-				if !usedInCategories[tag] {
-					regularTags = append(regularTags, tag)
+				args = append(args, pq.Array(lowered))
+				argIndex++
+			case models.AttributeTypeBoolean:
+				if f.Bool != nil {
+					conditions = append(conditions, fmt.Sprintf("bool_value = $%d", argIndex))
+					args = append(args, *f.Bool)
+					argIndex++
+				}
+			case models.AttributeTypeNumber:
+				if len(f.Numbers) > 0 {
+					conditions = append(conditions, fmt.Sprintf("number_value = ANY($%d::numeric[])", argIndex))
+					args = append(args, pq.Array(f.Numbers))
+					argIndex++
+				}
+				if f.Min != nil {
+					conditions = append(conditions, fmt.Sprintf("number_value >= $%d", argIndex))
+					args = append(args, *f.Min)
+					argIndex++
+				}
+				if f.Max != nil {
+					conditions = append(conditions, fmt.Sprintf("number_value <= $%d", argIndex))
+					args = append(args, *f.Max)
+					argIndex++
 				}
 			}
-		} else {
-			regularTags = tags
-		}
 
-		if len(regularTags) > 0 {
-			placeholders := make([]string, len(regularTags))
-			for i, tag := range regularTags {
-				placeholders[i] = fmt.Sprintf("$%d", argIndex)
-				args = append(args, tag)
-				argIndex++
+			clause.WriteString(fmt.Sprintf(` AND id IN (
+			SELECT product_id FROM product_attribute_values
+			WHERE attribute_id = $%d`, attributeArg))
+			for _, condition := range conditions {
+				clause.WriteString(" AND " + condition)
 			}
-			query += fmt.Sprintf(" AND tags && ARRAY[%s]", strings.Join(placeholders, ","))
+			clause.WriteString(")")
 		}
 	}
 
+	if tags, ok := filters["tags"].([]string); ok && len(tags) > 0 {
+		clause.WriteString(fmt.Sprintf(" AND tags && $%d", argIndex))
+		args = append(args, pq.Array(tags))
+		argIndex++
+	}
+
 	if region, ok := filters["region"].(string); ok && region != "" {
-		query += fmt.Sprintf(" AND region_code = $%d", argIndex)
+		clause.WriteString(fmt.Sprintf(" AND region_code = $%d", argIndex))
 		args = append(args, region)
 		argIndex++
 	}
 
 	if priceMin, ok := filters["price_min"].(int); ok {
-		query += fmt.Sprintf(" AND price_cents >= $%d", argIndex)
+		clause.WriteString(fmt.Sprintf(" AND price_cents >= $%d", argIndex))
 		args = append(args, priceMin)
 		argIndex++
 	}
 
 	if priceMax, ok := filters["price_max"].(int); ok {
-		query += fmt.Sprintf(" AND price_cents <= $%d", argIndex)
+		clause.WriteString(fmt.Sprintf(" AND price_cents <= $%d", argIndex))
 		args = append(args, priceMax)
 		argIndex++
 	}
 
+	// In a delivery zone only the stock of the locations serving it counts
 	if inStock, ok := filters["in_stock"].(bool); ok {
-		query += fmt.Sprintf(" AND in_stock = $%d", argIndex)
-		args = append(args, inStock)
+		if zone, _ := filters["zone"].(string); zone != "" {
+			clause.WriteString(fmt.Sprintf(" AND (in_stock AND %s > 0) = $%d", zoneStock(argIndex), argIndex+1))
+			args = append(args, zone, inStock)
+			argIndex += 2
+		} else {
+			clause.WriteString(fmt.Sprintf(" AND in_stock = $%d", argIndex))
+			args = append(args, inStock)
+			argIndex++
+		}
+	}
+
+	// Исключения по аллергенам и диете: нужные флаги должны быть у товара, запрещённых быть не должно
+	if required, ok := filters["dietary_required"].([]string); ok && len(required) > 0 {
+		clause.WriteString(fmt.Sprintf(" AND dietary_flags @> $%d", argIndex))
+		args = append(args, pq.Array(required))
 		argIndex++
 	}
 
-	var count int
-	err := r.db.QueryRow(query, args...).Scan(&count)
-	return count, err
+	if forbidden, ok := filters["dietary_forbidden"].([]string); ok && len(forbidden) > 0 {
+		clause.WriteString(fmt.Sprintf(" AND NOT dietary_flags && $%d", argIndex))
+		args = append(args, pq.Array(forbidden))
+		argIndex++
+	}
+
+	return clause.String(), args
 }
 
 // Admin methods
+
+// GetAllProducts returns every product ordered by slug, e.g. for exports
+func (r *ProductRepository) GetAllProducts() ([]models.Product, error) {
+	rows, err := r.db.Query(`SELECT ` + productColumns + ` FROM products ORDER BY slug`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var products []models.Product
+	for rows.Next() {
+		p, err := scanProduct(rows)
+		if err != nil {
+			return nil, err
+		}
+		products = append(products, *p)
+	}
+
+	return products, rows.Err()
+}
+
 func (r *ProductRepository) GetProductByID(id int) (*models.Product, error) {
 	query := `
-		SELECT id, slug, title, description, price_cents, currency, tags, region_code, images, in_stock, quantity, created_at
+		SELECT ` + productColumns + `
 		FROM products
 		WHERE id = $1
 	`
 
-	var p models.Product
-	err := r.db.QueryRow(query, id).Scan(
-		&p.ID, &p.Slug, &p.Title, &p.Description, &p.PriceCents, &p.Currency,
-		pq.Array(&p.Tags), &p.RegionCode, pq.Array(&p.Images), &p.InStock, &p.Quantity, &p.CreatedAt,
-	)
-
+	p, err := scanProduct(r.db.QueryRow(query, id))
 	if err == sql.ErrNoRows {
 		return nil, nil
 	}
@@ -445,14 +675,34 @@ func (r *ProductRepository) GetProductByID(id int) (*models.Product, error) {
 		return nil, err
 	}
 
-	return &p, nil
+	return p, nil
 }
 
-func (r *ProductRepository) CreateProduct(product *models.Product) error {
+// CreateProduct creates a product and starts its price history and, with
+// initial stock, its inventory ledger
+func (r *ProductRepository) CreateProduct(product *models.Product, change models.StockChange) error {
+	translations, err := translationsJSON(product.Translations)
+	if err != nil {
+		return err
+	}
+
 	query := `
-		INSERT INTO products (slug, title, description, price_cents, currency, tags, region_code, images, in_stock, quantity)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
-		RETURNING id, created_at
+		WITH created AS (
+			INSERT INTO products (slug, title, description, price_cents, currency, tags, region_code, images, in_stock, quantity, tax_category,
+				unit, min_quantity, quantity_step, dietary_flags, translations)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, COALESCE($15::TEXT[], '{}'), $21)
+			RETURNING id, price_cents, quantity, created_at
+		), history AS (
+			INSERT INTO price_history (product_id, price_cents, reason)
+			SELECT id, price_cents, 'initial' FROM created
+		), stock AS (
+			INSERT INTO location_stock (location_id, product_id, quantity)
+			SELECT ` + locationOrDefault(20) + `, id, quantity FROM created WHERE quantity > 0
+		), movement AS (
+			INSERT INTO inventory_movements (location_id, product_id, quantity_change, quantity_after, reason, actor_id, reference, note)
+			SELECT ` + locationOrDefault(20) + `, id, quantity, quantity, $16, $17, $18, $19 FROM created WHERE quantity > 0
+		)
+		SELECT id, created_at FROM created
 	`
 	return r.db.QueryRow(
 		query,
@@ -466,17 +716,52 @@ func (r *ProductRepository) CreateProduct(product *models.Product) error {
 		pq.Array(product.Images),
 		product.InStock,
 		product.Quantity,
+		product.TaxCategory,
+		product.Unit,
+		product.MinQuantity,
+		product.QuantityStep,
+		pq.Array(product.DietaryFlags),
+		change.Reason,
+		change.ActorID,
+		change.Reference,
+		change.Note,
+		change.LocationID,
+		translations,
 	).Scan(&product.ID, &product.CreatedAt)
 }
 
-func (r *ProductRepository) UpdateProduct(id int, product *models.Product) error {
+// UpdateProduct updates a product, adding a changed price to its history and
+// a changed quantity to its inventory ledger
+func (r *ProductRepository) UpdateProduct(id int, product *models.Product, change models.StockChange) error {
+	translations, err := translationsJSON(product.Translations)
+	if err != nil {
+		return err
+	}
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
 	query := `
-		UPDATE products
-		SET title = $1, description = $2, price_cents = $3, currency = $4, tags = $5, 
-		    region_code = $6, images = $7, in_stock = $8, quantity = $9
-		WHERE id = $10
+		WITH old AS (
+			SELECT price_cents FROM products WHERE id = $14
+		), updated AS (
+			UPDATE products
+			SET title = $1, description = $2, price_cents = $3, currency = $4, tags = $5,
+			    region_code = $6, images = $7, in_stock = $8, tax_category = $9,
+			    unit = $10, min_quantity = $11, quantity_step = $12, dietary_flags = COALESCE($13::TEXT[], '{}'),
+			    translations = $15
+			WHERE id = $14
+			RETURNING id, price_cents
+		)
+		INSERT INTO price_history (product_id, price_cents, previous_price_cents, reason)
+		SELECT updated.id, updated.price_cents, old.price_cents, 'update'
+		FROM updated, old
+		WHERE updated.price_cents <> old.price_cents
 	`
-	_, err := r.db.Exec(
+	_, err = tx.Exec(
 		query,
 		product.Title,
 		product.Description,
@@ -486,47 +771,86 @@ func (r *ProductRepository) UpdateProduct(id int, product *models.Product) error
 		product.RegionCode,
 		pq.Array(product.Images),
 		product.InStock,
-		product.Quantity,
+		product.TaxCategory,
+		product.Unit,
+		product.MinQuantity,
+		product.QuantityStep,
+		pq.Array(product.DietaryFlags),
 		id,
+		translations,
 	)
-	return err
+	if err != nil {
+		return err
+	}
+
+	// The stock of bundles and products with variants follows their
+	// components and variants
+	var derived bool
+	query = `
+		SELECT EXISTS (SELECT 1 FROM product_bundles WHERE product_id = $1)
+			OR EXISTS (SELECT 1 FROM product_variants WHERE product_id = $1 AND deleted_at IS NULL)
+	`
+	if err := tx.QueryRow(query, id).Scan(&derived); err != nil {
+		return err
+	}
+	if derived {
+		if err := syncBundles(tx, id); err != nil {
+			return err
+		}
+	} else if _, _, err := changeStock(tx, id, nil, stockTotal, product.Quantity, change); err != nil {
+		return err
+	}
+
+	return tx.Commit()
 }
 
-func (r *ProductRepository) UpdateProductQuantity(id int, quantity int) error {
-	query := `UPDATE products SET quantity = $1, in_stock = ($1 > 0) WHERE id = $2`
-	result, err := r.db.Exec(query, quantity, id)
+// UpdateProductQuantity sets the total stock of a product, changing the
+// stock at the location of the change by the difference
+func (r *ProductRepository) UpdateProductQuantity(id int, quantity int, change models.StockChange) error {
+	tx, err := r.db.Begin()
 	if err != nil {
 		return err
 	}
+	defer tx.Rollback()
 
-	rowsAffected, err := result.RowsAffected()
-	if err != nil {
+	if _, _, err := changeStock(tx, id, nil, stockTotal, quantity, change); err != nil {
 		return err
 	}
+	return tx.Commit()
+}
 
-	if rowsAffected == 0 {
-		return fmt.Errorf("product with id %d not found", id)
+// AdjustStock changes the stock of a product, or of its variant when
+// variantID is set, at the location of the change by delta, e.g. taking
+// stock when an order is paid. It returns the movement recorded, nil when
+// the stock didn't change.
+func (r *ProductRepository) AdjustStock(productID int, variantID *int, delta int, change models.StockChange) (*models.InventoryMovement, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, err
 	}
+	defer tx.Rollback()
 
-	return nil
+	movement, _, err := changeStock(tx, productID, variantID, stockAdded, delta, change)
+	if err != nil {
+		return nil, err
+	}
+	return movement, tx.Commit()
 }
 
-func (r *ProductRepository) DecreaseProductQuantity(id int, amount int) error {
-	// Use a subquery to calculate new quantity and update both fields
-	query := `
-		UPDATE products 
-		SET quantity = new_qty,
-		    in_stock = (new_qty > 0)
-		FROM (SELECT GREATEST(0, quantity - $1) as new_qty FROM products WHERE id = $2) AS subq
-		WHERE products.id = $2
-	`
-	_, err := r.db.Exec(query, amount, id)
-	return err
+// ArchiveProduct hides a product from the storefront
+func (r *ProductRepository) ArchiveProduct(id int) error {
+	return r.setProductArchived(id, `archived_at = COALESCE(archived_at, CURRENT_TIMESTAMP)`)
+}
+
+// RestoreProduct returns an archived product to the storefront
+func (r *ProductRepository) RestoreProduct(id int) error {
+	return r.setProductArchived(id, `archived_at = NULL`)
 }
 
-func (r *ProductRepository) DeleteProduct(id int) error {
-	query := `DELETE FROM products WHERE id = $1`
-	result, err := r.db.Exec(query, id)
+// setProductArchived archives or restores a product; bundles with it are
+// unavailable while it is archived
+func (r *ProductRepository) setProductArchived(id int, set string) error {
+	result, err := r.db.Exec(`UPDATE products SET `+set+` WHERE id = $1`, id)
 	if err != nil {
 		return err
 	}
@@ -540,5 +864,750 @@ func (r *ProductRepository) DeleteProduct(id int) error {
 		return fmt.Errorf("product with id %d not found", id)
 	}
 
+	return syncBundles(r.db, id)
+}
+
+// productReferencesQuery counts the orders, reviews, subscriptions,
+// subscription boxes and bundles that refer to a product, and its stock
+// movements, batches and price changes past its own stock keeping and
+// initial price. Receipts, write-offs, corrections and transfers are the
+// product's own and don't count.
+const productReferencesQuery = `
+	SELECT
+		(SELECT COUNT(*) FROM orders WHERE items @> jsonb_build_array(jsonb_build_object('product_id', $1::int))),
+		(SELECT COUNT(*) FROM reviews WHERE product_id = $1),
+		(SELECT COUNT(*) FROM product_subscriptions WHERE product_id = $1),
+		(SELECT COUNT(*) FROM subscription_boxes WHERE items @> jsonb_build_array(jsonb_build_object('product_id', $1::int))),
+		(SELECT COUNT(DISTINCT bundle_id) FROM bundle_components WHERE product_id = $1),
+		(SELECT COUNT(*) FROM inventory_movements WHERE product_id = $1 AND reason NOT IN ` + ownMovementReasons + `),
+		(SELECT COUNT(DISTINCT bm.batch_id) FROM batch_movements bm
+			JOIN inventory_movements m ON m.id = bm.movement_id
+			WHERE m.product_id = $1 AND m.reason NOT IN ` + ownMovementReasons + `),
+		(SELECT COUNT(*) FROM price_history WHERE product_id = $1 AND reason <> 'initial')
+`
+
+// ownMovementReasons are the reasons of the movements a product makes on its
+// own, deleted with it when it's purged
+const ownMovementReasons = `('receipt', 'write_off', 'correction', 'transfer')`
+
+func getProductReferences(db execQueryer, id int) (*models.ProductReferences, error) {
+	var refs models.ProductReferences
+	err := db.QueryRow(productReferencesQuery, id).Scan(
+		&refs.Orders, &refs.Reviews, &refs.Subscriptions, &refs.SubscriptionBoxes, &refs.Bundles,
+		&refs.StockMovements, &refs.Batches, &refs.PriceChanges,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &refs, nil
+}
+
+// PurgeProduct deletes a product with its variants, images, categories, cart
+// lines, batches, own stock movements and initial price, unless something
+// refers to it; the references are returned when there are any. The product
+// row is locked while the references are counted. Orders and subscription
+// boxes, which list products by id, take a share lock on their products
+// when they're saved, and everything else refers to products by foreign
+// key, so nothing can start referring to the product before it's gone.
+func (r *ProductRepository) PurgeProduct(id int) (*models.ProductReferences, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var locked int
+	err = tx.QueryRow(`SELECT id FROM products WHERE id = $1 FOR UPDATE`, id).Scan(&locked)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("product with id %d not found", id)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	refs, err := getProductReferences(tx, id)
+	if err != nil {
+		return nil, err
+	}
+	if *refs != (models.ProductReferences{}) {
+		return refs, nil
+	}
+
+	// The ledger refuses deletes except of the own movements of the product
+	// named for this transaction
+	if _, err := tx.Exec(`SELECT set_config('inventory.purge_product_id', $1, true)`, strconv.Itoa(id)); err != nil {
+		return nil, err
+	}
+	if _, err := tx.Exec(`DELETE FROM inventory_movements WHERE product_id = $1`, id); err != nil {
+		return nil, err
+	}
+	if _, err := tx.Exec(`DELETE FROM price_history WHERE product_id = $1`, id); err != nil {
+		return nil, err
+	}
+	if _, err := tx.Exec(`DELETE FROM products WHERE id = $1`, id); err != nil {
+		return nil, err
+	}
+
+	return nil, tx.Commit()
+}
+
+// Product variants
+
+const variantColumns = `id, product_id, sku, title, weight_grams, volume_ml, price_cents, quantity, in_stock,
+	barcode, sort_order, created_at`
+
+func scanVariant(row rowScanner) (*models.ProductVariant, error) {
+	var v models.ProductVariant
+	var weightGrams, volumeML sql.NullInt64
+	var barcode sql.NullString
+
+	err := row.Scan(
+		&v.ID, &v.ProductID, &v.SKU, &v.Title, &weightGrams, &volumeML, &v.PriceCents, &v.Quantity, &v.InStock,
+		&barcode, &v.SortOrder, &v.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if weightGrams.Valid {
+		w := int(weightGrams.Int64)
+		v.WeightGrams = &w
+	}
+	if volumeML.Valid {
+		ml := int(volumeML.Int64)
+		v.VolumeML = &ml
+	}
+	v.Barcode = barcode.String
+
+	return &v, nil
+}
+
+func (r *ProductRepository) GetVariantByID(id int) (*models.ProductVariant, error) {
+	query := `SELECT ` + variantColumns + ` FROM product_variants WHERE id = $1 AND deleted_at IS NULL`
+
+	v, err := scanVariant(r.db.QueryRow(query, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// GetVariantsByProductIDs returns the variants of the products keyed by product ID
+func (r *ProductRepository) GetVariantsByProductIDs(productIDs []int) (map[int][]models.ProductVariant, error) {
+	variants := make(map[int][]models.ProductVariant)
+	if len(productIDs) == 0 {
+		return variants, nil
+	}
+
+	query := `
+		SELECT ` + variantColumns + `
+		FROM product_variants
+		WHERE product_id = ANY($1) AND deleted_at IS NULL
+		ORDER BY sort_order, price_cents, id
+	`
+	rows, err := r.db.Query(query, pq.Array(productIDs))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		v, err := scanVariant(rows)
+		if err != nil {
+			return nil, err
+		}
+		variants[v.ProductID] = append(variants[v.ProductID], *v)
+	}
+
+	return variants, nil
+}
+
+// CreateVariant adds a variant with its initial stock. The stock of a product
+// with variants is the stock of its variants, so the stock the product had
+// of its own is written off.
+func (r *ProductRepository) CreateVariant(v *models.ProductVariant, change models.StockChange) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var locked int
+	err = tx.QueryRow(`SELECT id FROM products WHERE id = $1 FOR UPDATE`, v.ProductID).Scan(&locked)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("product with id %d not found", v.ProductID)
+	}
+	if err != nil {
+		return err
+	}
+
+	query := `
+		WITH created AS (
+			INSERT INTO product_variants (product_id, sku, title, weight_grams, volume_ml, price_cents, quantity, in_stock,
+				barcode, sort_order)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, NULLIF($9, ''), $10)
+			RETURNING id, product_id, price_cents, quantity, created_at
+		), history AS (
+			INSERT INTO price_history (product_id, variant_id, price_cents, reason)
+			SELECT product_id, id, price_cents, 'initial' FROM created
+		), stock AS (
+			INSERT INTO location_stock (location_id, product_id, variant_id, quantity)
+			SELECT ` + locationOrDefault(15) + `, product_id, id, quantity FROM created WHERE quantity > 0
+		), movement AS (
+			INSERT INTO inventory_movements (location_id, product_id, variant_id, quantity_change, quantity_after, reason, actor_id, reference, note)
+			SELECT ` + locationOrDefault(15) + `, product_id, id, quantity, quantity, $11, $12, $13, $14 FROM created WHERE quantity > 0
+		)
+		SELECT id, created_at FROM created
+	`
+	err = tx.QueryRow(
+		query,
+		v.ProductID,
+		v.SKU,
+		v.Title,
+		v.WeightGrams,
+		v.VolumeML,
+		v.PriceCents,
+		v.Quantity,
+		v.Quantity > 0,
+		v.Barcode,
+		v.SortOrder,
+		change.Reason,
+		change.ActorID,
+		change.Reference,
+		change.Note,
+		change.LocationID,
+	).Scan(&v.ID, &v.CreatedAt)
+	if err != nil {
+		return err
+	}
+
+	rows, err := tx.Query(`SELECT location_id FROM location_stock WHERE product_id = $1 AND variant_id IS NULL AND quantity > 0 ORDER BY location_id`, v.ProductID)
+	if err != nil {
+		return err
+	}
+	var locationIDs []int
+	for rows.Next() {
+		var locationID int
+		if err := rows.Scan(&locationID); err != nil {
+			rows.Close()
+			return err
+		}
+		locationIDs = append(locationIDs, locationID)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	writeOff := models.StockChange{Reason: models.MovementWriteOff, ActorID: change.ActorID, Reference: fmt.Sprintf("variant:%d", v.ID)}
+	for _, locationID := range locationIDs {
+		writeOff.LocationID = locationID
+		if _, _, err := changeStock(tx, v.ProductID, nil, stockAtLocation, 0, writeOff); err != nil {
+			return err
+		}
+	}
+	if _, err := tx.Exec(`DELETE FROM location_stock WHERE product_id = $1 AND variant_id IS NULL`, v.ProductID); err != nil {
+		return err
+	}
+
+	if err := syncProductFromVariants(tx, v.ProductID); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	v.InStock = v.Quantity > 0
+	return nil
+}
+
+// UpdateVariant updates a variant, adding a changed price to its history and
+// a changed quantity to its inventory ledger
+func (r *ProductRepository) UpdateVariant(id int, v *models.ProductVariant, change models.StockChange) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `
+		WITH old AS (
+			SELECT price_cents FROM product_variants WHERE id = $8
+		), updated AS (
+			UPDATE product_variants
+			SET sku = $1, title = $2, weight_grams = $3, volume_ml = $4, price_cents = $5,
+				barcode = NULLIF($6, ''), sort_order = $7, updated_at = NOW()
+			WHERE id = $8 AND deleted_at IS NULL
+			RETURNING id, product_id, price_cents
+		), history AS (
+			INSERT INTO price_history (product_id, variant_id, price_cents, previous_price_cents, reason)
+			SELECT updated.product_id, updated.id, updated.price_cents, old.price_cents, 'update'
+			FROM updated, old
+			WHERE updated.price_cents <> old.price_cents
+		)
+		SELECT product_id FROM updated
+	`
+	var productID int
+	err = tx.QueryRow(
+		query,
+		v.SKU,
+		v.Title,
+		v.WeightGrams,
+		v.VolumeML,
+		v.PriceCents,
+		v.Barcode,
+		v.SortOrder,
+		id,
+	).Scan(&productID)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("variant with id %d not found", id)
+	}
+	if err != nil {
+		return err
+	}
+
+	if _, _, err := changeStock(tx, productID, &id, stockTotal, v.Quantity, change); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	v.InStock = v.Quantity > 0
+	return nil
+}
+
+// DeleteVariant writes off the stock of a variant at every location and
+// deletes it. Deleted variants are kept for the inventory ledger and price
+// history; the cart and wishlist lines and open stock take counts of the
+// variant are removed. A product whose last variant is deleted has its own
+// stock again.
+func (r *ProductRepository) DeleteVariant(id int, change models.StockChange) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var productID int
+	query := `
+		SELECT v.product_id
+		FROM product_variants v
+		JOIN products p ON p.id = v.product_id
+		WHERE v.id = $1 AND v.deleted_at IS NULL
+		FOR UPDATE OF p
+	`
+	err = tx.QueryRow(query, id).Scan(&productID)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("variant with id %d not found", id)
+	}
+	if err != nil {
+		return err
+	}
+
+	rows, err := tx.Query(`SELECT location_id FROM location_stock WHERE variant_id = $1 AND quantity > 0 ORDER BY location_id`, id)
+	if err != nil {
+		return err
+	}
+	var locationIDs []int
+	for rows.Next() {
+		var locationID int
+		if err := rows.Scan(&locationID); err != nil {
+			rows.Close()
+			return err
+		}
+		locationIDs = append(locationIDs, locationID)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, locationID := range locationIDs {
+		change.LocationID = locationID
+		if _, _, err := changeStock(tx, productID, &id, stockAtLocation, 0, change); err != nil {
+			return err
+		}
+	}
+
+	queries := []string{
+		`UPDATE product_variants SET deleted_at = NOW(), quantity = 0, in_stock = false, updated_at = NOW() WHERE id = $1`,
+		`DELETE FROM location_stock WHERE variant_id = $1`,
+		`DELETE FROM cart_items WHERE variant_id = $1`,
+		`DELETE FROM wishlist_items WHERE variant_id = $1`,
+		`UPDATE product_subscriptions SET variant_id = NULL WHERE variant_id = $1`,
+		`DELETE FROM stock_take_counts WHERE variant_id = $1 AND stock_take_id IN (SELECT id FROM stock_takes WHERE status = 'open')`,
+	}
+	for _, q := range queries {
+		if _, err := tx.Exec(q, id); err != nil {
+			return err
+		}
+	}
+
+	// Without variants left, the product stock is its own stock at the
+	// locations again
+	query = `
+		UPDATE products
+		SET quantity = s.quantity, in_stock = (s.quantity > 0)
+		FROM (
+			SELECT COALESCE(SUM(quantity), 0) AS quantity
+			FROM location_stock
+			WHERE product_id = $1 AND variant_id IS NULL
+		) AS s
+		WHERE products.id = $1
+		  AND NOT EXISTS (SELECT 1 FROM product_variants WHERE product_id = $1 AND deleted_at IS NULL)
+	`
+	if _, err := tx.Exec(query, productID); err != nil {
+		return err
+	}
+	if err := syncProductFromVariants(tx, productID); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// syncProductFromVariants sets the product price to its cheapest variant and
+// its stock to the total variant stock, so catalog filters keep working.
+// Products without variants are left unchanged.
+func syncProductFromVariants(db execQueryer, productID int) error {
+	query := `
+		UPDATE products
+		SET price_cents = v.min_price,
+		    quantity = v.total_quantity,
+		    in_stock = (v.total_quantity > 0)
+		FROM (
+			SELECT MIN(price_cents) AS min_price, SUM(quantity) AS total_quantity
+			FROM product_variants
+			WHERE product_id = $1 AND deleted_at IS NULL
+		) AS v
+		WHERE products.id = $1 AND v.min_price IS NOT NULL
+	`
+	if _, err := db.Exec(query, productID); err != nil {
+		return err
+	}
+	return syncBundles(db, productID)
+}
+
+// Product bundles
+
+// syncBundles recomputes the stock of the bundles that are or contain the
+// given products: the number of whole bundles the component stock makes up.
+// Discount bundles are repriced from their component prices, recording price
+// changes in the price history.
+func syncBundles(db execQueryer, productIDs ...int) error {
+	query := `
+		WITH affected AS (
+			SELECT product_id AS bundle_id FROM product_bundles WHERE product_id = ANY($1)
+			UNION
+			SELECT bundle_id FROM bundle_components WHERE product_id = ANY($1)
+		), stock AS (
+			SELECT c.bundle_id,
+				MIN(CASE WHEN p.archived_at IS NOT NULL OR NOT COALESCE(v.in_stock, p.in_stock, false) THEN 0
+					ELSE COALESCE(v.quantity, p.quantity, 0) / c.quantity END) AS quantity,
+				SUM(COALESCE(v.price_cents, p.price_cents) * c.quantity) AS components_price_cents
+			FROM bundle_components c
+			JOIN products p ON p.id = c.product_id
+			LEFT JOIN product_variants v ON v.id = c.variant_id
+			WHERE c.bundle_id IN (SELECT bundle_id FROM affected)
+			GROUP BY c.bundle_id
+		), old AS (
+			SELECT id, price_cents FROM products WHERE id IN (SELECT bundle_id FROM stock)
+		), updated AS (
+			UPDATE products
+			SET quantity = stock.quantity,
+			    in_stock = (stock.quantity > 0),
+			    price_cents = CASE WHEN b.pricing = 'discount'
+			        THEN ROUND(stock.components_price_cents * (100 - b.discount_percent) / 100.0)::INTEGER
+			        ELSE products.price_cents END
+			FROM stock
+			JOIN product_bundles b ON b.product_id = stock.bundle_id
+			WHERE products.id = stock.bundle_id
+			RETURNING products.id, products.price_cents
+		)
+		INSERT INTO price_history (product_id, price_cents, previous_price_cents, reason)
+		SELECT updated.id, updated.price_cents, old.price_cents, 'update'
+		FROM updated
+		JOIN old ON old.id = updated.id
+		WHERE updated.price_cents <> old.price_cents
+	`
+	_, err := db.Exec(query, pq.Array(productIDs))
+	return err
+}
+
+// GetBundlesByProductIDs returns the bundles among the products keyed by
+// product ID, with their components filled in from the catalog
+func (r *ProductRepository) GetBundlesByProductIDs(productIDs []int) (map[int]*models.ProductBundle, error) {
+	bundles := make(map[int]*models.ProductBundle)
+	if len(productIDs) == 0 {
+		return bundles, nil
+	}
+
+	query := `
+		SELECT b.product_id, b.pricing, b.discount_percent, c.product_id, c.variant_id, c.quantity,
+			CASE WHEN v.id IS NULL THEN p.title ELSE p.title || ', ' || v.title END,
+			p.slug, COALESCE(v.price_cents, p.price_cents)
+		FROM product_bundles b
+		JOIN bundle_components c ON c.bundle_id = b.product_id
+		JOIN products p ON p.id = c.product_id
+		LEFT JOIN product_variants v ON v.id = c.variant_id
+		WHERE b.product_id = ANY($1)
+		ORDER BY b.product_id, c.id
+	`
+	rows, err := r.db.Query(query, pq.Array(productIDs))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var bundleID int
+		var pricing string
+		var discountPercent int
+		var c models.BundleComponent
+		err := rows.Scan(&bundleID, &pricing, &discountPercent, &c.ProductID, &c.VariantID, &c.Quantity,
+			&c.Title, &c.Slug, &c.PriceCents)
+		if err != nil {
+			return nil, err
+		}
+
+		bundle, ok := bundles[bundleID]
+		if !ok {
+			bundle = &models.ProductBundle{Pricing: pricing, DiscountPercent: discountPercent}
+			bundles[bundleID] = bundle
+		}
+		bundle.Components = append(bundle.Components, c)
+	}
+
+	return bundles, rows.Err()
+}
+
+// SetBundle makes a product a bundle of the components, replacing any
+// components it had, and syncs its stock and price with them
+func (r *ProductRepository) SetBundle(productID int, bundle *models.ProductBundle) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `
+		INSERT INTO product_bundles (product_id, pricing, discount_percent)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (product_id) DO UPDATE SET pricing = EXCLUDED.pricing, discount_percent = EXCLUDED.discount_percent
+	`
+	if _, err := tx.Exec(query, productID, bundle.Pricing, bundle.DiscountPercent); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`DELETE FROM bundle_components WHERE bundle_id = $1`, productID); err != nil {
+		return err
+	}
+	for _, c := range bundle.Components {
+		_, err := tx.Exec(`INSERT INTO bundle_components (bundle_id, product_id, variant_id, quantity) VALUES ($1, $2, $3, $4)`,
+			productID, c.ProductID, c.VariantID, c.Quantity)
+		if err != nil {
+			return err
+		}
+	}
+	if err := syncBundles(tx, productID); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// DeleteBundle turns a bundle back into a regular product, keeping its last
+// stock and price
+func (r *ProductRepository) DeleteBundle(productID int) error {
+	result, err := r.db.Exec(`DELETE FROM product_bundles WHERE product_id = $1`, productID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("bundle with product id %d not found", productID)
+	}
+
+	return nil
+}
+
+// IsBundleComponent reports whether a product is in any bundle. With a
+// variant ID only that variant of the product counts.
+func (r *ProductRepository) IsBundleComponent(productID int, variantID *int) (bool, error) {
+	query := `
+		SELECT EXISTS (
+			SELECT 1 FROM bundle_components
+			WHERE product_id = $1 AND ($2::INTEGER IS NULL OR variant_id = $2)
+		)
+	`
+	var exists bool
+	err := r.db.QueryRow(query, productID, variantID).Scan(&exists)
+	return exists, err
+}
+
+// Product images
+
+const productImageColumns = `id, product_id, url, storage_keys, content_type, width, height, size_bytes, variants, created_at`
+
+func scanProductImage(row rowScanner) (*models.ProductImage, error) {
+	var img models.ProductImage
+	var variantsJSON []byte
+	err := row.Scan(
+		&img.ID, &img.ProductID, &img.URL, pq.Array(&img.StorageKeys), &img.ContentType, &img.Width, &img.Height,
+		&img.SizeBytes, &variantsJSON, &img.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(variantsJSON, &img.Variants); err != nil {
+		return nil, err
+	}
+	return &img, nil
+}
+
+func (r *ProductRepository) GetProductImageByID(id int) (*models.ProductImage, error) {
+	query := `SELECT ` + productImageColumns + ` FROM product_images WHERE id = $1`
+
+	img, err := scanProductImage(r.db.QueryRow(query, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return img, nil
+}
+
+// GetProductImagesByProductIDs returns the uploaded images of the products
+// keyed by product ID, in the display order of the product images. Images
+// whose URL was removed from the product come last.
+func (r *ProductRepository) GetProductImagesByProductIDs(productIDs []int) (map[int][]models.ProductImage, error) {
+	images := make(map[int][]models.ProductImage)
+	if len(productIDs) == 0 {
+		return images, nil
+	}
+
+	query := `
+		SELECT pi.id, pi.product_id, pi.url, pi.storage_keys, pi.content_type, pi.width, pi.height, pi.size_bytes,
+			pi.variants, pi.created_at
+		FROM product_images pi
+		JOIN products p ON p.id = pi.product_id
+		WHERE pi.product_id = ANY($1)
+		ORDER BY array_position(p.images, pi.url) NULLS LAST, pi.id
+	`
+	rows, err := r.db.Query(query, pq.Array(productIDs))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		img, err := scanProductImage(rows)
+		if err != nil {
+			return nil, err
+		}
+		images[img.ProductID] = append(images[img.ProductID], *img)
+	}
+
+	return images, nil
+}
+
+// CreateProductImage saves an uploaded image and appends its URL to the
+// product images
+func (r *ProductRepository) CreateProductImage(img *models.ProductImage) error {
+	variantsJSON, err := json.Marshal(img.Variants)
+	if err != nil {
+		return err
+	}
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(`UPDATE products SET images = array_append(images, $1) WHERE id = $2`, img.URL, img.ProductID)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("product with id %d not found", img.ProductID)
+	}
+
+	query := `
+		INSERT INTO product_images (product_id, url, storage_keys, content_type, width, height, size_bytes, variants)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING id, created_at
+	`
+	err = tx.QueryRow(
+		query,
+		img.ProductID,
+		img.URL,
+		pq.Array(img.StorageKeys),
+		img.ContentType,
+		img.Width,
+		img.Height,
+		img.SizeBytes,
+		variantsJSON,
+	).Scan(&img.ID, &img.CreatedAt)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// DeleteProductImage deletes an uploaded image and removes its URL from the
+// product images. The stored files are left to the caller.
+func (r *ProductRepository) DeleteProductImage(id int) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var productID int
+	var url string
+	err = tx.QueryRow(`DELETE FROM product_images WHERE id = $1 RETURNING product_id, url`, id).Scan(&productID, &url)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("product image with id %d not found", id)
+	}
+	if err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec(`UPDATE products SET images = array_remove(images, $1) WHERE id = $2`, url, productID); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// SetProductImages replaces the image URLs of a product, e.g. to reorder them
+func (r *ProductRepository) SetProductImages(productID int, urls []string) error {
+	result, err := r.db.Exec(`UPDATE products SET images = $1 WHERE id = $2`, pq.Array(urls), productID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("product with id %d not found", productID)
+	}
+
 	return nil
 }
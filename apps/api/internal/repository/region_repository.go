@@ -2,6 +2,7 @@ package repository
 
 import (
 	"database/sql"
+	"fmt"
 
 	"gastroshop-api/internal/models"
 )
@@ -14,9 +15,24 @@ func NewRegionRepository(db *sql.DB) *RegionRepository {
 	return &RegionRepository{db: db}
 }
 
+const regionColumns = `code, name, geojson_feature, translations`
+
+func scanRegion(row rowScanner) (*models.Region, error) {
+	var region models.Region
+	var translationsJSON []byte
+	if err := row.Scan(&region.Code, &region.Name, &region.GeoJSONFeature, &translationsJSON); err != nil {
+		return nil, err
+	}
+	var err error
+	if region.Translations, err = scanTranslations(translationsJSON); err != nil {
+		return nil, err
+	}
+	return &region, nil
+}
+
 func (r *RegionRepository) GetRegions() ([]models.Region, error) {
 	query := `
-		SELECT code, name, geojson_feature
+		SELECT ` + regionColumns + `
 		FROM regions
 		ORDER BY name
 	`
@@ -29,12 +45,11 @@ func (r *RegionRepository) GetRegions() ([]models.Region, error) {
 
 	var regions []models.Region
 	for rows.Next() {
-		var region models.Region
-		err := rows.Scan(&region.Code, &region.Name, &region.GeoJSONFeature)
+		region, err := scanRegion(rows)
 		if err != nil {
 			return nil, err
 		}
-		regions = append(regions, region)
+		regions = append(regions, *region)
 	}
 
 	return regions, nil
@@ -42,14 +57,12 @@ func (r *RegionRepository) GetRegions() ([]models.Region, error) {
 
 func (r *RegionRepository) GetRegionByCode(code string) (*models.Region, error) {
 	query := `
-		SELECT code, name, geojson_feature
+		SELECT ` + regionColumns + `
 		FROM regions
 		WHERE code = $1
 	`
 
-	var region models.Region
-	err := r.db.QueryRow(query, code).Scan(&region.Code, &region.Name, &region.GeoJSONFeature)
-
+	region, err := scanRegion(r.db.QueryRow(query, code))
 	if err == sql.ErrNoRows {
 		return nil, nil
 	}
@@ -57,5 +70,28 @@ func (r *RegionRepository) GetRegionByCode(code string) (*models.Region, error)
 		return nil, err
 	}
 
-	return &region, nil
+	return region, nil
+}
+
+// UpdateRegion updates the name and translations of a region
+func (r *RegionRepository) UpdateRegion(region *models.Region) error {
+	translations, err := translationsJSON(region.Translations)
+	if err != nil {
+		return err
+	}
+
+	result, err := r.db.Exec(`UPDATE regions SET name = $1, translations = $2 WHERE code = $3`, region.Name, translations, region.Code)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("region with code %s not found", region.Code)
+	}
+
+	return nil
 }
@@ -0,0 +1,415 @@
+package repository
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"gastroshop-api/internal/models"
+)
+
+type SubscriptionRepository struct {
+	db *sql.DB
+}
+
+func NewSubscriptionRepository(db *sql.DB) *SubscriptionRepository {
+	return &SubscriptionRepository{db: db}
+}
+
+const subscriptionColumns = `id, user_id, product_id, variant_id, box_id, quantity, frequency, status, shipping_address,
+	payment_method_id, next_run_at, skip_next, failed_attempts, next_retry_at, last_order_id, created_at`
+
+func scanSubscription(row rowScanner) (*models.ProductSubscription, error) {
+	var sub models.ProductSubscription
+	var productID, variantID, boxID, lastOrderID sql.NullInt64
+	var paymentMethodID sql.NullString
+	var nextRetryAt sql.NullTime
+	var shippingJSON []byte
+
+	err := row.Scan(
+		&sub.ID, &sub.UserID, &productID, &variantID, &boxID, &sub.Quantity, &sub.Frequency, &sub.Status, &shippingJSON,
+		&paymentMethodID, &sub.NextRunAt, &sub.SkipNext, &sub.FailedAttempts, &nextRetryAt, &lastOrderID, &sub.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if productID.Valid {
+		id := int(productID.Int64)
+		sub.ProductID = &id
+	}
+	if variantID.Valid {
+		id := int(variantID.Int64)
+		sub.VariantID = &id
+	}
+	if boxID.Valid {
+		id := int(boxID.Int64)
+		sub.BoxID = &id
+	}
+	if lastOrderID.Valid {
+		id := int(lastOrderID.Int64)
+		sub.LastOrderID = &id
+	}
+	if nextRetryAt.Valid {
+		sub.NextRetryAt = &nextRetryAt.Time
+	}
+	sub.PaymentMethodID = paymentMethodID.String
+	sub.HasPaymentMethod = sub.PaymentMethodID != ""
+
+	if err := json.Unmarshal(shippingJSON, &sub.ShippingAddress); err != nil {
+		return nil, err
+	}
+
+	return &sub, nil
+}
+
+func (r *SubscriptionRepository) CreateSubscription(sub *models.ProductSubscription) error {
+	shippingJSON, err := json.Marshal(sub.ShippingAddress)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO product_subscriptions (user_id, product_id, variant_id, box_id, quantity, frequency, status,
+			shipping_address, payment_method_id, next_run_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, NULLIF($9, ''), $10)
+		RETURNING id, created_at
+	`
+	return r.db.QueryRow(
+		query,
+		sub.UserID,
+		sub.ProductID,
+		sub.VariantID,
+		sub.BoxID,
+		sub.Quantity,
+		sub.Frequency,
+		sub.Status,
+		shippingJSON,
+		sub.PaymentMethodID,
+		sub.NextRunAt,
+	).Scan(&sub.ID, &sub.CreatedAt)
+}
+
+func (r *SubscriptionRepository) GetSubscriptionByID(id int) (*models.ProductSubscription, error) {
+	query := `SELECT ` + subscriptionColumns + ` FROM product_subscriptions WHERE id = $1`
+
+	sub, err := scanSubscription(r.db.QueryRow(query, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return sub, nil
+}
+
+func (r *SubscriptionRepository) GetSubscriptionsByUserID(userID int) ([]models.ProductSubscription, error) {
+	query := `
+		SELECT ` + subscriptionColumns + `
+		FROM product_subscriptions
+		WHERE user_id = $1
+		ORDER BY created_at DESC
+	`
+	return r.querySubscriptions(query, userID)
+}
+
+func (r *SubscriptionRepository) GetAllSubscriptions(status string) ([]models.ProductSubscription, error) {
+	query := `
+		SELECT ` + subscriptionColumns + `
+		FROM product_subscriptions
+		WHERE ($1::text = '' OR status = $1)
+		ORDER BY created_at DESC
+	`
+	return r.querySubscriptions(query, status)
+}
+
+// GetDueSubscriptions returns active subscriptions whose next run has come and
+// past-due subscriptions whose next payment retry has come
+func (r *SubscriptionRepository) GetDueSubscriptions(now time.Time) ([]models.ProductSubscription, error) {
+	query := `
+		SELECT ` + subscriptionColumns + `
+		FROM product_subscriptions
+		WHERE (status = 'active' AND next_run_at <= $1)
+		   OR (status = 'past_due' AND next_retry_at <= $1)
+		ORDER BY next_run_at
+	`
+	return r.querySubscriptions(query, now)
+}
+
+func (r *SubscriptionRepository) querySubscriptions(query string, args ...interface{}) ([]models.ProductSubscription, error) {
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	subs := make([]models.ProductSubscription, 0)
+	for rows.Next() {
+		sub, err := scanSubscription(rows)
+		if err != nil {
+			return nil, err
+		}
+		subs = append(subs, *sub)
+	}
+
+	return subs, nil
+}
+
+// UpdateSubscription saves the customer-editable fields and the schedule
+func (r *SubscriptionRepository) UpdateSubscription(id int, sub *models.ProductSubscription) error {
+	shippingJSON, err := json.Marshal(sub.ShippingAddress)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		UPDATE product_subscriptions
+		SET quantity = $1, frequency = $2, status = $3, shipping_address = $4,
+			payment_method_id = NULLIF($5, ''), next_run_at = $6, skip_next = $7,
+			failed_attempts = $8, next_retry_at = $9, updated_at = NOW()
+		WHERE id = $10
+	`
+	result, err := r.db.Exec(
+		query,
+		sub.Quantity,
+		sub.Frequency,
+		sub.Status,
+		shippingJSON,
+		sub.PaymentMethodID,
+		sub.NextRunAt,
+		sub.SkipNext,
+		sub.FailedAttempts,
+		sub.NextRetryAt,
+		id,
+	)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("subscription with id %d not found", id)
+	}
+
+	return nil
+}
+
+// ClaimRun moves the next run of an active subscription forward and clears
+// skip_next. It returns false when another worker already claimed this run.
+func (r *SubscriptionRepository) ClaimRun(id int, scheduledAt, nextRunAt time.Time) (bool, error) {
+	query := `
+		UPDATE product_subscriptions
+		SET next_run_at = $1, skip_next = false, updated_at = NOW()
+		WHERE id = $2 AND status = 'active' AND next_run_at = $3
+	`
+	return r.execClaim(query, nextRunAt, id, scheduledAt)
+}
+
+// ReleaseRun gives back a run claimed by ClaimRun when its order could not
+// be placed, unless the subscription was rescheduled since
+func (r *SubscriptionRepository) ReleaseRun(id int, nextRunAt, scheduledAt time.Time) error {
+	query := `
+		UPDATE product_subscriptions
+		SET next_run_at = $1, updated_at = NOW()
+		WHERE id = $2 AND next_run_at = $3
+	`
+	_, err := r.db.Exec(query, scheduledAt, id, nextRunAt)
+	return err
+}
+
+// ClaimRetry clears the pending retry of a past-due subscription. It returns
+// false when another worker already claimed this retry.
+func (r *SubscriptionRepository) ClaimRetry(id int, retryAt time.Time) (bool, error) {
+	query := `
+		UPDATE product_subscriptions
+		SET next_retry_at = NULL, updated_at = NOW()
+		WHERE id = $1 AND status = 'past_due' AND next_retry_at = $2
+	`
+	return r.execClaim(query, id, retryAt)
+}
+
+func (r *SubscriptionRepository) execClaim(query string, args ...interface{}) (bool, error) {
+	result, err := r.db.Exec(query, args...)
+	if err != nil {
+		return false, err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return rowsAffected > 0, nil
+}
+
+func (r *SubscriptionRepository) SetLastOrder(id, orderID int) error {
+	_, err := r.db.Exec(`UPDATE product_subscriptions SET last_order_id = $1, updated_at = NOW() WHERE id = $2`, orderID, id)
+	return err
+}
+
+// UpdateBillingState records the outcome of a charge attempt
+func (r *SubscriptionRepository) UpdateBillingState(id int, status string, failedAttempts int, nextRetryAt *time.Time) error {
+	query := `
+		UPDATE product_subscriptions
+		SET status = $1, failed_attempts = $2, next_retry_at = $3, updated_at = NOW()
+		WHERE id = $4
+	`
+	_, err := r.db.Exec(query, status, failedAttempts, nextRetryAt, id)
+	return err
+}
+
+// Subscription boxes
+
+const subscriptionBoxColumns = `id, slug, title, description, items, active, created_at`
+
+func scanSubscriptionBox(row rowScanner) (*models.SubscriptionBox, error) {
+	var box models.SubscriptionBox
+	var description sql.NullString
+	var itemsJSON []byte
+
+	err := row.Scan(&box.ID, &box.Slug, &box.Title, &description, &itemsJSON, &box.Active, &box.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	box.Description = description.String
+
+	if err := json.Unmarshal(itemsJSON, &box.Items); err != nil {
+		return nil, err
+	}
+
+	return &box, nil
+}
+
+func (r *SubscriptionRepository) CreateBox(box *models.SubscriptionBox) error {
+	itemsJSON, err := json.Marshal(box.Items)
+	if err != nil {
+		return err
+	}
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := lockProducts(tx, boxProductIDs(box)); err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO subscription_boxes (slug, title, description, items, active)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at
+	`
+	err = tx.QueryRow(query, box.Slug, box.Title, box.Description, itemsJSON, box.Active).
+		Scan(&box.ID, &box.CreatedAt)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func boxProductIDs(box *models.SubscriptionBox) []int {
+	ids := make([]int, len(box.Items))
+	for i, item := range box.Items {
+		ids[i] = item.ProductID
+	}
+	return ids
+}
+
+func (r *SubscriptionRepository) GetBoxByID(id int) (*models.SubscriptionBox, error) {
+	query := `SELECT ` + subscriptionBoxColumns + ` FROM subscription_boxes WHERE id = $1`
+
+	box, err := scanSubscriptionBox(r.db.QueryRow(query, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return box, nil
+}
+
+func (r *SubscriptionRepository) GetBoxes(onlyActive bool) ([]models.SubscriptionBox, error) {
+	query := `SELECT ` + subscriptionBoxColumns + ` FROM subscription_boxes`
+	if onlyActive {
+		query += ` WHERE active = true`
+	}
+	query += ` ORDER BY created_at DESC`
+
+	rows, err := r.db.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	boxes := make([]models.SubscriptionBox, 0)
+	for rows.Next() {
+		box, err := scanSubscriptionBox(rows)
+		if err != nil {
+			return nil, err
+		}
+		boxes = append(boxes, *box)
+	}
+
+	return boxes, nil
+}
+
+func (r *SubscriptionRepository) UpdateBox(id int, box *models.SubscriptionBox) error {
+	itemsJSON, err := json.Marshal(box.Items)
+	if err != nil {
+		return err
+	}
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := lockProducts(tx, boxProductIDs(box)); err != nil {
+		return err
+	}
+
+	query := `
+		UPDATE subscription_boxes
+		SET title = $1, description = $2, items = $3, active = $4, updated_at = NOW()
+		WHERE id = $5
+	`
+	result, err := tx.Exec(query, box.Title, box.Description, itemsJSON, box.Active, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("subscription box with id %d not found", id)
+	}
+
+	return tx.Commit()
+}
+
+func (r *SubscriptionRepository) DeleteBox(id int) error {
+	result, err := r.db.Exec(`DELETE FROM subscription_boxes WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("subscription box with id %d not found", id)
+	}
+
+	return nil
+}
@@ -5,6 +5,8 @@ import (
 	"time"
 
 	"gastroshop-api/internal/models"
+
+	"github.com/lib/pq"
 )
 
 type UserRepository struct {
@@ -150,6 +152,39 @@ func (r *UserRepository) UpdateUserBlocked(id int, blocked bool) error {
 	return err
 }
 
+// GetDietaryRestrictions returns the dietary exclusions saved by the user
+func (r *UserRepository) GetDietaryRestrictions(userID int) ([]string, error) {
+	var restrictions []string
+	err := r.db.QueryRow(`SELECT dietary_restrictions FROM users WHERE id = $1`, userID).Scan(pq.Array(&restrictions))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return restrictions, err
+}
+
+func (r *UserRepository) SetDietaryRestrictions(userID int, restrictions []string) error {
+	query := `UPDATE users SET dietary_restrictions = COALESCE($1::TEXT[], '{}') WHERE id = $2`
+	_, err := r.db.Exec(query, pq.Array(restrictions), userID)
+	return err
+}
+
+// SetLocale sets the locale emails are sent to a user in
+func (r *UserRepository) SetLocale(userID int, locale string) error {
+	_, err := r.db.Exec(`UPDATE users SET locale = $1 WHERE id = $2`, locale, userID)
+	return err
+}
+
+// GetLocaleByEmail returns the locale of the account with the email, empty
+// when there is none, e.g. for guests
+func (r *UserRepository) GetLocaleByEmail(email string) (string, error) {
+	var locale string
+	err := r.db.QueryRow(`SELECT locale FROM users WHERE LOWER(email) = LOWER($1)`, email).Scan(&locale)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	return locale, err
+}
+
 // Email verification methods
 func (r *UserRepository) SetEmailVerificationToken(userID int, token string, expiresAt time.Time) error {
 	query := `UPDATE users SET email_verification_token = $1, email_verification_token_expires_at = $2 WHERE id = $3`
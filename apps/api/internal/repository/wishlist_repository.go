@@ -0,0 +1,202 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+
+	"gastroshop-api/internal/models"
+
+	"github.com/lib/pq"
+)
+
+// WishlistRepository stores customers' wishlists and their items
+type WishlistRepository struct {
+	db *sql.DB
+}
+
+func NewWishlistRepository(db *sql.DB) *WishlistRepository {
+	return &WishlistRepository{db: db}
+}
+
+const wishlistColumns = `id, user_id, name, share_token, created_at, updated_at`
+
+func scanWishlist(row rowScanner) (*models.Wishlist, error) {
+	var w models.Wishlist
+	if err := row.Scan(&w.ID, &w.UserID, &w.Name, &w.ShareToken, &w.CreatedAt, &w.UpdatedAt); err != nil {
+		return nil, err
+	}
+	w.Items = []models.WishlistItem{}
+	return &w, nil
+}
+
+// GetWishlists returns the wishlists of a user with their items, oldest
+// list first
+func (r *WishlistRepository) GetWishlists(userID int) ([]models.Wishlist, error) {
+	rows, err := r.db.Query(`SELECT `+wishlistColumns+` FROM wishlists WHERE user_id = $1 ORDER BY created_at, id`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	wishlists := []models.Wishlist{}
+	for rows.Next() {
+		w, err := scanWishlist(rows)
+		if err != nil {
+			return nil, err
+		}
+		wishlists = append(wishlists, *w)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if err := r.loadItems(wishlists); err != nil {
+		return nil, err
+	}
+	return wishlists, nil
+}
+
+func (r *WishlistRepository) GetWishlistByID(id int) (*models.Wishlist, error) {
+	return r.getWishlist(`SELECT `+wishlistColumns+` FROM wishlists WHERE id = $1`, id)
+}
+
+// GetWishlistByShareToken returns the wishlist shared with the token
+func (r *WishlistRepository) GetWishlistByShareToken(token string) (*models.Wishlist, error) {
+	return r.getWishlist(`SELECT `+wishlistColumns+` FROM wishlists WHERE share_token = $1`, token)
+}
+
+func (r *WishlistRepository) getWishlist(query string, arg interface{}) (*models.Wishlist, error) {
+	w, err := scanWishlist(r.db.QueryRow(query, arg))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	wishlists := []models.Wishlist{*w}
+	if err := r.loadItems(wishlists); err != nil {
+		return nil, err
+	}
+	return &wishlists[0], nil
+}
+
+// loadItems sets the items of the wishlists, oldest first
+func (r *WishlistRepository) loadItems(wishlists []models.Wishlist) error {
+	if len(wishlists) == 0 {
+		return nil
+	}
+	ids := make([]int, len(wishlists))
+	index := make(map[int]int)
+	for i, w := range wishlists {
+		ids[i] = w.ID
+		index[w.ID] = i
+	}
+
+	query := `
+		SELECT id, wishlist_id, product_id, variant_id, note, created_at
+		FROM wishlist_items
+		WHERE wishlist_id = ANY($1)
+		ORDER BY created_at, id
+	`
+	rows, err := r.db.Query(query, pq.Array(ids))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var item models.WishlistItem
+		if err := rows.Scan(&item.ID, &item.WishlistID, &item.ProductID, &item.VariantID, &item.Note, &item.CreatedAt); err != nil {
+			return err
+		}
+		w := &wishlists[index[item.WishlistID]]
+		w.Items = append(w.Items, item)
+	}
+
+	return rows.Err()
+}
+
+func (r *WishlistRepository) CreateWishlist(w *models.Wishlist) error {
+	query := `
+		INSERT INTO wishlists (user_id, name)
+		VALUES ($1, $2)
+		RETURNING id, created_at, updated_at
+	`
+	w.Items = []models.WishlistItem{}
+	return r.db.QueryRow(query, w.UserID, w.Name).Scan(&w.ID, &w.CreatedAt, &w.UpdatedAt)
+}
+
+// UpdateWishlist updates the name and share token of a wishlist
+func (r *WishlistRepository) UpdateWishlist(w *models.Wishlist) error {
+	query := `
+		UPDATE wishlists
+		SET name = $1, share_token = $2, updated_at = NOW()
+		WHERE id = $3
+		RETURNING updated_at
+	`
+	err := r.db.QueryRow(query, w.Name, w.ShareToken, w.ID).Scan(&w.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("wishlist with id %d not found", w.ID)
+	}
+	return err
+}
+
+func (r *WishlistRepository) DeleteWishlist(id int) error {
+	result, err := r.db.Exec(`DELETE FROM wishlists WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("wishlist with id %d not found", id)
+	}
+
+	return nil
+}
+
+// AddItem adds a product or variant to a wishlist; adding it again updates
+// its note. It reports whether the item is new to the list.
+func (r *WishlistRepository) AddItem(item *models.WishlistItem) (bool, error) {
+	query := `
+		INSERT INTO wishlist_items (wishlist_id, product_id, variant_id, note)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (wishlist_id, product_id, COALESCE(variant_id, 0))
+		DO UPDATE SET note = EXCLUDED.note
+		RETURNING id, created_at, xmax = 0
+	`
+	var created bool
+	err := r.db.QueryRow(query, item.WishlistID, item.ProductID, item.VariantID, item.Note).
+		Scan(&item.ID, &item.CreatedAt, &created)
+	if err != nil {
+		return false, err
+	}
+
+	if _, err := r.db.Exec(`UPDATE wishlists SET updated_at = NOW() WHERE id = $1`, item.WishlistID); err != nil {
+		return false, err
+	}
+	return created, nil
+}
+
+// RemoveItem removes an item from a wishlist
+func (r *WishlistRepository) RemoveItem(wishlistID, itemID int) error {
+	result, err := r.db.Exec(`DELETE FROM wishlist_items WHERE id = $1 AND wishlist_id = $2`, itemID, wishlistID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("wishlist item with id %d not found", itemID)
+	}
+
+	_, err = r.db.Exec(`UPDATE wishlists SET updated_at = NOW() WHERE id = $1`, wishlistID)
+	return err
+}
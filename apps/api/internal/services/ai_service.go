@@ -17,9 +17,10 @@ import (
 )
 
 type AIService struct {
-	config      *config.Config
-	productRepo *repository.ProductRepository
-	httpClient  *http.Client
+	config         *config.Config
+	productRepo    *repository.ProductRepository
+	pairingService *PairingService
+	httpClient     *http.Client
 }
 
 func NewAIService(cfg *config.Config, productRepo *repository.ProductRepository) *AIService {
@@ -30,6 +31,12 @@ func NewAIService(cfg *config.Config, productRepo *repository.ProductRepository)
 	}
 }
 
+// SetPairingService gives the model the curated pairings of the products it
+// recommends
+func (s *AIService) SetPairingService(pairingService *PairingService) {
+	s.pairingService = pairingService
+}
+
 type ChatMessage struct {
 	Role    string `json:"role"`
 	Content string `json:"content"`
@@ -57,12 +64,17 @@ type AIResponse struct {
 	HasProducts bool             `json:"has_products"`
 }
 
-func (s *AIService) ChatWithAI(userMessage string, conversationHistory []ChatMessage) (*AIResponse, error) {
+// ChatWithAI answers a customer message. Products that don't suit the
+// customer's dietary restrictions are neither shown to the model nor
+// recommended.
+func (s *AIService) ChatWithAI(userMessage string, conversationHistory []ChatMessage, restrictions []string) (*AIResponse, error) {
 	// Get product catalog for context
-	products, productsErr := s.productRepo.GetProducts(map[string]interface{}{
+	catalogFilters := map[string]interface{}{
 		"page_size": 50,
 		"page":      1,
-	})
+	}
+	ApplyDietaryExclusions(catalogFilters, restrictions)
+	products, productsErr := s.productRepo.GetProducts(catalogFilters)
 	if productsErr != nil {
 		log.Printf("Failed to get products for AI context: %v", productsErr)
 		products = []models.Product{}
@@ -72,7 +84,7 @@ func (s *AIService) ChatWithAI(userMessage string, conversationHistory []ChatMes
 	excludeProductIDs := s.extractProductIDsFromHistory(conversationHistory)
 
 	// Check if user is asking about previously recommended products or wants additional suggestions
-	lastRecommendedProducts := s.extractProductsFromLastResponse(conversationHistory)
+	lastRecommendedProducts := filterDietary(s.extractProductsFromLastResponse(conversationHistory), restrictions)
 	isQuestionAboutPreviousProducts := s.isQuestionAboutPreviousProducts(userMessage)
 	isRequestForMoreSuggestions := s.isRequestForMoreSuggestions(userMessage)
 
@@ -87,18 +99,18 @@ func (s *AIService) ChatWithAI(userMessage string, conversationHistory []ChatMes
 		log.Printf("User is asking for more suggestions, excluding %d previous products", len(excludeProductIDs))
 		// Extract all previously recommended product IDs more thoroughly
 		allPreviousIDs := s.extractAllProductIDsFromHistory(conversationHistory)
-		recommendedProducts = s.findRelevantProducts(userMessage, allPreviousIDs)
+		recommendedProducts = s.findRelevantProducts(userMessage, allPreviousIDs, restrictions)
 		// If still no products, get random ones excluding previous
 		if len(recommendedProducts) == 0 {
-			recommendedProducts = s.getRandomDifferentProducts(allPreviousIDs, 4)
+			recommendedProducts = s.getRandomDifferentProducts(allPreviousIDs, 4, restrictions)
 		}
 	} else {
 		// First, find relevant products based on user query with variety
-		recommendedProducts = s.findRelevantProducts(userMessage, excludeProductIDs)
+		recommendedProducts = s.findRelevantProducts(userMessage, excludeProductIDs, restrictions)
 	}
 
 	// Build system prompt with product context and selected products
-	systemPrompt := s.buildSystemPrompt(products, recommendedProducts)
+	systemPrompt := s.buildSystemPrompt(products, recommendedProducts, s.getPairings(recommendedProducts, restrictions), restrictions)
 
 	// Build messages
 	messages := []ChatMessage{
@@ -122,7 +134,7 @@ func (s *AIService) ChatWithAI(userMessage string, conversationHistory []ChatMes
 	// If API key is not set, use fallback response
 	if s.config.OpenAIAPIKey == "" {
 		log.Printf("WARNING: OpenAI API key not set, using fallback response")
-		return s.fallbackResponse(userMessage, recommendedProducts, conversationHistory)
+		return s.fallbackResponse(userMessage, recommendedProducts, conversationHistory, restrictions)
 	}
 
 	// Call OpenAI API - this is the REAL AI response
@@ -130,7 +142,7 @@ func (s *AIService) ChatWithAI(userMessage string, conversationHistory []ChatMes
 	response, err := s.callOpenAI(messages)
 	if err != nil {
 		log.Printf("OpenAI API error: %v, using fallback", err)
-		return s.fallbackResponse(userMessage, recommendedProducts, conversationHistory)
+		return s.fallbackResponse(userMessage, recommendedProducts, conversationHistory, restrictions)
 	}
 
 	log.Printf("OpenAI API response received: %d characters", len(response))
@@ -151,7 +163,7 @@ func (s *AIService) ChatWithAI(userMessage string, conversationHistory []ChatMes
 	}, nil
 }
 
-func (s *AIService) buildSystemPrompt(allProducts []models.Product, recommendedProducts []models.Product) string {
+func (s *AIService) buildSystemPrompt(allProducts []models.Product, recommendedProducts []models.Product, pairings map[int][]models.ProductPairing, restrictions []string) string {
 	prompt := `Ты - профессиональный гастрономический ассистент с глубокими знаниями в кулинарии и гастрономии, работающий в магазине премиальных сыров и деликатесов GastroShop. 
 
 Твоя задача - не просто перечислять продукты, а ДУМАТЬ и АНАЛИЗИРОВАТЬ:
@@ -219,6 +231,9 @@ func (s *AIService) buildSystemPrompt(allProducts []models.Product, recommendedP
 			if product.RegionCode != "" {
 				desc += fmt.Sprintf(" [%s]", product.RegionCode)
 			}
+			if dietary := describeDietaryFlags(product.DietaryFlags); dietary != "" {
+				desc += fmt.Sprintf(" (%s)", dietary)
+			}
 			productStrs = append(productStrs, desc)
 		}
 		prompt += strings.Join(productStrs, "\n")
@@ -239,6 +254,9 @@ func (s *AIService) buildSystemPrompt(allProducts []models.Product, recommendedP
 			if product.RegionCode != "" {
 				desc += fmt.Sprintf(" [%s]", product.RegionCode)
 			}
+			if dietary := describeDietaryFlags(product.DietaryFlags); dietary != "" {
+				desc += fmt.Sprintf(" (%s)", dietary)
+			}
 			prompt += desc + "\n"
 		}
 		prompt += "\nКРИТИЧЕСКИ ВАЖНО:\n"
@@ -266,6 +284,119 @@ func (s *AIService) buildSystemPrompt(allProducts []models.Product, recommendedP
 		prompt += "- ЕСЛИ в твоем ответе появится хотя бы один символ ** или * - ты НЕВЕРНО выполнил задачу!\n"
 	}
 
+	prompt += pairingsPrompt(recommendedProducts, pairings)
+	prompt += dietaryRestrictionsPrompt(restrictions)
+
+	return prompt
+}
+
+// getPairings returns the curated pairings of the products that suit the
+// dietary restrictions. Failures are logged so the chat still works.
+func (s *AIService) getPairings(products []models.Product, restrictions []string) map[int][]models.ProductPairing {
+	if s.pairingService == nil || len(products) == 0 {
+		return nil
+	}
+
+	ids := make([]int, len(products))
+	for i, p := range products {
+		ids[i] = p.ID
+	}
+	pairings, err := s.pairingService.GetPairingsByProductIDs(ids)
+	if err != nil {
+		log.Printf("Failed to get pairings for AI context: %v", err)
+		return nil
+	}
+	for id := range pairings {
+		pairings[id] = filterPairingsDietary(pairings[id], restrictions)
+	}
+	return pairings
+}
+
+// pairingTypeLabels names pairing types in prompts
+var pairingTypeLabels = map[string]string{
+	models.PairingClassic:    "классическое сочетание",
+	models.PairingComplement: "дополняет вкус",
+	models.PairingContrast:   "контраст",
+	models.PairingRegional:   "из одного региона",
+}
+
+// pairingsPrompt lists the curated pairings of the recommended products,
+// empty without pairings
+func pairingsPrompt(products []models.Product, pairings map[int][]models.ProductPairing) string {
+	var lines []string
+	for _, product := range products {
+		for _, pairing := range pairings[product.ID] {
+			if pairing.PairedProduct == nil {
+				continue
+			}
+			line := fmt.Sprintf("- %s + %s (%s)", product.Title, pairing.PairedProduct.Title, pairingTypeLabels[pairing.PairingType])
+			if pairing.Note != "" {
+				line += ": " + pairing.Note
+			}
+			lines = append(lines, line)
+		}
+	}
+	if len(lines) == 0 {
+		return ""
+	}
+
+	prompt := "\n\n=== ПРОВЕРЕННЫЕ СОЧЕТАНИЯ ===\n"
+	prompt += "Наши эксперты подобрали к этим продуктам пары из ассортимента:\n"
+	prompt += strings.Join(lines, "\n") + "\n"
+	prompt += "- Когда советуешь, с чем подать продукт, в первую очередь предлагай эти пары и объясняй их словами эксперта\n"
+	prompt += "- Не выдумывай других товаров магазина для сочетаний - общие советы (блюда, напитки) давай без названий товаров\n"
+	return prompt
+}
+
+// dietaryExclusionLabels names dietary exclusions in prompts
+var dietaryExclusionLabels = map[string]string{
+	models.ExcludeLactose:      "лактоза",
+	models.ExcludeAnimalRennet: "сычужный фермент животного происхождения",
+	models.ExcludeRawMilk:      "сыры из сырого (непастеризованного) молока",
+	models.ExcludeGluten:       "глютен",
+	models.ExcludeNuts:         "орехи",
+}
+
+// dietaryFlagLabels names product dietary flags in prompts
+var dietaryFlagLabels = map[string]string{
+	models.DietaryLactoseFree:      "без лактозы",
+	models.DietaryVegetarianRennet: "вегетарианский фермент",
+	models.DietaryPasteurized:      "пастеризованное молоко",
+	models.DietaryRawMilk:          "сырое молоко",
+	models.DietaryGluten:           "содержит глютен",
+	models.DietaryGlutenFree:       "без глютена",
+	models.DietaryNuts:             "содержит орехи",
+	models.DietaryNutFree:          "без орехов",
+}
+
+// describeDietaryFlags lists the dietary flags of a product for prompts
+func describeDietaryFlags(flags []string) string {
+	labels := make([]string, 0, len(flags))
+	for _, flag := range flags {
+		if label, ok := dietaryFlagLabels[flag]; ok {
+			labels = append(labels, label)
+		}
+	}
+	return strings.Join(labels, ", ")
+}
+
+// dietaryRestrictionsPrompt tells the model about the customer's saved
+// dietary restrictions, empty without restrictions
+func dietaryRestrictionsPrompt(restrictions []string) string {
+	var labels []string
+	for _, r := range restrictions {
+		if label, ok := dietaryExclusionLabels[r]; ok {
+			labels = append(labels, label)
+		}
+	}
+	if len(labels) == 0 {
+		return ""
+	}
+
+	prompt := "\n\n=== ОГРАНИЧЕНИЯ ПО ПИТАНИЮ КЛИЕНТА ===\n"
+	prompt += fmt.Sprintf("Клиент исключает из рациона: %s.\n", strings.Join(labels, ", "))
+	prompt += "- Товары в списках выше уже подобраны с учетом этих ограничений\n"
+	prompt += "- НЕ советуй товары и блюда, которые им не соответствуют, даже если клиент спросит про них - объясни причину и предложи подходящую альтернативу\n"
 	return prompt
 }
 
@@ -542,11 +673,13 @@ func (s *AIService) extractAllProductIDsFromHistory(conversationHistory []ChatMe
 }
 
 // getRandomDifferentProducts gets random products excluding specified IDs
-func (s *AIService) getRandomDifferentProducts(excludeIDs []int, limit int) []models.Product {
-	allProducts, err := s.productRepo.GetProducts(map[string]interface{}{
+func (s *AIService) getRandomDifferentProducts(excludeIDs []int, limit int, restrictions []string) []models.Product {
+	filters := map[string]interface{}{
 		"page_size": 50,
 		"page":      1,
-	})
+	}
+	ApplyDietaryExclusions(filters, restrictions)
+	allProducts, err := s.productRepo.GetProducts(filters)
 	if err != nil {
 		log.Printf("Error getting products for random selection: %v", err)
 		return []models.Product{}
@@ -766,17 +899,19 @@ func (s *AIService) isGenericRequest(message string) bool {
 	return false
 }
 
-func (s *AIService) findRelevantProducts(userMessage string, excludeProductIDs []int) []models.Product {
+func (s *AIService) findRelevantProducts(userMessage string, excludeProductIDs []int, restrictions []string) []models.Product {
 	message := strings.ToLower(userMessage)
 	var products []models.Product
 	var err error
 
 	// First, try to find specific product names in the message
 	// Get all products to check against
-	allProducts, err := s.productRepo.GetProducts(map[string]interface{}{
+	catalogFilters := map[string]interface{}{
 		"page_size": 100,
 		"page":      1,
-	})
+	}
+	ApplyDietaryExclusions(catalogFilters, restrictions)
+	allProducts, err := s.productRepo.GetProducts(catalogFilters)
 	if err != nil {
 		log.Printf("Error getting all products: %v", err)
 		allProducts = []models.Product{}
@@ -867,6 +1002,7 @@ func (s *AIService) findRelevantProducts(userMessage string, excludeProductIDs [
 		filters := map[string]interface{}{
 			"query": userMessage,
 		}
+		ApplyDietaryExclusions(filters, restrictions)
 		allResults, err := s.productRepo.GetProducts(filters)
 		if err != nil {
 			log.Printf("Error finding relevant products: %v", err)
@@ -904,6 +1040,7 @@ func (s *AIService) findRelevantProducts(userMessage string, excludeProductIDs [
 			log.Printf("Error finding relevant products by tags: %v", err)
 			products = []models.Product{}
 		}
+		products = filterDietary(products, restrictions)
 		log.Printf("Found %d products by tags %v", len(products), tags)
 	}
 
@@ -914,17 +1051,17 @@ func (s *AIService) findRelevantProducts(userMessage string, excludeProductIDs [
 	// 3. Generic request like "любые продукты"
 	if len(products) == 0 {
 		// Get more products for better variety in fallback
-		allProducts, err := s.productRepo.GetProducts(map[string]interface{}{
+		fallbackFilters := map[string]interface{}{
 			"page_size": 50,
 			"page":      1,
-		})
+		}
+		ApplyDietaryExclusions(fallbackFilters, restrictions)
+		allProducts, err := s.productRepo.GetProducts(fallbackFilters)
 		if err != nil {
 			log.Printf("Error getting fallback products: %v", err)
 			// Last resort: try to get any products at all
-			allProducts, err = s.productRepo.GetProducts(map[string]interface{}{
-				"page_size": 10,
-				"page":      1,
-			})
+			fallbackFilters["page_size"] = 10
+			allProducts, err = s.productRepo.GetProducts(fallbackFilters)
 			if err != nil {
 				log.Printf("Critical error getting any products: %v", err)
 				return []models.Product{}
@@ -1060,10 +1197,10 @@ func (s *AIService) extractProductsFromResponse(response string, products []mode
 	return recommendedProducts
 }
 
-func (s *AIService) fallbackResponse(userMessage string, products []models.Product, conversationHistory []ChatMessage) (*AIResponse, error) {
+func (s *AIService) fallbackResponse(userMessage string, products []models.Product, conversationHistory []ChatMessage, restrictions []string) (*AIResponse, error) {
 	// Use provided products or try to find some
 	if len(products) == 0 {
-		products = s.findRelevantProducts(userMessage, []int{})
+		products = s.findRelevantProducts(userMessage, []int{}, restrictions)
 	}
 
 	isQuestionAboutPrevious := s.isQuestionAboutPreviousProducts(userMessage)
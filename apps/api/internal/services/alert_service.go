@@ -0,0 +1,246 @@
+package services
+
+import (
+	"errors"
+	"log"
+	"strings"
+	"time"
+
+	"gastroshop-api/internal/models"
+	"gastroshop-api/internal/repository"
+)
+
+const (
+	// maxActiveAlertsPerEmail limits the alerts one email can wait for
+	maxActiveAlertsPerEmail = 50
+	// alertBatchSize limits the alerts sent per run of the alert job
+	alertBatchSize = 200
+)
+
+// AlertService subscribes customers to back-in-stock and price-drop alerts
+// on products and emails them once the product is back in stock or at their
+// target price. Alerts of guests wait for their email to be confirmed. Each
+// email gets at most dailyLimit alerts a day; the rest wait for a later run.
+type AlertService struct {
+	alertRepo    *repository.AlertRepository
+	productRepo  *repository.ProductRepository
+	userRepo     *repository.UserRepository
+	emailService *EmailService
+	dailyLimit   int
+}
+
+func NewAlertService(alertRepo *repository.AlertRepository, productRepo *repository.ProductRepository, userRepo *repository.UserRepository, emailService *EmailService) *AlertService {
+	return &AlertService{
+		alertRepo:    alertRepo,
+		productRepo:  productRepo,
+		userRepo:     userRepo,
+		emailService: emailService,
+		dailyLimit:   5,
+	}
+}
+
+// SetDailyLimit sets the number of alerts an email gets a day
+func (s *AlertService) SetDailyLimit(limit int) {
+	s.dailyLimit = limit
+}
+
+// Subscribe subscribes to an alert on the product with the slug, at the
+// account email of a signed-in user or at the email given by a guest. The
+// alert of a guest is pending until they follow the confirmation link
+// emailed to them.
+func (s *AlertService) Subscribe(userID *int, slug string, req *models.CreateProductAlertRequest) (*models.ProductAlert, error) {
+	product, err := s.productRepo.GetProductBySlug(slug)
+	if err != nil {
+		return nil, err
+	}
+	if product == nil || product.ArchivedAt != nil {
+		return nil, errors.New("product not found")
+	}
+
+	email := strings.TrimSpace(req.Email)
+	if userID != nil {
+		user, err := s.userRepo.GetUserByID(*userID)
+		if err != nil {
+			return nil, err
+		}
+		if user == nil {
+			return nil, errors.New("user not found")
+		}
+		email = user.Email
+	}
+	if err := validateEmailAddress(email); err != nil {
+		return nil, err
+	}
+
+	if err := validateAlert(req.Type, req.TargetPriceCents, product); err != nil {
+		return nil, err
+	}
+
+	active, err := s.alertRepo.CountActiveAlerts(email)
+	if err != nil {
+		return nil, err
+	}
+	if active >= maxActiveAlertsPerEmail {
+		return nil, errors.New("too many alerts for this email; unsubscribe from some first")
+	}
+
+	token, err := GenerateVerificationToken()
+	if err != nil {
+		return nil, err
+	}
+	alert := &models.ProductAlert{
+		ProductID:        product.ID,
+		UserID:           userID,
+		Email:            email,
+		Type:             req.Type,
+		Status:           models.AlertStatusActive,
+		UnsubscribeToken: token,
+		Title:            product.Title,
+		Slug:             product.Slug,
+		PriceCents:       product.PriceCents,
+	}
+	if req.Type == models.AlertPriceDrop {
+		alert.TargetPriceCents = req.TargetPriceCents
+	}
+	if userID == nil {
+		alert.Status = models.AlertStatusPending
+	}
+	if err := s.alertRepo.CreateAlert(alert); err != nil {
+		return nil, err
+	}
+
+	if alert.Status == models.AlertStatusPending {
+		if err := s.emailService.SendProductAlertConfirmationEmail(alert.Email, alert.Title, alert.UnsubscribeToken); err != nil {
+			log.Printf("Failed to send confirmation of alert %d: %v", alert.ID, err)
+		}
+	}
+	return alert, nil
+}
+
+// ConfirmAlert activates the pending alert of a guest with the token of its
+// confirmation link; confirming twice is not an error
+func (s *AlertService) ConfirmAlert(token string) (*models.ProductAlert, error) {
+	status, err := s.alertRepo.ConfirmAlert(token)
+	if err != nil {
+		return nil, err
+	}
+	if status == "" {
+		return nil, errors.New("alert not found")
+	}
+	return s.GetAlertByToken(token)
+}
+
+// GetAlertByToken returns the alert with the token of an alert or
+// confirmation email
+func (s *AlertService) GetAlertByToken(token string) (*models.ProductAlert, error) {
+	alert, err := s.alertRepo.GetAlertByToken(token)
+	if err != nil {
+		return nil, err
+	}
+	if alert == nil {
+		return nil, errors.New("alert not found")
+	}
+	return alert, nil
+}
+
+// validateAlert checks an alert on a product: back-in-stock alerts are for
+// products out of stock, price-drop alerts for a price below the current one
+func validateAlert(alertType string, targetPriceCents *int, product *models.Product) error {
+	switch alertType {
+	case models.AlertBackInStock:
+		if product.InStock && product.Quantity > 0 {
+			return errors.New("product is in stock")
+		}
+	case models.AlertPriceDrop:
+		if targetPriceCents == nil || *targetPriceCents <= 0 {
+			return errors.New("target price must be positive")
+		}
+		if *targetPriceCents >= product.PriceCents {
+			return errors.New("target price must be below the current price")
+		}
+	default:
+		return errors.New("type must be back_in_stock or price_drop")
+	}
+	return nil
+}
+
+// GetUserAlerts returns the active alerts of a user
+func (s *AlertService) GetUserAlerts(userID int) ([]models.ProductAlert, error) {
+	return s.alertRepo.GetUserAlerts(userID)
+}
+
+// Unsubscribe unsubscribes an active alert of a user
+func (s *AlertService) Unsubscribe(userID, id int) error {
+	return s.alertRepo.UnsubscribeUserAlert(userID, id)
+}
+
+// UnsubscribeByToken unsubscribes the alert with the unsubscribe token of an
+// alert email, or every alert of its email with all set. It returns the
+// number of alerts unsubscribed; unsubscribing twice is not an error.
+func (s *AlertService) UnsubscribeByToken(token string, all bool) (int, error) {
+	count, found, err := s.alertRepo.UnsubscribeByToken(token, all)
+	if err != nil {
+		return 0, err
+	}
+	if !found {
+		return 0, errors.New("alert not found")
+	}
+	return count, nil
+}
+
+// StartScheduler periodically emails the alerts that are due
+func (s *AlertService) StartScheduler(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			if err := s.ProcessAlerts(time.Now()); err != nil {
+				log.Printf("Alert scheduler error: %v", err)
+			}
+		}
+	}()
+}
+
+// ProcessAlerts emails the alerts whose product is back in stock or at the
+// target price, oldest first, and marks them sent. Alerts of emails that
+// reached the daily limit stay active for a later run.
+func (s *AlertService) ProcessAlerts(now time.Time) error {
+	due, err := s.alertRepo.GetDueAlerts(s.dailyLimit, now.Add(-24*time.Hour), alertBatchSize)
+	if err != nil {
+		return err
+	}
+
+	sent := make(map[string]int)
+	for i := range due {
+		a := &due[i]
+		email := strings.ToLower(a.Email)
+		if _, ok := sent[email]; !ok {
+			count, err := s.alertRepo.CountSentSince(a.Email, now.Add(-24*time.Hour))
+			if err != nil {
+				log.Printf("Failed to count alerts sent to %s: %v", a.Email, err)
+				continue
+			}
+			sent[email] = count
+		}
+		if !alertAllowed(sent[email], s.dailyLimit) {
+			continue
+		}
+
+		if err := s.emailService.SendProductAlertEmail(a.Email, a.Type, a.Title, a.Slug, a.PriceCents, a.UnsubscribeToken); err != nil {
+			log.Printf("Failed to send alert %d: %v", a.ID, err)
+			continue
+		}
+		sent[email]++
+		if err := s.alertRepo.MarkAlertSent(a.ID, now); err != nil {
+			log.Printf("Failed to mark alert %d sent: %v", a.ID, err)
+		}
+	}
+	return nil
+}
+
+// alertAllowed reports whether an email that got sent alerts in the last
+// day may get another; a limit of zero doesn't limit
+func alertAllowed(sent, limit int) bool {
+	return limit <= 0 || sent < limit
+}
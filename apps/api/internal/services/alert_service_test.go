@@ -0,0 +1,58 @@
+package services
+
+import (
+	"testing"
+
+	"gastroshop-api/internal/models"
+)
+
+func TestValidateAlert(t *testing.T) {
+	intPtr := func(v int) *int { return &v }
+	inStock := &models.Product{PriceCents: 1000, InStock: true, Quantity: 5}
+	soldOut := &models.Product{PriceCents: 1000, InStock: true, Quantity: 0}
+
+	tests := []struct {
+		name      string
+		alertType string
+		target    *int
+		product   *models.Product
+		wantErr   bool
+	}{
+		{name: "back in stock of sold out product", alertType: models.AlertBackInStock, product: soldOut},
+		{name: "back in stock of product in stock", alertType: models.AlertBackInStock, product: inStock, wantErr: true},
+		{name: "price drop", alertType: models.AlertPriceDrop, target: intPtr(800), product: inStock},
+		{name: "price drop of sold out product", alertType: models.AlertPriceDrop, target: intPtr(800), product: soldOut},
+		{name: "price drop without target", alertType: models.AlertPriceDrop, product: inStock, wantErr: true},
+		{name: "target at current price", alertType: models.AlertPriceDrop, target: intPtr(1000), product: inStock, wantErr: true},
+		{name: "zero target", alertType: models.AlertPriceDrop, target: intPtr(0), product: inStock, wantErr: true},
+		{name: "unknown type", alertType: "restock", product: soldOut, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateAlert(tt.alertType, tt.target, tt.product)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validateAlert() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestAlertAllowed(t *testing.T) {
+	tests := []struct {
+		sent  int
+		limit int
+		want  bool
+	}{
+		{sent: 0, limit: 5, want: true},
+		{sent: 4, limit: 5, want: true},
+		{sent: 5, limit: 5, want: false},
+		{sent: 100, limit: 0, want: true},
+	}
+
+	for _, tt := range tests {
+		if got := alertAllowed(tt.sent, tt.limit); got != tt.want {
+			t.Errorf("alertAllowed(%d, %d) = %v, want %v", tt.sent, tt.limit, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,345 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"gastroshop-api/internal/models"
+	"gastroshop-api/internal/repository"
+)
+
+// attributeCodePattern is the format of attribute codes, which name
+// attributes in product list filters such as attr.aging.min
+var attributeCodePattern = regexp.MustCompile(`^[a-z][a-z0-9_]*$`)
+
+// AttributeService manages product attributes and the attribute values of
+// products
+type AttributeService struct {
+	attributeRepo *repository.AttributeRepository
+	productRepo   *repository.ProductRepository
+}
+
+func NewAttributeService(attributeRepo *repository.AttributeRepository, productRepo *repository.ProductRepository) *AttributeService {
+	return &AttributeService{
+		attributeRepo: attributeRepo,
+		productRepo:   productRepo,
+	}
+}
+
+func (s *AttributeService) GetAttributes() ([]models.ProductAttribute, error) {
+	return s.attributeRepo.GetAttributes()
+}
+
+func (s *AttributeService) CreateAttribute(req *models.CreateProductAttributeRequest) (*models.ProductAttribute, error) {
+	attribute := &models.ProductAttribute{
+		Code:       req.Code,
+		Name:       req.Name,
+		Type:       req.Type,
+		Unit:       req.Unit,
+		Options:    req.Options,
+		Filterable: true,
+		SortOrder:  req.SortOrder,
+	}
+	if req.Filterable != nil {
+		attribute.Filterable = *req.Filterable
+	}
+	if err := validateAttribute(attribute); err != nil {
+		return nil, err
+	}
+
+	existing, err := s.attributeRepo.GetAttributeByCode(attribute.Code)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		return nil, errors.New("attribute with this code already exists")
+	}
+
+	if err := s.attributeRepo.CreateAttribute(attribute); err != nil {
+		return nil, err
+	}
+	return attribute, nil
+}
+
+// UpdateAttribute changes an attribute. Product values of enum options that
+// were removed are deleted.
+func (s *AttributeService) UpdateAttribute(id int, req *models.UpdateProductAttributeRequest) (*models.ProductAttribute, error) {
+	attribute, err := s.attributeRepo.GetAttributeByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if attribute == nil {
+		return nil, errors.New("attribute not found")
+	}
+
+	if req.Name != nil {
+		attribute.Name = *req.Name
+	}
+	if req.Unit != nil {
+		attribute.Unit = *req.Unit
+	}
+	if req.Options != nil {
+		attribute.Options = *req.Options
+	}
+	if req.Filterable != nil {
+		attribute.Filterable = *req.Filterable
+	}
+	if req.SortOrder != nil {
+		attribute.SortOrder = *req.SortOrder
+	}
+	if err := validateAttribute(attribute); err != nil {
+		return nil, err
+	}
+
+	if err := s.attributeRepo.UpdateAttribute(id, attribute); err != nil {
+		return nil, err
+	}
+	if attribute.Type == models.AttributeTypeEnum && req.Options != nil {
+		values := make([]string, len(attribute.Options))
+		for i, o := range attribute.Options {
+			values[i] = o.Value
+		}
+		if err := s.attributeRepo.RemoveAttributeOptionValues(id, values); err != nil {
+			return nil, err
+		}
+	}
+	return attribute, nil
+}
+
+// DeleteAttribute deletes an attribute and its values on all products
+func (s *AttributeService) DeleteAttribute(id int) error {
+	return s.attributeRepo.DeleteAttribute(id)
+}
+
+func (s *AttributeService) GetProductAttributes(productID int) ([]models.ProductAttributeValue, error) {
+	if err := s.checkProduct(productID); err != nil {
+		return nil, err
+	}
+
+	values, err := s.attributeRepo.GetAttributeValuesByProductIDs([]int{productID})
+	if err != nil {
+		return nil, err
+	}
+	if values[productID] == nil {
+		return []models.ProductAttributeValue{}, nil
+	}
+	return values[productID], nil
+}
+
+// SetProductAttributes replaces the attribute values of a product. Values
+// are keyed by attribute code; null values are left out.
+func (s *AttributeService) SetProductAttributes(productID int, rawValues map[string]interface{}) ([]models.ProductAttributeValue, error) {
+	if err := s.checkProduct(productID); err != nil {
+		return nil, err
+	}
+
+	attributes, err := s.attributesByCode()
+	if err != nil {
+		return nil, err
+	}
+
+	var values []models.ProductAttributeValue
+	for code, raw := range rawValues {
+		attribute, ok := attributes[code]
+		if !ok {
+			return nil, fmt.Errorf("unknown attribute %q", code)
+		}
+		if raw == nil {
+			continue
+		}
+		value, err := attributeValue(attribute, raw)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, models.ProductAttributeValue{
+			AttributeID: attribute.ID,
+			Type:        attribute.Type,
+			Value:       value,
+		})
+	}
+
+	if err := s.attributeRepo.SetProductAttributeValues(productID, values); err != nil {
+		return nil, err
+	}
+	return s.GetProductAttributes(productID)
+}
+
+// ResolveFilters checks product list filters against the attributes and
+// fills in the attribute ID, type and typed values of each filter
+func (s *AttributeService) ResolveFilters(filters []models.AttributeFilter) error {
+	if len(filters) == 0 {
+		return nil
+	}
+
+	attributes, err := s.attributesByCode()
+	if err != nil {
+		return err
+	}
+
+	for i := range filters {
+		attribute, ok := attributes[filters[i].Code]
+		if !ok || !attribute.Filterable {
+			return fmt.Errorf("unknown attribute filter %q", filters[i].Code)
+		}
+		if err := resolveAttributeFilter(attribute, &filters[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *AttributeService) attributesByCode() (map[string]*models.ProductAttribute, error) {
+	attributes, err := s.attributeRepo.GetAttributes()
+	if err != nil {
+		return nil, err
+	}
+
+	byCode := make(map[string]*models.ProductAttribute, len(attributes))
+	for i := range attributes {
+		byCode[attributes[i].Code] = &attributes[i]
+	}
+	return byCode, nil
+}
+
+func (s *AttributeService) checkProduct(productID int) error {
+	product, err := s.productRepo.GetProductByID(productID)
+	if err != nil {
+		return err
+	}
+	if product == nil {
+		return errors.New("product not found")
+	}
+	return nil
+}
+
+// validateAttribute checks the code and type of an attribute and the
+// fields that depend on the type. Enum options without a label are
+// labelled with their value.
+func validateAttribute(attribute *models.ProductAttribute) error {
+	if !attributeCodePattern.MatchString(attribute.Code) {
+		return errors.New("code must start with a letter and contain only lowercase letters, digits and underscores")
+	}
+	if attribute.Name == "" {
+		return errors.New("name is required")
+	}
+
+	switch attribute.Type {
+	case models.AttributeTypeEnum, models.AttributeTypeNumber, models.AttributeTypeBoolean, models.AttributeTypeText:
+	default:
+		return errors.New("type must be enum, number, boolean or text")
+	}
+
+	if attribute.Unit != "" && attribute.Type != models.AttributeTypeNumber {
+		return errors.New("only number attributes have a unit")
+	}
+
+	if attribute.Type != models.AttributeTypeEnum {
+		if len(attribute.Options) > 0 {
+			return errors.New("only enum attributes have options")
+		}
+		return nil
+	}
+
+	if len(attribute.Options) == 0 {
+		return errors.New("enum attributes need at least one option")
+	}
+	seen := make(map[string]bool, len(attribute.Options))
+	for i, option := range attribute.Options {
+		if option.Value == "" {
+			return errors.New("option value is required")
+		}
+		if seen[option.Value] {
+			return fmt.Errorf("duplicate option %q", option.Value)
+		}
+		seen[option.Value] = true
+		if option.Label == "" {
+			attribute.Options[i].Label = option.Value
+		}
+	}
+	return nil
+}
+
+// attributeValue checks a JSON value against the attribute type and returns
+// it as stored: a string, a float64 or a bool
+func attributeValue(attribute *models.ProductAttribute, raw interface{}) (interface{}, error) {
+	switch attribute.Type {
+	case models.AttributeTypeNumber:
+		if number, ok := raw.(float64); ok {
+			return number, nil
+		}
+		return nil, fmt.Errorf("%s must be a number", attribute.Code)
+
+	case models.AttributeTypeBoolean:
+		if b, ok := raw.(bool); ok {
+			return b, nil
+		}
+		return nil, fmt.Errorf("%s must be true or false", attribute.Code)
+
+	case models.AttributeTypeEnum:
+		value, _ := raw.(string)
+		for _, option := range attribute.Options {
+			if option.Value == value {
+				return value, nil
+			}
+		}
+		return nil, fmt.Errorf("%s must be one of its options", attribute.Code)
+
+	default:
+		value, ok := raw.(string)
+		if !ok || strings.TrimSpace(value) == "" {
+			return nil, fmt.Errorf("%s must be a non-empty string", attribute.Code)
+		}
+		return strings.TrimSpace(value), nil
+	}
+}
+
+// resolveAttributeFilter validates a filter against its attribute: enum
+// values must be options, booleans take a single true or false, and only
+// numbers take a range
+func resolveAttributeFilter(attribute *models.ProductAttribute, f *models.AttributeFilter) error {
+	f.AttributeID = attribute.ID
+	f.Type = attribute.Type
+
+	if attribute.Type != models.AttributeTypeNumber && (f.Min != nil || f.Max != nil) {
+		return fmt.Errorf("attribute %s has no range filter", attribute.Code)
+	}
+	if len(f.Values) == 0 && f.Min == nil && f.Max == nil {
+		return fmt.Errorf("attribute filter %s needs a value", attribute.Code)
+	}
+
+	switch attribute.Type {
+	case models.AttributeTypeNumber:
+		f.Numbers = nil
+		for _, value := range f.Values {
+			number, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				return fmt.Errorf("attribute filter %s must be a number", attribute.Code)
+			}
+			f.Numbers = append(f.Numbers, number)
+		}
+		if f.Min != nil && f.Max != nil && *f.Min > *f.Max {
+			return fmt.Errorf("attribute filter %s has min above max", attribute.Code)
+		}
+
+	case models.AttributeTypeBoolean:
+		if len(f.Values) != 1 {
+			return fmt.Errorf("attribute filter %s takes a single value", attribute.Code)
+		}
+		b, err := strconv.ParseBool(f.Values[0])
+		if err != nil {
+			return fmt.Errorf("attribute filter %s must be true or false", attribute.Code)
+		}
+		f.Bool = &b
+
+	case models.AttributeTypeEnum:
+		for _, value := range f.Values {
+			if _, err := attributeValue(attribute, value); err != nil {
+				return fmt.Errorf("unknown %s value %q", attribute.Code, value)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,146 @@
+package services
+
+import (
+	"testing"
+
+	"gastroshop-api/internal/models"
+)
+
+func TestValidateAttribute(t *testing.T) {
+	tests := []struct {
+		name      string
+		attribute models.ProductAttribute
+		wantErr   bool
+	}{
+		{name: "number with unit", attribute: models.ProductAttribute{Code: "aging", Name: "Выдержка", Type: models.AttributeTypeNumber, Unit: "мес."}},
+		{name: "enum", attribute: models.ProductAttribute{Code: "milk", Name: "Молоко", Type: models.AttributeTypeEnum, Options: []models.AttributeOption{{Value: "cow"}, {Value: "goat"}}}},
+		{name: "boolean", attribute: models.ProductAttribute{Code: "organic", Name: "Органик", Type: models.AttributeTypeBoolean}},
+		{name: "invalid code", attribute: models.ProductAttribute{Code: "Aging.min", Name: "Выдержка", Type: models.AttributeTypeNumber}, wantErr: true},
+		{name: "missing name", attribute: models.ProductAttribute{Code: "aging", Type: models.AttributeTypeNumber}, wantErr: true},
+		{name: "unknown type", attribute: models.ProductAttribute{Code: "aging", Name: "Выдержка", Type: "date"}, wantErr: true},
+		{name: "unit on text", attribute: models.ProductAttribute{Code: "note", Name: "Заметка", Type: models.AttributeTypeText, Unit: "г"}, wantErr: true},
+		{name: "enum without options", attribute: models.ProductAttribute{Code: "milk", Name: "Молоко", Type: models.AttributeTypeEnum}, wantErr: true},
+		{name: "duplicate option", attribute: models.ProductAttribute{Code: "milk", Name: "Молоко", Type: models.AttributeTypeEnum, Options: []models.AttributeOption{{Value: "cow"}, {Value: "cow"}}}, wantErr: true},
+		{name: "options on number", attribute: models.ProductAttribute{Code: "aging", Name: "Выдержка", Type: models.AttributeTypeNumber, Options: []models.AttributeOption{{Value: "12"}}}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateAttribute(&tt.attribute)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validateAttribute() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateAttributeLabelsOptions(t *testing.T) {
+	attribute := models.ProductAttribute{
+		Code:    "milk",
+		Name:    "Молоко",
+		Type:    models.AttributeTypeEnum,
+		Options: []models.AttributeOption{{Value: "cow", Label: "Коровье"}, {Value: "goat"}},
+	}
+	if err := validateAttribute(&attribute); err != nil {
+		t.Fatalf("validateAttribute() error = %v", err)
+	}
+	if attribute.Options[0].Label != "Коровье" || attribute.Options[1].Label != "goat" {
+		t.Errorf("options = %+v, want labels kept and defaulted to the value", attribute.Options)
+	}
+}
+
+func TestAttributeValue(t *testing.T) {
+	milk := &models.ProductAttribute{Code: "milk", Type: models.AttributeTypeEnum, Options: []models.AttributeOption{{Value: "cow"}, {Value: "goat"}}}
+	aging := &models.ProductAttribute{Code: "aging", Type: models.AttributeTypeNumber}
+	organic := &models.ProductAttribute{Code: "organic", Type: models.AttributeTypeBoolean}
+	note := &models.ProductAttribute{Code: "note", Type: models.AttributeTypeText}
+
+	tests := []struct {
+		name      string
+		attribute *models.ProductAttribute
+		raw       interface{}
+		want      interface{}
+		wantErr   bool
+	}{
+		{name: "enum option", attribute: milk, raw: "goat", want: "goat"},
+		{name: "unknown enum option", attribute: milk, raw: "camel", wantErr: true},
+		{name: "number", attribute: aging, raw: 12.0, want: 12.0},
+		{name: "number as string", attribute: aging, raw: "12", wantErr: true},
+		{name: "boolean", attribute: organic, raw: true, want: true},
+		{name: "boolean as string", attribute: organic, raw: "true", wantErr: true},
+		{name: "text is trimmed", attribute: note, raw: "  к белому вину ", want: "к белому вину"},
+		{name: "empty text", attribute: note, raw: " ", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := attributeValue(tt.attribute, tt.raw)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("attributeValue() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("attributeValue() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolveAttributeFilter(t *testing.T) {
+	milk := &models.ProductAttribute{ID: 1, Code: "milk", Type: models.AttributeTypeEnum, Options: []models.AttributeOption{{Value: "cow"}, {Value: "goat"}}}
+	aging := &models.ProductAttribute{ID: 2, Code: "aging", Type: models.AttributeTypeNumber}
+	organic := &models.ProductAttribute{ID: 3, Code: "organic", Type: models.AttributeTypeBoolean}
+	twelve := 12.0
+	six := 6.0
+
+	t.Run("number range", func(t *testing.T) {
+		f := models.AttributeFilter{Code: "aging", Min: &twelve}
+		if err := resolveAttributeFilter(aging, &f); err != nil {
+			t.Fatalf("resolveAttributeFilter() error = %v", err)
+		}
+		if f.AttributeID != 2 || f.Type != models.AttributeTypeNumber {
+			t.Errorf("filter = %+v, want attribute 2 of type number", f)
+		}
+	})
+
+	t.Run("number values", func(t *testing.T) {
+		f := models.AttributeFilter{Code: "aging", Values: []string{"12", "24"}}
+		if err := resolveAttributeFilter(aging, &f); err != nil {
+			t.Fatalf("resolveAttributeFilter() error = %v", err)
+		}
+		if len(f.Numbers) != 2 || f.Numbers[0] != 12 || f.Numbers[1] != 24 {
+			t.Errorf("Numbers = %v, want [12 24]", f.Numbers)
+		}
+	})
+
+	t.Run("boolean", func(t *testing.T) {
+		f := models.AttributeFilter{Code: "organic", Values: []string{"true"}}
+		if err := resolveAttributeFilter(organic, &f); err != nil {
+			t.Fatalf("resolveAttributeFilter() error = %v", err)
+		}
+		if f.Bool == nil || !*f.Bool {
+			t.Errorf("Bool = %v, want true", f.Bool)
+		}
+	})
+
+	errorTests := []struct {
+		name      string
+		attribute *models.ProductAttribute
+		filter    models.AttributeFilter
+	}{
+		{name: "unknown enum value", attribute: milk, filter: models.AttributeFilter{Values: []string{"camel"}}},
+		{name: "range on enum", attribute: milk, filter: models.AttributeFilter{Min: &twelve}},
+		{name: "no value", attribute: milk, filter: models.AttributeFilter{}},
+		{name: "number not parsed", attribute: aging, filter: models.AttributeFilter{Values: []string{"old"}}},
+		{name: "min above max", attribute: aging, filter: models.AttributeFilter{Min: &twelve, Max: &six}},
+		{name: "several booleans", attribute: organic, filter: models.AttributeFilter{Values: []string{"true", "false"}}},
+		{name: "boolean not parsed", attribute: organic, filter: models.AttributeFilter{Values: []string{"yes please"}}},
+	}
+
+	for _, tt := range errorTests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := resolveAttributeFilter(tt.attribute, &tt.filter); err == nil {
+				t.Error("resolveAttributeFilter() error = nil, want an error")
+			}
+		})
+	}
+}
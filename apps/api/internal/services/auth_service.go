@@ -68,6 +68,10 @@ func (s *AuthService) Register(email, password string) (*models.User, error) {
 }
 
 func (s *AuthService) validateEmail(email string) error {
+	return validateEmailAddress(email)
+}
+
+func validateEmailAddress(email string) error {
 	email = strings.TrimSpace(email)
 	if email == "" {
 		return errors.New("email is required")
@@ -225,6 +229,14 @@ func (s *AuthService) GetUserByID(userID int) (*models.User, error) {
 	return s.userRepo.GetUserByID(userID)
 }
 
+// SetLocale sets the locale emails are sent to a user in
+func (s *AuthService) SetLocale(userID int, locale string) error {
+	if !SupportedLocale(locale) {
+		return errors.New("unsupported locale")
+	}
+	return s.userRepo.SetLocale(userID, locale)
+}
+
 func (s *AuthService) Logout(refreshToken string) error {
 	if refreshToken == "" {
 		return nil
@@ -265,6 +277,37 @@ func (s *AuthService) UpdateUserBlocked(userID int, blocked bool) error {
 	return s.userRepo.UpdateUserBlocked(userID, blocked)
 }
 
+// GetDietaryRestrictions returns the dietary exclusions saved by the user
+func (s *AuthService) GetDietaryRestrictions(userID int) ([]string, error) {
+	restrictions, err := s.userRepo.GetDietaryRestrictions(userID)
+	if err != nil {
+		return nil, err
+	}
+	if restrictions == nil {
+		restrictions = []string{}
+	}
+	return restrictions, nil
+}
+
+// SetDietaryRestrictions saves the dietary exclusions of the user, which
+// recommendations and the AI assistant respect
+func (s *AuthService) SetDietaryRestrictions(userID int, restrictions []string) ([]string, error) {
+	if err := ValidateDietaryExclusions(restrictions); err != nil {
+		return nil, err
+	}
+
+	unique := []string{}
+	for _, r := range restrictions {
+		if !containsString(unique, r) {
+			unique = append(unique, r)
+		}
+	}
+	if err := s.userRepo.SetDietaryRestrictions(userID, unique); err != nil {
+		return nil, err
+	}
+	return unique, nil
+}
+
 func (s *AuthService) GenerateAccessToken(userID int) (string, error) {
 	claims := jwt.MapClaims{
 		"user_id": userID,
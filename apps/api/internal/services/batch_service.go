@@ -0,0 +1,289 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"gastroshop-api/internal/models"
+	"gastroshop-api/internal/repository"
+)
+
+const maxLotNumberLength = 100
+
+// Actions the batch scheduler takes on a batch nearing its best-before date
+const (
+	expiryNone     = ""
+	expiryDiscount = "discount"
+	expiryWriteOff = "write_off"
+)
+
+// ExpiryPolicy says what happens to batches nearing their best-before date.
+// Batches of products without variants are put on sale at DiscountPercent
+// off once they are DiscountDays or fewer from their best-before date, zero
+// days turning discounts off. Expired batches are written off when WriteOff
+// is set.
+type ExpiryPolicy struct {
+	DiscountDays    int
+	DiscountPercent int
+	WriteOff        bool
+}
+
+// BatchService receives stock in batches, reports the batches expiring
+// soon, traces lots to the orders sold with them and discounts or writes off
+// batches nearing their best-before date
+type BatchService struct {
+	batchRepo        *repository.BatchRepository
+	productRepo      *repository.ProductRepository
+	inventoryService *InventoryService
+	saleService      *SaleService
+	policy           ExpiryPolicy
+}
+
+func NewBatchService(batchRepo *repository.BatchRepository, productRepo *repository.ProductRepository, inventoryService *InventoryService, saleService *SaleService) *BatchService {
+	return &BatchService{
+		batchRepo:        batchRepo,
+		productRepo:      productRepo,
+		inventoryService: inventoryService,
+		saleService:      saleService,
+		policy:           ExpiryPolicy{DiscountDays: 3, DiscountPercent: 30, WriteOff: true},
+	}
+}
+
+// SetExpiryPolicy sets what happens to batches nearing their best-before date
+func (s *BatchService) SetExpiryPolicy(policy ExpiryPolicy) {
+	s.policy = policy
+}
+
+// GetBatches returns a page of batches, expiring first, with the total of
+// batches matching the filters
+func (s *BatchService) GetBatches(filters map[string]interface{}) ([]models.StockBatch, int, error) {
+	return s.batchRepo.GetBatches(filters)
+}
+
+// GetExpiringBatches returns a page of the batches with stock left that
+// expire within days from today, or have expired, with their total
+func (s *BatchService) GetExpiringBatches(days int, filters map[string]interface{}) ([]models.StockBatch, int, error) {
+	if days < 0 || days > 365 {
+		return nil, 0, errors.New("days must be between 0 and 365")
+	}
+	filters["best_before_until"] = batchDate(time.Now()).AddDate(0, 0, days)
+	filters["in_stock"] = true
+	return s.batchRepo.GetBatches(filters)
+}
+
+func (s *BatchService) GetBatch(id int) (*models.StockBatch, error) {
+	batch, err := s.batchRepo.GetBatchByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if batch == nil {
+		return nil, errors.New("batch not found")
+	}
+	return batch, nil
+}
+
+// ReceiveBatch receives a batch of a product or variant at a location, the
+// default location unless one is given, adding it to the stock there
+func (s *BatchService) ReceiveBatch(actorID int, req *models.CreateStockBatchRequest) (*models.StockBatch, error) {
+	producedOn, err := parseBatchDate(req.ProducedOn, "produced_on")
+	if err != nil {
+		return nil, err
+	}
+	bestBefore, err := parseBatchDate(req.BestBefore, "best_before")
+	if err != nil {
+		return nil, err
+	}
+
+	batch := &models.StockBatch{
+		ProductID:        req.ProductID,
+		VariantID:        req.VariantID,
+		LotNumber:        strings.TrimSpace(req.LotNumber),
+		ProducedOn:       producedOn,
+		BestBefore:       bestBefore,
+		ReceivedQuantity: req.Quantity,
+	}
+	if err := validateBatch(batch); err != nil {
+		return nil, err
+	}
+
+	location, err := s.inventoryService.getLocation(req.LocationID)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.inventoryService.getStock(req.ProductID, req.VariantID); err != nil {
+		return nil, err
+	}
+
+	change := models.StockChange{
+		Reason:     models.MovementReceipt,
+		ActorID:    &actorID,
+		Note:       strings.TrimSpace(req.Note),
+		LocationID: location.ID,
+	}
+	if _, err := s.batchRepo.CreateBatch(batch, change); err != nil {
+		return nil, err
+	}
+	return s.GetBatch(batch.ID)
+}
+
+// parseBatchDate parses an optional YYYY-MM-DD date of a batch
+func parseBatchDate(value, field string) (*time.Time, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return nil, nil
+	}
+	date, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return nil, fmt.Errorf("%s must be a date in YYYY-MM-DD format", field)
+	}
+	return &date, nil
+}
+
+// validateBatch checks a batch before it is received
+func validateBatch(b *models.StockBatch) error {
+	if b.LotNumber == "" {
+		return errors.New("lot number is required")
+	}
+	if len(b.LotNumber) > maxLotNumberLength {
+		return fmt.Errorf("lot number must be at most %d characters", maxLotNumberLength)
+	}
+	if b.ReceivedQuantity <= 0 {
+		return errors.New("quantity must be positive")
+	}
+	if b.ProducedOn != nil && b.BestBefore != nil && b.BestBefore.Before(*b.ProducedOn) {
+		return errors.New("best_before must not be before produced_on")
+	}
+	return nil
+}
+
+// WriteOffBatch writes off the stock left of a batch
+func (s *BatchService) WriteOffBatch(actorID, id int, note string) (*models.InventoryMovement, error) {
+	batch, err := s.GetBatch(id)
+	if err != nil {
+		return nil, err
+	}
+	if batch.Quantity == 0 {
+		return nil, errors.New("batch has no stock left")
+	}
+
+	change := models.StockChange{Reason: models.MovementWriteOff, ActorID: &actorID, Note: strings.TrimSpace(note)}
+	return s.batchRepo.WriteOffBatch(id, change)
+}
+
+// GetLotOrders returns the orders sold with stock of the lot of a batch, for
+// recalls
+func (s *BatchService) GetLotOrders(id int) ([]models.BatchOrder, error) {
+	if _, err := s.GetBatch(id); err != nil {
+		return nil, err
+	}
+	return s.batchRepo.GetLotOrders(id)
+}
+
+// StartScheduler periodically discounts and writes off batches nearing or
+// past their best-before date
+func (s *BatchService) StartScheduler(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			if err := s.ProcessExpiringBatches(time.Now()); err != nil {
+				log.Printf("Batch scheduler error: %v", err)
+			}
+		}
+	}()
+}
+
+// ProcessExpiringBatches applies the expiry policy to the batches with stock
+// left: expired batches are written off and batches close to expiry put on
+// sale until the end of their best-before date
+func (s *BatchService) ProcessExpiringBatches(now time.Time) error {
+	today := batchDate(now)
+	batches, err := s.batchRepo.GetExpiringBatches(today.AddDate(0, 0, s.policy.DiscountDays))
+	if err != nil {
+		return err
+	}
+
+	for i := range batches {
+		b := &batches[i]
+		switch expiryAction(b, today, s.policy) {
+		case expiryWriteOff:
+			change := models.StockChange{Reason: models.MovementWriteOff, Note: "expired"}
+			if _, err := s.batchRepo.WriteOffBatch(b.ID, change); err != nil {
+				log.Printf("Failed to write off batch %d: %v", b.ID, err)
+			}
+		case expiryDiscount:
+			s.discountBatch(b, now)
+		}
+	}
+	return nil
+}
+
+// discountBatch puts the product of a batch on sale until the end of the
+// batch's best-before date (UTC), or until the batch is sold out or written
+// off. The batch is marked discounted even when the
+// sale can't be started, e.g. when the product is on sale already, so that
+// it isn't tried again.
+func (s *BatchService) discountBatch(b *models.StockBatch, now time.Time) {
+	var saleID *int
+	product, err := s.productRepo.GetProductByID(b.ProductID)
+	if err == nil && product != nil {
+		req := &models.CreateProductSaleRequest{
+			SalePriceCents: discountPrice(regularPrice(product), s.policy.DiscountPercent),
+			StartsAt:       now,
+			EndsAt:         batchDate(*b.BestBefore).AddDate(0, 0, 1),
+		}
+		var sale *models.ProductSale
+		sale, err = s.saleService.CreateSale(b.ProductID, req)
+		if err == nil {
+			saleID = &sale.ID
+		}
+	}
+	if err != nil {
+		log.Printf("Failed to discount batch %d: %v", b.ID, err)
+	}
+
+	if err := s.batchRepo.SetBatchDiscounted(b.ID, saleID); err != nil {
+		log.Printf("Failed to mark batch %d discounted: %v", b.ID, err)
+	}
+}
+
+// expiryAction returns what the expiry policy does with a batch today:
+// write off expired batches, discount batches of products without variants
+// close to expiry once, or nothing
+func expiryAction(b *models.StockBatch, today time.Time, policy ExpiryPolicy) string {
+	if b.BestBefore == nil || b.Quantity == 0 {
+		return expiryNone
+	}
+	if b.BestBefore.Before(today) {
+		if policy.WriteOff {
+			return expiryWriteOff
+		}
+		return expiryNone
+	}
+	if policy.DiscountDays <= 0 || policy.DiscountPercent <= 0 || policy.DiscountPercent >= 100 || b.DiscountedAt != nil || b.VariantID != nil {
+		return expiryNone
+	}
+	if b.BestBefore.After(today.AddDate(0, 0, policy.DiscountDays)) {
+		return expiryNone
+	}
+	return expiryDiscount
+}
+
+// discountPrice returns a price lowered by percent, rounded down to whole
+// cents and at least one cent
+func discountPrice(priceCents, percent int) int {
+	discounted := priceCents * (100 - percent) / 100
+	if discounted < 1 {
+		return 1
+	}
+	return discounted
+}
+
+// batchDate returns the date of t as batch dates are stored: midnight UTC
+func batchDate(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
@@ -0,0 +1,104 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"gastroshop-api/internal/models"
+)
+
+func TestExpiryAction(t *testing.T) {
+	today := time.Date(2024, 3, 10, 0, 0, 0, 0, time.UTC)
+	policy := ExpiryPolicy{DiscountDays: 3, DiscountPercent: 30, WriteOff: true}
+	date := func(day int) *time.Time {
+		d := time.Date(2024, 3, day, 0, 0, 0, 0, time.UTC)
+		return &d
+	}
+	variantID := 7
+
+	tests := []struct {
+		name   string
+		batch  models.StockBatch
+		policy ExpiryPolicy
+		want   string
+	}{
+		{name: "no best-before date", batch: models.StockBatch{Quantity: 5}, policy: policy, want: expiryNone},
+		{name: "far from expiry", batch: models.StockBatch{Quantity: 5, BestBefore: date(20)}, policy: policy, want: expiryNone},
+		{name: "close to expiry", batch: models.StockBatch{Quantity: 5, BestBefore: date(13)}, policy: policy, want: expiryDiscount},
+		{name: "expires today", batch: models.StockBatch{Quantity: 5, BestBefore: date(10)}, policy: policy, want: expiryDiscount},
+		{name: "discounted already", batch: models.StockBatch{Quantity: 5, BestBefore: date(12), DiscountedAt: date(9)}, policy: policy, want: expiryNone},
+		{name: "variant batch", batch: models.StockBatch{Quantity: 5, BestBefore: date(12), VariantID: &variantID}, policy: policy, want: expiryNone},
+		{name: "discounts off", batch: models.StockBatch{Quantity: 5, BestBefore: date(12)}, policy: ExpiryPolicy{WriteOff: true}, want: expiryNone},
+		{name: "expired", batch: models.StockBatch{Quantity: 5, BestBefore: date(9)}, policy: policy, want: expiryWriteOff},
+		{name: "expired, write-off off", batch: models.StockBatch{Quantity: 5, BestBefore: date(9)}, policy: ExpiryPolicy{DiscountDays: 3, DiscountPercent: 30}, want: expiryNone},
+		{name: "no stock left", batch: models.StockBatch{BestBefore: date(9)}, policy: policy, want: expiryNone},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := expiryAction(&tt.batch, today, tt.policy); got != tt.want {
+				t.Errorf("expiryAction() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDiscountPrice(t *testing.T) {
+	tests := []struct {
+		price   int
+		percent int
+		want    int
+	}{
+		{price: 1000, percent: 30, want: 700},
+		{price: 999, percent: 30, want: 699},
+		{price: 1, percent: 50, want: 1},
+	}
+
+	for _, tt := range tests {
+		if got := discountPrice(tt.price, tt.percent); got != tt.want {
+			t.Errorf("discountPrice(%d, %d) = %d, want %d", tt.price, tt.percent, got, tt.want)
+		}
+	}
+}
+
+func TestValidateBatch(t *testing.T) {
+	produced := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	before := time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)
+	after := time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		batch   models.StockBatch
+		wantErr bool
+	}{
+		{name: "valid", batch: models.StockBatch{LotNumber: "L-1", ReceivedQuantity: 10, ProducedOn: &produced, BestBefore: &after}},
+		{name: "without dates", batch: models.StockBatch{LotNumber: "L-1", ReceivedQuantity: 10}},
+		{name: "no lot number", batch: models.StockBatch{ReceivedQuantity: 10}, wantErr: true},
+		{name: "no quantity", batch: models.StockBatch{LotNumber: "L-1"}, wantErr: true},
+		{name: "expires before production", batch: models.StockBatch{LotNumber: "L-1", ReceivedQuantity: 10, ProducedOn: &produced, BestBefore: &before}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateBatch(&tt.batch)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validateBatch() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestParseBatchDate(t *testing.T) {
+	date, err := parseBatchDate("2024-03-15", "best_before")
+	if err != nil || date == nil || !date.Equal(time.Date(2024, 3, 15, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("parseBatchDate() = %v, %v", date, err)
+	}
+
+	if date, err := parseBatchDate("", "best_before"); err != nil || date != nil {
+		t.Errorf("parseBatchDate(\"\") = %v, %v, want nil", date, err)
+	}
+
+	if _, err := parseBatchDate("15.03.2024", "best_before"); err == nil {
+		t.Error("parseBatchDate() accepted a date not in YYYY-MM-DD format")
+	}
+}
@@ -0,0 +1,204 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+
+	"gastroshop-api/internal/models"
+	"gastroshop-api/internal/repository"
+)
+
+const maxBundleComponents = 20
+
+// BundleService makes products bundles of other products, e.g. gift boxes
+// and tasting sets. Bundle stock follows the stock of the components.
+type BundleService struct {
+	productRepo *repository.ProductRepository
+}
+
+func NewBundleService(productRepo *repository.ProductRepository) *BundleService {
+	return &BundleService{productRepo: productRepo}
+}
+
+func (s *BundleService) GetBundle(productID int) (*models.ProductBundle, error) {
+	if _, err := s.getProduct(productID); err != nil {
+		return nil, err
+	}
+
+	bundles, err := s.productRepo.GetBundlesByProductIDs([]int{productID})
+	if err != nil {
+		return nil, err
+	}
+	if bundles[productID] == nil {
+		return nil, errors.New("product is not a bundle")
+	}
+	return bundles[productID], nil
+}
+
+// SetBundle makes a product a bundle of the components in the request,
+// replacing the components of an existing bundle
+func (s *BundleService) SetBundle(productID int, req *models.SetProductBundleRequest) (*models.ProductBundle, error) {
+	product, err := s.getProduct(productID)
+	if err != nil {
+		return nil, err
+	}
+
+	inBundle, err := s.productRepo.IsBundleComponent(productID, nil)
+	if err != nil {
+		return nil, err
+	}
+	if inBundle {
+		return nil, errors.New("product is in a bundle and can't be a bundle itself")
+	}
+
+	ids := []int{productID}
+	components := make(map[int]*models.Product)
+	for _, c := range req.Components {
+		if _, ok := components[c.ProductID]; ok || c.ProductID == productID {
+			continue
+		}
+		p, err := s.productRepo.GetProductByID(c.ProductID)
+		if err != nil {
+			return nil, err
+		}
+		if p != nil {
+			components[c.ProductID] = p
+			ids = append(ids, c.ProductID)
+		}
+	}
+
+	variants, err := s.productRepo.GetVariantsByProductIDs(ids)
+	if err != nil {
+		return nil, err
+	}
+	bundles, err := s.productRepo.GetBundlesByProductIDs(ids)
+	if err != nil {
+		return nil, err
+	}
+
+	bundle := &models.ProductBundle{
+		Pricing:         req.Pricing,
+		DiscountPercent: req.DiscountPercent,
+		Components:      req.Components,
+	}
+	if err := validateBundle(product, bundle, components, variants, bundles); err != nil {
+		return nil, err
+	}
+
+	if err := s.productRepo.SetBundle(productID, bundle); err != nil {
+		return nil, err
+	}
+
+	bundles, err = s.productRepo.GetBundlesByProductIDs([]int{productID})
+	if err != nil {
+		return nil, err
+	}
+	return bundles[productID], nil
+}
+
+// DeleteBundle turns a bundle back into a regular product
+func (s *BundleService) DeleteBundle(productID int) error {
+	if _, err := s.getProduct(productID); err != nil {
+		return err
+	}
+	return s.productRepo.DeleteBundle(productID)
+}
+
+func (s *BundleService) getProduct(productID int) (*models.Product, error) {
+	product, err := s.productRepo.GetProductByID(productID)
+	if err != nil {
+		return nil, err
+	}
+	if product == nil {
+		return nil, errors.New("product not found")
+	}
+	return product, nil
+}
+
+// validateBundle checks that the product can be the bundle. components holds
+// the component products found, and variants and bundles the variants and
+// bundles of the product and its components keyed by product ID.
+func validateBundle(product *models.Product, bundle *models.ProductBundle, components map[int]*models.Product,
+	variants map[int][]models.ProductVariant, bundles map[int]*models.ProductBundle) error {
+	switch bundle.Pricing {
+	case models.BundlePricingFixed:
+		if bundle.DiscountPercent != 0 {
+			return errors.New("discount is only used with discount pricing")
+		}
+	case models.BundlePricingDiscount:
+		if bundle.DiscountPercent < 0 || bundle.DiscountPercent > 99 {
+			return errors.New("discount must be between 0 and 99 percent")
+		}
+		if product.CompareAtPriceCents != nil {
+			return errors.New("product is on sale; end the sale before switching to discount pricing")
+		}
+	default:
+		return errors.New("pricing must be fixed or discount")
+	}
+
+	if len(variants[product.ID]) > 0 {
+		return errors.New("products with variants can't be bundles")
+	}
+	if product.Unit != models.UnitPiece {
+		return errors.New("bundles must be sold by the piece")
+	}
+
+	if len(bundle.Components) == 0 {
+		return errors.New("bundle must have at least one component")
+	}
+	if len(bundle.Components) > maxBundleComponents {
+		return fmt.Errorf("bundle can have at most %d components", maxBundleComponents)
+	}
+
+	type line struct{ productID, variantID int }
+	seen := make(map[line]bool)
+	for _, c := range bundle.Components {
+		if c.ProductID == product.ID {
+			return errors.New("bundle can't contain itself")
+		}
+		if c.Quantity <= 0 {
+			return errors.New("component quantity must be positive")
+		}
+
+		p := components[c.ProductID]
+		if p == nil {
+			return fmt.Errorf("component product %d not found", c.ProductID)
+		}
+		if p.ArchivedAt != nil {
+			return fmt.Errorf("%s is archived", p.Title)
+		}
+		if bundles[c.ProductID] != nil {
+			return fmt.Errorf("%s is a bundle; bundles can't contain bundles", p.Title)
+		}
+		if p.Unit != models.UnitPiece {
+			return fmt.Errorf("%s is sold by weight; bundles can only contain piece goods", p.Title)
+		}
+
+		l := line{productID: c.ProductID}
+		if pv := variants[c.ProductID]; len(pv) > 0 {
+			if c.VariantID == nil {
+				return fmt.Errorf("%s has variants; choose one", p.Title)
+			}
+			found := false
+			for _, v := range pv {
+				if v.ID == *c.VariantID {
+					found = true
+					break
+				}
+			}
+			if !found {
+				return fmt.Errorf("variant %d of %s not found", *c.VariantID, p.Title)
+			}
+			l.variantID = *c.VariantID
+		} else if c.VariantID != nil {
+			return fmt.Errorf("%s has no variants", p.Title)
+		}
+
+		if seen[l] {
+			return fmt.Errorf("%s is listed more than once", p.Title)
+		}
+		seen[l] = true
+	}
+
+	return nil
+}
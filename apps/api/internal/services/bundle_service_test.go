@@ -0,0 +1,167 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"gastroshop-api/internal/models"
+)
+
+func TestValidateBundle(t *testing.T) {
+	intPtr := func(v int) *int { return &v }
+	archivedAt := time.Now()
+	salePrice := 1000
+
+	box := &models.Product{ID: 1, Title: "Подарочный набор", Unit: models.UnitPiece}
+	components := map[int]*models.Product{
+		2: {ID: 2, Title: "Комте", Unit: models.UnitPiece},
+		3: {ID: 3, Title: "Мёд", Unit: models.UnitPiece},
+		4: {ID: 4, Title: "Пармезан", Unit: models.UnitGram},
+		5: {ID: 5, Title: "Старый набор", Unit: models.UnitPiece, ArchivedAt: &archivedAt},
+		6: {ID: 6, Title: "Сырная тарелка", Unit: models.UnitPiece},
+	}
+	variants := map[int][]models.ProductVariant{
+		3: {{ID: 30, ProductID: 3}, {ID: 31, ProductID: 3}},
+	}
+	bundles := map[int]*models.ProductBundle{6: {Pricing: models.BundlePricingFixed}}
+
+	tests := []struct {
+		name    string
+		product *models.Product
+		bundle  models.ProductBundle
+		wantErr bool
+	}{
+		{
+			name:    "fixed",
+			product: box,
+			bundle: models.ProductBundle{Pricing: models.BundlePricingFixed, Components: []models.BundleComponent{
+				{ProductID: 2, Quantity: 2},
+				{ProductID: 3, VariantID: intPtr(30), Quantity: 1},
+				{ProductID: 3, VariantID: intPtr(31), Quantity: 1},
+			}},
+		},
+		{
+			name:    "discount",
+			product: box,
+			bundle: models.ProductBundle{Pricing: models.BundlePricingDiscount, DiscountPercent: 15, Components: []models.BundleComponent{
+				{ProductID: 2, Quantity: 1},
+			}},
+		},
+		{
+			name:    "unknown pricing",
+			product: box,
+			bundle:  models.ProductBundle{Pricing: "free", Components: []models.BundleComponent{{ProductID: 2, Quantity: 1}}},
+			wantErr: true,
+		},
+		{
+			name:    "discount with fixed pricing",
+			product: box,
+			bundle:  models.ProductBundle{Pricing: models.BundlePricingFixed, DiscountPercent: 10, Components: []models.BundleComponent{{ProductID: 2, Quantity: 1}}},
+			wantErr: true,
+		},
+		{
+			name:    "full discount",
+			product: box,
+			bundle:  models.ProductBundle{Pricing: models.BundlePricingDiscount, DiscountPercent: 100, Components: []models.BundleComponent{{ProductID: 2, Quantity: 1}}},
+			wantErr: true,
+		},
+		{
+			name:    "discount pricing while on sale",
+			product: &models.Product{ID: 1, Unit: models.UnitPiece, CompareAtPriceCents: &salePrice},
+			bundle:  models.ProductBundle{Pricing: models.BundlePricingDiscount, Components: []models.BundleComponent{{ProductID: 2, Quantity: 1}}},
+			wantErr: true,
+		},
+		{
+			name:    "sold by weight",
+			product: &models.Product{ID: 1, Unit: models.UnitKilogram},
+			bundle:  models.ProductBundle{Pricing: models.BundlePricingFixed, Components: []models.BundleComponent{{ProductID: 2, Quantity: 1}}},
+			wantErr: true,
+		},
+		{
+			name:    "no components",
+			product: box,
+			bundle:  models.ProductBundle{Pricing: models.BundlePricingFixed},
+			wantErr: true,
+		},
+		{
+			name:    "itself",
+			product: box,
+			bundle:  models.ProductBundle{Pricing: models.BundlePricingFixed, Components: []models.BundleComponent{{ProductID: 1, Quantity: 1}}},
+			wantErr: true,
+		},
+		{
+			name:    "zero quantity",
+			product: box,
+			bundle:  models.ProductBundle{Pricing: models.BundlePricingFixed, Components: []models.BundleComponent{{ProductID: 2}}},
+			wantErr: true,
+		},
+		{
+			name:    "unknown component",
+			product: box,
+			bundle:  models.ProductBundle{Pricing: models.BundlePricingFixed, Components: []models.BundleComponent{{ProductID: 9, Quantity: 1}}},
+			wantErr: true,
+		},
+		{
+			name:    "weighed component",
+			product: box,
+			bundle:  models.ProductBundle{Pricing: models.BundlePricingFixed, Components: []models.BundleComponent{{ProductID: 4, Quantity: 1}}},
+			wantErr: true,
+		},
+		{
+			name:    "archived component",
+			product: box,
+			bundle:  models.ProductBundle{Pricing: models.BundlePricingFixed, Components: []models.BundleComponent{{ProductID: 5, Quantity: 1}}},
+			wantErr: true,
+		},
+		{
+			name:    "nested bundle",
+			product: box,
+			bundle:  models.ProductBundle{Pricing: models.BundlePricingFixed, Components: []models.BundleComponent{{ProductID: 6, Quantity: 1}}},
+			wantErr: true,
+		},
+		{
+			name:    "variant missing",
+			product: box,
+			bundle:  models.ProductBundle{Pricing: models.BundlePricingFixed, Components: []models.BundleComponent{{ProductID: 3, Quantity: 1}}},
+			wantErr: true,
+		},
+		{
+			name:    "variant of another product",
+			product: box,
+			bundle:  models.ProductBundle{Pricing: models.BundlePricingFixed, Components: []models.BundleComponent{{ProductID: 3, VariantID: intPtr(40), Quantity: 1}}},
+			wantErr: true,
+		},
+		{
+			name:    "variant of product without variants",
+			product: box,
+			bundle:  models.ProductBundle{Pricing: models.BundlePricingFixed, Components: []models.BundleComponent{{ProductID: 2, VariantID: intPtr(30), Quantity: 1}}},
+			wantErr: true,
+		},
+		{
+			name:    "duplicate component",
+			product: box,
+			bundle: models.ProductBundle{Pricing: models.BundlePricingFixed, Components: []models.BundleComponent{
+				{ProductID: 2, Quantity: 1},
+				{ProductID: 2, Quantity: 2},
+			}},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateBundle(tt.product, &tt.bundle, components, variants, bundles)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validateBundle() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	t.Run("bundle with variants", func(t *testing.T) {
+		bundle := models.ProductBundle{Pricing: models.BundlePricingFixed, Components: []models.BundleComponent{{ProductID: 2, Quantity: 1}}}
+		withVariants := map[int][]models.ProductVariant{1: {{ID: 10, ProductID: 1}}}
+		if err := validateBundle(box, &bundle, components, withVariants, bundles); err == nil {
+			t.Error("validateBundle() accepted a product with variants")
+		}
+	})
+}
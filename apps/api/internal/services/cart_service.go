@@ -0,0 +1,113 @@
+package services
+
+import (
+	"errors"
+
+	"gastroshop-api/internal/models"
+	"gastroshop-api/internal/repository"
+)
+
+type CartService struct {
+	cartRepo    *repository.CartRepository
+	productRepo *repository.ProductRepository
+}
+
+func NewCartService(cartRepo *repository.CartRepository, productRepo *repository.ProductRepository) *CartService {
+	return &CartService{
+		cartRepo:    cartRepo,
+		productRepo: productRepo,
+	}
+}
+
+// GetCart returns the cart with current products, variants and prices
+func (s *CartService) GetCart(userID int) (*models.Cart, error) {
+	items, err := s.cartRepo.GetCartItems(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	productIDs := make([]int, 0, len(items))
+	for _, item := range items {
+		productIDs = append(productIDs, item.ProductID)
+	}
+	variants, err := s.productRepo.GetVariantsByProductIDs(productIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	cart := &models.Cart{Items: make([]models.CartItem, 0, len(items))}
+	for _, item := range items {
+		product, err := s.productRepo.GetProductByID(item.ProductID)
+		if err != nil {
+			return nil, err
+		}
+		// Lines of deleted or archived products can't be ordered any more
+		if product == nil || product.ArchivedAt != nil {
+			continue
+		}
+		item.Product = product
+		item.PriceCents = product.PriceCents
+
+		if item.VariantID != nil {
+			for _, v := range variants[product.ID] {
+				if v.ID == *item.VariantID {
+					variant := v
+					item.Variant = &variant
+					item.PriceCents = variant.PriceCents
+				}
+			}
+			if item.Variant == nil {
+				continue
+			}
+		}
+
+		cart.Items = append(cart.Items, item)
+		cart.TotalCents += lineAmountCents(item.PriceCents, item.Quantity, product.Unit)
+	}
+
+	return cart, nil
+}
+
+// AddItem adds a product or variant to the cart. Products with variants can
+// only be added as one of their variants.
+func (s *CartService) AddItem(userID int, req *models.AddToCartRequest) (*models.Cart, error) {
+	if req.Quantity <= 0 {
+		return nil, errors.New("quantity must be positive")
+	}
+
+	line := models.OrderItem{ProductID: req.ProductID, VariantID: req.VariantID}
+	product, _, available, err := resolveOrderLine(s.productRepo, &line)
+	if err != nil {
+		return nil, err
+	}
+	if product.ArchivedAt != nil {
+		return nil, errors.New("product is no longer available")
+	}
+
+	inCart, err := s.cartRepo.GetCartItemQuantity(userID, req.ProductID, req.VariantID)
+	if err != nil {
+		return nil, err
+	}
+	if err := validateOrderQuantity(product, inCart+req.Quantity); err != nil {
+		return nil, err
+	}
+	if available < inCart+req.Quantity {
+		return nil, errors.New("product out of stock or insufficient quantity")
+	}
+
+	if _, err := s.cartRepo.AddCartItem(userID, req.ProductID, req.VariantID, req.Quantity); err != nil {
+		return nil, err
+	}
+	return s.GetCart(userID)
+}
+
+func (s *CartService) RemoveItem(userID, productID int, variantID *int) (*models.Cart, error) {
+	removed, err := s.cartRepo.RemoveCartItem(userID, productID, variantID)
+	if err != nil {
+		return nil, err
+	}
+	if !removed {
+		return nil, errors.New("item not found in cart")
+	}
+	return s.GetCart(userID)
+}
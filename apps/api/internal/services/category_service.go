@@ -0,0 +1,227 @@
+package services
+
+import (
+	"errors"
+	"regexp"
+
+	"gastroshop-api/internal/models"
+	"gastroshop-api/internal/repository"
+)
+
+// slugPattern is the format of category and imported product slugs
+var slugPattern = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+
+type CategoryService struct {
+	categoryRepo *repository.CategoryRepository
+	productRepo  *repository.ProductRepository
+}
+
+func NewCategoryService(categoryRepo *repository.CategoryRepository, productRepo *repository.ProductRepository) *CategoryService {
+	return &CategoryService{
+		categoryRepo: categoryRepo,
+		productRepo:  productRepo,
+	}
+}
+
+// GetCategoryTree returns the top-level categories with their children nested
+func (s *CategoryService) GetCategoryTree() ([]models.Category, error) {
+	categories, err := s.categoryRepo.GetCategories()
+	if err != nil {
+		return nil, err
+	}
+	return buildCategoryTree(categories), nil
+}
+
+// GetCategories returns all categories as a flat list
+func (s *CategoryService) GetCategories() ([]models.Category, error) {
+	return s.categoryRepo.GetCategories()
+}
+
+func (s *CategoryService) CreateCategory(req *models.CreateCategoryRequest) (*models.Category, error) {
+	category := &models.Category{
+		ParentID:    req.ParentID,
+		Slug:        req.Slug,
+		Name:        req.Name,
+		Description: req.Description,
+		ImageURL:    req.ImageURL,
+		SortOrder:   req.SortOrder,
+	}
+	translations, err := MergeTranslations(nil, req.Translations)
+	if err != nil {
+		return nil, err
+	}
+	category.Translations = translations
+	if err := s.validateCategory(category); err != nil {
+		return nil, err
+	}
+
+	if err := s.categoryRepo.CreateCategory(category); err != nil {
+		return nil, err
+	}
+	return category, nil
+}
+
+func (s *CategoryService) UpdateCategory(id int, req *models.UpdateCategoryRequest) (*models.Category, error) {
+	category, err := s.categoryRepo.GetCategoryByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if category == nil {
+		return nil, errors.New("category not found")
+	}
+
+	if req.ParentID != nil {
+		category.ParentID = req.ParentID
+		if *req.ParentID == 0 {
+			category.ParentID = nil
+		}
+	}
+	if req.Slug != nil {
+		category.Slug = *req.Slug
+	}
+	if req.Name != nil {
+		category.Name = *req.Name
+	}
+	if req.Description != nil {
+		category.Description = *req.Description
+	}
+	if req.ImageURL != nil {
+		category.ImageURL = *req.ImageURL
+	}
+	if req.SortOrder != nil {
+		category.SortOrder = *req.SortOrder
+	}
+	if req.Translations != nil {
+		if category.Translations, err = MergeTranslations(category.Translations, req.Translations); err != nil {
+			return nil, err
+		}
+	}
+	if err := s.validateCategory(category); err != nil {
+		return nil, err
+	}
+
+	if err := s.categoryRepo.UpdateCategory(id, category); err != nil {
+		return nil, err
+	}
+	return category, nil
+}
+
+// DeleteCategory deletes a category without subcategories; its product
+// assignments are removed with it
+func (s *CategoryService) DeleteCategory(id int) error {
+	hasChildren, err := s.categoryRepo.HasChildren(id)
+	if err != nil {
+		return err
+	}
+	if hasChildren {
+		return errors.New("category has subcategories")
+	}
+	return s.categoryRepo.DeleteCategory(id)
+}
+
+func (s *CategoryService) GetProductCategories(productID int) ([]models.Category, error) {
+	return s.categoryRepo.GetProductCategories(productID)
+}
+
+func (s *CategoryService) SetProductCategories(productID int, categoryIDs []int) ([]models.Category, error) {
+	product, err := s.productRepo.GetProductByID(productID)
+	if err != nil {
+		return nil, err
+	}
+	if product == nil {
+		return nil, errors.New("product not found")
+	}
+
+	for _, id := range categoryIDs {
+		category, err := s.categoryRepo.GetCategoryByID(id)
+		if err != nil {
+			return nil, err
+		}
+		if category == nil {
+			return nil, errors.New("category not found")
+		}
+	}
+
+	if err := s.categoryRepo.SetProductCategories(productID, categoryIDs); err != nil {
+		return nil, err
+	}
+	return s.categoryRepo.GetProductCategories(productID)
+}
+
+func (s *CategoryService) validateCategory(category *models.Category) error {
+	if !slugPattern.MatchString(category.Slug) {
+		return errors.New("slug must contain only lowercase letters, digits and hyphens")
+	}
+	if category.Name == "" {
+		return errors.New("name is required")
+	}
+
+	existing, err := s.categoryRepo.GetCategoryBySlug(category.Slug)
+	if err != nil {
+		return err
+	}
+	if existing != nil && existing.ID != category.ID {
+		return errors.New("category with this slug already exists")
+	}
+
+	if category.ParentID == nil {
+		return nil
+	}
+	categories, err := s.categoryRepo.GetCategories()
+	if err != nil {
+		return err
+	}
+	return validateCategoryParent(categories, category.ID, *category.ParentID)
+}
+
+// validateCategoryParent checks that parentID exists and that making it the
+// parent of the category wouldn't create a cycle. categoryID is 0 for a new
+// category.
+func validateCategoryParent(categories []models.Category, categoryID, parentID int) error {
+	parents := make(map[int]*int, len(categories))
+	for _, c := range categories {
+		parents[c.ID] = c.ParentID
+	}
+
+	if _, ok := parents[parentID]; !ok {
+		return errors.New("parent category not found")
+	}
+
+	// Walk up from the new parent; meeting the category means it would
+	// become its own ancestor
+	for id := &parentID; id != nil; id = parents[*id] {
+		if *id == categoryID {
+			return errors.New("category can't be moved under itself or its subcategory")
+		}
+	}
+	return nil
+}
+
+// buildCategoryTree nests a flat category list by parent, keeping the order
+// of the list within each level. Categories whose parent is missing from the
+// list are treated as top-level.
+func buildCategoryTree(categories []models.Category) []models.Category {
+	known := make(map[int]bool, len(categories))
+	for _, c := range categories {
+		known[c.ID] = true
+	}
+
+	children := make(map[int][]models.Category)
+	var roots []models.Category
+	for _, c := range categories {
+		if c.ParentID != nil && known[*c.ParentID] {
+			children[*c.ParentID] = append(children[*c.ParentID], c)
+		} else {
+			roots = append(roots, c)
+		}
+	}
+
+	var attach func(nodes []models.Category) []models.Category
+	attach = func(nodes []models.Category) []models.Category {
+		for i := range nodes {
+			nodes[i].Children = attach(children[nodes[i].ID])
+		}
+		return nodes
+	}
+	return attach(roots)
+}
@@ -0,0 +1,72 @@
+package services
+
+import (
+	"reflect"
+	"testing"
+
+	"gastroshop-api/internal/models"
+)
+
+func categoryParent(id int) *int {
+	return &id
+}
+
+func TestBuildCategoryTree(t *testing.T) {
+	categories := []models.Category{
+		{ID: 1, Slug: "cheese"},
+		{ID: 2, Slug: "deli"},
+		{ID: 3, Slug: "soft-cheese", ParentID: categoryParent(1)},
+		{ID: 4, Slug: "brie", ParentID: categoryParent(3)},
+		{ID: 5, Slug: "hard-cheese", ParentID: categoryParent(1)},
+		{ID: 6, Slug: "orphan", ParentID: categoryParent(99)},
+	}
+
+	tree := buildCategoryTree(categories)
+
+	var slugs func(nodes []models.Category) []string
+	slugs = func(nodes []models.Category) []string {
+		var out []string
+		for _, n := range nodes {
+			out = append(out, n.Slug)
+			for _, s := range slugs(n.Children) {
+				out = append(out, n.Slug+"/"+s)
+			}
+		}
+		return out
+	}
+
+	want := []string{"cheese", "cheese/soft-cheese", "cheese/soft-cheese/brie", "cheese/hard-cheese", "deli", "orphan"}
+	if got := slugs(tree); !reflect.DeepEqual(got, want) {
+		t.Errorf("buildCategoryTree() = %v, want %v", got, want)
+	}
+}
+
+func TestValidateCategoryParent(t *testing.T) {
+	categories := []models.Category{
+		{ID: 1},
+		{ID: 2, ParentID: categoryParent(1)},
+		{ID: 3, ParentID: categoryParent(2)},
+	}
+
+	tests := []struct {
+		name       string
+		categoryID int
+		parentID   int
+		wantErr    bool
+	}{
+		{name: "new category", categoryID: 0, parentID: 3},
+		{name: "move to sibling branch", categoryID: 3, parentID: 1},
+		{name: "itself", categoryID: 2, parentID: 2, wantErr: true},
+		{name: "own descendant", categoryID: 1, parentID: 3, wantErr: true},
+		{name: "missing parent", categoryID: 0, parentID: 42, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateCategoryParent(categories, tt.categoryID, tt.parentID)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validateCategoryParent() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,114 @@
+package services
+
+import (
+	"errors"
+	"time"
+
+	"gastroshop-api/internal/models"
+	"gastroshop-api/internal/repository"
+)
+
+type DeliveryService struct {
+	deliveryRepo *repository.DeliveryRepository
+}
+
+func NewDeliveryService(deliveryRepo *repository.DeliveryRepository) *DeliveryService {
+	return &DeliveryService{deliveryRepo: deliveryRepo}
+}
+
+// GetAvailableSlots returns bookable slots for a zone on the given day
+func (s *DeliveryService) GetAvailableSlots(zone string, date time.Time) ([]models.DeliverySlot, error) {
+	return s.deliveryRepo.GetSlots(zone, date, true)
+}
+
+// Admin methods
+
+func (s *DeliveryService) GetSlots(zone string, date time.Time) ([]models.DeliverySlot, error) {
+	return s.deliveryRepo.GetSlots(zone, date, false)
+}
+
+func (s *DeliveryService) GetSlotByID(id int) (*models.DeliverySlot, error) {
+	return s.deliveryRepo.GetSlotByID(id)
+}
+
+func (s *DeliveryService) CreateSlot(req *models.CreateDeliverySlotRequest) (*models.DeliverySlot, error) {
+	slot := &models.DeliverySlot{
+		Zone:     req.Zone,
+		StartsAt: req.StartsAt,
+		EndsAt:   req.EndsAt,
+		Capacity: req.Capacity,
+		Active:   true,
+	}
+	if req.Active != nil {
+		slot.Active = *req.Active
+	}
+
+	if err := validateDeliverySlot(slot); err != nil {
+		return nil, err
+	}
+
+	if err := s.deliveryRepo.CreateSlot(slot); err != nil {
+		return nil, err
+	}
+
+	return slot, nil
+}
+
+func (s *DeliveryService) UpdateSlot(id int, req *models.UpdateDeliverySlotRequest) (*models.DeliverySlot, error) {
+	slot, err := s.deliveryRepo.GetSlotByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if slot == nil {
+		return nil, errors.New("delivery slot not found")
+	}
+
+	if req.Zone != nil {
+		slot.Zone = *req.Zone
+	}
+	if req.StartsAt != nil {
+		slot.StartsAt = *req.StartsAt
+	}
+	if req.EndsAt != nil {
+		slot.EndsAt = *req.EndsAt
+	}
+	if req.Capacity != nil {
+		slot.Capacity = *req.Capacity
+	}
+	if req.Active != nil {
+		slot.Active = *req.Active
+	}
+
+	if err := validateDeliverySlot(slot); err != nil {
+		return nil, err
+	}
+
+	// Capacity can't drop below places already booked by customers
+	updated, err := s.deliveryRepo.UpdateSlot(id, slot)
+	if err != nil {
+		return nil, err
+	}
+	if !updated {
+		return nil, errors.New("capacity cannot be less than reserved places")
+	}
+
+	slot.Available = slot.Capacity - slot.Reserved
+	return slot, nil
+}
+
+func (s *DeliveryService) DeleteSlot(id int) error {
+	return s.deliveryRepo.DeleteSlot(id)
+}
+
+func validateDeliverySlot(slot *models.DeliverySlot) error {
+	if slot.Zone == "" {
+		return errors.New("zone is required")
+	}
+	if !slot.EndsAt.After(slot.StartsAt) {
+		return errors.New("slot end must be after slot start")
+	}
+	if slot.Capacity < 0 {
+		return errors.New("capacity cannot be negative")
+	}
+	return nil
+}
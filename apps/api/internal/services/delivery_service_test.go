@@ -0,0 +1,47 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"gastroshop-api/internal/models"
+)
+
+func TestValidateDeliverySlot(t *testing.T) {
+	start := time.Date(2026, 10, 20, 10, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		slot    models.DeliverySlot
+		wantErr bool
+	}{
+		{
+			name: "valid slot",
+			slot: models.DeliverySlot{Zone: "center", StartsAt: start, EndsAt: start.Add(2 * time.Hour), Capacity: 5},
+		},
+		{
+			name:    "missing zone",
+			slot:    models.DeliverySlot{StartsAt: start, EndsAt: start.Add(2 * time.Hour), Capacity: 5},
+			wantErr: true,
+		},
+		{
+			name:    "end before start",
+			slot:    models.DeliverySlot{Zone: "center", StartsAt: start, EndsAt: start.Add(-time.Hour), Capacity: 5},
+			wantErr: true,
+		},
+		{
+			name:    "negative capacity",
+			slot:    models.DeliverySlot{Zone: "center", StartsAt: start, EndsAt: start.Add(time.Hour), Capacity: -1},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateDeliverySlot(&tt.slot)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validateDeliverySlot() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
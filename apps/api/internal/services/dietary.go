@@ -0,0 +1,140 @@
+package services
+
+import (
+	"fmt"
+
+	"gastroshop-api/internal/models"
+)
+
+// dietaryFlags lists the allergen and dietary flags of products
+var dietaryFlags = []string{
+	models.DietaryLactoseFree,
+	models.DietaryVegetarianRennet,
+	models.DietaryPasteurized,
+	models.DietaryRawMilk,
+	models.DietaryGluten,
+	models.DietaryGlutenFree,
+	models.DietaryNuts,
+	models.DietaryNutFree,
+}
+
+// dietaryConflicts lists the flags a product can't have together
+var dietaryConflicts = [][2]string{
+	{models.DietaryPasteurized, models.DietaryRawMilk},
+	{models.DietaryGluten, models.DietaryGlutenFree},
+	{models.DietaryNuts, models.DietaryNutFree},
+}
+
+// dietaryExclusions maps each dietary exclusion to the flag a product needs
+// and the flag it must not have. Every exclusion needs a flag, so products
+// without data are left out, e.g. only nut_free products pass "nuts".
+var dietaryExclusions = map[string]struct {
+	required  string
+	forbidden string
+}{
+	models.ExcludeLactose:      {required: models.DietaryLactoseFree},
+	models.ExcludeAnimalRennet: {required: models.DietaryVegetarianRennet},
+	models.ExcludeRawMilk:      {required: models.DietaryPasteurized, forbidden: models.DietaryRawMilk},
+	models.ExcludeGluten:       {required: models.DietaryGlutenFree, forbidden: models.DietaryGluten},
+	models.ExcludeNuts:         {required: models.DietaryNutFree, forbidden: models.DietaryNuts},
+}
+
+// ValidateDietaryFlags checks product flags against the known flags
+func ValidateDietaryFlags(flags []string) error {
+	seen := make(map[string]bool, len(flags))
+	for _, flag := range flags {
+		if !containsString(dietaryFlags, flag) {
+			return fmt.Errorf("unknown dietary flag %q", flag)
+		}
+		if seen[flag] {
+			return fmt.Errorf("duplicate dietary flag %q", flag)
+		}
+		seen[flag] = true
+	}
+	for _, pair := range dietaryConflicts {
+		if seen[pair[0]] && seen[pair[1]] {
+			return fmt.Errorf("a product can't be both %s and %s", pair[0], pair[1])
+		}
+	}
+	return nil
+}
+
+// ValidateDietaryExclusions checks product list exclusions and saved user
+// restrictions against the known exclusions
+func ValidateDietaryExclusions(exclusions []string) error {
+	for _, exclusion := range exclusions {
+		if _, ok := dietaryExclusions[exclusion]; !ok {
+			return fmt.Errorf("unknown dietary exclusion %q", exclusion)
+		}
+	}
+	return nil
+}
+
+// ApplyDietaryExclusions adds the product list filters for the exclusions
+func ApplyDietaryExclusions(filters map[string]interface{}, exclusions []string) {
+	required, forbidden := dietaryFilterFlags(exclusions)
+	if len(required) > 0 {
+		filters["dietary_required"] = required
+	}
+	if len(forbidden) > 0 {
+		filters["dietary_forbidden"] = forbidden
+	}
+}
+
+// dietaryFilterFlags returns the flags products need and the flags they
+// must not have to pass the exclusions; unknown exclusions are ignored
+func dietaryFilterFlags(exclusions []string) (required, forbidden []string) {
+	for _, exclusion := range exclusions {
+		rule, ok := dietaryExclusions[exclusion]
+		if !ok {
+			continue
+		}
+		if rule.required != "" && !containsString(required, rule.required) {
+			required = append(required, rule.required)
+		}
+		if rule.forbidden != "" && !containsString(forbidden, rule.forbidden) {
+			forbidden = append(forbidden, rule.forbidden)
+		}
+	}
+	return required, forbidden
+}
+
+// filterDietary returns the products that pass the exclusions, keeping
+// their order
+func filterDietary(products []models.Product, exclusions []string) []models.Product {
+	required, forbidden := dietaryFilterFlags(exclusions)
+	if len(required) == 0 && len(forbidden) == 0 {
+		return products
+	}
+
+	filtered := make([]models.Product, 0, len(products))
+	for _, p := range products {
+		if dietarySuitable(p.DietaryFlags, required, forbidden) {
+			filtered = append(filtered, p)
+		}
+	}
+	return filtered
+}
+
+func dietarySuitable(flags, required, forbidden []string) bool {
+	for _, flag := range required {
+		if !containsString(flags, flag) {
+			return false
+		}
+	}
+	for _, flag := range forbidden {
+		if containsString(flags, flag) {
+			return false
+		}
+	}
+	return true
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
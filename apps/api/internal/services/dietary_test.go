@@ -0,0 +1,109 @@
+package services
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"gastroshop-api/internal/models"
+)
+
+func TestValidateDietaryFlags(t *testing.T) {
+	tests := []struct {
+		name    string
+		flags   []string
+		wantErr bool
+	}{
+		{name: "none", flags: nil},
+		{name: "known flags", flags: []string{models.DietaryLactoseFree, models.DietaryPasteurized, models.DietaryNuts}},
+		{name: "unknown flag", flags: []string{"vegan"}, wantErr: true},
+		{name: "duplicate flag", flags: []string{models.DietaryGluten, models.DietaryGluten}, wantErr: true},
+		{name: "pasteurized and raw", flags: []string{models.DietaryPasteurized, models.DietaryRawMilk}, wantErr: true},
+		{name: "gluten and gluten free", flags: []string{models.DietaryGluten, models.DietaryGlutenFree}, wantErr: true},
+		{name: "nuts and nut free", flags: []string{models.DietaryNutFree, models.DietaryNuts}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateDietaryFlags(tt.flags)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateDietaryFlags() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateDietaryExclusions(t *testing.T) {
+	if err := ValidateDietaryExclusions([]string{models.ExcludeLactose, models.ExcludeNuts}); err != nil {
+		t.Errorf("ValidateDietaryExclusions() error = %v", err)
+	}
+	if err := ValidateDietaryExclusions([]string{models.DietaryLactoseFree}); err == nil {
+		t.Error("ValidateDietaryExclusions() accepted a product flag as exclusion")
+	}
+}
+
+func TestApplyDietaryExclusions(t *testing.T) {
+	filters := map[string]interface{}{}
+	ApplyDietaryExclusions(filters, []string{models.ExcludeLactose, models.ExcludeGluten, models.ExcludeNuts, models.ExcludeGluten})
+
+	wantRequired := []string{models.DietaryLactoseFree, models.DietaryGlutenFree, models.DietaryNutFree}
+	if got := filters["dietary_required"]; !reflect.DeepEqual(got, wantRequired) {
+		t.Errorf("dietary_required = %v, want %v", got, wantRequired)
+	}
+	if got := filters["dietary_forbidden"]; !reflect.DeepEqual(got, []string{models.DietaryGluten, models.DietaryNuts}) {
+		t.Errorf("dietary_forbidden = %v, want [gluten nuts]", got)
+	}
+
+	empty := map[string]interface{}{}
+	ApplyDietaryExclusions(empty, nil)
+	if len(empty) != 0 {
+		t.Errorf("filters without exclusions = %v, want none", empty)
+	}
+}
+
+func TestFilterDietary(t *testing.T) {
+	products := []models.Product{
+		{ID: 1, DietaryFlags: []string{models.DietaryLactoseFree, models.DietaryVegetarianRennet, models.DietaryPasteurized}},
+		{ID: 2, DietaryFlags: []string{models.DietaryRawMilk}},
+		{ID: 3, DietaryFlags: []string{models.DietaryLactoseFree, models.DietaryNuts}},
+		{ID: 4},
+		{ID: 5, DietaryFlags: []string{models.DietaryGlutenFree, models.DietaryNutFree, models.DietaryPasteurized}},
+	}
+
+	tests := []struct {
+		name       string
+		exclusions []string
+		want       []int
+	}{
+		{name: "no exclusions", exclusions: nil, want: []int{1, 2, 3, 4, 5}},
+		{name: "lactose needs the flag", exclusions: []string{models.ExcludeLactose}, want: []int{1, 3}},
+		{name: "raw milk needs pasteurized", exclusions: []string{models.ExcludeRawMilk}, want: []int{1, 5}},
+		{name: "gluten needs the flag", exclusions: []string{models.ExcludeGluten}, want: []int{5}},
+		{name: "nuts needs the flag", exclusions: []string{models.ExcludeNuts}, want: []int{5}},
+		{name: "animal rennet and lactose", exclusions: []string{models.ExcludeAnimalRennet, models.ExcludeLactose}, want: []int{1}},
+		{name: "animal rennet and nuts", exclusions: []string{models.ExcludeAnimalRennet, models.ExcludeNuts}, want: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []int
+			for _, p := range filterDietary(products, tt.exclusions) {
+				got = append(got, p.ID)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("filterDietary() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDietaryRestrictionsPrompt(t *testing.T) {
+	if got := dietaryRestrictionsPrompt(nil); got != "" {
+		t.Errorf("dietaryRestrictionsPrompt(nil) = %q, want empty", got)
+	}
+
+	got := dietaryRestrictionsPrompt([]string{models.ExcludeGluten, models.ExcludeNuts})
+	if !strings.Contains(got, "глютен, орехи") {
+		t.Errorf("dietaryRestrictionsPrompt() = %q, want the restrictions listed", got)
+	}
+}
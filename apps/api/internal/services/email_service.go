@@ -6,6 +6,12 @@ import (
 	"html/template"
 	"log"
 	"net/smtp"
+	"strings"
+	texttemplate "text/template"
+	"time"
+
+	"gastroshop-api/internal/models"
+	"gastroshop-api/internal/repository"
 )
 
 type EmailService struct {
@@ -15,6 +21,9 @@ type EmailService struct {
 	smtpPassword string
 	smtpFrom     string
 	baseURL      string
+	// Admins' templates, and the accounts whose locale emails are sent in
+	templateRepo *repository.EmailTemplateRepository
+	userRepo     *repository.UserRepository
 }
 
 type EmailConfig struct {
@@ -37,7 +46,19 @@ func NewEmailService(config EmailConfig) *EmailService {
 	}
 }
 
+// SetLocalization sends emails in the locale of the recipient's account,
+// using the admins' templates for that locale
+func (s *EmailService) SetLocalization(templateRepo *repository.EmailTemplateRepository, userRepo *repository.UserRepository) {
+	s.templateRepo = templateRepo
+	s.userRepo = userRepo
+}
+
 func (s *EmailService) SendEmail(to, subject, body string) error {
+	return s.sendEmail(to, subject, body, nil)
+}
+
+// sendEmail sends an HTML email with extra headers, e.g. List-Unsubscribe
+func (s *EmailService) sendEmail(to, subject, body string, extraHeaders map[string]string) error {
 	if s.smtpHost == "" || s.smtpPort == "" {
 		log.Printf("SMTP not configured, skipping email to %s: %s", to, subject)
 		return nil // Don't fail if SMTP is not configured (for development)
@@ -56,6 +77,9 @@ func (s *EmailService) SendEmail(to, subject, body string) error {
 	headers["Subject"] = subject
 	headers["MIME-Version"] = "1.0"
 	headers["Content-Type"] = "text/html; charset=UTF-8"
+	for k, v := range extraHeaders {
+		headers[k] = v
+	}
 
 	// Build email message
 	message := ""
@@ -88,7 +112,7 @@ func (s *EmailService) SendRegistrationEmail(to, name string) error {
 		"Email": to,
 	}
 
-	body, err := s.renderTemplate("registration_email", data)
+	subject, body, err := s.render(to, "registration_email", subject, data)
 	if err != nil {
 		return err
 	}
@@ -108,7 +132,7 @@ func (s *EmailService) SendVerificationEmail(to, token string) error {
 		"Token":           token,
 	}
 
-	body, err := s.renderTemplate("verification_email", data)
+	subject, body, err := s.render(to, "verification_email", subject, data)
 	if err != nil {
 		return err
 	}
@@ -128,7 +152,7 @@ func (s *EmailService) SendPasswordResetEmail(to, token string) error {
 		"Token":    token,
 	}
 
-	body, err := s.renderTemplate("password_reset_email", data)
+	subject, body, err := s.render(to, "password_reset_email", subject, data)
 	if err != nil {
 		return err
 	}
@@ -157,7 +181,7 @@ func (s *EmailService) SendOrderStatusEmail(to string, orderID int, status strin
 		"TotalAmount": totalAmount,
 	}
 
-	body, err := s.renderTemplate("order_status_email", data)
+	subject, body, err := s.render(to, "order_status_email", subject, data)
 	if err != nil {
 		return err
 	}
@@ -184,7 +208,7 @@ func (s *EmailService) SendPaymentNotificationEmail(to string, orderID int, paym
 		"StatusRaw":  status,
 	}
 
-	body, err := s.renderTemplate("payment_notification_email", data)
+	subject, body, err := s.render(to, "payment_notification_email", subject, data)
 	if err != nil {
 		return err
 	}
@@ -192,10 +216,195 @@ func (s *EmailService) SendPaymentNotificationEmail(to string, orderID int, paym
 	return s.SendEmail(to, subject, body)
 }
 
-// renderTemplate renders email template
-func (s *EmailService) renderTemplate(templateName string, data map[string]interface{}) (string, error) {
-	templates := map[string]string{
-		"registration_email": `
+// SendGuestOrderEmail sends a guest the order status with a link to view the order
+func (s *EmailService) SendGuestOrderEmail(to string, orderID int, status string, accessToken string, totalAmountCents int) error {
+	subject := fmt.Sprintf("Ваш заказ #%d в GastroShop", orderID)
+
+	statusText := map[string]string{
+		"pending":   "Ожидает оплаты",
+		"paid":      "Оплачен",
+		"shipped":   "Отправлен",
+		"delivered": "Доставлен",
+		"canceled":  "Отменен",
+	}
+
+	data := map[string]interface{}{
+		"OrderID":     orderID,
+		"Status":      statusText[status],
+		"StatusRaw":   status,
+		"TotalAmount": formatCurrency(totalAmountCents),
+		"OrderURL":    fmt.Sprintf("%s/orders/guest?token=%s", s.baseURL, accessToken),
+	}
+
+	subject, body, err := s.render(to, "guest_order_email", subject, data)
+	if err != nil {
+		return err
+	}
+
+	return s.SendEmail(to, subject, body)
+}
+
+// SendSubscriptionPaymentLinkEmail sends a payment link for a subscription order
+func (s *EmailService) SendSubscriptionPaymentLinkEmail(to string, orderID int, paymentURL string, amountCents int) error {
+	subject := fmt.Sprintf("Оплатите заказ #%d по подписке", orderID)
+
+	data := map[string]interface{}{
+		"OrderID":    orderID,
+		"PaymentURL": paymentURL,
+		"Amount":     formatCurrency(amountCents),
+	}
+
+	subject, body, err := s.render(to, "subscription_payment_link_email", subject, data)
+	if err != nil {
+		return err
+	}
+
+	return s.SendEmail(to, subject, body)
+}
+
+// SendSubscriptionPaymentFailedEmail notifies about a failed subscription charge.
+// A nil nextRetryAt means no more retries are scheduled and the subscription is paused.
+func (s *EmailService) SendSubscriptionPaymentFailedEmail(to string, subscriptionID int, orderID int, nextRetryAt *time.Time) error {
+	subject := fmt.Sprintf("Не удалось оплатить заказ #%d по подписке", orderID)
+
+	data := map[string]interface{}{
+		"SubscriptionID":  subscriptionID,
+		"OrderID":         orderID,
+		"SubscriptionURL": fmt.Sprintf("%s/account/subscriptions", s.baseURL),
+	}
+	if nextRetryAt != nil {
+		data["NextRetry"] = nextRetryAt.Format("02.01.2006")
+	}
+
+	subject, body, err := s.render(to, "subscription_payment_failed_email", subject, data)
+	if err != nil {
+		return err
+	}
+
+	return s.SendEmail(to, subject, body)
+}
+
+// SendProductAlertEmail tells a customer a product is back in stock or its
+// price dropped, with a link to the unsubscribe page and the one-click
+// unsubscribe endpoint in the List-Unsubscribe headers
+func (s *EmailService) SendProductAlertEmail(to, alertType, title, slug string, priceCents int, unsubscribeToken string) error {
+	subject := fmt.Sprintf("%s снова в наличии", title)
+	if alertType == models.AlertPriceDrop {
+		subject = fmt.Sprintf("%s подешевел", title)
+	}
+
+	unsubscribeURL := fmt.Sprintf("%s/alerts/unsubscribe?token=%s", s.baseURL, unsubscribeToken)
+	data := map[string]interface{}{
+		"PriceDrop":      alertType == models.AlertPriceDrop,
+		"Title":          title,
+		"ProductURL":     fmt.Sprintf("%s/products/%s", s.baseURL, slug),
+		"Price":          formatCurrency(priceCents),
+		"UnsubscribeURL": unsubscribeURL,
+	}
+
+	subject, body, err := s.render(to, "product_alert_email", subject, data)
+	if err != nil {
+		return err
+	}
+
+	return s.sendEmail(to, subject, body, map[string]string{
+		"List-Unsubscribe":      fmt.Sprintf("<%s/api/alerts/unsubscribe/%s>", s.baseURL, unsubscribeToken),
+		"List-Unsubscribe-Post": "List-Unsubscribe=One-Click",
+	})
+}
+
+// SendProductAlertConfirmationEmail asks a guest to confirm an alert on a
+// product before it is sent to their email
+func (s *EmailService) SendProductAlertConfirmationEmail(to, title, token string) error {
+	subject := fmt.Sprintf("Подтвердите уведомление о товаре %s", title)
+
+	data := map[string]interface{}{
+		"Title":      title,
+		"ConfirmURL": fmt.Sprintf("%s/alerts/confirm?token=%s", s.baseURL, token),
+	}
+
+	subject, body, err := s.render(to, "product_alert_confirmation_email", subject, data)
+	if err != nil {
+		return err
+	}
+
+	return s.SendEmail(to, subject, body)
+}
+
+// render renders an email in the locale of its recipient: with the admins'
+// template for that locale, else for the default locale, else with the
+// built-in template and subject. A template that fails to render falls back
+// to the built-in one.
+func (s *EmailService) render(to, templateName, subject string, data map[string]interface{}) (string, string, error) {
+	if s.templateRepo != nil {
+		locale := s.recipientLocale(to)
+		tmpl, err := s.templateRepo.GetTemplate(templateName, locale, models.DefaultLocale)
+		if err != nil {
+			log.Printf("Failed to get email template %s: %v", templateName, err)
+		} else if tmpl != nil {
+			localizedSubject, body, err := renderEmailTemplate(tmpl, data)
+			if err == nil {
+				return localizedSubject, body, nil
+			}
+			log.Printf("Failed to render email template %s in %s: %v", tmpl.Name, tmpl.Locale, err)
+		}
+	}
+
+	body, err := s.renderTemplate(templateName, data)
+	return subject, body, err
+}
+
+// recipientLocale returns the locale of the account with the email, the
+// default locale for guests
+func (s *EmailService) recipientLocale(email string) string {
+	if s.userRepo == nil {
+		return models.DefaultLocale
+	}
+	locale, err := s.userRepo.GetLocaleByEmail(email)
+	if err != nil {
+		log.Printf("Failed to get locale of %s: %v", email, err)
+	}
+	if !SupportedLocale(locale) {
+		return models.DefaultLocale
+	}
+	return locale
+}
+
+// renderEmailTemplate renders the subject and body of an admin's template
+func renderEmailTemplate(tmpl *models.EmailTemplate, data map[string]interface{}) (string, string, error) {
+	subjectTmpl, bodyTmpl, err := parseEmailTemplate(tmpl.Name, tmpl.Subject, tmpl.Body)
+	if err != nil {
+		return "", "", err
+	}
+
+	var subject, body bytes.Buffer
+	if err := subjectTmpl.Execute(&subject, data); err != nil {
+		return "", "", fmt.Errorf("failed to execute subject: %w", err)
+	}
+	if err := bodyTmpl.Execute(&body, data); err != nil {
+		return "", "", fmt.Errorf("failed to execute body: %w", err)
+	}
+	return strings.TrimSpace(subject.String()), body.String(), nil
+}
+
+// parseEmailTemplate parses the subject of an email as text and its body
+// as HTML
+func parseEmailTemplate(name, subject, body string) (*texttemplate.Template, *template.Template, error) {
+	subjectTmpl, err := texttemplate.New(name + "_subject").Option("missingkey=zero").Parse(subject)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid subject: %w", err)
+	}
+	bodyTmpl, err := template.New(name).Option("missingkey=zero").Parse(body)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid body: %w", err)
+	}
+	return subjectTmpl, bodyTmpl, nil
+}
+
+// builtinEmailTemplates are the bodies of the emails in the default
+// locale, keyed by template name
+var builtinEmailTemplates = map[string]string{
+	"registration_email": `
 <!DOCTYPE html>
 <html>
 <head>
@@ -232,7 +441,7 @@ func (s *EmailService) renderTemplate(templateName string, data map[string]inter
 </body>
 </html>
 `,
-		"verification_email": `
+	"verification_email": `
 <!DOCTYPE html>
 <html>
 <head>
@@ -268,7 +477,7 @@ func (s *EmailService) renderTemplate(templateName string, data map[string]inter
 </body>
 </html>
 `,
-		"password_reset_email": `
+	"password_reset_email": `
 <!DOCTYPE html>
 <html>
 <head>
@@ -308,7 +517,7 @@ func (s *EmailService) renderTemplate(templateName string, data map[string]inter
 </body>
 </html>
 `,
-		"order_status_email": `
+	"order_status_email": `
 <!DOCTYPE html>
 <html>
 <head>
@@ -356,7 +565,7 @@ func (s *EmailService) renderTemplate(templateName string, data map[string]inter
 </body>
 </html>
 `,
-		"payment_notification_email": `
+	"payment_notification_email": `
 <!DOCTYPE html>
 <html>
 <head>
@@ -398,9 +607,201 @@ func (s *EmailService) renderTemplate(templateName string, data map[string]inter
 </body>
 </html>
 `,
-	}
+	"guest_order_email": `
+<!DOCTYPE html>
+<html>
+<head>
+	<meta charset="UTF-8">
+	<style>
+		body { font-family: Arial, sans-serif; line-height: 1.6; color: #333; }
+		.container { max-width: 600px; margin: 0 auto; padding: 20px; }
+		.header { background: #4CAF50; color: white; padding: 20px; text-align: center; }
+		.content { padding: 20px; background: #f9f9f9; }
+		.order-info { background: white; padding: 15px; border-radius: 5px; margin: 15px 0; }
+		.status { display: inline-block; padding: 5px 15px; background: #4CAF50; color: white; border-radius: 3px; font-weight: bold; }
+		.button { display: inline-block; padding: 12px 30px; background: #4CAF50; color: white; text-decoration: none; border-radius: 5px; margin: 20px 0; }
+		.footer { text-align: center; padding: 20px; font-size: 12px; color: #666; }
+	</style>
+</head>
+<body>
+	<div class="container">
+		<div class="header">
+			<h1>Заказ #{{.OrderID}}</h1>
+		</div>
+		<div class="content">
+			<p>Здравствуйте!</p>
+			{{if eq .StatusRaw "pending"}}
+			<p>Спасибо за заказ в GastroShop! Мы получили ваш заказ <strong>#{{.OrderID}}</strong>.</p>
+			{{else}}
+			<p>Статус вашего заказа <strong>#{{.OrderID}}</strong> был обновлен.</p>
+			{{end}}
+			<div class="order-info">
+				<p><strong>Статус:</strong> <span class="status">{{.Status}}</span></p>
+				<p><strong>Сумма:</strong> {{.TotalAmount}} ₽</p>
+			</div>
+			<p>Следить за заказом можно по ссылке ниже. Не передавайте ее другим людям.</p>
+			<p style="text-align: center;">
+				<a href="{{.OrderURL}}" class="button">Открыть заказ</a>
+			</p>
+			<p>Зарегистрируйтесь с этим email, и заказ появится в вашем личном кабинете.</p>
+		</div>
+		<div class="footer">
+			<p>GastroShop - Ваш гастрономический магазин</p>
+		</div>
+	</div>
+</body>
+</html>
+`,
+	"subscription_payment_link_email": `
+<!DOCTYPE html>
+<html>
+<head>
+	<meta charset="UTF-8">
+	<style>
+		body { font-family: Arial, sans-serif; line-height: 1.6; color: #333; }
+		.container { max-width: 600px; margin: 0 auto; padding: 20px; }
+		.header { background: #4CAF50; color: white; padding: 20px; text-align: center; }
+		.content { padding: 20px; background: #f9f9f9; }
+		.button { display: inline-block; padding: 12px 30px; background: #4CAF50; color: white; text-decoration: none; border-radius: 5px; margin: 20px 0; }
+		.footer { text-align: center; padding: 20px; font-size: 12px; color: #666; }
+	</style>
+</head>
+<body>
+	<div class="container">
+		<div class="header">
+			<h1>Ваш заказ по подписке готов</h1>
+		</div>
+		<div class="content">
+			<p>Здравствуйте!</p>
+			<p>Мы сформировали очередной заказ <strong>#{{.OrderID}}</strong> по вашей подписке.</p>
+			<p><strong>Сумма к оплате:</strong> {{.Amount}} ₽</p>
+			<p style="text-align: center;">
+				<a href="{{.PaymentURL}}" class="button">Оплатить заказ</a>
+			</p>
+			<p>Заказ будет передан в доставку после оплаты.</p>
+		</div>
+		<div class="footer">
+			<p>GastroShop - Ваш гастрономический магазин</p>
+		</div>
+	</div>
+</body>
+</html>
+`,
+	"subscription_payment_failed_email": `
+<!DOCTYPE html>
+<html>
+<head>
+	<meta charset="UTF-8">
+	<style>
+		body { font-family: Arial, sans-serif; line-height: 1.6; color: #333; }
+		.container { max-width: 600px; margin: 0 auto; padding: 20px; }
+		.header { background: #f44336; color: white; padding: 20px; text-align: center; }
+		.content { padding: 20px; background: #f9f9f9; }
+		.button { display: inline-block; padding: 12px 30px; background: #4CAF50; color: white; text-decoration: none; border-radius: 5px; margin: 20px 0; }
+		.footer { text-align: center; padding: 20px; font-size: 12px; color: #666; }
+	</style>
+</head>
+<body>
+	<div class="container">
+		<div class="header">
+			<h1>Не удалось списать оплату</h1>
+		</div>
+		<div class="content">
+			<p>Здравствуйте!</p>
+			<p>Нам не удалось списать оплату за заказ <strong>#{{.OrderID}}</strong> по вашей подписке.</p>
+			{{if .NextRetry}}
+			<p>Мы повторим попытку {{.NextRetry}}. Пожалуйста, проверьте, что на карте достаточно средств, или укажите другой способ оплаты.</p>
+			{{else}}
+			<p>Все попытки оплаты исчерпаны, поэтому заказ отменен, а подписка приостановлена. Вы можете обновить способ оплаты и возобновить подписку в личном кабинете.</p>
+			{{end}}
+			<p style="text-align: center;">
+				<a href="{{.SubscriptionURL}}" class="button">Управлять подпиской</a>
+			</p>
+		</div>
+		<div class="footer">
+			<p>GastroShop - Ваш гастрономический магазин</p>
+		</div>
+	</div>
+</body>
+</html>
+`,
+	"product_alert_email": `
+<!DOCTYPE html>
+<html>
+<head>
+	<meta charset="UTF-8">
+	<style>
+		body { font-family: Arial, sans-serif; line-height: 1.6; color: #333; }
+		.container { max-width: 600px; margin: 0 auto; padding: 20px; }
+		.header { background: #4CAF50; color: white; padding: 20px; text-align: center; }
+		.content { padding: 20px; background: #f9f9f9; }
+		.button { display: inline-block; padding: 12px 30px; background: #4CAF50; color: white; text-decoration: none; border-radius: 5px; margin: 20px 0; }
+		.footer { text-align: center; padding: 20px; font-size: 12px; color: #666; }
+	</style>
+</head>
+<body>
+	<div class="container">
+		<div class="header">
+			<h1>{{if .PriceDrop}}Цена снижена{{else}}Снова в наличии{{end}}</h1>
+		</div>
+		<div class="content">
+			<p>Здравствуйте!</p>
+			{{if .PriceDrop}}
+			<p>Цена на <strong>{{.Title}}</strong> опустилась до {{.Price}} ₽.</p>
+			{{else}}
+			<p><strong>{{.Title}}</strong> снова в наличии по цене {{.Price}} ₽. Успейте заказать, пока товар не закончился.</p>
+			{{end}}
+			<p style="text-align: center;">
+				<a href="{{.ProductURL}}" class="button">Перейти к товару</a>
+			</p>
+		</div>
+		<div class="footer">
+			<p>Вы получили это письмо, потому что подписались на уведомление о товаре. <a href="{{.UnsubscribeURL}}">Отписаться</a></p>
+			<p>GastroShop - Ваш гастрономический магазин</p>
+		</div>
+	</div>
+</body>
+</html>
+`,
+	"product_alert_confirmation_email": `
+<!DOCTYPE html>
+<html>
+<head>
+	<meta charset="UTF-8">
+	<style>
+		body { font-family: Arial, sans-serif; line-height: 1.6; color: #333; }
+		.container { max-width: 600px; margin: 0 auto; padding: 20px; }
+		.header { background: #4CAF50; color: white; padding: 20px; text-align: center; }
+		.content { padding: 20px; background: #f9f9f9; }
+		.button { display: inline-block; padding: 12px 30px; background: #4CAF50; color: white; text-decoration: none; border-radius: 5px; margin: 20px 0; }
+		.footer { text-align: center; padding: 20px; font-size: 12px; color: #666; }
+	</style>
+</head>
+<body>
+	<div class="container">
+		<div class="header">
+			<h1>Подтвердите уведомление</h1>
+		</div>
+		<div class="content">
+			<p>Здравствуйте!</p>
+			<p>Вы попросили сообщить вам о товаре <strong>{{.Title}}</strong>. Подтвердите, что это ваш email, и мы напишем, как только придет время.</p>
+			<p style="text-align: center;">
+				<a href="{{.ConfirmURL}}" class="button">Подтвердить</a>
+			</p>
+			<p>Если вы не подписывались, просто проигнорируйте это письмо.</p>
+		</div>
+		<div class="footer">
+			<p>GastroShop - Ваш гастрономический магазин</p>
+		</div>
+	</div>
+</body>
+</html>
+`,
+}
 
-	tmpl, exists := templates[templateName]
+// renderTemplate renders email template
+func (s *EmailService) renderTemplate(templateName string, data map[string]interface{}) (string, error) {
+	tmpl, exists := builtinEmailTemplates[templateName]
 	if !exists {
 		return "", fmt.Errorf("template %s not found", templateName)
 	}
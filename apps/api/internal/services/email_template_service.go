@@ -0,0 +1,93 @@
+package services
+
+import (
+	"errors"
+	"sort"
+
+	"gastroshop-api/internal/models"
+	"gastroshop-api/internal/repository"
+)
+
+// EmailTemplateService manages admins' versions of the built-in emails per
+// locale. Emails without a version for the recipient's locale use the one
+// for the default locale, and else the built-in email.
+type EmailTemplateService struct {
+	templateRepo *repository.EmailTemplateRepository
+}
+
+func NewEmailTemplateService(templateRepo *repository.EmailTemplateRepository) *EmailTemplateService {
+	return &EmailTemplateService{templateRepo: templateRepo}
+}
+
+// GetTemplates lists the built-in emails by name with their admin versions
+// by locale
+func (s *EmailTemplateService) GetTemplates() ([]models.EmailTemplateSet, error) {
+	templates, err := s.templateRepo.GetTemplates()
+	if err != nil {
+		return nil, err
+	}
+	return emailTemplateSets(templates), nil
+}
+
+// emailTemplateSets groups templates by the built-in email they replace,
+// listing every built-in email by name
+func emailTemplateSets(templates []models.EmailTemplate) []models.EmailTemplateSet {
+	names := make([]string, 0, len(builtinEmailTemplates))
+	for name := range builtinEmailTemplates {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	sets := make([]models.EmailTemplateSet, len(names))
+	index := make(map[string]int, len(names))
+	for i, name := range names {
+		sets[i] = models.EmailTemplateSet{Name: name, Locales: map[string]models.EmailTemplate{}}
+		index[name] = i
+	}
+	for _, t := range templates {
+		if i, ok := index[t.Name]; ok {
+			sets[i].Locales[t.Locale] = t
+		}
+	}
+	return sets
+}
+
+// SetTemplate sets an admin's version of a built-in email in a locale. The
+// subject and body must parse as templates.
+func (s *EmailTemplateService) SetTemplate(adminID int, name, locale string, req *models.SetEmailTemplateRequest) (*models.EmailTemplate, error) {
+	if err := validateEmailTemplate(name, locale, req.Subject, req.Body); err != nil {
+		return nil, err
+	}
+
+	tmpl := &models.EmailTemplate{
+		Name:      name,
+		Locale:    locale,
+		Subject:   req.Subject,
+		Body:      req.Body,
+		UpdatedBy: &adminID,
+	}
+	if err := s.templateRepo.SaveTemplate(tmpl); err != nil {
+		return nil, err
+	}
+	return tmpl, nil
+}
+
+func validateEmailTemplate(name, locale, subject, body string) error {
+	if _, ok := builtinEmailTemplates[name]; !ok {
+		return errors.New("email template not found")
+	}
+	if !SupportedLocale(locale) {
+		return errors.New("unsupported locale")
+	}
+	_, _, err := parseEmailTemplate(name, subject, body)
+	return err
+}
+
+// DeleteTemplate removes an admin's version of an email in a locale, going
+// back to the fallback
+func (s *EmailTemplateService) DeleteTemplate(name, locale string) error {
+	if err := s.templateRepo.DeleteTemplate(name, locale); err != nil {
+		return errors.New("email template not found")
+	}
+	return nil
+}
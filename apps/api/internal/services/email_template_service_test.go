@@ -0,0 +1,81 @@
+package services
+
+import (
+	"strings"
+	"testing"
+
+	"gastroshop-api/internal/models"
+)
+
+func TestValidateEmailTemplate(t *testing.T) {
+	tests := []struct {
+		name     string
+		template string
+		locale   string
+		subject  string
+		body     string
+		wantErr  bool
+	}{
+		{name: "valid", template: "order_status_email", locale: "en", subject: "Order #{{.OrderID}} updated", body: "<p>{{.StatusRaw}}</p>"},
+		{name: "default locale", template: "order_status_email", locale: "ru", subject: "Заказ #{{.OrderID}}", body: "<p>{{.Status}}</p>"},
+		{name: "unknown email", template: "newsletter", locale: "en", subject: "News", body: "<p>News</p>", wantErr: true},
+		{name: "unsupported locale", template: "order_status_email", locale: "de", subject: "Bestellung", body: "<p></p>", wantErr: true},
+		{name: "invalid subject", template: "order_status_email", locale: "en", subject: "Order {{.OrderID", body: "<p></p>", wantErr: true},
+		{name: "invalid body", template: "order_status_email", locale: "en", subject: "Order", body: "{{if .Items}}<p>", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateEmailTemplate(tt.template, tt.locale, tt.subject, tt.body)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validateEmailTemplate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRenderEmailTemplate(t *testing.T) {
+	tmpl := &models.EmailTemplate{
+		Name:    "product_alert_email",
+		Locale:  "en",
+		Subject: "{{if .PriceDrop}}{{.Title}} is cheaper{{else}}{{.Title}} is back{{end}}",
+		Body:    `<p><a href="{{.ProductURL}}">{{.Title}}</a> {{.Missing}}</p>`,
+	}
+	data := map[string]interface{}{
+		"PriceDrop":  true,
+		"Title":      "Comté & Beaufort",
+		"ProductURL": "https://example.com/products/comte",
+	}
+
+	subject, body, err := renderEmailTemplate(tmpl, data)
+	if err != nil {
+		t.Fatalf("renderEmailTemplate() error = %v", err)
+	}
+	if subject != "Comté & Beaufort is cheaper" {
+		t.Errorf("subject = %q, want the title unescaped", subject)
+	}
+	if !strings.Contains(body, "Comté &amp; Beaufort") {
+		t.Errorf("body = %q, want the title escaped", body)
+	}
+}
+
+func TestEmailTemplateSets(t *testing.T) {
+	templates := []models.EmailTemplate{
+		{Name: "order_status_email", Locale: "en", Subject: "Order updated"},
+		{Name: "removed_email", Locale: "en", Subject: "Gone"},
+	}
+
+	sets := emailTemplateSets(templates)
+	if len(sets) != len(builtinEmailTemplates) {
+		t.Fatalf("emailTemplateSets() returned %d sets, want one per built-in email (%d)", len(sets), len(builtinEmailTemplates))
+	}
+	for i, set := range sets {
+		if i > 0 && sets[i-1].Name >= set.Name {
+			t.Errorf("emailTemplateSets() not sorted by name at %q", set.Name)
+		}
+		_, hasEN := set.Locales["en"]
+		if hasEN != (set.Name == "order_status_email") {
+			t.Errorf("set %q has en = %v", set.Name, hasEN)
+		}
+	}
+}
@@ -0,0 +1,319 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"gastroshop-api/internal/models"
+	"gastroshop-api/internal/repository"
+)
+
+const maxMovementReferenceLength = 100
+
+// InventoryService records stock movements outside of orders, lists the
+// inventory ledger, moves stock between locations and runs stock takes
+type InventoryService struct {
+	inventoryRepo *repository.InventoryRepository
+	productRepo   *repository.ProductRepository
+	locationRepo  *repository.LocationRepository
+}
+
+func NewInventoryService(inventoryRepo *repository.InventoryRepository, productRepo *repository.ProductRepository, locationRepo *repository.LocationRepository) *InventoryService {
+	return &InventoryService{
+		inventoryRepo: inventoryRepo,
+		productRepo:   productRepo,
+		locationRepo:  locationRepo,
+	}
+}
+
+// GetMovements returns a page of the inventory ledger, newest first, with
+// the total of movements matching the filters
+func (s *InventoryService) GetMovements(filters map[string]interface{}) ([]models.InventoryMovement, int, error) {
+	if reason, ok := filters["reason"].(string); ok && !validMovementReason(reason) {
+		return nil, 0, errors.New("unknown movement reason")
+	}
+	return s.inventoryRepo.GetMovements(filters)
+}
+
+// RecordMovement records stock received, written off, returned, reserved or
+// corrected by an admin at a location, the default location unless one is
+// given, and changes the stock there by it
+func (s *InventoryService) RecordMovement(actorID int, req *models.CreateInventoryMovementRequest) (*models.InventoryMovement, error) {
+	if err := validateMovement(req.Reason, req.QuantityChange); err != nil {
+		return nil, err
+	}
+	reference := strings.TrimSpace(req.Reference)
+	if len(reference) > maxMovementReferenceLength {
+		return nil, fmt.Errorf("reference must be at most %d characters", maxMovementReferenceLength)
+	}
+
+	location, err := s.getLocation(req.LocationID)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.getStock(req.ProductID, req.VariantID); err != nil {
+		return nil, err
+	}
+	stock, err := s.inventoryRepo.GetLocationQuantity(location.ID, req.ProductID, req.VariantID)
+	if err != nil {
+		return nil, err
+	}
+	if stock+req.QuantityChange < 0 {
+		return nil, fmt.Errorf("only %d in stock at %s", stock, location.Name)
+	}
+
+	change := models.StockChange{
+		Reason:     req.Reason,
+		ActorID:    &actorID,
+		Reference:  reference,
+		Note:       strings.TrimSpace(req.Note),
+		LocationID: location.ID,
+	}
+	return s.productRepo.AdjustStock(req.ProductID, req.VariantID, req.QuantityChange, change)
+}
+
+// getLocation returns a location, the default location when id is zero
+func (s *InventoryService) getLocation(id int) (*models.Location, error) {
+	location, err := s.locationRepo.GetLocationByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if location == nil {
+		return nil, errors.New("location not found")
+	}
+	return location, nil
+}
+
+// getStock returns the stock of a product, or of its variant, making sure
+// the stock is kept for it: products with variants keep stock per variant,
+// and bundle stock follows the components
+func (s *InventoryService) getStock(productID int, variantID *int) (int, error) {
+	product, err := s.productRepo.GetProductByID(productID)
+	if err != nil {
+		return 0, err
+	}
+	if product == nil {
+		return 0, errors.New("product not found")
+	}
+
+	bundles, err := s.productRepo.GetBundlesByProductIDs([]int{productID})
+	if err != nil {
+		return 0, err
+	}
+	if bundles[productID] != nil {
+		return 0, fmt.Errorf("%s is a bundle; its stock follows its components", product.Title)
+	}
+
+	variants, err := s.productRepo.GetVariantsByProductIDs([]int{productID})
+	if err != nil {
+		return 0, err
+	}
+	return stockOf(product, variants[productID], variantID)
+}
+
+// stockOf returns the stock of the product or of the variant with variantID
+// among its variants
+func stockOf(product *models.Product, variants []models.ProductVariant, variantID *int) (int, error) {
+	if variantID == nil {
+		if len(variants) > 0 {
+			return 0, fmt.Errorf("%s has variants; choose one", product.Title)
+		}
+		return product.Quantity, nil
+	}
+
+	for _, v := range variants {
+		if v.ID == *variantID {
+			return v.Quantity, nil
+		}
+	}
+	return 0, fmt.Errorf("variant %d of %s not found", *variantID, product.Title)
+}
+
+func validMovementReason(reason string) bool {
+	switch reason {
+	case models.MovementReceipt, models.MovementSale, models.MovementReturn, models.MovementWriteOff,
+		models.MovementCorrection, models.MovementReservation, models.MovementTransfer:
+		return true
+	}
+	return false
+}
+
+// validateMovement checks a movement recorded by an admin. Sales are
+// recorded by orders and transfers by stock transfers; receipts and returns
+// add stock and write-offs take it.
+func validateMovement(reason string, quantityChange int) error {
+	if !validMovementReason(reason) {
+		return errors.New("unknown movement reason")
+	}
+	if reason == models.MovementSale {
+		return errors.New("sales are recorded by orders")
+	}
+	if reason == models.MovementTransfer {
+		return errors.New("transfers are recorded by stock transfers")
+	}
+	if quantityChange == 0 {
+		return errors.New("quantity change must not be zero")
+	}
+
+	switch reason {
+	case models.MovementReceipt, models.MovementReturn:
+		if quantityChange < 0 {
+			return fmt.Errorf("%s must add stock", reason)
+		}
+	case models.MovementWriteOff:
+		if quantityChange > 0 {
+			return errors.New("write_off must take stock")
+		}
+	}
+	return nil
+}
+
+// Stock transfers
+
+// GetTransfers returns a page of stock transfers, newest first, with the
+// total of transfers matching the filters
+func (s *InventoryService) GetTransfers(filters map[string]interface{}) ([]models.StockTransfer, int, error) {
+	return s.inventoryRepo.GetTransfers(filters)
+}
+
+// CreateTransfer moves stock of a product or variant from one location to
+// another; the location moved to must be active
+func (s *InventoryService) CreateTransfer(actorID int, req *models.CreateStockTransferRequest) (*models.StockTransfer, error) {
+	if err := validateTransfer(req); err != nil {
+		return nil, err
+	}
+
+	from, err := s.getLocation(req.FromLocationID)
+	if err != nil {
+		return nil, err
+	}
+	to, err := s.getLocation(req.ToLocationID)
+	if err != nil {
+		return nil, err
+	}
+	if !to.Active {
+		return nil, fmt.Errorf("%s is not active", to.Name)
+	}
+	if _, err := s.getStock(req.ProductID, req.VariantID); err != nil {
+		return nil, err
+	}
+
+	t := &models.StockTransfer{
+		FromLocationID: from.ID,
+		ToLocationID:   to.ID,
+		ProductID:      req.ProductID,
+		VariantID:      req.VariantID,
+		Quantity:       req.Quantity,
+		ActorID:        &actorID,
+		Note:           strings.TrimSpace(req.Note),
+	}
+	if err := s.inventoryRepo.CreateTransfer(t); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// validateTransfer checks a transfer sent by an admin
+func validateTransfer(req *models.CreateStockTransferRequest) error {
+	if req.Quantity <= 0 {
+		return errors.New("quantity must be positive")
+	}
+	if req.FromLocationID == req.ToLocationID {
+		return errors.New("stock must be moved to another location")
+	}
+	return nil
+}
+
+// Stock takes
+
+func (s *InventoryService) GetStockTakes() ([]models.StockTake, error) {
+	return s.inventoryRepo.GetStockTakes()
+}
+
+func (s *InventoryService) GetStockTake(id int) (*models.StockTake, error) {
+	st, err := s.inventoryRepo.GetStockTakeByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if st == nil {
+		return nil, errors.New("stock take not found")
+	}
+	return st, nil
+}
+
+// CreateStockTake opens a stock take to record counted stock at a location,
+// the default location unless one is given
+func (s *InventoryService) CreateStockTake(actorID int, req *models.CreateStockTakeRequest) (*models.StockTake, error) {
+	location, err := s.getLocation(req.LocationID)
+	if err != nil {
+		return nil, err
+	}
+
+	st := &models.StockTake{
+		LocationID: location.ID,
+		Note:       strings.TrimSpace(req.Note),
+		CreatedBy:  &actorID,
+		Counts:     []models.StockTakeCount{},
+	}
+	if err := s.inventoryRepo.CreateStockTake(st); err != nil {
+		return nil, err
+	}
+	return st, nil
+}
+
+// SetStockTakeCounts records counted quantities of an open stock take
+func (s *InventoryService) SetStockTakeCounts(id int, req *models.SetStockTakeCountsRequest) (*models.StockTake, error) {
+	if err := validateStockTakeCounts(req.Counts); err != nil {
+		return nil, err
+	}
+	for _, c := range req.Counts {
+		if _, err := s.getStock(c.ProductID, c.VariantID); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := s.inventoryRepo.SetStockTakeCounts(id, req.Counts); err != nil {
+		return nil, err
+	}
+	return s.GetStockTake(id)
+}
+
+// CompleteStockTake sets the stock to the counted quantities, recording the
+// differences as corrections by the admin
+func (s *InventoryService) CompleteStockTake(actorID, id int) (*models.StockTake, error) {
+	if err := s.inventoryRepo.CompleteStockTake(id, &actorID); err != nil {
+		return nil, err
+	}
+	return s.GetStockTake(id)
+}
+
+// CancelStockTake cancels an open stock take without changing stock
+func (s *InventoryService) CancelStockTake(id int) error {
+	return s.inventoryRepo.CancelStockTake(id)
+}
+
+// validateStockTakeCounts checks counts sent by an admin: each product or
+// variant counted once, with a quantity that isn't negative
+func validateStockTakeCounts(counts []models.StockTakeCountInput) error {
+	if len(counts) == 0 {
+		return errors.New("at least one count is required")
+	}
+
+	type line struct{ productID, variantID int }
+	seen := make(map[line]bool)
+	for _, c := range counts {
+		if c.CountedQuantity < 0 {
+			return errors.New("counted quantity must not be negative")
+		}
+		l := line{productID: c.ProductID}
+		if c.VariantID != nil {
+			l.variantID = *c.VariantID
+		}
+		if seen[l] {
+			return fmt.Errorf("product %d is counted more than once", c.ProductID)
+		}
+		seen[l] = true
+	}
+	return nil
+}
@@ -0,0 +1,115 @@
+package services
+
+import (
+	"testing"
+
+	"gastroshop-api/internal/models"
+)
+
+func TestValidateMovement(t *testing.T) {
+	tests := []struct {
+		name           string
+		reason         string
+		quantityChange int
+		wantErr        bool
+	}{
+		{name: "receipt", reason: models.MovementReceipt, quantityChange: 10},
+		{name: "write-off", reason: models.MovementWriteOff, quantityChange: -2},
+		{name: "correction down", reason: models.MovementCorrection, quantityChange: -1},
+		{name: "reservation released", reason: models.MovementReservation, quantityChange: 3},
+		{name: "return", reason: models.MovementReturn, quantityChange: 1},
+		{name: "unknown reason", reason: "theft", quantityChange: -1, wantErr: true},
+		{name: "sale", reason: models.MovementSale, quantityChange: -1, wantErr: true},
+		{name: "transfer", reason: models.MovementTransfer, quantityChange: -1, wantErr: true},
+		{name: "zero", reason: models.MovementCorrection, wantErr: true},
+		{name: "negative receipt", reason: models.MovementReceipt, quantityChange: -5, wantErr: true},
+		{name: "positive write-off", reason: models.MovementWriteOff, quantityChange: 5, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateMovement(tt.reason, tt.quantityChange)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validateMovement() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestStockOf(t *testing.T) {
+	intPtr := func(v int) *int { return &v }
+	product := &models.Product{ID: 1, Title: "Комте", Quantity: 7}
+	variants := []models.ProductVariant{{ID: 10, Quantity: 3}, {ID: 11, Quantity: 5}}
+
+	if got, err := stockOf(product, nil, nil); err != nil || got != 7 {
+		t.Errorf("stockOf(product) = %d, %v, want 7", got, err)
+	}
+	if got, err := stockOf(product, variants, intPtr(11)); err != nil || got != 5 {
+		t.Errorf("stockOf(variant) = %d, %v, want 5", got, err)
+	}
+	if _, err := stockOf(product, variants, nil); err == nil {
+		t.Error("stockOf() of a product with variants without a variant should fail")
+	}
+	if _, err := stockOf(product, variants, intPtr(12)); err == nil {
+		t.Error("stockOf() of another product's variant should fail")
+	}
+}
+
+func TestValidateTransfer(t *testing.T) {
+	tests := []struct {
+		name    string
+		req     models.CreateStockTransferRequest
+		wantErr bool
+	}{
+		{name: "valid", req: models.CreateStockTransferRequest{FromLocationID: 1, ToLocationID: 2, ProductID: 5, Quantity: 3}},
+		{name: "zero", req: models.CreateStockTransferRequest{FromLocationID: 1, ToLocationID: 2, ProductID: 5}, wantErr: true},
+		{name: "negative", req: models.CreateStockTransferRequest{FromLocationID: 1, ToLocationID: 2, ProductID: 5, Quantity: -3}, wantErr: true},
+		{name: "same location", req: models.CreateStockTransferRequest{FromLocationID: 2, ToLocationID: 2, ProductID: 5, Quantity: 3}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateTransfer(&tt.req)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validateTransfer() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateStockTakeCounts(t *testing.T) {
+	intPtr := func(v int) *int { return &v }
+	tests := []struct {
+		name    string
+		counts  []models.StockTakeCountInput
+		wantErr bool
+	}{
+		{
+			name: "products and variants",
+			counts: []models.StockTakeCountInput{
+				{ProductID: 1, CountedQuantity: 4},
+				{ProductID: 2, VariantID: intPtr(20), CountedQuantity: 0},
+				{ProductID: 2, VariantID: intPtr(21), CountedQuantity: 9},
+			},
+		},
+		{name: "empty", wantErr: true},
+		{name: "negative", counts: []models.StockTakeCountInput{{ProductID: 1, CountedQuantity: -1}}, wantErr: true},
+		{
+			name: "counted twice",
+			counts: []models.StockTakeCountInput{
+				{ProductID: 2, VariantID: intPtr(20), CountedQuantity: 1},
+				{ProductID: 2, VariantID: intPtr(20), CountedQuantity: 2},
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateStockTakeCounts(tt.counts)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validateStockTakeCounts() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,148 @@
+package services
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gastroshop-api/internal/models"
+)
+
+// supportedLocales are the locales catalog texts and emails are available
+// in; texts are written in models.DefaultLocale
+var supportedLocales = map[string]bool{
+	models.LocaleRU: true,
+	models.LocaleEN: true,
+}
+
+// SupportedLocale reports whether texts are available in a locale
+func SupportedLocale(locale string) bool {
+	return supportedLocales[locale]
+}
+
+// NegotiateLocale picks the locale of a request: the lang query parameter
+// when supported, else the preferred supported language of the
+// Accept-Language header, else the default locale. Regional variants such as
+// en-GB match their language.
+func NegotiateLocale(lang, acceptLanguage string) string {
+	if locale := baseLanguage(lang); SupportedLocale(locale) {
+		return locale
+	}
+
+	type candidate struct {
+		locale string
+		q      float64
+	}
+	var candidates []candidate
+	for _, part := range strings.Split(acceptLanguage, ",") {
+		fields := strings.Split(part, ";")
+		locale := baseLanguage(fields[0])
+		if !SupportedLocale(locale) {
+			continue
+		}
+		q := 1.0
+		for _, param := range fields[1:] {
+			param = strings.TrimSpace(param)
+			if strings.HasPrefix(param, "q=") {
+				if v, err := strconv.ParseFloat(strings.TrimPrefix(param, "q="), 64); err == nil {
+					q = v
+				}
+			}
+		}
+		if q > 0 {
+			candidates = append(candidates, candidate{locale: locale, q: q})
+		}
+	}
+	if len(candidates) == 0 {
+		return models.DefaultLocale
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].q > candidates[j].q })
+	return candidates[0].locale
+}
+
+// baseLanguage returns the lowercase language of a language tag, e.g. "en"
+// for "en-US"
+func baseLanguage(tag string) string {
+	tag = strings.ToLower(strings.TrimSpace(tag))
+	if i := strings.IndexAny(tag, "-_"); i >= 0 {
+		tag = tag[:i]
+	}
+	return tag
+}
+
+// MergeTranslations merges translations given by locale into the existing
+// ones: texts are trimmed, a locale given replaces its translation and a
+// locale given without texts is removed. Texts in the default locale belong
+// in the main fields, not in translations.
+func MergeTranslations(existing, update map[string]models.Translation) (map[string]models.Translation, error) {
+	merged := make(map[string]models.Translation, len(existing)+len(update))
+	for locale, t := range existing {
+		merged[locale] = t
+	}
+
+	for locale, t := range update {
+		if !SupportedLocale(locale) {
+			return nil, fmt.Errorf("unsupported locale %q", locale)
+		}
+		if locale == models.DefaultLocale {
+			return nil, fmt.Errorf("texts in %q go in the main fields, not in translations", locale)
+		}
+		t = models.Translation{
+			Title:       strings.TrimSpace(t.Title),
+			Name:        strings.TrimSpace(t.Name),
+			Description: strings.TrimSpace(t.Description),
+		}
+		if t == (models.Translation{}) {
+			delete(merged, locale)
+			continue
+		}
+		merged[locale] = t
+	}
+	return merged, nil
+}
+
+// LocalizeProduct puts the texts of a product in a locale, falling back to
+// the default locale text by text. Localized products leave out their
+// translations.
+func LocalizeProduct(p *models.Product, locale string) {
+	t := p.Translations[locale]
+	p.Title = translated(t.Title, p.Title)
+	p.Description = translated(t.Description, p.Description)
+	p.Translations = nil
+}
+
+func LocalizeProducts(products []models.Product, locale string) {
+	for i := range products {
+		LocalizeProduct(&products[i], locale)
+	}
+}
+
+func LocalizeRegions(regions []models.Region, locale string) {
+	for i := range regions {
+		r := &regions[i]
+		r.Name = translated(r.Translations[locale].Name, r.Name)
+		r.Translations = nil
+	}
+}
+
+// LocalizeCategories puts the texts of categories and their descendants in
+// a locale
+func LocalizeCategories(categories []models.Category, locale string) {
+	for i := range categories {
+		c := &categories[i]
+		t := c.Translations[locale]
+		c.Name = translated(t.Name, c.Name)
+		c.Description = translated(t.Description, c.Description)
+		c.Translations = nil
+		LocalizeCategories(c.Children, locale)
+	}
+}
+
+func translated(text, fallback string) string {
+	if text != "" {
+		return text
+	}
+	return fallback
+}
@@ -0,0 +1,147 @@
+package services
+
+import (
+	"reflect"
+	"testing"
+
+	"gastroshop-api/internal/models"
+)
+
+func TestNegotiateLocale(t *testing.T) {
+	tests := []struct {
+		name           string
+		lang           string
+		acceptLanguage string
+		want           string
+	}{
+		{name: "nothing given", want: "ru"},
+		{name: "lang", lang: "en", want: "en"},
+		{name: "lang wins over header", lang: "ru", acceptLanguage: "en", want: "ru"},
+		{name: "unsupported lang falls back to header", lang: "de", acceptLanguage: "en-US", want: "en"},
+		{name: "uppercase lang", lang: "EN", want: "en"},
+		{name: "regional variant", acceptLanguage: "en-GB,en;q=0.9", want: "en"},
+		{name: "first supported language", acceptLanguage: "de-DE,de;q=0.9,en;q=0.8,ru;q=0.7", want: "en"},
+		{name: "highest quality", acceptLanguage: "en;q=0.5, ru;q=0.8", want: "ru"},
+		{name: "equal quality keeps order", acceptLanguage: "en, ru", want: "en"},
+		{name: "refused language", acceptLanguage: "en;q=0", want: "ru"},
+		{name: "only unsupported", acceptLanguage: "fr-FR, de", want: "ru"},
+		{name: "wildcard", acceptLanguage: "*", want: "ru"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := NegotiateLocale(tt.lang, tt.acceptLanguage); got != tt.want {
+				t.Errorf("NegotiateLocale(%q, %q) = %q, want %q", tt.lang, tt.acceptLanguage, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMergeTranslations(t *testing.T) {
+	existing := map[string]models.Translation{
+		"en": {Title: "Comté 18 months", Description: "Hard cheese"},
+	}
+
+	tests := []struct {
+		name     string
+		existing map[string]models.Translation
+		update   map[string]models.Translation
+		want     map[string]models.Translation
+		wantErr  bool
+	}{
+		{
+			name:   "new translation, trimmed",
+			update: map[string]models.Translation{"en": {Title: " Comté "}},
+			want:   map[string]models.Translation{"en": {Title: "Comté"}},
+		},
+		{
+			name:     "replaces a locale",
+			existing: existing,
+			update:   map[string]models.Translation{"en": {Title: "Comté 24 months"}},
+			want:     map[string]models.Translation{"en": {Title: "Comté 24 months"}},
+		},
+		{
+			name:     "empty texts remove a locale",
+			existing: existing,
+			update:   map[string]models.Translation{"en": {Title: "  "}},
+			want:     map[string]models.Translation{},
+		},
+		{
+			name:     "nothing given keeps translations",
+			existing: existing,
+			want:     existing,
+		},
+		{
+			name:    "default locale",
+			update:  map[string]models.Translation{"ru": {Title: "Комте"}},
+			wantErr: true,
+		},
+		{
+			name:    "unsupported locale",
+			update:  map[string]models.Translation{"de": {Title: "Comté"}},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := MergeTranslations(tt.existing, tt.update)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("MergeTranslations() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("MergeTranslations() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	if existing["en"].Title != "Comté 18 months" {
+		t.Errorf("MergeTranslations() changed the existing translations")
+	}
+}
+
+func TestLocalizeProduct(t *testing.T) {
+	newProduct := func() *models.Product {
+		return &models.Product{
+			Title:        "Комте 18 месяцев",
+			Description:  "Твёрдый сыр",
+			Translations: map[string]models.Translation{"en": {Title: "Comté 18 months"}},
+		}
+	}
+
+	p := newProduct()
+	LocalizeProduct(p, "en")
+	if p.Title != "Comté 18 months" || p.Description != "Твёрдый сыр" {
+		t.Errorf("LocalizeProduct(en) = %q, %q, want translated title and default description", p.Title, p.Description)
+	}
+	if p.Translations != nil {
+		t.Errorf("LocalizeProduct() kept the translations")
+	}
+
+	p = newProduct()
+	LocalizeProduct(p, "ru")
+	if p.Title != "Комте 18 месяцев" {
+		t.Errorf("LocalizeProduct(ru) title = %q, want the default title", p.Title)
+	}
+}
+
+func TestLocalizeCategories(t *testing.T) {
+	categories := []models.Category{
+		{
+			Name:         "Сыры",
+			Translations: map[string]models.Translation{"en": {Name: "Cheese"}},
+			Children: []models.Category{
+				{Name: "Твёрдые", Translations: map[string]models.Translation{"en": {Name: "Hard cheese"}}},
+				{Name: "Мягкие"},
+			},
+		},
+	}
+
+	LocalizeCategories(categories, "en")
+
+	got := []string{categories[0].Name, categories[0].Children[0].Name, categories[0].Children[1].Name}
+	want := []string{"Cheese", "Hard cheese", "Мягкие"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("LocalizeCategories() names = %v, want %v", got, want)
+	}
+}
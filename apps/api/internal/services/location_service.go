@@ -0,0 +1,349 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"gastroshop-api/internal/models"
+	"gastroshop-api/internal/repository"
+)
+
+// LocationService manages the locations stock is kept at, scopes catalog
+// stock to delivery zones and picks the location orders are fulfilled from
+type LocationService struct {
+	locationRepo *repository.LocationRepository
+	productRepo  *repository.ProductRepository
+	orderRepo    *repository.OrderRepository
+}
+
+func NewLocationService(locationRepo *repository.LocationRepository, productRepo *repository.ProductRepository, orderRepo *repository.OrderRepository) *LocationService {
+	return &LocationService{
+		locationRepo: locationRepo,
+		productRepo:  productRepo,
+		orderRepo:    orderRepo,
+	}
+}
+
+func (s *LocationService) GetLocations() ([]models.Location, error) {
+	return s.locationRepo.GetLocations()
+}
+
+func (s *LocationService) GetLocation(id int) (*models.Location, error) {
+	location, err := s.locationRepo.GetLocationByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if location == nil {
+		return nil, errors.New("location not found")
+	}
+	return location, nil
+}
+
+func (s *LocationService) CreateLocation(req *models.CreateLocationRequest) (*models.Location, error) {
+	location := &models.Location{
+		Code:      strings.TrimSpace(req.Code),
+		Name:      strings.TrimSpace(req.Name),
+		City:      strings.TrimSpace(req.City),
+		Address:   strings.TrimSpace(req.Address),
+		Zones:     normalizeZones(req.Zones),
+		IsDefault: req.IsDefault,
+		Priority:  req.Priority,
+		Active:    true,
+	}
+	if req.Active != nil {
+		location.Active = *req.Active
+	}
+
+	if err := validateLocation(location); err != nil {
+		return nil, err
+	}
+	if err := s.locationRepo.CreateLocation(location); err != nil {
+		return nil, err
+	}
+	return location, nil
+}
+
+// UpdateLocation updates a location. The default location stays the default
+// until another location is made the default.
+func (s *LocationService) UpdateLocation(id int, req *models.UpdateLocationRequest) (*models.Location, error) {
+	location, err := s.GetLocation(id)
+	if err != nil {
+		return nil, err
+	}
+	wasDefault := location.IsDefault
+
+	if req.Name != nil {
+		location.Name = strings.TrimSpace(*req.Name)
+	}
+	if req.City != nil {
+		location.City = strings.TrimSpace(*req.City)
+	}
+	if req.Address != nil {
+		location.Address = strings.TrimSpace(*req.Address)
+	}
+	if req.Zones != nil {
+		location.Zones = normalizeZones(req.Zones)
+	}
+	if req.IsDefault != nil {
+		location.IsDefault = *req.IsDefault
+	}
+	if req.Priority != nil {
+		location.Priority = *req.Priority
+	}
+	if req.Active != nil {
+		location.Active = *req.Active
+	}
+
+	if wasDefault && !location.IsDefault {
+		return nil, errors.New("make another location the default instead")
+	}
+	if err := validateLocation(location); err != nil {
+		return nil, err
+	}
+	if err := s.locationRepo.UpdateLocation(id, location); err != nil {
+		return nil, err
+	}
+	return s.GetLocation(id)
+}
+
+// validateLocation checks a location before it is stored
+func validateLocation(l *models.Location) error {
+	if !slugPattern.MatchString(l.Code) {
+		return errors.New("code must contain only lowercase letters, digits and hyphens")
+	}
+	if l.Name == "" {
+		return errors.New("name is required")
+	}
+	if l.Priority < 0 {
+		return errors.New("priority cannot be negative")
+	}
+	if l.IsDefault && !l.Active {
+		return errors.New("the default location must be active")
+	}
+	return nil
+}
+
+// normalizeZones trims zones and drops empty and repeated ones
+func normalizeZones(zones []string) []string {
+	normalized := []string{}
+	seen := make(map[string]bool)
+	for _, zone := range zones {
+		zone = strings.TrimSpace(zone)
+		if zone == "" || seen[zone] {
+			continue
+		}
+		seen[zone] = true
+		normalized = append(normalized, zone)
+	}
+	return normalized
+}
+
+// GetStock returns a page of the stock kept at locations with the total of
+// rows matching the filters
+func (s *LocationService) GetStock(filters map[string]interface{}) ([]models.LocationStock, int, error) {
+	return s.locationRepo.GetStock(filters)
+}
+
+// applyZoneStock sets the stock of the products and their variants to the
+// stock at the locations serving the zone
+func (s *LocationService) applyZoneStock(zone string, products []*models.Product) error {
+	ids := make([]int, len(products))
+	for i, p := range products {
+		ids[i] = p.ID
+	}
+
+	productStock, variantStock, err := s.locationRepo.GetZoneStock(zone, ids)
+	if err != nil {
+		return err
+	}
+	scopeStock(products, productStock, variantStock)
+	return nil
+}
+
+// scopeStock sets the stock of the products and their variants to the given
+// quantities. Products and variants marked out of stock stay out of stock.
+func scopeStock(products []*models.Product, productStock, variantStock map[int]int) {
+	for _, p := range products {
+		p.Quantity = productStock[p.ID]
+		p.InStock = p.InStock && p.Quantity > 0
+		for i := range p.Variants {
+			v := &p.Variants[i]
+			v.Quantity = variantStock[v.ID]
+			v.InStock = v.InStock && v.Quantity > 0
+		}
+	}
+}
+
+// ChooseFulfilmentLocation picks the location an order of the items,
+// delivered to the zone, is fulfilled from
+func (s *LocationService) ChooseFulfilmentLocation(zone string, items []models.OrderItem) (*models.Location, error) {
+	locations, err := s.locationRepo.GetLocations()
+	if err != nil {
+		return nil, err
+	}
+
+	needs, err := s.stockNeeds(items)
+	if err != nil {
+		return nil, err
+	}
+
+	var ids []int
+	seen := make(map[int]bool)
+	for key := range needs {
+		if !seen[key.productID] {
+			seen[key.productID] = true
+			ids = append(ids, key.productID)
+		}
+	}
+	rows, err := s.locationRepo.GetStockByProductIDs(ids)
+	if err != nil {
+		return nil, err
+	}
+
+	stock := make(map[int]map[lineKey]int)
+	for _, row := range rows {
+		if stock[row.LocationID] == nil {
+			stock[row.LocationID] = make(map[lineKey]int)
+		}
+		stock[row.LocationID][newLineKey(row.ProductID, row.VariantID)] = row.Quantity
+	}
+
+	return pickFulfilmentLocation(locations, stock, needs, zone), nil
+}
+
+// stockNeeds returns the stock an order of the items takes per product and
+// variant; bundles take the stock of their components
+func (s *LocationService) stockNeeds(items []models.OrderItem) (map[lineKey]int, error) {
+	ids := make([]int, len(items))
+	for i, item := range items {
+		ids[i] = item.ProductID
+	}
+	bundles, err := s.productRepo.GetBundlesByProductIDs(ids)
+	if err != nil {
+		return nil, err
+	}
+
+	needs := make(map[lineKey]int)
+	for _, item := range items {
+		if bundle := bundles[item.ProductID]; bundle != nil && item.VariantID == nil {
+			for _, c := range bundle.Components {
+				needs[newLineKey(c.ProductID, c.VariantID)] += item.Quantity * c.Quantity
+			}
+			continue
+		}
+		needs[orderLineKey(item)] += item.Quantity
+	}
+	return needs, nil
+}
+
+// pickFulfilmentLocation picks the location an order is fulfilled from.
+// Active locations serving the zone come first, then the other active
+// locations, each by priority as the locations are listed. The first
+// location with all the stock the order needs is picked, otherwise the one
+// with the most of it, and the default location when none has any.
+func pickFulfilmentLocation(locations []models.Location, stock map[int]map[lineKey]int, needs map[lineKey]int, zone string) *models.Location {
+	var candidates []*models.Location
+	for _, serving := range []bool{true, false} {
+		for i := range locations {
+			l := &locations[i]
+			if l.Active && servesZone(l, zone) == serving {
+				candidates = append(candidates, l)
+			}
+		}
+	}
+
+	var best *models.Location
+	bestCovered := 0
+	for _, l := range candidates {
+		covered, complete := 0, true
+		for key, quantity := range needs {
+			available := stock[l.ID][key]
+			if available < quantity {
+				covered += available
+				complete = false
+			} else {
+				covered += quantity
+			}
+		}
+		if complete {
+			return l
+		}
+		if covered > bestCovered {
+			best, bestCovered = l, covered
+		}
+	}
+	if best != nil {
+		return best
+	}
+
+	for i := range locations {
+		if locations[i].IsDefault {
+			return &locations[i]
+		}
+	}
+	return nil
+}
+
+// servesZone reports whether a location delivers to the zone; every
+// location serves orders without one
+func servesZone(l *models.Location, zone string) bool {
+	if zone == "" {
+		return true
+	}
+	for _, z := range l.Zones {
+		if z == zone {
+			return true
+		}
+	}
+	return false
+}
+
+// SetFulfilmentLocation overrides the location a pending or paid order is
+// fulfilled from. The stock taken for a paid order is returned at the
+// previous location and taken at the new one, together with the change.
+func (s *LocationService) SetFulfilmentLocation(actorID, orderID, locationID int) (*models.Order, error) {
+	order, err := s.orderRepo.GetOrderByID(orderID)
+	if err != nil {
+		return nil, err
+	}
+	if order == nil {
+		return nil, errors.New("order not found")
+	}
+	if order.Status != "pending" && order.Status != "paid" {
+		return nil, errors.New("only pending or paid orders can change location")
+	}
+
+	location, err := s.GetLocation(locationID)
+	if err != nil {
+		return nil, err
+	}
+	if !location.Active {
+		return nil, fmt.Errorf("%s is not active", location.Name)
+	}
+	previous, err := s.GetLocation(orderLocation(order))
+	if err != nil {
+		return nil, err
+	}
+	if previous.ID == location.ID {
+		return order, nil
+	}
+
+	note := fmt.Sprintf("fulfilment moved from %s to %s", previous.Code, location.Code)
+	returned := models.StockChange{Reason: models.MovementReturn, ActorID: &actorID, Reference: orderReference(order.ID), Note: note, LocationID: previous.ID}
+	var stock []models.LineStockChange
+	if order.Status == "paid" {
+		deltas := quantityDeltas(nil, order.Items)
+		bundles, err := s.productRepo.GetBundlesByProductIDs(deltaProductIDs(deltas))
+		if err != nil {
+			return nil, err
+		}
+		sold := models.StockChange{ActorID: &actorID, Reference: orderReference(order.ID), Note: note, LocationID: location.ID}
+		stock = orderStockChanges(order.Items, deltas, bundles, sold)
+	}
+
+	if err := s.orderRepo.MoveFulfilment(order, previous.ID, location.ID, returned, stock); err != nil {
+		return nil, err
+	}
+	return order, nil
+}
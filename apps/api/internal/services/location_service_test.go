@@ -0,0 +1,146 @@
+package services
+
+import (
+	"reflect"
+	"testing"
+
+	"gastroshop-api/internal/models"
+)
+
+func TestValidateLocation(t *testing.T) {
+	tests := []struct {
+		name     string
+		location models.Location
+		wantErr  bool
+	}{
+		{name: "valid", location: models.Location{Code: "spb-1", Name: "Склад на Обводном", Active: true}},
+		{name: "default", location: models.Location{Code: "main", Name: "Основной склад", IsDefault: true, Active: true}},
+		{name: "bad code", location: models.Location{Code: "СПб", Name: "Склад"}, wantErr: true},
+		{name: "no name", location: models.Location{Code: "spb"}, wantErr: true},
+		{name: "negative priority", location: models.Location{Code: "spb", Name: "Склад", Priority: -1}, wantErr: true},
+		{name: "inactive default", location: models.Location{Code: "main", Name: "Склад", IsDefault: true}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateLocation(&tt.location)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validateLocation() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestNormalizeZones(t *testing.T) {
+	got := normalizeZones([]string{" msk-center ", "", "msk-south", "msk-center"})
+	want := []string{"msk-center", "msk-south"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("normalizeZones() = %v, want %v", got, want)
+	}
+	if got := normalizeZones(nil); got == nil || len(got) != 0 {
+		t.Errorf("normalizeZones(nil) = %v, want empty", got)
+	}
+}
+
+func TestPickFulfilmentLocation(t *testing.T) {
+	intPtr := func(v int) *int { return &v }
+	locations := []models.Location{
+		{ID: 1, Code: "main", IsDefault: true, Active: true, Zones: []string{"msk"}, Priority: 0},
+		{ID: 2, Code: "spb", Active: true, Zones: []string{"spb"}, Priority: 1},
+		{ID: 3, Code: "spb-2", Active: true, Zones: []string{"spb"}, Priority: 2},
+		{ID: 4, Code: "closed", Active: false, Zones: []string{"spb"}},
+	}
+	comte, honey := newLineKey(10, nil), newLineKey(11, intPtr(110))
+
+	tests := []struct {
+		name  string
+		stock map[int]map[lineKey]int
+		needs map[lineKey]int
+		zone  string
+		want  int
+	}{
+		{
+			name:  "serving location with the stock",
+			stock: map[int]map[lineKey]int{1: {comte: 5}, 2: {comte: 5}},
+			needs: map[lineKey]int{comte: 2},
+			zone:  "spb",
+			want:  2,
+		},
+		{
+			name:  "next serving location by priority",
+			stock: map[int]map[lineKey]int{1: {comte: 5}, 2: {comte: 1}, 3: {comte: 2}},
+			needs: map[lineKey]int{comte: 2},
+			zone:  "spb",
+			want:  3,
+		},
+		{
+			name:  "another zone's location with all the stock",
+			stock: map[int]map[lineKey]int{1: {comte: 5, honey: 1}, 2: {comte: 5}},
+			needs: map[lineKey]int{comte: 2, honey: 1},
+			zone:  "spb",
+			want:  1,
+		},
+		{
+			name:  "most of the stock",
+			stock: map[int]map[lineKey]int{1: {comte: 1}, 3: {comte: 2, honey: 1}},
+			needs: map[lineKey]int{comte: 3, honey: 2},
+			zone:  "spb",
+			want:  3,
+		},
+		{
+			name:  "inactive location skipped",
+			stock: map[int]map[lineKey]int{4: {comte: 5}},
+			needs: map[lineKey]int{comte: 1},
+			zone:  "spb",
+			want:  1,
+		},
+		{
+			name:  "no zone",
+			stock: map[int]map[lineKey]int{2: {comte: 5}},
+			needs: map[lineKey]int{comte: 1},
+			want:  2,
+		},
+		{
+			name:  "no stock anywhere",
+			needs: map[lineKey]int{comte: 1},
+			zone:  "spb",
+			want:  1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := pickFulfilmentLocation(locations, tt.stock, tt.needs, tt.zone)
+			if got == nil || got.ID != tt.want {
+				t.Errorf("pickFulfilmentLocation() = %v, want location %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestScopeStock(t *testing.T) {
+	comte := &models.Product{ID: 1, InStock: true, Quantity: 12}
+	honey := &models.Product{ID: 2, InStock: true, Quantity: 8, Variants: []models.ProductVariant{
+		{ID: 20, InStock: true, Quantity: 5},
+		{ID: 21, InStock: false, Quantity: 3},
+	}}
+	hidden := &models.Product{ID: 3, InStock: false, Quantity: 4}
+
+	scopeStock([]*models.Product{comte, honey, hidden}, map[int]int{1: 0, 2: 3, 3: 4}, map[int]int{21: 3})
+
+	if comte.Quantity != 0 || comte.InStock {
+		t.Errorf("comte = %d, %v, want out of stock in the zone", comte.Quantity, comte.InStock)
+	}
+	if honey.Quantity != 3 || !honey.InStock {
+		t.Errorf("honey = %d, %v, want 3 in stock", honey.Quantity, honey.InStock)
+	}
+	if v := honey.Variants[0]; v.Quantity != 0 || v.InStock {
+		t.Errorf("variant 20 = %d, %v, want out of stock in the zone", v.Quantity, v.InStock)
+	}
+	if v := honey.Variants[1]; v.Quantity != 3 || v.InStock {
+		t.Errorf("variant 21 = %d, %v, want marked out of stock", v.Quantity, v.InStock)
+	}
+	if hidden.InStock {
+		t.Error("a product marked out of stock should stay out of stock")
+	}
+}
@@ -0,0 +1,379 @@
+package services
+
+import (
+	"errors"
+	"log"
+	"sort"
+
+	"gastroshop-api/internal/models"
+	"gastroshop-api/internal/repository"
+)
+
+// OrderEditService lets admins change the items of pending and paid orders.
+// Totals and tax are recomputed, stock is adjusted for paid orders with the
+// edit and the payment difference is settled with a surcharge link or a
+// partial refund.
+type OrderEditService struct {
+	orderRepo      *repository.OrderRepository
+	productRepo    *repository.ProductRepository
+	paymentService *PaymentService
+}
+
+func NewOrderEditService(orderRepo *repository.OrderRepository, productRepo *repository.ProductRepository, paymentService *PaymentService) *OrderEditService {
+	return &OrderEditService{
+		orderRepo:      orderRepo,
+		productRepo:    productRepo,
+		paymentService: paymentService,
+	}
+}
+
+// AddItem adds a product or variant to the order at its current price.
+// Adding one that is already in the order increases the quantity of its line.
+func (s *OrderEditService) AddItem(actorID, orderID int, req *models.AddOrderItemRequest) (*models.OrderEditResult, error) {
+	if req.Quantity <= 0 {
+		return nil, errors.New("quantity must be positive")
+	}
+
+	key := newLineKey(req.ProductID, req.VariantID)
+	return s.edit(actorID, orderID, "item_added", req.Note, func(order *models.Order) ([]models.OrderItem, error) {
+		items := cloneOrderItems(order.Items)
+		for i := range items {
+			if orderLineKey(items[i]) == key {
+				items[i].Quantity += req.Quantity
+				return items, nil
+			}
+		}
+
+		item := models.OrderItem{ProductID: req.ProductID, VariantID: req.VariantID, Quantity: req.Quantity}
+		product, variant, _, err := resolveOrderLine(s.productRepo, &item)
+		if err != nil {
+			return nil, err
+		}
+		if product.ArchivedAt != nil {
+			return nil, errors.New("product is no longer available")
+		}
+		item.PriceCents = product.PriceCents
+		if variant != nil {
+			item.PriceCents = variant.PriceCents
+		}
+
+		return append(items, item), nil
+	})
+}
+
+// UpdateItemQuantity sets the quantity of an order line
+func (s *OrderEditService) UpdateItemQuantity(actorID, orderID, productID int, variantID *int, req *models.UpdateOrderItemRequest) (*models.OrderEditResult, error) {
+	if req.Quantity <= 0 {
+		return nil, errors.New("quantity must be positive")
+	}
+
+	key := newLineKey(productID, variantID)
+	return s.edit(actorID, orderID, "item_changed", req.Note, func(order *models.Order) ([]models.OrderItem, error) {
+		items := cloneOrderItems(order.Items)
+		for i := range items {
+			if orderLineKey(items[i]) == key {
+				items[i].Quantity = req.Quantity
+				return items, nil
+			}
+		}
+		return nil, errors.New("item not found in order")
+	})
+}
+
+// RemoveItem removes an order line. The last line can't be removed; cancel
+// the order instead.
+func (s *OrderEditService) RemoveItem(actorID, orderID, productID int, variantID *int, note string) (*models.OrderEditResult, error) {
+	key := newLineKey(productID, variantID)
+	return s.edit(actorID, orderID, "item_removed", note, func(order *models.Order) ([]models.OrderItem, error) {
+		items := make([]models.OrderItem, 0, len(order.Items))
+		for _, item := range order.Items {
+			if orderLineKey(item) != key {
+				items = append(items, item)
+			}
+		}
+		if len(items) == len(order.Items) {
+			return nil, errors.New("item not found in order")
+		}
+		if len(items) == 0 {
+			return nil, errors.New("order must contain at least one item, cancel it instead")
+		}
+		return items, nil
+	})
+}
+
+func (s *OrderEditService) GetHistory(orderID int) ([]models.OrderHistoryEntry, error) {
+	return s.orderRepo.GetOrderHistory(orderID)
+}
+
+// edit applies a change of the order items and settles the result
+func (s *OrderEditService) edit(actorID, orderID int, action, note string, change func(order *models.Order) ([]models.OrderItem, error)) (*models.OrderEditResult, error) {
+	order, err := s.orderRepo.GetOrderByID(orderID)
+	if err != nil {
+		return nil, err
+	}
+	if order == nil {
+		return nil, errors.New("order not found")
+	}
+	if order.Status != "pending" && order.Status != "paid" {
+		return nil, errors.New("only pending or paid orders can be edited")
+	}
+
+	items, err := change(order)
+	if err != nil {
+		return nil, err
+	}
+
+	// Paid orders already took their items from stock, so only the difference
+	// has to be available. Pending orders need the full quantity.
+	paid := order.Status == "paid"
+	deltas := quantityDeltas(order.Items, items)
+	for i := range items {
+		item := &items[i]
+		delta := deltas[orderLineKey(*item)]
+
+		// Lines left alone or reduced keep their stored price and SKU, even
+		// when their variant was deleted since; only added stock is checked
+		// against the catalog
+		var product *models.Product
+		if delta <= 0 {
+			product, err = s.productRepo.GetProductByID(item.ProductID)
+			if err != nil {
+				return nil, err
+			}
+			if product == nil {
+				return nil, errors.New("product not found")
+			}
+			if delta < 0 {
+				if err := validateOrderQuantity(product, item.Quantity); err != nil {
+					return nil, err
+				}
+			}
+		} else {
+			var available int
+			product, _, available, err = resolveOrderLine(s.productRepo, item)
+			if err != nil {
+				return nil, err
+			}
+
+			if err := validateOrderQuantity(product, item.Quantity); err != nil {
+				return nil, err
+			}
+
+			needed := item.Quantity
+			if paid {
+				needed = delta
+			}
+			if available < needed {
+				return nil, errors.New("product out of stock or insufficient quantity")
+			}
+		}
+
+		if item.Unit == "" {
+			item.Unit = product.Unit
+		}
+		if item.TaxCategory == "" {
+			item.TaxCategory = product.TaxCategory
+		}
+	}
+
+	before := cloneOrderItems(order.Items)
+	amountBefore := order.AmountCents
+
+	order.Items = items
+	applyOrderTax(order, order.PricesIncludeTax)
+
+	result := &models.OrderEditResult{
+		Order:           order,
+		AmountDiffCents: order.AmountCents - amountBefore,
+	}
+
+	entry := &models.OrderHistoryEntry{
+		OrderID: order.ID,
+		ActorID: &actorID,
+		Action:  action,
+		Details: map[string]interface{}{
+			"items_before":        before,
+			"items_after":         items,
+			"amount_before_cents": amountBefore,
+			"amount_after_cents":  order.AmountCents,
+		},
+	}
+	if note != "" {
+		entry.Details["note"] = note
+	}
+
+	// Items added to a paid order are sold, removed ones returned, with the edit
+	var stock []models.LineStockChange
+	if paid {
+		bundles, err := s.productRepo.GetBundlesByProductIDs(deltaProductIDs(deltas))
+		if err != nil {
+			return nil, err
+		}
+		change := models.StockChange{ActorID: &actorID, Reference: orderReference(order.ID), Note: note, LocationID: orderLocation(order)}
+		stock = orderStockChanges(order.Items, deltas, bundles, change)
+	}
+
+	if err := s.orderRepo.UpdateOrderItems(order, entry, stock); err != nil {
+		return nil, err
+	}
+
+	s.settle(actorID, order, paid, entry.ID, result)
+
+	return result, nil
+}
+
+// settle charges or refunds the payment difference of an edited order. A
+// failure doesn't undo the edit; it is reported in the result and the history.
+func (s *OrderEditService) settle(actorID int, order *models.Order, paid bool, editID int, result *models.OrderEditResult) {
+	diff := result.AmountDiffCents
+
+	var entry *models.OrderHistoryEntry
+	switch {
+	case paid && diff > 0:
+		response, err := s.paymentService.CreateSupplementaryPayment(order, diff)
+		if err != nil {
+			result.PaymentError = err.Error()
+			entry = paymentHistoryEntry(actorID, order.ID, "supplementary_payment_failed", diff, map[string]interface{}{"error": err.Error()})
+			break
+		}
+		result.PaymentID = response.PaymentID
+		result.PaymentURL = response.PaymentURL
+		entry = paymentHistoryEntry(actorID, order.ID, "supplementary_payment_requested", diff, map[string]interface{}{"payment_id": response.PaymentID})
+
+	case paid && diff < 0:
+		refundID, err := s.paymentService.RefundPayment(order, -diff, editID)
+		if err != nil {
+			result.PaymentError = err.Error()
+			entry = paymentHistoryEntry(actorID, order.ID, "refund_failed", -diff, map[string]interface{}{"error": err.Error()})
+			break
+		}
+		result.RefundID = refundID
+		entry = paymentHistoryEntry(actorID, order.ID, "refund_issued", -diff, map[string]interface{}{"refund_id": refundID})
+
+	case !paid && diff != 0 && order.PaymentID != "":
+		// The customer's payment link is for the old total; replace it
+		response, err := s.paymentService.CreatePayment(order)
+		if err != nil {
+			result.PaymentError = err.Error()
+			entry = paymentHistoryEntry(actorID, order.ID, "payment_link_failed", order.AmountCents, map[string]interface{}{"error": err.Error()})
+			break
+		}
+		order.PaymentID = response.PaymentID
+		result.PaymentID = response.PaymentID
+		result.PaymentURL = response.PaymentURL
+		entry = paymentHistoryEntry(actorID, order.ID, "payment_link_replaced", order.AmountCents, map[string]interface{}{"payment_id": response.PaymentID})
+	}
+
+	if entry == nil {
+		return
+	}
+	if err := s.orderRepo.AddOrderHistory(entry); err != nil {
+		log.Printf("Failed to record history of order %d: %v", order.ID, err)
+	}
+}
+
+func paymentHistoryEntry(actorID, orderID int, action string, amountCents int, details map[string]interface{}) *models.OrderHistoryEntry {
+	details["amount_cents"] = amountCents
+	return &models.OrderHistoryEntry{
+		OrderID: orderID,
+		ActorID: &actorID,
+		Action:  action,
+		Details: details,
+	}
+}
+
+// lineKey identifies an order line by product and variant; variantID is 0
+// for products without variants
+type lineKey struct {
+	productID int
+	variantID int
+}
+
+func newLineKey(productID int, variantID *int) lineKey {
+	key := lineKey{productID: productID}
+	if variantID != nil {
+		key.variantID = *variantID
+	}
+	return key
+}
+
+func orderLineKey(item models.OrderItem) lineKey {
+	return newLineKey(item.ProductID, item.VariantID)
+}
+
+func (k lineKey) variant() *int {
+	if k.variantID == 0 {
+		return nil
+	}
+	id := k.variantID
+	return &id
+}
+
+// quantityDeltas returns the change in quantity per order line between two
+// versions of the order items; unchanged lines are omitted
+func quantityDeltas(before, after []models.OrderItem) map[lineKey]int {
+	deltas := make(map[lineKey]int)
+	for _, item := range after {
+		deltas[orderLineKey(item)] += item.Quantity
+	}
+	for _, item := range before {
+		deltas[orderLineKey(item)] -= item.Quantity
+	}
+	for key, delta := range deltas {
+		if delta == 0 {
+			delete(deltas, key)
+		}
+	}
+	return deltas
+}
+
+func deltaProductIDs(deltas map[lineKey]int) []int {
+	ids := make([]int, 0, len(deltas))
+	for key := range deltas {
+		ids = append(ids, key.productID)
+	}
+	return ids
+}
+
+// orderStockChanges returns the stock taken for the quantity deltas of an
+// edited paid order, negative for stock returned, with bundles taken as their
+// components. Changes are ordered by product and variant so concurrent edits
+// lock stock in the same order.
+func orderStockChanges(items []models.OrderItem, deltas map[lineKey]int, bundles map[int]*models.ProductBundle, change models.StockChange) []models.LineStockChange {
+	var changes []models.LineStockChange
+	for key, delta := range deltas {
+		line := -1
+		for i := range items {
+			if orderLineKey(items[i]) == key {
+				line = i
+			}
+		}
+
+		lineChange := change
+		lineChange.Reason = models.MovementSale
+		if delta < 0 {
+			lineChange.Reason = models.MovementReturn
+		}
+
+		if bundle := bundles[key.productID]; bundle != nil && key.variantID == 0 {
+			for _, c := range bundle.Components {
+				changes = append(changes, models.LineStockChange{Line: line, ProductID: c.ProductID, VariantID: c.VariantID, Quantity: delta * c.Quantity, Change: lineChange})
+			}
+			continue
+		}
+		changes = append(changes, models.LineStockChange{Line: line, ProductID: key.productID, VariantID: key.variant(), Quantity: delta, Change: lineChange})
+	}
+
+	sort.Slice(changes, func(i, j int) bool {
+		a, b := changes[i], changes[j]
+		if a.ProductID != b.ProductID {
+			return a.ProductID < b.ProductID
+		}
+		return newLineKey(a.ProductID, a.VariantID).variantID < newLineKey(b.ProductID, b.VariantID).variantID
+	})
+	return changes
+}
+
+func cloneOrderItems(items []models.OrderItem) []models.OrderItem {
+	return append([]models.OrderItem(nil), items...)
+}
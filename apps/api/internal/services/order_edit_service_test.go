@@ -0,0 +1,94 @@
+package services
+
+import (
+	"reflect"
+	"testing"
+
+	"gastroshop-api/internal/models"
+)
+
+func TestQuantityDeltas(t *testing.T) {
+	small, large := 10, 11
+	before := []models.OrderItem{
+		{ProductID: 1, Quantity: 2},
+		{ProductID: 2, Quantity: 1},
+		{ProductID: 3, VariantID: &small, Quantity: 4},
+	}
+
+	tests := []struct {
+		name  string
+		after []models.OrderItem
+		want  map[lineKey]int
+	}{
+		{
+			name:  "unchanged",
+			after: before,
+			want:  map[lineKey]int{},
+		},
+		{
+			name: "added and increased",
+			after: []models.OrderItem{
+				{ProductID: 1, Quantity: 5},
+				{ProductID: 2, Quantity: 1},
+				{ProductID: 3, VariantID: &small, Quantity: 4},
+				{ProductID: 3, VariantID: &large, Quantity: 1},
+			},
+			want: map[lineKey]int{{productID: 1}: 3, {productID: 3, variantID: 11}: 1},
+		},
+		{
+			name: "removed and decreased",
+			after: []models.OrderItem{
+				{ProductID: 1, Quantity: 1},
+				{ProductID: 3, VariantID: &small, Quantity: 4},
+			},
+			want: map[lineKey]int{{productID: 1}: -1, {productID: 2}: -1},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := quantityDeltas(before, tt.after); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("quantityDeltas() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOrderStockChanges(t *testing.T) {
+	variant := 11
+	items := []models.OrderItem{
+		{ProductID: 5, Quantity: 2},
+		{ProductID: 3, VariantID: &variant, Quantity: 1},
+	}
+	deltas := map[lineKey]int{
+		{productID: 5}:                1,
+		{productID: 3, variantID: 11}: 1,
+		{productID: 2}:                -2,
+	}
+	bundles := map[int]*models.ProductBundle{
+		5: {Components: []models.BundleComponent{{ProductID: 4, Quantity: 2}, {ProductID: 1, Quantity: 1}}},
+	}
+
+	changes := orderStockChanges(items, deltas, bundles, models.StockChange{Reference: "order:7"})
+
+	type change struct {
+		line, productID, variantID, quantity int
+		reason                               string
+	}
+	got := make([]change, len(changes))
+	for i, c := range changes {
+		got[i] = change{c.Line, c.ProductID, newLineKey(c.ProductID, c.VariantID).variantID, c.Quantity, c.Change.Reason}
+		if c.Change.Reference != "order:7" {
+			t.Errorf("change %d reference = %q", i, c.Change.Reference)
+		}
+	}
+	want := []change{
+		{0, 1, 0, 1, models.MovementSale},
+		{-1, 2, 0, -2, models.MovementReturn},
+		{1, 3, 11, 1, models.MovementSale},
+		{0, 4, 0, 2, models.MovementSale},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("orderStockChanges() = %v, want %v", got, want)
+	}
+}
@@ -2,58 +2,191 @@ package services
 
 import (
 	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
 
 	"gastroshop-api/internal/models"
 	"gastroshop-api/internal/repository"
 )
 
 type OrderService struct {
-	orderRepo   *repository.OrderRepository
-	productRepo *repository.ProductRepository
+	orderRepo       *repository.OrderRepository
+	productRepo     *repository.ProductRepository
+	deliveryRepo    *repository.DeliveryRepository
+	emailService    *EmailService
+	locationService *LocationService
+
+	pricesIncludeTax bool
 }
 
-func NewOrderService(orderRepo *repository.OrderRepository, productRepo *repository.ProductRepository) *OrderService {
+func NewOrderService(orderRepo *repository.OrderRepository, productRepo *repository.ProductRepository, deliveryRepo *repository.DeliveryRepository) *OrderService {
 	return &OrderService{
-		orderRepo:   orderRepo,
-		productRepo: productRepo,
+		orderRepo:        orderRepo,
+		productRepo:      productRepo,
+		deliveryRepo:     deliveryRepo,
+		pricesIncludeTax: true,
 	}
 }
 
-func (s *OrderService) CreateOrder(userID *int, items []models.OrderItem, shippingAddress map[string]interface{}) (*models.Order, error) {
-	// Validate items and calculate total
-	totalCents := 0
-	for _, item := range items {
-		product, err := s.productRepo.GetProductByID(item.ProductID)
-		if err != nil {
-			return nil, err
-		}
-		if product == nil {
-			return nil, errors.New("product not found")
-		}
-		if !product.InStock || product.Quantity < item.Quantity {
-			return nil, errors.New("product out of stock or insufficient quantity")
-		}
+// SetEmailService sets email service for guest order notifications
+func (s *OrderService) SetEmailService(emailService *EmailService) {
+	s.emailService = emailService
+}
 
-		totalCents += item.PriceCents * item.Quantity
-	}
+// SetLocationService sets the location service that picks the location
+// orders are fulfilled from
+func (s *OrderService) SetLocationService(locationService *LocationService) {
+	s.locationService = locationService
+}
 
+// SetPricesIncludeTax configures whether product prices already include VAT
+func (s *OrderService) SetPricesIncludeTax(pricesIncludeTax bool) {
+	s.pricesIncludeTax = pricesIncludeTax
+}
+
+func (s *OrderService) CreateOrder(userID *int, items []models.OrderItem, shippingAddress map[string]interface{}, deliverySlotID *int) (*models.Order, error) {
 	order := &models.Order{
 		UserID:          userID,
 		Items:           items,
-		AmountCents:     totalCents,
-		Currency:        "RUB",
-		Status:          "pending",
 		ShippingAddress: shippingAddress,
+		DeliverySlotID:  deliverySlotID,
+	}
+	if err := s.placeOrder(order); err != nil {
+		return nil, err
 	}
+	return order, nil
+}
 
-	if err := s.orderRepo.CreateOrder(order); err != nil {
+// CreateGuestOrder places an order without an account. The returned order
+// carries the access token the guest uses to view it.
+func (s *OrderService) CreateGuestOrder(email, phone string, items []models.OrderItem, shippingAddress map[string]interface{}, deliverySlotID *int) (*models.Order, error) {
+	email = strings.TrimSpace(email)
+	phone = strings.TrimSpace(phone)
+	if err := validateEmailAddress(email); err != nil {
 		return nil, err
 	}
+	if phone == "" {
+		return nil, errors.New("phone is required")
+	}
 
+	token, err := GenerateVerificationToken()
+	if err != nil {
+		return nil, err
+	}
+
+	order := &models.Order{
+		Items:           items,
+		ShippingAddress: shippingAddress,
+		DeliverySlotID:  deliverySlotID,
+		GuestEmail:      email,
+		GuestPhone:      phone,
+		AccessToken:     token,
+	}
+	if err := s.placeOrder(order); err != nil {
+		return nil, err
+	}
+
+	s.notifyGuest(order)
 	return order, nil
 }
 
-// DecreaseProductQuantities decreases product quantities when order is paid
+// placeOrder validates items, picks the location the order is fulfilled
+// from, books the delivery slot and stores the order
+func (s *OrderService) placeOrder(order *models.Order) error {
+	deliverySlotID := order.DeliverySlotID
+
+	// Validate items and take each line's price, unit and tax category from
+	// the catalog; prices sent by the client are ignored. The product price
+	// is the sale price during a sale and the bundle price for bundles.
+	// Variant lines are priced from the variant.
+	for i := range order.Items {
+		item := &order.Items[i]
+		product, variant, available, err := resolveOrderLine(s.productRepo, item)
+		if err != nil {
+			return err
+		}
+		if product.ArchivedAt != nil {
+			return errors.New("product is no longer available")
+		}
+		if err := validateOrderQuantity(product, item.Quantity); err != nil {
+			return err
+		}
+		if available < item.Quantity {
+			return errors.New("product out of stock or insufficient quantity")
+		}
+
+		item.PriceCents = product.PriceCents
+		if variant != nil {
+			item.PriceCents = variant.PriceCents
+		}
+		item.Unit = product.Unit
+		item.TaxCategory = product.TaxCategory
+	}
+	applyOrderTax(order, s.pricesIncludeTax)
+
+	// The delivery slot must be in the zone of the shipping address
+	zone := ""
+	if deliverySlotID != nil {
+		slot, err := s.deliveryRepo.GetSlotByID(*deliverySlotID)
+		if err != nil {
+			return err
+		}
+		if slot == nil {
+			return errors.New("delivery slot is not available")
+		}
+		if !strings.EqualFold(shippingZone(order.ShippingAddress), slot.Zone) {
+			return errors.New("delivery slot is not in the zone of the shipping address")
+		}
+		zone = slot.Zone
+	}
+
+	// Ship from a location serving the delivery zone that has the stock
+	if s.locationService != nil {
+		location, err := s.locationService.ChooseFulfilmentLocation(zone, order.Items)
+		if err != nil {
+			return err
+		}
+		if location != nil {
+			order.FulfilmentLocationID = &location.ID
+		}
+	}
+
+	// Reserve a place in the delivery slot before the order is stored
+	if deliverySlotID != nil {
+		reserved, err := s.deliveryRepo.ReserveSlot(*deliverySlotID)
+		if err != nil {
+			return err
+		}
+		if !reserved {
+			return errors.New("delivery slot is not available")
+		}
+	}
+
+	order.Currency = "RUB"
+	order.Status = "pending"
+
+	if err := s.orderRepo.CreateOrder(order); err != nil {
+		if deliverySlotID != nil {
+			if releaseErr := s.deliveryRepo.ReleaseSlot(*deliverySlotID); releaseErr != nil {
+				log.Printf("Failed to release delivery slot %d: %v", *deliverySlotID, releaseErr)
+			}
+		}
+		return err
+	}
+
+	return nil
+}
+
+// shippingZone returns the delivery zone given in a shipping address
+func shippingZone(address map[string]interface{}) string {
+	zone, _ := address["zone"].(string)
+	return strings.TrimSpace(zone)
+}
+
+// DecreaseProductQuantities decreases product quantities at the order's
+// fulfilment location when order is paid
 func (s *OrderService) DecreaseProductQuantities(orderID int) error {
 	order, err := s.orderRepo.GetOrderByID(orderID)
 	if err != nil {
@@ -63,21 +196,215 @@ func (s *OrderService) DecreaseProductQuantities(orderID int) error {
 		return errors.New("order not found")
 	}
 
-	for _, item := range order.Items {
-		if err := s.productRepo.DecreaseProductQuantity(item.ProductID, item.Quantity); err != nil {
+	change := models.StockChange{Reason: models.MovementSale, Reference: orderReference(order.ID), LocationID: orderLocation(order)}
+	traced := false
+	for i := range order.Items {
+		item := &order.Items[i]
+		batches, err := adjustLineStock(s.productRepo, item.ProductID, item.VariantID, item.Quantity, change)
+		if err != nil {
 			return err
 		}
+		if len(batches) > 0 {
+			item.Batches = batches
+			traced = true
+		}
 	}
 
+	// Record the batches sold on the order lines for recalls
+	if traced {
+		return s.orderRepo.SetOrderItemBatches(order.ID, order.Items)
+	}
 	return nil
 }
 
+// resolveOrderLine checks an order line against the catalog and returns its
+// product, its variant and the stock available for it. Lines of products with
+// variants must name one of them; the line takes the variant's SKU.
+func resolveOrderLine(productRepo *repository.ProductRepository, item *models.OrderItem) (*models.Product, *models.ProductVariant, int, error) {
+	product, err := productRepo.GetProductByID(item.ProductID)
+	if err != nil {
+		return nil, nil, 0, err
+	}
+	if product == nil {
+		return nil, nil, 0, errors.New("product not found")
+	}
+
+	variants, err := productRepo.GetVariantsByProductIDs([]int{product.ID})
+	if err != nil {
+		return nil, nil, 0, err
+	}
+
+	if item.VariantID == nil {
+		if len(variants[product.ID]) > 0 {
+			return nil, nil, 0, errors.New("variant is required for this product")
+		}
+		if !product.InStock {
+			return product, nil, 0, nil
+		}
+		return product, nil, product.Quantity, nil
+	}
+
+	for _, v := range variants[product.ID] {
+		if v.ID == *item.VariantID {
+			variant := v
+			item.SKU = variant.SKU
+			if !variant.InStock {
+				return product, &variant, 0, nil
+			}
+			return product, &variant, variant.Quantity, nil
+		}
+	}
+	return nil, nil, 0, errors.New("variant not found")
+}
+
+// adjustLineStock takes the quantity of a product or variant from stock, or
+// returns it when the quantity is negative, recording the change in the
+// inventory ledger. Bundles take and return the stock of their components.
+// It returns the batches the stock was taken from.
+func adjustLineStock(productRepo *repository.ProductRepository, productID int, variantID *int, quantity int, change models.StockChange) ([]models.BatchAllocation, error) {
+	if quantity == 0 {
+		return nil, nil
+	}
+
+	if variantID == nil {
+		bundles, err := productRepo.GetBundlesByProductIDs([]int{productID})
+		if err != nil {
+			return nil, err
+		}
+		if bundle := bundles[productID]; bundle != nil {
+			var batches []models.BatchAllocation
+			for _, c := range bundle.Components {
+				taken, err := adjustLineStock(productRepo, c.ProductID, c.VariantID, quantity*c.Quantity, change)
+				if err != nil {
+					return batches, err
+				}
+				batches = append(batches, taken...)
+			}
+			return batches, nil
+		}
+	}
+
+	movement, err := productRepo.AdjustStock(productID, variantID, -quantity, change)
+	if err != nil || movement == nil || quantity < 0 {
+		return nil, err
+	}
+	return movement.Batches, nil
+}
+
+// orderReference is the inventory movement reference of an order
+func orderReference(orderID int) string {
+	return fmt.Sprintf("order:%d", orderID)
+}
+
+// orderLocation is the location the stock of an order is taken from, zero
+// for the default location
+func orderLocation(order *models.Order) int {
+	if order.FulfilmentLocationID == nil {
+		return 0
+	}
+	return *order.FulfilmentLocationID
+}
+
 func (s *OrderService) GetOrderByID(id int) (*models.Order, error) {
 	return s.orderRepo.GetOrderByID(id)
 }
 
 func (s *OrderService) UpdateOrderStatus(id int, status string) error {
-	return s.orderRepo.UpdateOrderStatus(id, status)
+	if err := s.orderRepo.UpdateOrderStatus(id, status); err != nil {
+		return err
+	}
+
+	if s.emailService != nil {
+		order, err := s.orderRepo.GetOrderByID(id)
+		if err != nil {
+			log.Printf("Failed to get order %d for guest notification: %v", id, err)
+		} else if order != nil {
+			s.notifyGuest(order)
+		}
+	}
+	return nil
+}
+
+// BuildInvoice builds the invoice of an order from its stored tax breakdown
+func (s *OrderService) BuildInvoice(order *models.Order) (*models.Invoice, error) {
+	invoice := &models.Invoice{
+		Number:           fmt.Sprintf("INV-%06d", order.ID),
+		OrderID:          order.ID,
+		IssuedAt:         order.CreatedAt,
+		Status:           order.Status,
+		Currency:         order.Currency,
+		Lines:            make([]models.InvoiceLine, 0, len(order.Items)),
+		TaxBreakdown:     order.TaxBreakdown,
+		TaxCents:         order.TaxCents,
+		TotalCents:       order.AmountCents,
+		PricesIncludeTax: order.PricesIncludeTax,
+	}
+
+	for _, item := range order.Items {
+		title := fmt.Sprintf("Товар %d", item.ProductID)
+		product, err := s.productRepo.GetProductByID(item.ProductID)
+		if err != nil {
+			return nil, err
+		}
+		if product != nil {
+			title = product.Title
+		}
+		if item.VariantID != nil {
+			variant, err := s.productRepo.GetVariantByID(*item.VariantID)
+			if err != nil {
+				return nil, err
+			}
+			if variant != nil {
+				title += ", " + variant.Title
+			}
+		}
+
+		net, tax, gross := calculateLineTax(item.PriceCents, item.Quantity, item.Unit, item.TaxCategory, order.PricesIncludeTax)
+		invoice.Lines = append(invoice.Lines, models.InvoiceLine{
+			ProductID:   item.ProductID,
+			VariantID:   item.VariantID,
+			SKU:         item.SKU,
+			Title:       title,
+			Quantity:    item.Quantity,
+			Unit:        item.Unit,
+			PriceCents:  item.PriceCents,
+			TaxCategory: item.TaxCategory,
+			RatePercent: taxRatePercent(item.TaxCategory),
+			NetCents:    net,
+			TaxCents:    tax,
+			GrossCents:  gross,
+		})
+	}
+	invoice.NetCents = invoice.TotalCents - invoice.TaxCents
+
+	return invoice, nil
+}
+
+// GetOrderByAccessToken returns a guest order by its access token
+func (s *OrderService) GetOrderByAccessToken(token string) (*models.Order, error) {
+	if token == "" {
+		return nil, nil
+	}
+	return s.orderRepo.GetOrderByAccessToken(token)
+}
+
+// AttachGuestOrders moves guest orders placed with the email to the user's account
+func (s *OrderService) AttachGuestOrders(email string, userID int) (int64, error) {
+	return s.orderRepo.AttachGuestOrders(strings.TrimSpace(email), userID)
+}
+
+// notifyGuest emails the guest the order status with a link to the order.
+// Orders placed by registered users are skipped.
+func (s *OrderService) notifyGuest(order *models.Order) {
+	if s.emailService == nil || order.UserID != nil || order.GuestEmail == "" || order.AccessToken == "" {
+		return
+	}
+
+	go func() {
+		if err := s.emailService.SendGuestOrderEmail(order.GuestEmail, order.ID, order.Status, order.AccessToken, order.AmountCents); err != nil {
+			log.Printf("Failed to send guest order email for order %d: %v", order.ID, err)
+		}
+	}()
 }
 
 func (s *OrderService) UpdateOrderPaymentID(id int, paymentID string) error {
@@ -89,5 +416,49 @@ func (s *OrderService) GetOrdersByUserID(userID int) ([]models.Order, error) {
 }
 
 func (s *OrderService) GetAllOrders() ([]models.Order, error) {
-	return s.orderRepo.GetAllOrders()
+	orders, err := s.orderRepo.GetAllOrders()
+	if err != nil {
+		return orders, err
+	}
+	if err := s.attachDeliverySlots(orders); err != nil {
+		return nil, err
+	}
+	return orders, nil
+}
+
+// GetDeliveryOrders returns orders to be delivered on the given day, with their slots
+func (s *OrderService) GetDeliveryOrders(date time.Time, zone string) ([]models.Order, error) {
+	orders, err := s.orderRepo.GetOrdersByDeliveryDate(date, zone)
+	if err != nil {
+		return orders, err
+	}
+	if err := s.attachDeliverySlots(orders); err != nil {
+		return nil, err
+	}
+	return orders, nil
+}
+
+// attachDeliverySlots loads booked slots for the orders in one query
+func (s *OrderService) attachDeliverySlots(orders []models.Order) error {
+	var slotIDs []int
+	for _, order := range orders {
+		if order.DeliverySlotID != nil {
+			slotIDs = append(slotIDs, *order.DeliverySlotID)
+		}
+	}
+	if len(slotIDs) == 0 {
+		return nil
+	}
+
+	slots, err := s.deliveryRepo.GetSlotsByIDs(slotIDs)
+	if err != nil {
+		return err
+	}
+
+	for i := range orders {
+		if orders[i].DeliverySlotID != nil {
+			orders[i].DeliverySlot = slots[*orders[i].DeliverySlotID]
+		}
+	}
+	return nil
 }
@@ -77,7 +77,7 @@ func (m *mockOrderRepository) GetAllOrders() ([]models.Order, error) {
 func TestOrderService_CreateOrder(t *testing.T) {
 	orderRepo := newMockOrderRepository()
 	productRepo := newMockProductRepository()
-	service := NewOrderService(orderRepo, productRepo)
+	service := NewOrderService(orderRepo, productRepo, nil)
 
 	// Create test product
 	testProduct := &models.Product{
@@ -97,7 +97,7 @@ func TestOrderService_CreateOrder(t *testing.T) {
 		"city":    "Test City",
 	}
 
-	order, err := service.CreateOrder(&userID, items, shippingAddress)
+	order, err := service.CreateOrder(&userID, items, shippingAddress, nil)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -115,14 +115,14 @@ func TestOrderService_CreateOrder(t *testing.T) {
 func TestOrderService_CreateOrder_ProductNotFound(t *testing.T) {
 	orderRepo := newMockOrderRepository()
 	productRepo := newMockProductRepository()
-	service := NewOrderService(orderRepo, productRepo)
+	service := NewOrderService(orderRepo, productRepo, nil)
 
 	userID := 1
 	items := []models.OrderItem{
 		{ProductID: 999, Quantity: 1, PriceCents: 1000},
 	}
 
-	order, err := service.CreateOrder(&userID, items, map[string]interface{}{})
+	order, err := service.CreateOrder(&userID, items, map[string]interface{}{}, nil)
 	if err == nil {
 		t.Error("expected error for non-existent product")
 	}
@@ -134,7 +134,7 @@ func TestOrderService_CreateOrder_ProductNotFound(t *testing.T) {
 func TestOrderService_CreateOrder_OutOfStock(t *testing.T) {
 	orderRepo := newMockOrderRepository()
 	productRepo := newMockProductRepository()
-	service := NewOrderService(orderRepo, productRepo)
+	service := NewOrderService(orderRepo, productRepo, nil)
 
 	testProduct := &models.Product{
 		ID:       1,
@@ -148,7 +148,7 @@ func TestOrderService_CreateOrder_OutOfStock(t *testing.T) {
 		{ProductID: 1, Quantity: 1, PriceCents: 1000},
 	}
 
-	order, err := service.CreateOrder(&userID, items, map[string]interface{}{})
+	order, err := service.CreateOrder(&userID, items, map[string]interface{}{}, nil)
 	if err == nil {
 		t.Error("expected error for out of stock product")
 	}
@@ -160,7 +160,7 @@ func TestOrderService_CreateOrder_OutOfStock(t *testing.T) {
 func TestOrderService_UpdateOrderStatus(t *testing.T) {
 	orderRepo := newMockOrderRepository()
 	productRepo := newMockProductRepository()
-	service := NewOrderService(orderRepo, productRepo)
+	service := NewOrderService(orderRepo, productRepo, nil)
 
 	// Create order
 	order := &models.Order{
@@ -182,7 +182,7 @@ func TestOrderService_UpdateOrderStatus(t *testing.T) {
 func TestOrderService_GetOrdersByUserID(t *testing.T) {
 	orderRepo := newMockOrderRepository()
 	productRepo := newMockProductRepository()
-	service := NewOrderService(orderRepo, productRepo)
+	service := NewOrderService(orderRepo, productRepo, nil)
 
 	userID := 1
 	order := &models.Order{
@@ -0,0 +1,407 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"gastroshop-api/internal/models"
+	"gastroshop-api/internal/repository"
+)
+
+const (
+	// cheeseTag marks the products that can go on a cheese board
+	cheeseTag = "cheese"
+
+	maxPairingNoteLength = 500
+
+	maxCheeseBoardSize           = 6
+	maxCheeseBoardAccompaniments = 6
+)
+
+// validPairingTypes lists the pairing types
+var validPairingTypes = map[string]bool{
+	models.PairingClassic:    true,
+	models.PairingComplement: true,
+	models.PairingContrast:   true,
+	models.PairingRegional:   true,
+}
+
+// cheeseStyles maps tags to the styles a cheese board mixes, checked in
+// order so that a soft blue counts as blue
+var cheeseStyles = []struct {
+	tag   string
+	style string
+}{
+	{"blue", "blue"},
+	{"soft", "soft"},
+	{"bloomy", "soft"},
+	{"hard", "hard"},
+	{"aged", "hard"},
+}
+
+// PairingService manages curated product pairings and builds cheese boards
+// from them
+type PairingService struct {
+	pairingRepo *repository.PairingRepository
+	productRepo *repository.ProductRepository
+}
+
+func NewPairingService(pairingRepo *repository.PairingRepository, productRepo *repository.ProductRepository) *PairingService {
+	return &PairingService{
+		pairingRepo: pairingRepo,
+		productRepo: productRepo,
+	}
+}
+
+// GetProductPairings returns the products that pair well with a product,
+// leaving out archived and out of stock ones
+func (s *PairingService) GetProductPairings(slug string) ([]models.ProductPairing, error) {
+	product, err := s.productRepo.GetProductBySlug(slug)
+	if err != nil {
+		return nil, err
+	}
+	if product == nil || product.ArchivedAt != nil {
+		return nil, errors.New("product not found")
+	}
+
+	pairings, err := s.pairingRepo.GetPairingsByProductIDs([]int{product.ID}, true)
+	if err != nil {
+		return nil, err
+	}
+	return nonNilPairings(pairings[product.ID]), nil
+}
+
+// GetPairings returns all pairings of a product for admins
+func (s *PairingService) GetPairings(productID int) ([]models.ProductPairing, error) {
+	if _, err := s.getProduct(productID); err != nil {
+		return nil, err
+	}
+
+	pairings, err := s.pairingRepo.GetPairingsByProductIDs([]int{productID}, false)
+	if err != nil {
+		return nil, err
+	}
+	return nonNilPairings(pairings[productID]), nil
+}
+
+// GetPairingsByProductIDs returns the available pairings of the products
+// keyed by product ID
+func (s *PairingService) GetPairingsByProductIDs(productIDs []int) (map[int][]models.ProductPairing, error) {
+	return s.pairingRepo.GetPairingsByProductIDs(productIDs, true)
+}
+
+func (s *PairingService) CreatePairing(productID int, req *models.CreateProductPairingRequest) (*models.ProductPairing, error) {
+	if _, err := s.getProduct(productID); err != nil {
+		return nil, err
+	}
+	if req.PairedProductID == productID {
+		return nil, errors.New("product can't be paired with itself")
+	}
+	paired, err := s.productRepo.GetProductByID(req.PairedProductID)
+	if err != nil {
+		return nil, err
+	}
+	if paired == nil {
+		return nil, errors.New("paired product not found")
+	}
+
+	note := strings.TrimSpace(req.Note)
+	if err := validatePairing(req.PairingType, note); err != nil {
+		return nil, err
+	}
+
+	exists, err := s.pairingRepo.PairingExists(productID, req.PairedProductID)
+	if err != nil {
+		return nil, err
+	}
+	if exists {
+		return nil, errors.New("products are already paired")
+	}
+
+	pairing := &models.ProductPairing{
+		ProductID:       productID,
+		PairedProductID: req.PairedProductID,
+		PairingType:     req.PairingType,
+		Note:            note,
+		PairedProduct:   paired,
+	}
+	if err := s.pairingRepo.CreatePairing(pairing); err != nil {
+		return nil, err
+	}
+	return pairing, nil
+}
+
+func (s *PairingService) UpdatePairing(productID, pairingID int, req *models.UpdateProductPairingRequest) (*models.ProductPairing, error) {
+	pairing, err := s.getPairing(productID, pairingID)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.PairingType != nil {
+		pairing.PairingType = *req.PairingType
+	}
+	if req.Note != nil {
+		pairing.Note = strings.TrimSpace(*req.Note)
+	}
+	if err := validatePairing(pairing.PairingType, pairing.Note); err != nil {
+		return nil, err
+	}
+
+	if err := s.pairingRepo.UpdatePairing(pairingID, pairing.PairingType, pairing.Note); err != nil {
+		return nil, err
+	}
+
+	pairing.PairedProduct, err = s.productRepo.GetProductByID(pairing.PairedProductID)
+	if err != nil {
+		return nil, err
+	}
+	return pairing, nil
+}
+
+func (s *PairingService) DeletePairing(productID, pairingID int) error {
+	if _, err := s.getPairing(productID, pairingID); err != nil {
+		return err
+	}
+	return s.pairingRepo.DeletePairing(pairingID)
+}
+
+// BuildCheeseBoard picks cheeses of different styles, starting from the
+// cheese with the given slug if any, and adds the products that pair with
+// most of them. Products that don't suit the dietary restrictions are left
+// out.
+func (s *PairingService) BuildCheeseBoard(size, accompaniments int, startSlug string, restrictions []string) (*models.CheeseBoard, error) {
+	if size < 1 || size > maxCheeseBoardSize {
+		return nil, fmt.Errorf("cheese board size must be between 1 and %d", maxCheeseBoardSize)
+	}
+	if accompaniments < 0 || accompaniments > maxCheeseBoardAccompaniments {
+		return nil, fmt.Errorf("cheese board accompaniments must be between 0 and %d", maxCheeseBoardAccompaniments)
+	}
+
+	cheeses, err := s.productRepo.GetProductsByTags([]string{cheeseTag})
+	if err != nil {
+		return nil, err
+	}
+	cheeses = filterDietary(cheeses, restrictions)
+
+	first := 0
+	if startSlug != "" {
+		for _, cheese := range cheeses {
+			if strings.EqualFold(cheese.Slug, startSlug) {
+				first = cheese.ID
+				break
+			}
+		}
+		if first == 0 {
+			return nil, errors.New("cheese not found")
+		}
+	}
+
+	ids := make([]int, len(cheeses))
+	for i, cheese := range cheeses {
+		ids[i] = cheese.ID
+	}
+	pairings, err := s.pairingRepo.GetPairingsByProductIDs(ids, true)
+	if err != nil {
+		return nil, err
+	}
+	for id := range pairings {
+		pairings[id] = filterPairingsDietary(pairings[id], restrictions)
+	}
+
+	return buildCheeseBoard(cheeses, pairings, first, size, accompaniments), nil
+}
+
+func (s *PairingService) getProduct(productID int) (*models.Product, error) {
+	product, err := s.productRepo.GetProductByID(productID)
+	if err != nil {
+		return nil, err
+	}
+	if product == nil {
+		return nil, errors.New("product not found")
+	}
+	return product, nil
+}
+
+// getPairing returns a pairing of the product from its side
+func (s *PairingService) getPairing(productID, pairingID int) (*models.ProductPairing, error) {
+	pairing, err := s.pairingRepo.GetPairingByID(pairingID)
+	if err != nil {
+		return nil, err
+	}
+	if pairing == nil {
+		return nil, errors.New("pairing not found")
+	}
+
+	switch productID {
+	case pairing.ProductID:
+	case pairing.PairedProductID:
+		pairing.ProductID, pairing.PairedProductID = pairing.PairedProductID, pairing.ProductID
+	default:
+		return nil, errors.New("pairing not found")
+	}
+	return pairing, nil
+}
+
+func validatePairing(pairingType, note string) error {
+	if !validPairingTypes[pairingType] {
+		return errors.New("invalid pairing type")
+	}
+	if utf8.RuneCountInString(note) > maxPairingNoteLength {
+		return fmt.Errorf("pairing note must be at most %d characters", maxPairingNoteLength)
+	}
+	return nil
+}
+
+func nonNilPairings(pairings []models.ProductPairing) []models.ProductPairing {
+	if pairings == nil {
+		return []models.ProductPairing{}
+	}
+	return pairings
+}
+
+// filterPairingsDietary leaves out pairings with products that don't suit
+// the dietary restrictions
+func filterPairingsDietary(pairings []models.ProductPairing, exclusions []string) []models.ProductPairing {
+	required, forbidden := dietaryFilterFlags(exclusions)
+	if len(required) == 0 && len(forbidden) == 0 {
+		return pairings
+	}
+
+	filtered := make([]models.ProductPairing, 0, len(pairings))
+	for _, pairing := range pairings {
+		if pairing.PairedProduct != nil && dietarySuitable(pairing.PairedProduct.DietaryFlags, required, forbidden) {
+			filtered = append(filtered, pairing)
+		}
+	}
+	return filtered
+}
+
+func isCheese(product models.Product) bool {
+	return containsString(product.Tags, cheeseTag)
+}
+
+// cheeseStyle returns the style of a cheese, empty when its tags don't tell
+func cheeseStyle(product models.Product) string {
+	for _, s := range cheeseStyles {
+		if containsString(product.Tags, s.tag) {
+			return s.style
+		}
+	}
+	return ""
+}
+
+// rankPairedProducts returns the products paired with the given ones, most
+// paired first, leaving out the given products themselves
+func rankPairedProducts(products []models.Product, pairings map[int][]models.ProductPairing) []models.Product {
+	given := make(map[int]bool, len(products))
+	for _, p := range products {
+		given[p.ID] = true
+	}
+
+	counts := make(map[int]int)
+	var ranked []models.Product
+	for _, p := range products {
+		for _, pairing := range pairings[p.ID] {
+			other := pairing.PairedProduct
+			if other == nil || given[other.ID] {
+				continue
+			}
+			if counts[other.ID] == 0 {
+				ranked = append(ranked, *other)
+			}
+			counts[other.ID]++
+		}
+	}
+
+	sort.SliceStable(ranked, func(i, j int) bool {
+		return counts[ranked[i].ID] > counts[ranked[j].ID]
+	})
+	return ranked
+}
+
+// buildCheeseBoard picks size cheeses, first the one with the given ID if
+// any, then each time a cheese of a style not yet on the board with the most
+// pairings. The accompaniments are the products other than cheeses that
+// pair with most cheeses of the board.
+func buildCheeseBoard(cheeses []models.Product, pairings map[int][]models.ProductPairing, first, size, accompaniments int) *models.CheeseBoard {
+	board := &models.CheeseBoard{
+		Cheeses:        []models.Product{},
+		Accompaniments: []models.CheeseBoardAccompaniment{},
+	}
+
+	// Cheeses are scored by the products other than cheeses they pair with
+	pairingCount := func(cheese models.Product) int {
+		count := 0
+		for _, pairing := range pairings[cheese.ID] {
+			if pairing.PairedProduct != nil && !isCheese(*pairing.PairedProduct) {
+				count++
+			}
+		}
+		return count
+	}
+
+	onBoard := make(map[int]bool)
+	styles := make(map[string]bool)
+	add := func(cheese models.Product) {
+		board.Cheeses = append(board.Cheeses, cheese)
+		onBoard[cheese.ID] = true
+		styles[cheeseStyle(cheese)] = true
+	}
+
+	for _, cheese := range cheeses {
+		if cheese.ID == first {
+			add(cheese)
+			break
+		}
+	}
+
+	for len(board.Cheeses) < size {
+		best := -1
+		bestNewStyle, bestCount := false, 0
+		for i, cheese := range cheeses {
+			if onBoard[cheese.ID] {
+				continue
+			}
+			style := cheeseStyle(cheese)
+			newStyle := style != "" && !styles[style]
+			count := pairingCount(cheese)
+			if best < 0 || (newStyle && !bestNewStyle) || (newStyle == bestNewStyle && count > bestCount) {
+				best, bestNewStyle, bestCount = i, newStyle, count
+			}
+		}
+		if best < 0 {
+			break
+		}
+		add(cheeses[best])
+	}
+
+	byProduct := make(map[int]int)
+	for _, cheese := range board.Cheeses {
+		for _, pairing := range pairings[cheese.ID] {
+			other := pairing.PairedProduct
+			if other == nil || onBoard[other.ID] || isCheese(*other) {
+				continue
+			}
+			i, ok := byProduct[other.ID]
+			if !ok {
+				i = len(board.Accompaniments)
+				byProduct[other.ID] = i
+				board.Accompaniments = append(board.Accompaniments, models.CheeseBoardAccompaniment{Product: *other})
+			}
+			pairing.PairedProduct = nil
+			board.Accompaniments[i].Pairings = append(board.Accompaniments[i].Pairings, pairing)
+		}
+	}
+
+	sort.SliceStable(board.Accompaniments, func(i, j int) bool {
+		return len(board.Accompaniments[i].Pairings) > len(board.Accompaniments[j].Pairings)
+	})
+	if len(board.Accompaniments) > accompaniments {
+		board.Accompaniments = board.Accompaniments[:accompaniments]
+	}
+
+	return board
+}
@@ -0,0 +1,142 @@
+package services
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"gastroshop-api/internal/models"
+)
+
+func TestValidatePairing(t *testing.T) {
+	tests := []struct {
+		name        string
+		pairingType string
+		note        string
+		wantErr     bool
+	}{
+		{name: "classic", pairingType: models.PairingClassic, note: "Вино и сыр"},
+		{name: "no note", pairingType: models.PairingRegional},
+		{name: "unknown type", pairingType: "perfect", wantErr: true},
+		{name: "note too long", pairingType: models.PairingContrast, note: strings.Repeat("я", maxPairingNoteLength+1), wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validatePairing(tt.pairingType, tt.note)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validatePairing() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// pairingsOf builds the pairings of a product with the given products
+func pairingsOf(productID int, paired ...models.Product) []models.ProductPairing {
+	pairings := make([]models.ProductPairing, len(paired))
+	for i := range paired {
+		pairings[i] = models.ProductPairing{
+			ProductID:       productID,
+			PairedProductID: paired[i].ID,
+			PairingType:     models.PairingClassic,
+			PairedProduct:   &paired[i],
+		}
+	}
+	return pairings
+}
+
+func productIDs(products []models.Product) []int {
+	ids := []int{}
+	for _, p := range products {
+		ids = append(ids, p.ID)
+	}
+	return ids
+}
+
+func TestRankPairedProducts(t *testing.T) {
+	honey := models.Product{ID: 10, Tags: []string{"honey"}}
+	wine := models.Product{ID: 11, Tags: []string{"wine"}}
+	blue := models.Product{ID: 2, Tags: []string{"cheese", "blue"}}
+	products := []models.Product{{ID: 1}, blue}
+
+	pairings := map[int][]models.ProductPairing{
+		1: pairingsOf(1, wine, blue),
+		2: pairingsOf(2, honey, wine),
+	}
+
+	got := productIDs(rankPairedProducts(products, pairings))
+	if want := []int{11, 10}; !reflect.DeepEqual(got, want) {
+		t.Errorf("rankPairedProducts() = %v, want %v", got, want)
+	}
+}
+
+func TestBuildCheeseBoard(t *testing.T) {
+	brie := models.Product{ID: 1, Tags: []string{"cheese", "soft"}}
+	camembert := models.Product{ID: 2, Tags: []string{"cheese", "soft"}}
+	comte := models.Product{ID: 3, Tags: []string{"cheese", "aged"}}
+	dorblu := models.Product{ID: 4, Tags: []string{"cheese", "blue"}}
+	cheeses := []models.Product{brie, camembert, comte, dorblu}
+
+	honey := models.Product{ID: 10}
+	wine := models.Product{ID: 11}
+	walnuts := models.Product{ID: 12}
+	pairings := map[int][]models.ProductPairing{
+		1: pairingsOf(1, wine, camembert),
+		2: pairingsOf(2, wine, honey, walnuts),
+		3: pairingsOf(3, wine),
+		4: pairingsOf(4, honey),
+	}
+
+	t.Run("mixes styles", func(t *testing.T) {
+		board := buildCheeseBoard(cheeses, pairings, 0, 3, 2)
+		if got, want := productIDs(board.Cheeses), []int{2, 3, 4}; !reflect.DeepEqual(got, want) {
+			t.Errorf("cheeses = %v, want %v", got, want)
+		}
+
+		var accompaniments []int
+		for _, a := range board.Accompaniments {
+			accompaniments = append(accompaniments, a.Product.ID)
+		}
+		if want := []int{11, 10}; !reflect.DeepEqual(accompaniments, want) {
+			t.Errorf("accompaniments = %v, want %v", accompaniments, want)
+		}
+		if n := len(board.Accompaniments[0].Pairings); n != 2 {
+			t.Errorf("wine pairings = %d, want 2", n)
+		}
+	})
+
+	t.Run("starts from the given cheese", func(t *testing.T) {
+		board := buildCheeseBoard(cheeses, pairings, 1, 2, 3)
+		if got, want := productIDs(board.Cheeses), []int{1, 3}; !reflect.DeepEqual(got, want) {
+			t.Errorf("cheeses = %v, want %v", got, want)
+		}
+		for _, a := range board.Accompaniments {
+			if a.Product.ID == camembert.ID {
+				t.Error("cheese off the board listed as accompaniment")
+			}
+		}
+	})
+
+	t.Run("fewer cheeses than size", func(t *testing.T) {
+		board := buildCheeseBoard(cheeses[:2], pairings, 0, 5, 0)
+		if len(board.Cheeses) != 2 || len(board.Accompaniments) != 0 {
+			t.Errorf("board = %d cheeses, %d accompaniments, want 2 and 0", len(board.Cheeses), len(board.Accompaniments))
+		}
+	})
+}
+
+func TestPairingsPrompt(t *testing.T) {
+	if got := pairingsPrompt([]models.Product{{ID: 1}}, nil); got != "" {
+		t.Errorf("pairingsPrompt() without pairings = %q, want empty", got)
+	}
+
+	comte := models.Product{ID: 1, Title: "Комте"}
+	wine := models.Product{ID: 2, Title: "Шабли"}
+	pairings := map[int][]models.ProductPairing{1: pairingsOf(1, wine)}
+	pairings[1][0].Note = "Минеральность вина и орехи сыра"
+
+	got := pairingsPrompt([]models.Product{comte}, pairings)
+	if !strings.Contains(got, "Комте + Шабли (классическое сочетание): Минеральность вина и орехи сыра") {
+		t.Errorf("pairingsPrompt() = %q, want the pairing listed", got)
+	}
+}
@@ -0,0 +1,38 @@
+package services
+
+import (
+	"testing"
+
+	"gastroshop-api/internal/models"
+)
+
+func TestProductReferenceSummary(t *testing.T) {
+	tests := []struct {
+		name string
+		refs models.ProductReferences
+		want string
+	}{
+		{name: "unreferenced", refs: models.ProductReferences{}, want: ""},
+		{name: "single order", refs: models.ProductReferences{Orders: 1}, want: "1 order"},
+		{
+			name: "several kinds",
+			refs: models.ProductReferences{Orders: 3, Reviews: 1, SubscriptionBoxes: 2},
+			want: "3 orders, 1 review, 2 subscription boxes",
+		},
+		{name: "subscriptions", refs: models.ProductReferences{Subscriptions: 2}, want: "2 subscriptions"},
+		{name: "bundle", refs: models.ProductReferences{Bundles: 1}, want: "1 bundle"},
+		{
+			name: "history",
+			refs: models.ProductReferences{StockMovements: 2, Batches: 1, PriceChanges: 3},
+			want: "2 stock movements, 1 batch, 3 price changes",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ProductReferenceSummary(&tt.refs); got != tt.want {
+				t.Errorf("ProductReferenceSummary() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
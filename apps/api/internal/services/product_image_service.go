@@ -0,0 +1,341 @@
+package services
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"log"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+
+	"gastroshop-api/internal/models"
+	"gastroshop-api/internal/repository"
+	"gastroshop-api/internal/storage"
+
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp"
+)
+
+// productImageTypes are the accepted image types with their file extensions
+var productImageTypes = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+	"image/webp": ".webp",
+}
+
+// productImageSizes are the resized variants made for every upload, by the
+// longest side in pixels
+var productImageSizes = []struct {
+	name    string
+	maxSide int
+}{
+	{models.ImageVariantThumb, 320},
+	{models.ImageVariantMedium, 960},
+}
+
+// maxProductImageSide limits the dimensions of uploads, as the whole image
+// is decoded in memory
+const maxProductImageSide = 8000
+
+// ProductImageService stores uploaded product images with their resized
+// and WebP variants
+type ProductImageService struct {
+	productRepo *repository.ProductRepository
+	storage     storage.Storage
+	webpEncoder string
+}
+
+// NewProductImageService creates the service. WebP variants are made with
+// the cwebp binary at webpEncoderPath and skipped when it isn't available.
+func NewProductImageService(productRepo *repository.ProductRepository, store storage.Storage, webpEncoderPath string) *ProductImageService {
+	s := &ProductImageService{productRepo: productRepo, storage: store}
+	if webpEncoderPath != "" {
+		path, err := exec.LookPath(webpEncoderPath)
+		if err != nil {
+			log.Printf("WebP image variants disabled: %v", err)
+		} else {
+			s.webpEncoder = path
+		}
+	}
+	return s
+}
+
+func (s *ProductImageService) GetImages(productID int) ([]models.ProductImage, error) {
+	if _, err := s.getProduct(productID); err != nil {
+		return nil, err
+	}
+
+	images, err := s.productRepo.GetProductImagesByProductIDs([]int{productID})
+	if err != nil {
+		return nil, err
+	}
+	if images[productID] == nil {
+		return []models.ProductImage{}, nil
+	}
+	return images[productID], nil
+}
+
+// UploadImage validates an uploaded image, stores it with its variants and
+// appends it to the product images
+func (s *ProductImageService) UploadImage(productID int, data []byte) (*models.ProductImage, error) {
+	if _, err := s.getProduct(productID); err != nil {
+		return nil, err
+	}
+
+	contentType := http.DetectContentType(data)
+	ext, ok := productImageTypes[contentType]
+	if !ok {
+		return nil, errors.New("unsupported image type, use JPEG, PNG or WebP")
+	}
+
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, errors.New("invalid image")
+	}
+	if config.Width > maxProductImageSide || config.Height > maxProductImageSide {
+		return nil, fmt.Errorf("image must be at most %dx%d pixels", maxProductImageSide, maxProductImageSide)
+	}
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, errors.New("invalid image")
+	}
+
+	token, err := imageToken()
+	if err != nil {
+		return nil, err
+	}
+	base := fmt.Sprintf("products/%d/%s", productID, token)
+
+	img := &models.ProductImage{
+		ProductID:   productID,
+		ContentType: contentType,
+		Width:       config.Width,
+		Height:      config.Height,
+		SizeBytes:   len(data),
+		Variants:    map[string]string{},
+	}
+
+	// Files stored before a failure are removed again
+	stored := false
+	defer func() {
+		if !stored {
+			s.deleteFiles(img.StorageKeys)
+		}
+	}()
+	put := func(key string, data []byte, contentType string) (string, error) {
+		url, err := s.storage.Put(key, bytes.NewReader(data), int64(len(data)), contentType)
+		if err != nil {
+			return "", err
+		}
+		img.StorageKeys = append(img.StorageKeys, key)
+		return url, nil
+	}
+
+	if img.URL, err = put(base+ext, data, contentType); err != nil {
+		return nil, err
+	}
+	if contentType == "image/webp" {
+		img.Variants[models.ImageVariantWebP] = img.URL
+	} else if err := s.putWebP(img, models.ImageVariantWebP, base+".webp", data, put); err != nil {
+		return nil, err
+	}
+
+	for _, size := range productImageSizes {
+		resized, resizedType, err := resizeImage(src, contentType, size.maxSide)
+		if err != nil {
+			return nil, err
+		}
+		key := fmt.Sprintf("%s-%s%s", base, size.name, productImageTypes[resizedType])
+		if img.Variants[size.name], err = put(key, resized, resizedType); err != nil {
+			return nil, err
+		}
+
+		webpName := size.name + "_" + models.ImageVariantWebP
+		if err := s.putWebP(img, webpName, base+"-"+size.name+".webp", resized, put); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := s.productRepo.CreateProductImage(img); err != nil {
+		return nil, err
+	}
+	stored = true
+
+	return img, nil
+}
+
+// putWebP stores a WebP version of a JPEG or PNG image as the named variant
+// when a WebP encoder is available
+func (s *ProductImageService) putWebP(img *models.ProductImage, name, key string, data []byte,
+	put func(key string, data []byte, contentType string) (string, error)) error {
+	if s.webpEncoder == "" {
+		return nil
+	}
+
+	webp, err := s.encodeWebP(data)
+	if err != nil {
+		return err
+	}
+	url, err := put(key, webp, "image/webp")
+	if err != nil {
+		return err
+	}
+	img.Variants[name] = url
+	return nil
+}
+
+// encodeWebP converts a JPEG or PNG image with cwebp
+func (s *ProductImageService) encodeWebP(data []byte) ([]byte, error) {
+	dir, err := os.MkdirTemp("", "product-image")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+
+	in := filepath.Join(dir, "image")
+	out := filepath.Join(dir, "image.webp")
+	if err := os.WriteFile(in, data, 0o600); err != nil {
+		return nil, err
+	}
+
+	output, err := exec.Command(s.webpEncoder, "-quiet", "-q", "80", "-metadata", "none", in, "-o", out).CombinedOutput()
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode WebP: %v: %s", err, output)
+	}
+	return os.ReadFile(out)
+}
+
+// DeleteImage removes an uploaded image from the product and deletes its files
+func (s *ProductImageService) DeleteImage(productID, imageID int) error {
+	img, err := s.productRepo.GetProductImageByID(imageID)
+	if err != nil {
+		return err
+	}
+	if img == nil || img.ProductID != productID {
+		return errors.New("image not found")
+	}
+
+	if err := s.productRepo.DeleteProductImage(imageID); err != nil {
+		return err
+	}
+	s.deleteFiles(img.StorageKeys)
+	return nil
+}
+
+// ReorderImages sets the display order of the product images; urls must
+// list every current image URL once
+func (s *ProductImageService) ReorderImages(productID int, urls []string) ([]string, error) {
+	product, err := s.getProduct(productID)
+	if err != nil {
+		return nil, err
+	}
+	if !sameImageURLs(product.Images, urls) {
+		return nil, errors.New("urls must list every product image exactly once")
+	}
+
+	if err := s.productRepo.SetProductImages(productID, urls); err != nil {
+		return nil, err
+	}
+	return urls, nil
+}
+
+func (s *ProductImageService) getProduct(productID int) (*models.Product, error) {
+	product, err := s.productRepo.GetProductByID(productID)
+	if err != nil {
+		return nil, err
+	}
+	if product == nil {
+		return nil, errors.New("product not found")
+	}
+	return product, nil
+}
+
+// deleteFiles deletes stored files, logging failures as the image records
+// are already gone
+func (s *ProductImageService) deleteFiles(keys []string) {
+	for _, key := range keys {
+		if err := s.storage.Delete(key); err != nil {
+			log.Printf("Failed to delete stored image %s: %v", key, err)
+		}
+	}
+}
+
+func imageToken() (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// resizeImage scales the image to fit maxSide and encodes it. PNG images
+// stay PNG to keep transparency; others become JPEG on a white background.
+func resizeImage(src image.Image, contentType string, maxSide int) ([]byte, string, error) {
+	bounds := src.Bounds()
+	width, height := fitWithin(bounds.Dx(), bounds.Dy(), maxSide)
+	rect := image.Rect(0, 0, width, height)
+
+	var buf bytes.Buffer
+	if contentType == "image/png" {
+		dst := image.NewNRGBA(rect)
+		draw.CatmullRom.Scale(dst, rect, src, bounds, draw.Src, nil)
+		if err := png.Encode(&buf, dst); err != nil {
+			return nil, "", err
+		}
+		return buf.Bytes(), "image/png", nil
+	}
+
+	dst := image.NewRGBA(rect)
+	draw.Draw(dst, rect, image.NewUniform(color.White), image.Point{}, draw.Src)
+	draw.CatmullRom.Scale(dst, rect, src, bounds, draw.Over, nil)
+	if err := jpeg.Encode(&buf, dst, &jpeg.Options{Quality: 85}); err != nil {
+		return nil, "", err
+	}
+	return buf.Bytes(), "image/jpeg", nil
+}
+
+// fitWithin scales width and height down to fit a square of maxSide,
+// keeping the aspect ratio. Smaller images keep their size.
+func fitWithin(width, height, maxSide int) (int, int) {
+	if width <= maxSide && height <= maxSide {
+		return width, height
+	}
+	if width >= height {
+		return maxSide, atLeastOne(height * maxSide / width)
+	}
+	return atLeastOne(width * maxSide / height), maxSide
+}
+
+func atLeastOne(n int) int {
+	if n < 1 {
+		return 1
+	}
+	return n
+}
+
+// sameImageURLs reports whether urls holds exactly the current image URLs in
+// any order
+func sameImageURLs(current, urls []string) bool {
+	if len(current) != len(urls) {
+		return false
+	}
+	counts := make(map[string]int, len(current))
+	for _, url := range current {
+		counts[url]++
+	}
+	for _, url := range urls {
+		if counts[url] == 0 {
+			return false
+		}
+		counts[url]--
+	}
+	return true
+}
@@ -0,0 +1,96 @@
+package services
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"net/http"
+	"testing"
+)
+
+func TestFitWithin(t *testing.T) {
+	tests := []struct {
+		name                   string
+		width, height, maxSide int
+		wantWidth, wantHeight  int
+	}{
+		{name: "landscape", width: 4000, height: 3000, maxSide: 320, wantWidth: 320, wantHeight: 240},
+		{name: "portrait", width: 1000, height: 2000, maxSide: 960, wantWidth: 480, wantHeight: 960},
+		{name: "square", width: 1200, height: 1200, maxSide: 320, wantWidth: 320, wantHeight: 320},
+		{name: "smaller is not upscaled", width: 200, height: 100, maxSide: 320, wantWidth: 200, wantHeight: 100},
+		{name: "thin strip keeps a pixel", width: 5000, height: 2, maxSide: 320, wantWidth: 320, wantHeight: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w, h := fitWithin(tt.width, tt.height, tt.maxSide)
+			if w != tt.wantWidth || h != tt.wantHeight {
+				t.Errorf("fitWithin() = %dx%d, want %dx%d", w, h, tt.wantWidth, tt.wantHeight)
+			}
+		})
+	}
+}
+
+func TestSameImageURLs(t *testing.T) {
+	current := []string{"a.jpg", "b.jpg", "c.jpg"}
+	tests := []struct {
+		name string
+		urls []string
+		want bool
+	}{
+		{name: "same order", urls: []string{"a.jpg", "b.jpg", "c.jpg"}, want: true},
+		{name: "reordered", urls: []string{"c.jpg", "a.jpg", "b.jpg"}, want: true},
+		{name: "missing", urls: []string{"a.jpg", "b.jpg"}, want: false},
+		{name: "duplicate", urls: []string{"a.jpg", "a.jpg", "b.jpg"}, want: false},
+		{name: "unknown", urls: []string{"a.jpg", "b.jpg", "d.jpg"}, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := sameImageURLs(current, tt.urls); got != tt.want {
+				t.Errorf("sameImageURLs() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResizeImage(t *testing.T) {
+	src := image.NewNRGBA(image.Rect(0, 0, 800, 400))
+	for x := 0; x < 800; x++ {
+		for y := 0; y < 400; y++ {
+			src.Set(x, y, color.NRGBA{R: 200, G: 100, B: 50, A: 255})
+		}
+	}
+
+	tests := []struct {
+		contentType string
+		wantType    string
+	}{
+		{contentType: "image/jpeg", wantType: "image/jpeg"},
+		{contentType: "image/webp", wantType: "image/jpeg"},
+		{contentType: "image/png", wantType: "image/png"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.contentType, func(t *testing.T) {
+			data, gotType, err := resizeImage(src, tt.contentType, 320)
+			if err != nil {
+				t.Fatalf("resizeImage() error = %v", err)
+			}
+			if gotType != tt.wantType {
+				t.Errorf("resizeImage() type = %s, want %s", gotType, tt.wantType)
+			}
+			if detected := http.DetectContentType(data); detected != tt.wantType {
+				t.Errorf("encoded data is %s, want %s", detected, tt.wantType)
+			}
+
+			config, _, err := image.DecodeConfig(bytes.NewReader(data))
+			if err != nil {
+				t.Fatalf("DecodeConfig() error = %v", err)
+			}
+			if config.Width != 320 || config.Height != 160 {
+				t.Errorf("resized to %dx%d, want 320x160", config.Width, config.Height)
+			}
+		})
+	}
+}
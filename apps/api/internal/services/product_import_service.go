@@ -0,0 +1,286 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"time"
+
+	"gastroshop-api/internal/models"
+	"gastroshop-api/internal/repository"
+)
+
+// importProgressEvery is how many rows are processed between progress saves
+const importProgressEvery = 50
+
+// ProductImportService imports products from spreadsheets in background jobs
+// and exports the catalog in the same format
+type ProductImportService struct {
+	importJobRepo *repository.ImportJobRepository
+	productRepo   *repository.ProductRepository
+	regionRepo    *repository.RegionRepository
+}
+
+func NewProductImportService(importJobRepo *repository.ImportJobRepository, productRepo *repository.ProductRepository, regionRepo *repository.RegionRepository) *ProductImportService {
+	return &ProductImportService{
+		importJobRepo: importJobRepo,
+		productRepo:   productRepo,
+		regionRepo:    regionRepo,
+	}
+}
+
+// StartImport parses the file and starts a background job for its rows.
+// Files that can't be parsed are rejected right away.
+func (s *ProductImportService) StartImport(userID int, filename string, data []byte, dryRun bool) (*models.ProductImportJob, error) {
+	format, err := SheetFormat(filename)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := readProductSheet(data, format)
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return nil, errors.New("file has no product rows")
+	}
+
+	job := &models.ProductImportJob{
+		UserID:    &userID,
+		Filename:  filename,
+		DryRun:    dryRun,
+		Status:    models.ImportStatusQueued,
+		TotalRows: len(rows),
+		Errors:    []models.ImportRowError{},
+	}
+	if err := s.importJobRepo.CreateImportJob(job); err != nil {
+		return nil, err
+	}
+
+	go s.run(*job, rows)
+
+	return job, nil
+}
+
+// FailInterruptedImports marks the jobs left unfinished by a restart as
+// failed; their background runs are gone. Rows imported before the restart
+// stay imported.
+func (s *ProductImportService) FailInterruptedImports() error {
+	failed, err := s.importJobRepo.FailUnfinishedImportJobs("import was interrupted by a server restart, upload the file again")
+	if err != nil {
+		return err
+	}
+	if failed > 0 {
+		log.Printf("Marked %d interrupted import jobs as failed", failed)
+	}
+	return nil
+}
+
+func (s *ProductImportService) GetImportJob(id int) (*models.ProductImportJob, error) {
+	return s.importJobRepo.GetImportJobByID(id)
+}
+
+func (s *ProductImportService) GetImportJobs() ([]models.ProductImportJob, error) {
+	return s.importJobRepo.GetImportJobs(50)
+}
+
+// ExportProducts writes the whole catalog in the import format
+func (s *ProductImportService) ExportProducts(w io.Writer, format string) error {
+	products, err := s.productRepo.GetAllProducts()
+	if err != nil {
+		return err
+	}
+	return writeProductSheet(w, format, products)
+}
+
+// productImport is a validated row ready to be written
+type productImport struct {
+	line    int
+	product *models.Product
+	exists  bool
+}
+
+// run validates all rows and, unless it's a dry run, writes them when none
+// has errors
+func (s *ProductImportService) run(job models.ProductImportJob, rows []sheetRow) {
+	job.Status = models.ImportStatusValidating
+	s.saveProgress(&job)
+
+	imports, err := s.validateRows(&job, rows)
+	if err != nil {
+		s.finish(&job, models.ImportStatusFailed, models.ImportRowError{Message: err.Error()})
+		return
+	}
+	if job.DryRun {
+		s.finish(&job, models.ImportStatusCompleted)
+		return
+	}
+	if len(job.Errors) > 0 {
+		s.finish(&job, models.ImportStatusFailed)
+		return
+	}
+
+	job.Status = models.ImportStatusImporting
+	job.ProcessedRows = 0
+	job.CreatedCount = 0
+	job.UpdatedCount = 0
+	s.saveProgress(&job)
+
+	// Stock of new products is received, stock of existing ones corrected
+	change := models.StockChange{ActorID: job.UserID, Reference: fmt.Sprintf("import:%d", job.ID)}
+	for i, imp := range imports {
+		if imp.exists {
+			change.Reason = models.MovementCorrection
+			err = s.productRepo.UpdateProduct(imp.product.ID, imp.product, change)
+		} else {
+			change.Reason = models.MovementReceipt
+			err = s.productRepo.CreateProduct(imp.product, change)
+		}
+
+		switch {
+		case err != nil:
+			job.Errors = append(job.Errors, models.ImportRowError{Row: imp.line, Message: err.Error()})
+		case imp.exists:
+			job.UpdatedCount++
+		default:
+			job.CreatedCount++
+		}
+
+		job.ProcessedRows = i + 1
+		if job.ProcessedRows%importProgressEvery == 0 {
+			s.saveProgress(&job)
+		}
+	}
+
+	s.finish(&job, models.ImportStatusCompleted)
+}
+
+// validateRows checks every row against the catalog and collects row errors
+// in the job. The created and updated counts tell what the import would do.
+func (s *ProductImportService) validateRows(job *models.ProductImportJob, rows []sheetRow) ([]productImport, error) {
+	regions, err := s.regionRepo.GetRegions()
+	if err != nil {
+		return nil, err
+	}
+	regionCodes := make(map[string]bool, len(regions))
+	for _, region := range regions {
+		regionCodes[region.Code] = true
+	}
+
+	var imports []productImport
+	slugLines := make(map[string]int)
+	for i, row := range rows {
+		imp, rowErrs, err := s.validateRow(row, regionCodes, slugLines)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(rowErrs) > 0 {
+			job.Errors = append(job.Errors, rowErrs...)
+		} else {
+			imports = append(imports, *imp)
+			if imp.exists {
+				job.UpdatedCount++
+			} else {
+				job.CreatedCount++
+			}
+		}
+
+		job.ProcessedRows = i + 1
+		if job.ProcessedRows%importProgressEvery == 0 {
+			s.saveProgress(job)
+		}
+	}
+
+	return imports, nil
+}
+
+func (s *ProductImportService) validateRow(row sheetRow, regionCodes map[string]bool, slugLines map[string]int) (*productImport, []models.ImportRowError, error) {
+	rowError := func(column, message string) []models.ImportRowError {
+		return []models.ImportRowError{{Row: row.line, Column: column, Message: message}}
+	}
+
+	slug := row.values["slug"]
+	if !slugPattern.MatchString(slug) {
+		return nil, rowError("slug", "slug must contain only lowercase letters, digits and hyphens"), nil
+	}
+	if line, ok := slugLines[slug]; ok {
+		return nil, rowError("slug", fmt.Sprintf("slug already used in row %d", line)), nil
+	}
+	slugLines[slug] = row.line
+
+	product, err := s.productRepo.GetProductBySlug(slug)
+	if err != nil {
+		return nil, nil, err
+	}
+	exists := product != nil
+
+	if exists {
+		variants, err := s.productRepo.GetVariantsByProductIDs([]int{product.ID})
+		if err != nil {
+			return nil, nil, err
+		}
+		// Price and stock of products with variants follow their variants.
+		// Sheets exported from the shop carry them unchanged, which is fine.
+		priceChanged := sheetPriceChanged(row.values["price"], product.PriceCents)
+		quantityChanged := sheetQuantityChanged(row.values["quantity"], product.Quantity)
+		if len(variants[product.ID]) > 0 && (priceChanged || quantityChanged) {
+			return nil, rowError("", "product has variants, update price and quantity per variant"), nil
+		}
+
+		// Bundle stock, and the price of discount bundles, follow their components
+		bundles, err := s.productRepo.GetBundlesByProductIDs([]int{product.ID})
+		if err != nil {
+			return nil, nil, err
+		}
+		if bundle := bundles[product.ID]; bundle != nil {
+			if quantityChanged {
+				return nil, rowError("quantity", "product is a bundle, its stock follows its components"), nil
+			}
+			if bundle.Pricing == models.BundlePricingDiscount && priceChanged {
+				return nil, rowError("price", "product is a discount bundle, its price follows its components"), nil
+			}
+		}
+	} else {
+		// Same defaults as products created by an admin
+		product = &models.Product{
+			Slug:         slug,
+			Currency:     "RUB",
+			Tags:         []string{},
+			Images:       []string{},
+			TaxCategory:  models.TaxCategoryVAT20,
+			Unit:         models.UnitPiece,
+			MinQuantity:  1,
+			QuantityStep: 1,
+		}
+	}
+
+	currentPrice := product.PriceCents
+	errs := applySheetRow(product, row)
+	// The regular price is restored when the sale ends
+	if exists && product.CompareAtPriceCents != nil && product.PriceCents != currentPrice {
+		errs = append(errs, models.ImportRowError{Row: row.line, Column: "price", Message: "product is on sale, price can't be changed"})
+	}
+	if product.RegionCode != "" && !regionCodes[product.RegionCode] {
+		errs = append(errs, models.ImportRowError{Row: row.line, Column: "region_code", Message: "unknown region"})
+	}
+	if len(errs) > 0 {
+		return nil, errs, nil
+	}
+
+	return &productImport{line: row.line, product: product, exists: exists}, nil, nil
+}
+
+func (s *ProductImportService) saveProgress(job *models.ProductImportJob) {
+	if err := s.importJobRepo.UpdateImportJob(job); err != nil {
+		log.Printf("Failed to save progress of import job %d: %v", job.ID, err)
+	}
+}
+
+func (s *ProductImportService) finish(job *models.ProductImportJob, status string, errs ...models.ImportRowError) {
+	now := time.Now()
+	job.Status = status
+	job.FinishedAt = &now
+	job.Errors = append(job.Errors, errs...)
+	s.saveProgress(job)
+}
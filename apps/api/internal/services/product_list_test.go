@@ -0,0 +1,71 @@
+package services
+
+import (
+	"testing"
+
+	"gastroshop-api/internal/models"
+)
+
+func TestPriceBuckets(t *testing.T) {
+	buckets := priceBuckets([]int{50000, 100000}, map[int]int{0: 3, 2: 1})
+
+	if len(buckets) != 3 {
+		t.Fatalf("expected 3 buckets, got %d", len(buckets))
+	}
+
+	want := []struct {
+		min   int
+		max   int // 0 for the open-ended bucket
+		count int
+	}{
+		{min: 0, max: 50000, count: 3},
+		{min: 50000, max: 100000, count: 0},
+		{min: 100000, max: 0, count: 1},
+	}
+	for i, w := range want {
+		b := buckets[i]
+		max := 0
+		if b.MaxCents != nil {
+			max = *b.MaxCents
+		}
+		if b.MinCents != w.min || max != w.max || b.Count != w.count {
+			t.Errorf("bucket %d = {%d, %d, %d}, want {%d, %d, %d}", i, b.MinCents, max, b.Count, w.min, w.max, w.count)
+		}
+	}
+}
+
+func TestWithoutFilters(t *testing.T) {
+	filters := map[string]interface{}{"region": "FR", "price_min": 100, "price_max": 500}
+
+	got := withoutFilters(filters, "price_min", "price_max")
+
+	if len(got) != 1 || got["region"] != "FR" {
+		t.Errorf("withoutFilters() = %v, want only region", got)
+	}
+	if len(filters) != 3 {
+		t.Errorf("withoutFilters() modified the original filters: %v", filters)
+	}
+}
+
+func TestProductCursor(t *testing.T) {
+	cursor := &models.ProductCursor{Sort: models.ProductSortPriceAsc, Value: "129900", ID: 42}
+	encoded := EncodeProductCursor(cursor)
+
+	decoded, err := ParseProductCursor(encoded, models.ProductSortPriceAsc)
+	if err != nil {
+		t.Fatalf("ParseProductCursor() error = %v", err)
+	}
+	if *decoded != *cursor {
+		t.Errorf("ParseProductCursor() = %+v, want %+v", decoded, cursor)
+	}
+
+	if _, err := ParseProductCursor(encoded, models.ProductSortNewest); err == nil {
+		t.Error("expected error for a cursor of another sort")
+	}
+	if _, err := ParseProductCursor("not a cursor", models.ProductSortPriceAsc); err == nil {
+		t.Error("expected error for an invalid cursor")
+	}
+	if EncodeProductCursor(nil) != "" {
+		t.Error("expected empty string for no cursor")
+	}
+}
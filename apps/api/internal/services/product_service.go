@@ -1,40 +1,200 @@
 package services
 
 import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"log"
+	"strings"
+	"time"
+
 	"gastroshop-api/internal/models"
 	"gastroshop-api/internal/repository"
 )
 
 type ProductService struct {
-	productRepo *repository.ProductRepository
+	productRepo      *repository.ProductRepository
+	imageService     *ProductImageService
+	attributeService *AttributeService
+	locationService  *LocationService
 }
 
 func NewProductService(productRepo *repository.ProductRepository) *ProductService {
 	return &ProductService{productRepo: productRepo}
 }
 
-func (s *ProductService) GetProducts(filters map[string]interface{}) ([]models.Product, int, error) {
-	fmt.Printf("DEBUG: ProductService.GetProducts called\n")
-	products, err := s.productRepo.GetProducts(filters)
+// SetImageService sets the image service that deletes the files of purged products
+func (s *ProductService) SetImageService(imageService *ProductImageService) {
+	s.imageService = imageService
+}
+
+// SetAttributeService sets the attribute service that loads the attribute
+// values of returned products
+func (s *ProductService) SetAttributeService(attributeService *AttributeService) {
+	s.attributeService = attributeService
+}
+
+// SetLocationService sets the location service that scopes the stock of
+// returned products to a delivery zone
+func (s *ProductService) SetLocationService(locationService *LocationService) {
+	s.locationService = locationService
+}
+
+// GetProducts returns a page of the filtered products. With filters["zone"]
+// their stock is the stock at the locations serving the delivery zone.
+func (s *ProductService) GetProducts(filters map[string]interface{}) (*models.ProductPage, error) {
+	page, err := s.productRepo.GetProductPage(filters)
 	if err != nil {
-		fmt.Printf("DEBUG: ProductService.GetProducts error: %v\n", err)
-		return nil, 0, err
+		return nil, err
+	}
+
+	if err := s.attachVariants(page.Products); err != nil {
+		return nil, err
+	}
+	if zone, _ := filters["zone"].(string); zone != "" {
+		products := make([]*models.Product, len(page.Products))
+		for i := range page.Products {
+			products[i] = &page.Products[i]
+		}
+		if err := s.ScopeStockToZone(zone, products...); err != nil {
+			return nil, err
+		}
+	}
+	return page, nil
+}
+
+// ScopeStockToZone sets the stock of the products and their variants to the
+// stock at the locations serving the delivery zone
+func (s *ProductService) ScopeStockToZone(zone string, products ...*models.Product) error {
+	if s.locationService == nil || len(products) == 0 {
+		return nil
+	}
+	return s.locationService.applyZoneStock(zone, products)
+}
+
+// ValidProductSort reports whether sort is a known sort mode of the product list
+func ValidProductSort(sort string) bool {
+	switch sort {
+	case models.ProductSortNewest, models.ProductSortPriceAsc, models.ProductSortPriceDesc,
+		models.ProductSortPopularity, models.ProductSortRating, models.ProductSortRelevance:
+		return true
+	}
+	return false
+}
+
+// EncodeProductCursor turns a cursor into the opaque string handed to clients
+func EncodeProductCursor(cursor *models.ProductCursor) string {
+	if cursor == nil {
+		return ""
 	}
+	data, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
 
-	fmt.Printf("DEBUG: ProductService.GetProducts - got %d products, now counting\n", len(products))
-	total, err := s.productRepo.CountProducts(filters)
+// ParseProductCursor decodes a cursor string. The cursor must have been
+// issued for the same sort mode, as its value is a key of that sort.
+func ParseProductCursor(value, sort string) (*models.ProductCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(value)
 	if err != nil {
-		fmt.Printf("DEBUG: ProductService.CountProducts error: %v\n", err)
-		return nil, 0, err
+		return nil, errors.New("invalid cursor")
 	}
 
-	fmt.Printf("DEBUG: ProductService.GetProducts - total: %d\n", total)
-	return products, total, nil
+	var cursor models.ProductCursor
+	if err := json.Unmarshal(data, &cursor); err != nil || cursor.ID <= 0 || cursor.Value == "" {
+		return nil, errors.New("invalid cursor")
+	}
+	if cursor.Sort != sort {
+		return nil, errors.New("cursor belongs to a different sort")
+	}
+	return &cursor, nil
+}
+
+// StartRankingRefresher periodically recomputes the popularity and rating
+// product rankings
+func (s *ProductService) StartRankingRefresher(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			if err := s.productRepo.RefreshProductRankings(); err != nil {
+				log.Printf("Product ranking refresh error: %v", err)
+			}
+		}
+	}()
+}
+
+// priceBucketBounds are the limits of the price facet buckets in cents:
+// up to 500 ₽, 500–1000 ₽, 1000–2000 ₽, 2000–5000 ₽ and from 5000 ₽
+var priceBucketBounds = []int{50000, 100000, 200000, 500000}
+
+// GetProductFacets counts the products per tag, region, category, price
+// bucket and stock state for the filters, each facet ignoring its own filter
+func (s *ProductService) GetProductFacets(filters map[string]interface{}) (*models.ProductFacets, error) {
+	var facets models.ProductFacets
+	var err error
+
+	if facets.Tags, err = s.productRepo.CountProductsByTag(withoutFilters(filters, "tags")); err != nil {
+		return nil, err
+	}
+	if facets.Regions, err = s.productRepo.CountProductsByRegion(withoutFilters(filters, "region")); err != nil {
+		return nil, err
+	}
+	if facets.Categories, err = s.productRepo.CountProductsByCategory(withoutFilters(filters, "categories")); err != nil {
+		return nil, err
+	}
+
+	bucketCounts, err := s.productRepo.CountProductsByPriceBucket(withoutFilters(filters, "price_min", "price_max"), priceBucketBounds)
+	if err != nil {
+		return nil, err
+	}
+	facets.PriceBuckets = priceBuckets(priceBucketBounds, bucketCounts)
+
+	facets.InStock, facets.OutOfStock, err = s.productRepo.CountProductsByStock(withoutFilters(filters, "in_stock"))
+	if err != nil {
+		return nil, err
+	}
+
+	return &facets, nil
+}
+
+// withoutFilters returns a copy of the filters without the given keys
+func withoutFilters(filters map[string]interface{}, keys ...string) map[string]interface{} {
+	out := make(map[string]interface{}, len(filters))
+	for k, v := range filters {
+		out[k] = v
+	}
+	for _, k := range keys {
+		delete(out, k)
+	}
+	return out
+}
+
+// priceBuckets turns counts per width_bucket index into price buckets,
+// including empty ones so the storefront can show a stable list
+func priceBuckets(bounds []int, counts map[int]int) []models.PriceBucketCount {
+	buckets := make([]models.PriceBucketCount, 0, len(bounds)+1)
+	for i := 0; i <= len(bounds); i++ {
+		bucket := models.PriceBucketCount{Count: counts[i]}
+		if i > 0 {
+			bucket.MinCents = bounds[i-1]
+		}
+		if i < len(bounds) {
+			max := bounds[i]
+			bucket.MaxCents = &max
+		}
+		buckets = append(buckets, bucket)
+	}
+	return buckets
 }
 
 func (s *ProductService) GetProductBySlug(slug string) (*models.Product, error) {
-	return s.productRepo.GetProductBySlug(slug)
+	product, err := s.productRepo.GetProductBySlug(slug)
+	if err != nil || product == nil {
+		return product, err
+	}
+	return product, s.attachVariants(nil, product)
 }
 
 func (s *ProductService) GetProductsByRegion(regionCode string) ([]models.Product, error) {
@@ -47,25 +207,305 @@ func (s *ProductService) GetProductsByTags(tags []string) ([]models.Product, err
 
 // Admin methods
 func (s *ProductService) GetProductByID(id int) (*models.Product, error) {
-	return s.productRepo.GetProductByID(id)
+	product, err := s.productRepo.GetProductByID(id)
+	if err != nil || product == nil {
+		return product, err
+	}
+	return product, s.attachVariants(nil, product)
 }
 
-func (s *ProductService) CreateProduct(product *models.Product) error {
-	return s.productRepo.CreateProduct(product)
+// CreateProduct creates a product; its initial stock is recorded as received
+// by the admin
+func (s *ProductService) CreateProduct(product *models.Product, actorID int) error {
+	return s.productRepo.CreateProduct(product, models.StockChange{Reason: models.MovementReceipt, ActorID: &actorID})
 }
 
-func (s *ProductService) UpdateProduct(id int, product *models.Product) error {
-	return s.productRepo.UpdateProduct(id, product)
+// UpdateProduct updates a product; a changed quantity is recorded as a
+// correction by the admin
+func (s *ProductService) UpdateProduct(id int, product *models.Product, actorID int) error {
+	return s.productRepo.UpdateProduct(id, product, models.StockChange{Reason: models.MovementCorrection, ActorID: &actorID})
 }
 
-func (s *ProductService) UpdateProductQuantity(id int, quantity int) error {
-	return s.productRepo.UpdateProductQuantity(id, quantity)
+func (s *ProductService) UpdateProductQuantity(id int, quantity int, actorID int, note string) error {
+	change := models.StockChange{Reason: models.MovementCorrection, ActorID: &actorID, Note: note}
+	return s.productRepo.UpdateProductQuantity(id, quantity, change)
 }
 
-func (s *ProductService) DecreaseProductQuantity(id int, amount int) error {
-	return s.productRepo.DecreaseProductQuantity(id, amount)
+// ArchiveProduct hides a product from the storefront. Archived products
+// can't be ordered but still resolve for orders, statistics and reviews.
+func (s *ProductService) ArchiveProduct(id int) error {
+	return s.productRepo.ArchiveProduct(id)
 }
 
-func (s *ProductService) DeleteProduct(id int) error {
-	return s.productRepo.DeleteProduct(id)
+func (s *ProductService) RestoreProduct(id int) (*models.Product, error) {
+	if err := s.productRepo.RestoreProduct(id); err != nil {
+		return nil, err
+	}
+	return s.GetProductByID(id)
+}
+
+// PurgeProduct deletes a product for good. Products that orders, reviews,
+// subscriptions, boxes or bundles refer to, or that have stock, batch or
+// price history, are kept, and their references returned.
+func (s *ProductService) PurgeProduct(id int) (*models.ProductReferences, error) {
+	images, err := s.productRepo.GetProductImagesByProductIDs([]int{id})
+	if err != nil {
+		return nil, err
+	}
+	refs, err := s.productRepo.PurgeProduct(id)
+	if err != nil || refs != nil {
+		return refs, err
+	}
+
+	if s.imageService != nil {
+		for _, img := range images[id] {
+			s.imageService.deleteFiles(img.StorageKeys)
+		}
+	}
+	return nil, nil
+}
+
+// ProductReferenceSummary describes the references of a product, e.g.
+// "3 orders, 1 review"; it is empty for an unreferenced product
+func ProductReferenceSummary(refs *models.ProductReferences) string {
+	counts := []struct {
+		count            int
+		singular, plural string
+	}{
+		{refs.Orders, "order", "orders"},
+		{refs.Reviews, "review", "reviews"},
+		{refs.Subscriptions, "subscription", "subscriptions"},
+		{refs.SubscriptionBoxes, "subscription box", "subscription boxes"},
+		{refs.Bundles, "bundle", "bundles"},
+		{refs.StockMovements, "stock movement", "stock movements"},
+		{refs.Batches, "batch", "batches"},
+		{refs.PriceChanges, "price change", "price changes"},
+	}
+
+	var parts []string
+	for _, c := range counts {
+		switch {
+		case c.count == 1:
+			parts = append(parts, "1 "+c.singular)
+		case c.count > 1:
+			parts = append(parts, fmt.Sprintf("%d %s", c.count, c.plural))
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// attachVariants loads the variants, uploaded image variants, attribute
+// values and bundle components of the products with a query each and sets
+// their price range
+func (s *ProductService) attachVariants(products []models.Product, extra ...*models.Product) error {
+	targets := make([]*models.Product, 0, len(products)+len(extra))
+	for i := range products {
+		targets = append(targets, &products[i])
+	}
+	targets = append(targets, extra...)
+
+	ids := make([]int, len(targets))
+	for i, p := range targets {
+		ids[i] = p.ID
+	}
+
+	variants, err := s.productRepo.GetVariantsByProductIDs(ids)
+	if err != nil {
+		return err
+	}
+
+	images, err := s.productRepo.GetProductImagesByProductIDs(ids)
+	if err != nil {
+		return err
+	}
+
+	bundles, err := s.productRepo.GetBundlesByProductIDs(ids)
+	if err != nil {
+		return err
+	}
+
+	var attributes map[int][]models.ProductAttributeValue
+	if s.attributeService != nil {
+		attributes, err = s.attributeService.attributeRepo.GetAttributeValuesByProductIDs(ids)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, p := range targets {
+		p.Variants = variants[p.ID]
+		p.PriceRange = variantPriceRange(p.Variants)
+		p.ImageVariants = productImageVariants(images[p.ID])
+		p.Attributes = attributes[p.ID]
+		p.Bundle = bundles[p.ID]
+	}
+	return nil
+}
+
+// productImageVariants maps image URLs to their variants, nil without images
+func productImageVariants(images []models.ProductImage) map[string]map[string]string {
+	if len(images) == 0 {
+		return nil
+	}
+
+	variants := make(map[string]map[string]string, len(images))
+	for _, img := range images {
+		variants[img.URL] = img.Variants
+	}
+	return variants
+}
+
+// variantPriceRange returns the price range of the variants, nil without variants
+func variantPriceRange(variants []models.ProductVariant) *models.PriceRange {
+	if len(variants) == 0 {
+		return nil
+	}
+
+	r := &models.PriceRange{MinCents: variants[0].PriceCents, MaxCents: variants[0].PriceCents}
+	for _, v := range variants[1:] {
+		if v.PriceCents < r.MinCents {
+			r.MinCents = v.PriceCents
+		}
+		if v.PriceCents > r.MaxCents {
+			r.MaxCents = v.PriceCents
+		}
+	}
+	return r
+}
+
+// Product variants
+
+func (s *ProductService) CreateVariant(productID int, req *models.CreateProductVariantRequest, actorID int) (*models.ProductVariant, error) {
+	product, err := s.productRepo.GetProductByID(productID)
+	if err != nil {
+		return nil, err
+	}
+	if product == nil {
+		return nil, errors.New("product not found")
+	}
+
+	// Bundles are made of fixed components, and components are referred to
+	// without a variant
+	bundles, err := s.productRepo.GetBundlesByProductIDs([]int{productID})
+	if err != nil {
+		return nil, err
+	}
+	if bundles[productID] != nil {
+		return nil, errors.New("bundles can't have variants")
+	}
+	inBundle, err := s.productRepo.IsBundleComponent(productID, nil)
+	if err != nil {
+		return nil, err
+	}
+	if inBundle {
+		return nil, errors.New("product is in a bundle; remove it from the bundle before adding variants")
+	}
+
+	variant := &models.ProductVariant{
+		ProductID:   productID,
+		SKU:         req.SKU,
+		Title:       req.Title,
+		WeightGrams: req.WeightGrams,
+		VolumeML:    req.VolumeML,
+		PriceCents:  req.PriceCents,
+		Quantity:    req.Quantity,
+		Barcode:     req.Barcode,
+		SortOrder:   req.SortOrder,
+	}
+	if err := validateVariant(variant); err != nil {
+		return nil, err
+	}
+
+	if err := s.productRepo.CreateVariant(variant, models.StockChange{Reason: models.MovementReceipt, ActorID: &actorID}); err != nil {
+		return nil, err
+	}
+	return variant, nil
+}
+
+func (s *ProductService) UpdateVariant(productID, variantID int, req *models.UpdateProductVariantRequest, actorID int) (*models.ProductVariant, error) {
+	variant, err := s.getProductVariant(productID, variantID)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.SKU != nil {
+		variant.SKU = *req.SKU
+	}
+	if req.Title != nil {
+		variant.Title = *req.Title
+	}
+	if req.WeightGrams != nil {
+		variant.WeightGrams = req.WeightGrams
+	}
+	if req.VolumeML != nil {
+		variant.VolumeML = req.VolumeML
+	}
+	if req.PriceCents != nil {
+		variant.PriceCents = *req.PriceCents
+	}
+	if req.Quantity != nil {
+		variant.Quantity = *req.Quantity
+	}
+	if req.Barcode != nil {
+		variant.Barcode = *req.Barcode
+	}
+	if req.SortOrder != nil {
+		variant.SortOrder = *req.SortOrder
+	}
+	if err := validateVariant(variant); err != nil {
+		return nil, err
+	}
+
+	if err := s.productRepo.UpdateVariant(variantID, variant, models.StockChange{Reason: models.MovementCorrection, ActorID: &actorID}); err != nil {
+		return nil, err
+	}
+	return variant, nil
+}
+
+// DeleteVariant deletes a variant, writing off its stock
+func (s *ProductService) DeleteVariant(productID, variantID, actorID int) error {
+	if _, err := s.getProductVariant(productID, variantID); err != nil {
+		return err
+	}
+
+	inBundle, err := s.productRepo.IsBundleComponent(productID, &variantID)
+	if err != nil {
+		return err
+	}
+	if inBundle {
+		return errors.New("variant is in a bundle; remove it from the bundle first")
+	}
+	return s.productRepo.DeleteVariant(variantID, models.StockChange{
+		Reason:    models.MovementWriteOff,
+		ActorID:   &actorID,
+		Reference: fmt.Sprintf("variant:%d", variantID),
+	})
+}
+
+// getProductVariant returns the variant, making sure it belongs to the product
+func (s *ProductService) getProductVariant(productID, variantID int) (*models.ProductVariant, error) {
+	variant, err := s.productRepo.GetVariantByID(variantID)
+	if err != nil {
+		return nil, err
+	}
+	if variant == nil || variant.ProductID != productID {
+		return nil, errors.New("variant not found")
+	}
+	return variant, nil
+}
+
+func validateVariant(v *models.ProductVariant) error {
+	if v.SKU == "" || v.Title == "" {
+		return errors.New("sku and title are required")
+	}
+	if v.PriceCents < 0 {
+		return errors.New("price must not be negative")
+	}
+	if v.Quantity < 0 {
+		return errors.New("quantity must not be negative")
+	}
+	if (v.WeightGrams != nil && *v.WeightGrams <= 0) || (v.VolumeML != nil && *v.VolumeML <= 0) {
+		return errors.New("weight and volume must be positive")
+	}
+	return nil
 }
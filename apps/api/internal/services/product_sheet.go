@@ -0,0 +1,341 @@
+package services
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"gastroshop-api/internal/models"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// Spreadsheet formats of the product import and export
+const (
+	SheetFormatCSV  = "csv"
+	SheetFormatXLSX = "xlsx"
+)
+
+// productSheetColumns are the columns of the product spreadsheet. Prices are
+// in rubles; tags and images are separated by commas.
+var productSheetColumns = []string{"slug", "title", "description", "price", "quantity", "tags", "images", "region_code"}
+
+var utf8BOM = []byte("\xef\xbb\xbf")
+
+// sheetFormulaPrefixes are the first characters that make spreadsheet apps
+// run a CSV value as a formula
+const sheetFormulaPrefixes = "=+-@\t\r"
+
+// SheetFormat returns the spreadsheet format of a file by its extension
+func SheetFormat(filename string) (string, error) {
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".csv":
+		return SheetFormatCSV, nil
+	case ".xlsx":
+		return SheetFormatXLSX, nil
+	}
+	return "", errors.New("unsupported file format, use .csv or .xlsx")
+}
+
+// sheetRow is a data row of a product spreadsheet with the values of the
+// columns present in the header
+type sheetRow struct {
+	line   int
+	values map[string]string
+}
+
+// readProductSheet parses a product spreadsheet. The header row names the
+// columns, in any order; only slug is required. Empty rows are skipped.
+func readProductSheet(data []byte, format string) ([]sheetRow, error) {
+	var records [][]string
+	switch format {
+	case SheetFormatCSV:
+		data = bytes.TrimPrefix(data, utf8BOM)
+		reader := csv.NewReader(bytes.NewReader(data))
+		reader.Comma = csvDelimiter(data)
+		reader.FieldsPerRecord = -1
+		var err error
+		if records, err = reader.ReadAll(); err != nil {
+			return nil, fmt.Errorf("invalid CSV: %v", err)
+		}
+	case SheetFormatXLSX:
+		f, err := excelize.OpenReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, fmt.Errorf("invalid XLSX: %v", err)
+		}
+		defer f.Close()
+		if records, err = f.GetRows(f.GetSheetName(0)); err != nil {
+			return nil, fmt.Errorf("invalid XLSX: %v", err)
+		}
+	default:
+		return nil, errors.New("unsupported file format")
+	}
+
+	if len(records) == 0 {
+		return nil, errors.New("file is empty")
+	}
+
+	header := make([]string, len(records[0]))
+	seen := make(map[string]bool)
+	for i, name := range records[0] {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if !isProductSheetColumn(name) {
+			return nil, fmt.Errorf("unknown column %q", name)
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("duplicate column %q", name)
+		}
+		seen[name] = true
+		header[i] = name
+	}
+	if !seen["slug"] {
+		return nil, errors.New("column \"slug\" is required")
+	}
+
+	var rows []sheetRow
+	for i, record := range records[1:] {
+		row := sheetRow{line: i + 2, values: make(map[string]string, len(header))}
+		empty := true
+		for j, name := range header {
+			var value string
+			if j < len(record) {
+				value = unescapeSheetValue(strings.TrimSpace(record[j]))
+			}
+			if value != "" {
+				empty = false
+			}
+			row.values[name] = value
+		}
+		if !empty {
+			rows = append(rows, row)
+		}
+	}
+
+	return rows, nil
+}
+
+// csvDelimiter guesses the delimiter from the header line: Excel with a
+// Russian locale saves CSV with semicolons
+func csvDelimiter(data []byte) rune {
+	header := data
+	if i := bytes.IndexByte(data, '\n'); i >= 0 {
+		header = data[:i]
+	}
+	if bytes.Count(header, []byte(";")) > bytes.Count(header, []byte(",")) {
+		return ';'
+	}
+	return ','
+}
+
+func isProductSheetColumn(name string) bool {
+	for _, column := range productSheetColumns {
+		if column == name {
+			return true
+		}
+	}
+	return false
+}
+
+// writeProductSheet writes products in the import format
+func writeProductSheet(w io.Writer, format string, products []models.Product) error {
+	switch format {
+	case SheetFormatCSV:
+		// The BOM makes Excel read the file as UTF-8
+		if _, err := w.Write(utf8BOM); err != nil {
+			return err
+		}
+		writer := csv.NewWriter(w)
+		if err := writer.Write(productSheetColumns); err != nil {
+			return err
+		}
+		for _, p := range products {
+			record := productSheetRecord(p)
+			for i := range record {
+				record[i] = escapeSheetValue(record[i])
+			}
+			if err := writer.Write(record); err != nil {
+				return err
+			}
+		}
+		writer.Flush()
+		return writer.Error()
+
+	case SheetFormatXLSX:
+		f := excelize.NewFile()
+		defer f.Close()
+		sheet := f.GetSheetName(0)
+
+		records := [][]string{productSheetColumns}
+		for _, p := range products {
+			records = append(records, productSheetRecord(p))
+		}
+		for i, record := range records {
+			cell, err := excelize.CoordinatesToCellName(1, i+1)
+			if err != nil {
+				return err
+			}
+			values := make([]interface{}, len(record))
+			for j, v := range record {
+				values[j] = v
+			}
+			if err := f.SetSheetRow(sheet, cell, &values); err != nil {
+				return err
+			}
+		}
+		return f.Write(w)
+	}
+	return errors.New("unsupported file format")
+}
+
+// escapeSheetValue prefixes a value that would run as a formula with an
+// apostrophe, which spreadsheet apps show the value as text for. XLSX cells
+// are written as text and need no escaping.
+func escapeSheetValue(value string) string {
+	if value != "" && strings.ContainsRune(sheetFormulaPrefixes, rune(value[0])) {
+		return "'" + value
+	}
+	return value
+}
+
+// unescapeSheetValue removes the apostrophe added by escapeSheetValue
+func unescapeSheetValue(value string) string {
+	if len(value) > 1 && value[0] == '\'' && strings.ContainsRune(sheetFormulaPrefixes, rune(value[1])) {
+		return value[1:]
+	}
+	return value
+}
+
+func productSheetRecord(p models.Product) []string {
+	return []string{
+		p.Slug,
+		p.Title,
+		p.Description,
+		formatRubles(p.PriceCents),
+		strconv.Itoa(p.Quantity),
+		strings.Join(p.Tags, ", "),
+		strings.Join(p.Images, ", "),
+		p.RegionCode,
+	}
+}
+
+// sheetPriceChanged reports whether a price cell is set to other than the
+// current price; exported sheets carry the current price of every product
+func sheetPriceChanged(value string, current int) bool {
+	if value == "" {
+		return false
+	}
+	cents, err := parseRubles(value)
+	return err != nil || cents != current
+}
+
+// sheetQuantityChanged reports whether a quantity cell is set to other than
+// the current stock
+func sheetQuantityChanged(value string, current int) bool {
+	if value == "" {
+		return false
+	}
+	quantity, err := strconv.Atoi(value)
+	return err != nil || quantity != current
+}
+
+// applySheetRow sets the product fields given in the row. Empty cells of
+// optional columns leave the field unchanged; an empty tags or images cell
+// clears the list.
+func applySheetRow(product *models.Product, row sheetRow) []models.ImportRowError {
+	var errs []models.ImportRowError
+	failed := make(map[string]bool)
+	fail := func(column, message string) {
+		if !failed[column] {
+			errs = append(errs, models.ImportRowError{Row: row.line, Column: column, Message: message})
+			failed[column] = true
+		}
+	}
+
+	if value, ok := row.values["title"]; ok && value != "" {
+		product.Title = value
+	}
+	if value, ok := row.values["description"]; ok && value != "" {
+		product.Description = value
+	}
+	if value, ok := row.values["price"]; ok && value != "" {
+		cents, err := parseRubles(value)
+		if err != nil {
+			fail("price", err.Error())
+		} else {
+			product.PriceCents = cents
+		}
+	}
+	if value, ok := row.values["quantity"]; ok && value != "" {
+		quantity, err := strconv.Atoi(value)
+		if err != nil || quantity < 0 {
+			fail("quantity", "quantity must be a non-negative integer")
+		} else {
+			product.Quantity = quantity
+			product.InStock = quantity > 0
+		}
+	}
+	if value, ok := row.values["tags"]; ok {
+		product.Tags = splitSheetList(value)
+	}
+	if value, ok := row.values["images"]; ok {
+		product.Images = splitSheetList(value)
+	}
+	if value, ok := row.values["region_code"]; ok && value != "" {
+		product.RegionCode = strings.ToUpper(value)
+	}
+
+	if product.Title == "" {
+		fail("title", "title is required")
+	}
+	if product.PriceCents <= 0 {
+		fail("price", "price must be positive")
+	}
+	if product.RegionCode == "" {
+		fail("region_code", "region_code is required")
+	}
+
+	return errs
+}
+
+func splitSheetList(value string) []string {
+	items := []string{}
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// parseRubles parses a price in rubles such as "1805", "1805.5" or
+// "1 805,50" into cents
+func parseRubles(value string) (int, error) {
+	value = strings.NewReplacer(" ", "", "\u00a0", "", ",", ".").Replace(value)
+
+	whole, fraction, hasFraction := strings.Cut(value, ".")
+	if hasFraction && (len(fraction) == 0 || len(fraction) > 2) {
+		return 0, errors.New("price must have at most two decimal places")
+	}
+	for len(fraction) < 2 {
+		fraction += "0"
+	}
+
+	rubles, err := strconv.Atoi(whole)
+	if err != nil || rubles < 0 {
+		return 0, errors.New("price must be a number")
+	}
+	kopecks, err := strconv.Atoi(fraction)
+	if err != nil || kopecks < 0 {
+		return 0, errors.New("price must be a number")
+	}
+	return rubles*100 + kopecks, nil
+}
+
+func formatRubles(cents int) string {
+	return fmt.Sprintf("%d.%02d", cents/100, cents%100)
+}
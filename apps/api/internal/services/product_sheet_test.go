@@ -0,0 +1,176 @@
+package services
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"gastroshop-api/internal/models"
+)
+
+func TestParseRubles(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    int
+		wantErr bool
+	}{
+		{value: "1805", want: 180500},
+		{value: "1805.5", want: 180550},
+		{value: "1 805,50", want: 180550},
+		{value: "0.99", want: 99},
+		{value: "12.345", wantErr: true},
+		{value: "12.", wantErr: true},
+		{value: "-5", wantErr: true},
+		{value: "abc", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := parseRubles(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseRubles() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("parseRubles() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReadProductSheetCSV(t *testing.T) {
+	data := []byte("\xef\xbb\xbfSlug;Price;Tags\ncomte;1805,50;cheese, hard\n;;\nbrie;900;\n")
+
+	rows, err := readProductSheet(data, SheetFormatCSV)
+	if err != nil {
+		t.Fatalf("readProductSheet() error = %v", err)
+	}
+
+	want := []sheetRow{
+		{line: 2, values: map[string]string{"slug": "comte", "price": "1805,50", "tags": "cheese, hard"}},
+		{line: 4, values: map[string]string{"slug": "brie", "price": "900", "tags": ""}},
+	}
+	if !reflect.DeepEqual(rows, want) {
+		t.Errorf("readProductSheet() = %+v, want %+v", rows, want)
+	}
+}
+
+func TestReadProductSheetHeader(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+	}{
+		{name: "unknown column", data: "slug,colour\nbrie,white\n"},
+		{name: "duplicate column", data: "slug,price,price\nbrie,1,2\n"},
+		{name: "missing slug", data: "title,price\nBrie,900\n"},
+		{name: "empty", data: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := readProductSheet([]byte(tt.data), SheetFormatCSV); err == nil {
+				t.Error("expected error")
+			}
+		})
+	}
+}
+
+func TestProductSheetRoundTrip(t *testing.T) {
+	products := []models.Product{{
+		Slug:        "comte-18",
+		Title:       "Комте 18 месяцев, кг",
+		Description: "=HYPERLINK(\"http://example.com\")",
+		PriceCents:  329000,
+		Quantity:    12,
+		Tags:        []string{"cheese", "hard"},
+		Images:      []string{"/images/comte.png"},
+		RegionCode:  "FR",
+	}}
+
+	for _, format := range []string{SheetFormatCSV, SheetFormatXLSX} {
+		t.Run(format, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := writeProductSheet(&buf, format, products); err != nil {
+				t.Fatalf("writeProductSheet() error = %v", err)
+			}
+
+			if format == SheetFormatCSV && !bytes.Contains(buf.Bytes(), []byte(`'=HYPERLINK`)) {
+				t.Errorf("writeProductSheet() didn't escape the formula: %s", buf.Bytes())
+			}
+
+			rows, err := readProductSheet(buf.Bytes(), format)
+			if err != nil {
+				t.Fatalf("readProductSheet() error = %v", err)
+			}
+			if len(rows) != 1 {
+				t.Fatalf("expected 1 row, got %d", len(rows))
+			}
+
+			var got models.Product
+			got.Slug = rows[0].values["slug"]
+			if errs := applySheetRow(&got, rows[0]); len(errs) > 0 {
+				t.Fatalf("applySheetRow() errors = %+v", errs)
+			}
+			got.InStock = false
+			if !reflect.DeepEqual(got, products[0]) {
+				t.Errorf("round trip = %+v, want %+v", got, products[0])
+			}
+		})
+	}
+}
+
+func TestApplySheetRow(t *testing.T) {
+	existing := models.Product{Slug: "brie", Title: "Бри", PriceCents: 90000, RegionCode: "FR", Tags: []string{"cheese"}}
+
+	t.Run("partial update keeps other fields", func(t *testing.T) {
+		product := existing
+		errs := applySheetRow(&product, sheetRow{line: 2, values: map[string]string{"slug": "brie", "quantity": "5", "title": ""}})
+		if len(errs) > 0 {
+			t.Fatalf("unexpected errors: %+v", errs)
+		}
+		if product.Title != "Бри" || product.Quantity != 5 || !product.InStock || len(product.Tags) != 1 {
+			t.Errorf("unexpected product %+v", product)
+		}
+	})
+
+	t.Run("new product needs title, price and region", func(t *testing.T) {
+		var product models.Product
+		errs := applySheetRow(&product, sheetRow{line: 3, values: map[string]string{"slug": "new", "price": "abc"}})
+		columns := map[string]bool{}
+		for _, e := range errs {
+			if e.Row != 3 {
+				t.Errorf("error for row %d, want 3", e.Row)
+			}
+			columns[e.Column] = true
+		}
+		if len(errs) != 3 || !columns["title"] || !columns["price"] || !columns["region_code"] {
+			t.Errorf("unexpected errors: %+v", errs)
+		}
+	})
+}
+
+func TestSheetValueChanged(t *testing.T) {
+	tests := []struct {
+		name    string
+		changed func(value string, current int) bool
+		value   string
+		current int
+		want    bool
+	}{
+		{name: "empty price", changed: sheetPriceChanged, value: "", current: 180500, want: false},
+		{name: "exported price", changed: sheetPriceChanged, value: "1805.00", current: 180500, want: false},
+		{name: "same price written differently", changed: sheetPriceChanged, value: "1 805", current: 180500, want: false},
+		{name: "new price", changed: sheetPriceChanged, value: "1900", current: 180500, want: true},
+		{name: "invalid price", changed: sheetPriceChanged, value: "abc", current: 180500, want: true},
+		{name: "empty quantity", changed: sheetQuantityChanged, value: "", current: 12, want: false},
+		{name: "exported quantity", changed: sheetQuantityChanged, value: "12", current: 12, want: false},
+		{name: "new quantity", changed: sheetQuantityChanged, value: "3", current: 12, want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.changed(tt.value, tt.current); got != tt.want {
+				t.Errorf("changed(%q, %d) = %v, want %v", tt.value, tt.current, got, tt.want)
+			}
+		})
+	}
+}
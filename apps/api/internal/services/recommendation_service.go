@@ -1,31 +1,77 @@
 package services
 
 import (
+	"log"
 	"strings"
 
 	"gastroshop-api/internal/models"
 	"gastroshop-api/internal/repository"
 )
 
+// pairedRecommendationLimit caps the curated pairings added to recommendations
+const pairedRecommendationLimit = 2
+
 type RecommendationService struct {
-	productRepo *repository.ProductRepository
+	productRepo    *repository.ProductRepository
+	pairingService *PairingService
 }
 
 func NewRecommendationService(productRepo *repository.ProductRepository) *RecommendationService {
 	return &RecommendationService{productRepo: productRepo}
 }
 
-func (s *RecommendationService) GetRecommendations(query string, tags []string) ([]models.Product, error) {
+// SetPairingService makes recommendations include products curated to pair
+// with the suggested ones
+func (s *RecommendationService) SetPairingService(pairingService *PairingService) {
+	s.pairingService = pairingService
+}
+
+// GetRecommendations suggests products for a query or tags, followed by the
+// products that pair best with them, leaving out products that don't suit
+// the dietary restrictions
+func (s *RecommendationService) GetRecommendations(query string, tags []string, restrictions []string) ([]models.Product, error) {
+	var products []models.Product
+	var err error
 	if query != "" {
-		return s.getRecommendationsByQuery(query)
+		products, err = s.getRecommendationsByQuery(query, restrictions)
+	} else if len(tags) > 0 {
+		products, err = s.productRepo.GetProductsByTags(tags)
+		products = filterDietary(products, restrictions)
+	} else {
+		return []models.Product{}, nil
+	}
+	if err != nil {
+		return nil, err
 	}
-	if len(tags) > 0 {
-		return s.productRepo.GetProductsByTags(tags)
+
+	return s.addPairedProducts(products, restrictions), nil
+}
+
+// addPairedProducts appends the products paired with most of the
+// recommendations. Pairings are an extra, so failures are only logged.
+func (s *RecommendationService) addPairedProducts(products []models.Product, restrictions []string) []models.Product {
+	if s.pairingService == nil || len(products) == 0 {
+		return products
+	}
+
+	ids := make([]int, len(products))
+	for i, p := range products {
+		ids[i] = p.ID
+	}
+	pairings, err := s.pairingService.GetPairingsByProductIDs(ids)
+	if err != nil {
+		log.Printf("Failed to get pairings for recommendations: %v", err)
+		return products
+	}
+
+	paired := filterDietary(rankPairedProducts(products, pairings), restrictions)
+	if len(paired) > pairedRecommendationLimit {
+		paired = paired[:pairedRecommendationLimit]
 	}
-	return []models.Product{}, nil
+	return append(products, paired...)
 }
 
-func (s *RecommendationService) getRecommendationsByQuery(query string) ([]models.Product, error) {
+func (s *RecommendationService) getRecommendationsByQuery(query string, restrictions []string) ([]models.Product, error) {
 	// Simple rule-based recommendation system
 	// Extract tags from natural language query
 	tags := s.extractTagsFromQuery(query)
@@ -35,6 +81,7 @@ func (s *RecommendationService) getRecommendationsByQuery(query string) ([]model
 	if err != nil {
 		return nil, err
 	}
+	products = filterDietary(products, restrictions)
 
 	// If no products found by tags, try broader search
 	if len(products) == 0 {
@@ -42,6 +89,7 @@ func (s *RecommendationService) getRecommendationsByQuery(query string) ([]model
 		filters := map[string]interface{}{
 			"query": query,
 		}
+		ApplyDietaryExclusions(filters, restrictions)
 		products, err = s.productRepo.GetProducts(filters)
 		if err != nil {
 			return nil, err
@@ -54,6 +102,7 @@ func (s *RecommendationService) getRecommendationsByQuery(query string) ([]model
 			"page_size": 4,
 			"page":      1,
 		}
+		ApplyDietaryExclusions(filters, restrictions)
 		products, err = s.productRepo.GetProducts(filters)
 		if err != nil {
 			return nil, err
@@ -20,7 +20,7 @@ func TestRecommendationService_GetRecommendations(t *testing.T) {
 
 	// Test with tags
 	tags := []string{"cheese", "italian"}
-	recommendations, err := service.GetRecommendations("", tags)
+	recommendations, err := service.GetRecommendations("", tags, nil)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -54,7 +54,7 @@ func TestRecommendationService_GetRecommendations_WithQuery(t *testing.T) {
 
 	// Test with query
 	query := "hard cheese"
-	recommendations, err := service.GetRecommendations(query, nil)
+	recommendations, err := service.GetRecommendations(query, nil, nil)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
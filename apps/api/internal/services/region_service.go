@@ -1,6 +1,9 @@
 package services
 
 import (
+	"errors"
+	"strings"
+
 	"gastroshop-api/internal/models"
 	"gastroshop-api/internal/repository"
 )
@@ -25,6 +28,35 @@ func (s *RegionService) GetRegionByCode(code string) (*models.Region, error) {
 	return s.regionRepo.GetRegionByCode(code)
 }
 
+// UpdateRegion renames a region and merges its translations by locale
+func (s *RegionService) UpdateRegion(code string, req *models.UpdateRegionRequest) (*models.Region, error) {
+	region, err := s.regionRepo.GetRegionByCode(code)
+	if err != nil {
+		return nil, err
+	}
+	if region == nil {
+		return nil, errors.New("region not found")
+	}
+
+	if req.Name != nil {
+		name := strings.TrimSpace(*req.Name)
+		if name == "" {
+			return nil, errors.New("name is required")
+		}
+		region.Name = name
+	}
+	if req.Translations != nil {
+		if region.Translations, err = MergeTranslations(region.Translations, req.Translations); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := s.regionRepo.UpdateRegion(region); err != nil {
+		return nil, err
+	}
+	return region, nil
+}
+
 func (s *RegionService) GetRegionProducts(code string) ([]models.Product, error) {
 	return s.productRepo.GetProductsByRegion(code)
 }
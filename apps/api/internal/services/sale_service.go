@@ -0,0 +1,214 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"gastroshop-api/internal/models"
+	"gastroshop-api/internal/repository"
+)
+
+// SaleService schedules sale prices of products and reports their price
+// history
+type SaleService struct {
+	priceRepo   *repository.PriceRepository
+	productRepo *repository.ProductRepository
+}
+
+func NewSaleService(priceRepo *repository.PriceRepository, productRepo *repository.ProductRepository) *SaleService {
+	return &SaleService{
+		priceRepo:   priceRepo,
+		productRepo: productRepo,
+	}
+}
+
+// GetPriceReport returns the past prices and the sales of a product
+func (s *SaleService) GetPriceReport(productID int) (*models.PriceReport, error) {
+	if _, err := s.getProduct(productID); err != nil {
+		return nil, err
+	}
+
+	history, err := s.priceRepo.GetPriceHistory(productID)
+	if err != nil {
+		return nil, err
+	}
+	sales, err := s.priceRepo.GetSalesByProductID(productID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.PriceReport{ProductID: productID, History: history, Sales: sales}, nil
+}
+
+func (s *SaleService) GetSales(productID int) ([]models.ProductSale, error) {
+	if _, err := s.getProduct(productID); err != nil {
+		return nil, err
+	}
+	return s.priceRepo.GetSalesByProductID(productID)
+}
+
+// CreateSale schedules a sale price. A sale whose start has already come is
+// activated right away.
+func (s *SaleService) CreateSale(productID int, req *models.CreateProductSaleRequest) (*models.ProductSale, error) {
+	product, err := s.getProduct(productID)
+	if err != nil {
+		return nil, err
+	}
+
+	variants, err := s.productRepo.GetVariantsByProductIDs([]int{productID})
+	if err != nil {
+		return nil, err
+	}
+	if len(variants[productID]) > 0 {
+		return nil, errors.New("sales of products with variants are not supported")
+	}
+	bundles, err := s.productRepo.GetBundlesByProductIDs([]int{productID})
+	if err != nil {
+		return nil, err
+	}
+	if b := bundles[productID]; b != nil && b.Pricing == models.BundlePricingDiscount {
+		return nil, errors.New("discount bundles are priced from their components; use fixed pricing to put them on sale")
+	}
+
+	now := time.Now()
+	if err := validateSale(regularPrice(product), req.SalePriceCents, req.StartsAt, req.EndsAt, now); err != nil {
+		return nil, err
+	}
+
+	overlaps, err := s.priceRepo.HasOverlappingSale(productID, req.StartsAt, req.EndsAt)
+	if err != nil {
+		return nil, err
+	}
+	if overlaps {
+		return nil, errors.New("product already has a sale in this period")
+	}
+
+	sale := &models.ProductSale{
+		ProductID:      productID,
+		SalePriceCents: req.SalePriceCents,
+		StartsAt:       req.StartsAt,
+		EndsAt:         req.EndsAt,
+		Status:         models.SaleStatusScheduled,
+	}
+	if err := s.priceRepo.CreateSale(sale); err != nil {
+		return nil, err
+	}
+
+	if !sale.StartsAt.After(now) {
+		if err := s.priceRepo.ActivateSale(sale); err != nil {
+			return nil, err
+		}
+		if sale.Status == models.SaleStatusCanceled {
+			return nil, errors.New("sale price must be below the regular price")
+		}
+	}
+	return sale, nil
+}
+
+// CancelSale cancels a scheduled sale or ends an active one early,
+// returning the product to its regular price
+func (s *SaleService) CancelSale(productID, saleID int) error {
+	sale, err := s.priceRepo.GetSaleByID(saleID)
+	if err != nil {
+		return err
+	}
+	if sale == nil || sale.ProductID != productID {
+		return errors.New("sale not found")
+	}
+
+	switch sale.Status {
+	case models.SaleStatusScheduled:
+		return s.priceRepo.SetSaleStatus(sale.ID, models.SaleStatusCanceled)
+	case models.SaleStatusActive:
+		return s.priceRepo.EndSale(sale, models.SaleStatusCanceled)
+	default:
+		return fmt.Errorf("sale is already %s", sale.Status)
+	}
+}
+
+// StartScheduler periodically activates and ends sales
+func (s *SaleService) StartScheduler(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			if err := s.ProcessSales(time.Now()); err != nil {
+				log.Printf("Sale scheduler error: %v", err)
+			}
+		}
+	}()
+}
+
+// ProcessSales ends the sales whose end has come and then activates the
+// sales whose start has come, so that back-to-back sales hand over.
+// Scheduled sales that were missed entirely are marked as ended.
+func (s *SaleService) ProcessSales(now time.Time) error {
+	ending, err := s.priceRepo.GetSalesToEnd(now)
+	if err != nil {
+		return err
+	}
+	for i := range ending {
+		if err := s.priceRepo.EndSale(&ending[i], models.SaleStatusEnded); err != nil {
+			log.Printf("Failed to end sale %d: %v", ending[i].ID, err)
+		}
+	}
+
+	starting, err := s.priceRepo.GetSalesToStart(now)
+	if err != nil {
+		return err
+	}
+	for i := range starting {
+		sale := &starting[i]
+		if !sale.EndsAt.After(now) {
+			err = s.priceRepo.SetSaleStatus(sale.ID, models.SaleStatusEnded)
+		} else {
+			err = s.priceRepo.ActivateSale(sale)
+		}
+		if err != nil {
+			log.Printf("Failed to start sale %d: %v", sale.ID, err)
+		} else if sale.Status == models.SaleStatusCanceled {
+			log.Printf("Canceled sale %d: its price is no longer below the product price", sale.ID)
+		}
+	}
+	return nil
+}
+
+func (s *SaleService) getProduct(productID int) (*models.Product, error) {
+	product, err := s.productRepo.GetProductByID(productID)
+	if err != nil {
+		return nil, err
+	}
+	if product == nil {
+		return nil, errors.New("product not found")
+	}
+	return product, nil
+}
+
+// regularPrice returns the price of a product without an active sale
+func regularPrice(product *models.Product) int {
+	if product.CompareAtPriceCents != nil {
+		return *product.CompareAtPriceCents
+	}
+	return product.PriceCents
+}
+
+// validateSale checks that a sale lowers the regular price and ends after
+// it starts and in the future
+func validateSale(regularPriceCents, salePriceCents int, startsAt, endsAt, now time.Time) error {
+	if salePriceCents <= 0 {
+		return errors.New("sale price must be positive")
+	}
+	if salePriceCents >= regularPriceCents {
+		return errors.New("sale price must be below the regular price")
+	}
+	if !endsAt.After(startsAt) {
+		return errors.New("sale must end after it starts")
+	}
+	if !endsAt.After(now) {
+		return errors.New("sale must end in the future")
+	}
+	return nil
+}
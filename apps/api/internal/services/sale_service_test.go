@@ -0,0 +1,48 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"gastroshop-api/internal/models"
+)
+
+func TestValidateSale(t *testing.T) {
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	day := 24 * time.Hour
+
+	tests := []struct {
+		name      string
+		salePrice int
+		startsAt  time.Time
+		endsAt    time.Time
+		wantErr   bool
+	}{
+		{name: "future sale", salePrice: 800, startsAt: now.Add(day), endsAt: now.Add(7 * day)},
+		{name: "already started", salePrice: 800, startsAt: now.Add(-day), endsAt: now.Add(day)},
+		{name: "zero price", salePrice: 0, startsAt: now, endsAt: now.Add(day), wantErr: true},
+		{name: "not below regular price", salePrice: 1000, startsAt: now, endsAt: now.Add(day), wantErr: true},
+		{name: "ends before start", salePrice: 800, startsAt: now.Add(2 * day), endsAt: now.Add(day), wantErr: true},
+		{name: "already over", salePrice: 800, startsAt: now.Add(-2 * day), endsAt: now.Add(-day), wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateSale(1000, tt.salePrice, tt.startsAt, tt.endsAt, now)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validateSale() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRegularPrice(t *testing.T) {
+	if got := regularPrice(&models.Product{PriceCents: 1000}); got != 1000 {
+		t.Errorf("regularPrice() = %d, want 1000", got)
+	}
+
+	compareAt := 1200
+	if got := regularPrice(&models.Product{PriceCents: 900, CompareAtPriceCents: &compareAt}); got != 1200 {
+		t.Errorf("regularPrice() on sale = %d, want 1200", got)
+	}
+}
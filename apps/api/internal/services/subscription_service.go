@@ -0,0 +1,652 @@
+package services
+
+import (
+	"errors"
+	"log"
+	"time"
+
+	"gastroshop-api/internal/models"
+	"gastroshop-api/internal/repository"
+)
+
+// dunningSchedule is the delay before each retry of a failed subscription
+// charge, counted from the failed attempt. Once it is exhausted the order is
+// canceled and the subscription is paused.
+var dunningSchedule = []time.Duration{
+	24 * time.Hour,
+	3 * 24 * time.Hour,
+	7 * 24 * time.Hour,
+}
+
+type SubscriptionService struct {
+	subscriptionRepo *repository.SubscriptionRepository
+	productRepo      *repository.ProductRepository
+	userRepo         *repository.UserRepository
+	orderService     *OrderService
+	paymentService   *PaymentService
+	emailService     *EmailService
+}
+
+func NewSubscriptionService(
+	subscriptionRepo *repository.SubscriptionRepository,
+	productRepo *repository.ProductRepository,
+	userRepo *repository.UserRepository,
+	orderService *OrderService,
+	paymentService *PaymentService,
+	emailService *EmailService,
+) *SubscriptionService {
+	return &SubscriptionService{
+		subscriptionRepo: subscriptionRepo,
+		productRepo:      productRepo,
+		userRepo:         userRepo,
+		orderService:     orderService,
+		paymentService:   paymentService,
+		emailService:     emailService,
+	}
+}
+
+// Customer methods
+
+func (s *SubscriptionService) GetUserSubscriptions(userID int) ([]models.ProductSubscription, error) {
+	return s.subscriptionRepo.GetSubscriptionsByUserID(userID)
+}
+
+func (s *SubscriptionService) CreateSubscription(userID int, req *models.CreateSubscriptionRequest) (*models.ProductSubscription, error) {
+	if (req.ProductID == nil) == (req.BoxID == nil) {
+		return nil, errors.New("either product_id or box_id is required")
+	}
+	if !validFrequency(req.Frequency) {
+		return nil, errors.New("frequency must be weekly or monthly")
+	}
+
+	quantity := req.Quantity
+	if quantity == 0 {
+		quantity = 1
+	}
+	if quantity < 0 {
+		return nil, errors.New("quantity must be positive")
+	}
+
+	if req.ProductID != nil {
+		line := models.OrderItem{ProductID: *req.ProductID, VariantID: req.VariantID}
+		if _, _, _, err := resolveOrderLine(s.productRepo, &line); err != nil {
+			return nil, err
+		}
+	} else {
+		box, err := s.subscriptionRepo.GetBoxByID(*req.BoxID)
+		if err != nil {
+			return nil, err
+		}
+		if box == nil || !box.Active {
+			return nil, errors.New("subscription box not found")
+		}
+	}
+
+	if req.PaymentMethodID != "" {
+		if _, err := s.paymentService.GetUserPaymentMethod(userID, req.PaymentMethodID); err != nil {
+			return nil, err
+		}
+	}
+
+	nextRunAt := time.Now()
+	if req.StartAt != nil && req.StartAt.After(nextRunAt) {
+		nextRunAt = *req.StartAt
+	}
+
+	sub := &models.ProductSubscription{
+		UserID:          userID,
+		ProductID:       req.ProductID,
+		VariantID:       req.VariantID,
+		BoxID:           req.BoxID,
+		Quantity:        quantity,
+		Frequency:       req.Frequency,
+		Status:          "active",
+		ShippingAddress: req.ShippingAddress,
+		PaymentMethodID: req.PaymentMethodID,
+		NextRunAt:       nextRunAt,
+	}
+
+	// Every delivery must be an orderable quantity of each product
+	if _, err := s.buildOrderItems(sub); err != nil {
+		return nil, err
+	}
+
+	if err := s.subscriptionRepo.CreateSubscription(sub); err != nil {
+		return nil, err
+	}
+	sub.HasPaymentMethod = sub.PaymentMethodID != ""
+
+	return sub, nil
+}
+
+func (s *SubscriptionService) UpdateSubscription(userID, id int, req *models.UpdateSubscriptionRequest) (*models.ProductSubscription, error) {
+	sub, err := s.getUserSubscription(userID, id)
+	if err != nil {
+		return nil, err
+	}
+	if sub.Status == "canceled" {
+		return nil, errors.New("subscription is canceled")
+	}
+
+	if req.Quantity != nil {
+		if *req.Quantity <= 0 {
+			return nil, errors.New("quantity must be positive")
+		}
+		sub.Quantity = *req.Quantity
+		if _, err := s.buildOrderItems(sub); err != nil {
+			return nil, err
+		}
+	}
+	if req.Frequency != nil {
+		if !validFrequency(*req.Frequency) {
+			return nil, errors.New("frequency must be weekly or monthly")
+		}
+		sub.Frequency = *req.Frequency
+	}
+	if req.ShippingAddress != nil {
+		sub.ShippingAddress = req.ShippingAddress
+	}
+	if req.PaymentMethodID != nil {
+		if *req.PaymentMethodID != "" {
+			if _, err := s.paymentService.GetUserPaymentMethod(userID, *req.PaymentMethodID); err != nil {
+				return nil, err
+			}
+		}
+		sub.PaymentMethodID = *req.PaymentMethodID
+		// A new card for a past-due subscription is tried on the next scheduler run
+		if sub.Status == "past_due" && sub.PaymentMethodID != "" {
+			now := time.Now()
+			sub.NextRetryAt = &now
+		}
+	}
+
+	if err := s.subscriptionRepo.UpdateSubscription(id, sub); err != nil {
+		return nil, err
+	}
+	sub.HasPaymentMethod = sub.PaymentMethodID != ""
+
+	return sub, nil
+}
+
+func (s *SubscriptionService) PauseSubscription(userID, id int) (*models.ProductSubscription, error) {
+	sub, err := s.getUserSubscription(userID, id)
+	if err != nil {
+		return nil, err
+	}
+	if sub.Status != "active" && sub.Status != "past_due" {
+		return nil, errors.New("only active subscriptions can be paused")
+	}
+
+	sub.Status = "paused"
+	sub.NextRetryAt = nil
+	if err := s.subscriptionRepo.UpdateSubscription(id, sub); err != nil {
+		return nil, err
+	}
+	return sub, nil
+}
+
+func (s *SubscriptionService) ResumeSubscription(userID, id int) (*models.ProductSubscription, error) {
+	sub, err := s.getUserSubscription(userID, id)
+	if err != nil {
+		return nil, err
+	}
+	if sub.Status != "paused" {
+		return nil, errors.New("only paused subscriptions can be resumed")
+	}
+
+	sub.Status = "active"
+	sub.FailedAttempts = 0
+	sub.NextRetryAt = nil
+	// Runs missed while paused are not delivered retroactively
+	if now := time.Now(); sub.NextRunAt.Before(now) {
+		sub.NextRunAt = now
+	}
+	if err := s.subscriptionRepo.UpdateSubscription(id, sub); err != nil {
+		return nil, err
+	}
+	return sub, nil
+}
+
+// SkipNextDelivery marks the upcoming run to be skipped without creating an order
+func (s *SubscriptionService) SkipNextDelivery(userID, id int) (*models.ProductSubscription, error) {
+	sub, err := s.getUserSubscription(userID, id)
+	if err != nil {
+		return nil, err
+	}
+	if sub.Status != "active" {
+		return nil, errors.New("only active subscriptions can skip a delivery")
+	}
+
+	sub.SkipNext = true
+	if err := s.subscriptionRepo.UpdateSubscription(id, sub); err != nil {
+		return nil, err
+	}
+	return sub, nil
+}
+
+func (s *SubscriptionService) CancelSubscription(userID, id int) (*models.ProductSubscription, error) {
+	sub, err := s.getUserSubscription(userID, id)
+	if err != nil {
+		return nil, err
+	}
+	if sub.Status == "canceled" {
+		return sub, nil
+	}
+
+	sub.Status = "canceled"
+	sub.NextRetryAt = nil
+	if err := s.subscriptionRepo.UpdateSubscription(id, sub); err != nil {
+		return nil, err
+	}
+	return sub, nil
+}
+
+// getUserSubscription loads a subscription owned by the user. Subscriptions of
+// other users are reported as not found.
+func (s *SubscriptionService) getUserSubscription(userID, id int) (*models.ProductSubscription, error) {
+	sub, err := s.subscriptionRepo.GetSubscriptionByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if sub == nil || sub.UserID != userID {
+		return nil, errors.New("subscription not found")
+	}
+	return sub, nil
+}
+
+// Subscription boxes
+
+func (s *SubscriptionService) GetActiveBoxes() ([]models.SubscriptionBox, error) {
+	return s.subscriptionRepo.GetBoxes(true)
+}
+
+// Admin methods
+
+func (s *SubscriptionService) GetAllSubscriptions(status string) ([]models.ProductSubscription, error) {
+	return s.subscriptionRepo.GetAllSubscriptions(status)
+}
+
+func (s *SubscriptionService) GetBoxes() ([]models.SubscriptionBox, error) {
+	return s.subscriptionRepo.GetBoxes(false)
+}
+
+func (s *SubscriptionService) CreateBox(req *models.CreateSubscriptionBoxRequest) (*models.SubscriptionBox, error) {
+	box := &models.SubscriptionBox{
+		Slug:        req.Slug,
+		Title:       req.Title,
+		Description: req.Description,
+		Items:       req.Items,
+		Active:      true,
+	}
+	if req.Active != nil {
+		box.Active = *req.Active
+	}
+
+	if err := s.validateBoxItems(box.Items); err != nil {
+		return nil, err
+	}
+
+	if err := s.subscriptionRepo.CreateBox(box); err != nil {
+		return nil, err
+	}
+	return box, nil
+}
+
+func (s *SubscriptionService) UpdateBox(id int, req *models.UpdateSubscriptionBoxRequest) (*models.SubscriptionBox, error) {
+	box, err := s.subscriptionRepo.GetBoxByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if box == nil {
+		return nil, errors.New("subscription box not found")
+	}
+
+	if req.Title != nil {
+		box.Title = *req.Title
+	}
+	if req.Description != nil {
+		box.Description = *req.Description
+	}
+	if req.Items != nil {
+		if err := s.validateBoxItems(req.Items); err != nil {
+			return nil, err
+		}
+		box.Items = req.Items
+	}
+	if req.Active != nil {
+		box.Active = *req.Active
+	}
+
+	if err := s.subscriptionRepo.UpdateBox(id, box); err != nil {
+		return nil, err
+	}
+	return box, nil
+}
+
+func (s *SubscriptionService) DeleteBox(id int) error {
+	return s.subscriptionRepo.DeleteBox(id)
+}
+
+func (s *SubscriptionService) validateBoxItems(items []models.SubscriptionBoxItem) error {
+	if len(items) == 0 {
+		return errors.New("box must contain at least one product")
+	}
+	for _, item := range items {
+		if item.Quantity <= 0 {
+			return errors.New("box item quantity must be positive")
+		}
+		line := models.OrderItem{ProductID: item.ProductID, VariantID: item.VariantID}
+		if _, _, _, err := resolveOrderLine(s.productRepo, &line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Scheduler
+
+// StartScheduler processes due subscriptions every interval in the background
+func (s *SubscriptionService) StartScheduler(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			if err := s.ProcessDueSubscriptions(time.Now()); err != nil {
+				log.Printf("Subscription scheduler error: %v", err)
+			}
+		}
+	}()
+}
+
+// ProcessDueSubscriptions creates orders for subscriptions whose run has come
+// and retries failed charges of past-due subscriptions
+func (s *SubscriptionService) ProcessDueSubscriptions(now time.Time) error {
+	subs, err := s.subscriptionRepo.GetDueSubscriptions(now)
+	if err != nil {
+		return err
+	}
+
+	for i := range subs {
+		sub := &subs[i]
+
+		var err error
+		if sub.Status == "past_due" {
+			err = s.retryCharge(sub, now)
+		} else {
+			err = s.runSubscription(sub, now)
+		}
+		if err != nil {
+			log.Printf("Failed to process subscription %d: %v", sub.ID, err)
+		}
+	}
+
+	return nil
+}
+
+func (s *SubscriptionService) runSubscription(sub *models.ProductSubscription, now time.Time) error {
+	// Runs missed while the scheduler was down collapse into this one
+	nextRunAt := nextRunAfter(sub.NextRunAt, sub.Frequency)
+	for !nextRunAt.After(now) {
+		nextRunAt = nextRunAfter(nextRunAt, sub.Frequency)
+	}
+
+	claimed, err := s.subscriptionRepo.ClaimRun(sub.ID, sub.NextRunAt, nextRunAt)
+	if err != nil {
+		return err
+	}
+	if !claimed {
+		return nil
+	}
+	if sub.SkipNext {
+		log.Printf("Subscription %d: delivery skipped by customer", sub.ID)
+		return nil
+	}
+
+	// The run is claimed up front so no other worker places the order too;
+	// it is given back when no order could be placed, to try again later
+	items, err := s.buildOrderItems(sub)
+	if err != nil {
+		return s.releaseRun(sub, nextRunAt, err)
+	}
+
+	order, err := s.orderService.CreateOrder(&sub.UserID, items, sub.ShippingAddress, nil)
+	if err != nil {
+		return s.releaseRun(sub, nextRunAt, err)
+	}
+
+	if err := s.subscriptionRepo.SetLastOrder(sub.ID, order.ID); err != nil {
+		log.Printf("Failed to record last order for subscription %d: %v", sub.ID, err)
+	}
+
+	if sub.PaymentMethodID == "" {
+		// Without a saved method the customer pays by link; each retry counts
+		// as an attempt, so the subscription gets another retry or is paused
+		// like after a failed charge
+		retryAt, err := s.missedPayment(sub, order, now)
+		if err != nil {
+			return err
+		}
+		if retryAt == nil {
+			s.notifyPaymentFailed(sub, order.ID, nil)
+			return nil
+		}
+		return s.sendPaymentLink(sub, order)
+	}
+	return s.chargeOrder(sub, order, now)
+}
+
+// releaseRun puts back the run claimed for nextRunAt after its order failed
+func (s *SubscriptionService) releaseRun(sub *models.ProductSubscription, nextRunAt time.Time, cause error) error {
+	if err := s.subscriptionRepo.ReleaseRun(sub.ID, nextRunAt, sub.NextRunAt); err != nil {
+		log.Printf("Failed to release run of subscription %d: %v", sub.ID, err)
+	}
+	return cause
+}
+
+func (s *SubscriptionService) retryCharge(sub *models.ProductSubscription, now time.Time) error {
+	claimed, err := s.subscriptionRepo.ClaimRetry(sub.ID, *sub.NextRetryAt)
+	if err != nil {
+		return err
+	}
+	if !claimed {
+		return nil
+	}
+
+	var order *models.Order
+	if sub.LastOrderID != nil {
+		order, err = s.orderService.GetOrderByID(*sub.LastOrderID)
+		if err != nil {
+			return err
+		}
+	}
+
+	// The order was paid or canceled in the meantime: nothing left to collect
+	if order == nil || order.Status != "pending" {
+		return s.subscriptionRepo.UpdateBillingState(sub.ID, "active", 0, nil)
+	}
+
+	if sub.PaymentMethodID == "" {
+		// Without a saved method the customer pays by link; each retry counts
+		// as an attempt, so the subscription gets another retry or is paused
+		// like after a failed charge
+		retryAt, err := s.missedPayment(sub, order, now)
+		if err != nil {
+			return err
+		}
+		if retryAt == nil {
+			s.notifyPaymentFailed(sub, order.ID, nil)
+			return nil
+		}
+		return s.sendPaymentLink(sub, order)
+	}
+	return s.chargeOrder(sub, order, now)
+}
+
+// chargeOrder charges the saved payment method and moves the subscription
+// along the dunning schedule when the charge fails
+func (s *SubscriptionService) chargeOrder(sub *models.ProductSubscription, order *models.Order, now time.Time) error {
+	status, err := s.paymentService.ChargeSavedPaymentMethod(order, sub.PaymentMethodID)
+	if err == nil && status != "canceled" {
+		if status == "paid" {
+			if err := s.orderService.UpdateOrderStatus(order.ID, "paid"); err != nil {
+				return err
+			}
+			if err := s.orderService.DecreaseProductQuantities(order.ID); err != nil {
+				log.Printf("Failed to decrease product quantities for order %d: %v", order.ID, err)
+			}
+		}
+		// Pending charges are settled by the payment webhook
+		return s.subscriptionRepo.UpdateBillingState(sub.ID, "active", 0, nil)
+	}
+	if err != nil {
+		log.Printf("Subscription %d: charge for order %d failed: %v", sub.ID, order.ID, err)
+	}
+
+	retryAt, err := s.missedPayment(sub, order, now)
+	if err != nil {
+		return err
+	}
+	s.notifyPaymentFailed(sub, order.ID, retryAt)
+	return nil
+}
+
+// missedPayment moves the subscription along the dunning schedule after an
+// attempt to collect the order failed. Once the schedule is exhausted the
+// order is canceled and the subscription paused. It returns the next retry,
+// nil when paused.
+func (s *SubscriptionService) missedPayment(sub *models.ProductSubscription, order *models.Order, now time.Time) (*time.Time, error) {
+	status, attempts, retryAt := billingAfterMissedPayment(sub.FailedAttempts, now)
+	if status == "paused" {
+		if err := s.orderService.UpdateOrderStatus(order.ID, "canceled"); err != nil {
+			log.Printf("Failed to cancel order %d: %v", order.ID, err)
+		}
+	}
+	if err := s.subscriptionRepo.UpdateBillingState(sub.ID, status, attempts, retryAt); err != nil {
+		return nil, err
+	}
+	return retryAt, nil
+}
+
+// sendPaymentLink creates a checkout payment for the order and emails the link
+func (s *SubscriptionService) sendPaymentLink(sub *models.ProductSubscription, order *models.Order) error {
+	payment, err := s.paymentService.CreatePayment(order)
+	if err != nil {
+		return err
+	}
+
+	email := s.userEmail(sub.UserID)
+	if email == "" || s.emailService == nil {
+		return nil
+	}
+	return s.emailService.SendSubscriptionPaymentLinkEmail(email, order.ID, payment.PaymentURL, order.AmountCents)
+}
+
+func (s *SubscriptionService) notifyPaymentFailed(sub *models.ProductSubscription, orderID int, retryAt *time.Time) {
+	email := s.userEmail(sub.UserID)
+	if email == "" || s.emailService == nil {
+		return
+	}
+	if err := s.emailService.SendSubscriptionPaymentFailedEmail(email, sub.ID, orderID, retryAt); err != nil {
+		log.Printf("Failed to send subscription payment failed email: %v", err)
+	}
+}
+
+func (s *SubscriptionService) userEmail(userID int) string {
+	user, err := s.userRepo.GetUserByID(userID)
+	if err != nil || user == nil {
+		log.Printf("Failed to get user %d for subscription email: %v", userID, err)
+		return ""
+	}
+	return user.Email
+}
+
+// buildOrderItems expands the subscription into order items at current prices;
+// variant lines are priced when the order is placed. Each line times the
+// subscription quantity must be an orderable quantity of the product.
+func (s *SubscriptionService) buildOrderItems(sub *models.ProductSubscription) ([]models.OrderItem, error) {
+	var lines []models.SubscriptionBoxItem
+	if sub.ProductID != nil {
+		// One delivery of a weighed product is its minimum quantity
+		product, err := s.productRepo.GetProductByID(*sub.ProductID)
+		if err != nil {
+			return nil, err
+		}
+		if product == nil {
+			return nil, errors.New("product not found")
+		}
+		base := 1
+		if product.MinQuantity > 1 {
+			base = product.MinQuantity
+		}
+		lines = []models.SubscriptionBoxItem{{ProductID: *sub.ProductID, VariantID: sub.VariantID, Quantity: base}}
+	} else {
+		box, err := s.subscriptionRepo.GetBoxByID(*sub.BoxID)
+		if err != nil {
+			return nil, err
+		}
+		if box == nil {
+			return nil, errors.New("subscription box not found")
+		}
+		lines = box.Items
+	}
+
+	items := make([]models.OrderItem, 0, len(lines))
+	for _, line := range lines {
+		product, err := s.productRepo.GetProductByID(line.ProductID)
+		if err != nil {
+			return nil, err
+		}
+		if product == nil {
+			return nil, errors.New("product not found")
+		}
+		if product.ArchivedAt != nil {
+			return nil, errors.New("product is no longer available")
+		}
+		quantity := line.Quantity * sub.Quantity
+		if err := validateOrderQuantity(product, quantity); err != nil {
+			return nil, err
+		}
+		items = append(items, models.OrderItem{
+			ProductID:  product.ID,
+			VariantID:  line.VariantID,
+			Quantity:   quantity,
+			PriceCents: product.PriceCents,
+		})
+	}
+
+	return items, nil
+}
+
+func validFrequency(frequency string) bool {
+	return frequency == "weekly" || frequency == "monthly"
+}
+
+// nextRunAfter returns the run following t for the given frequency
+func nextRunAfter(t time.Time, frequency string) time.Time {
+	if frequency == "monthly" {
+		return t.AddDate(0, 1, 0)
+	}
+	return t.AddDate(0, 0, 7)
+}
+
+// billingAfterMissedPayment returns the billing state of a subscription
+// after a missed payment: past_due with the next retry, or paused without one
+// once the dunning schedule is exhausted
+func billingAfterMissedPayment(failedAttempts int, now time.Time) (string, int, *time.Time) {
+	attempts := failedAttempts + 1
+	retryAt, ok := nextRetryAt(now, attempts)
+	if !ok {
+		return "paused", attempts, nil
+	}
+	return "past_due", attempts, &retryAt
+}
+
+// nextRetryAt returns when to retry after the given number of failed attempts.
+// It returns false once the dunning schedule is exhausted.
+func nextRetryAt(failedAt time.Time, failedAttempts int) (time.Time, bool) {
+	if failedAttempts < 1 || failedAttempts > len(dunningSchedule) {
+		return time.Time{}, false
+	}
+	return failedAt.Add(dunningSchedule[failedAttempts-1]), true
+}
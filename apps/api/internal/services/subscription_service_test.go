@@ -0,0 +1,96 @@
+package services
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNextRunAfter(t *testing.T) {
+	start := time.Date(2026, 1, 31, 9, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name      string
+		frequency string
+		want      time.Time
+	}{
+		{name: "weekly", frequency: "weekly", want: time.Date(2026, 2, 7, 9, 0, 0, 0, time.UTC)},
+		{name: "monthly normalizes month end", frequency: "monthly", want: time.Date(2026, 3, 3, 9, 0, 0, 0, time.UTC)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := nextRunAfter(start, tt.frequency); !got.Equal(tt.want) {
+				t.Errorf("nextRunAfter() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNextRetryAt(t *testing.T) {
+	failedAt := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		attempts int
+		want     time.Time
+		wantOK   bool
+	}{
+		{name: "first failure", attempts: 1, want: failedAt.Add(24 * time.Hour), wantOK: true},
+		{name: "last retry", attempts: len(dunningSchedule), want: failedAt.Add(dunningSchedule[len(dunningSchedule)-1]), wantOK: true},
+		{name: "schedule exhausted", attempts: len(dunningSchedule) + 1},
+		{name: "no failures", attempts: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := nextRetryAt(failedAt, tt.attempts)
+			if ok != tt.wantOK {
+				t.Fatalf("nextRetryAt() ok = %v, want %v", ok, tt.wantOK)
+			}
+			if ok && !got.Equal(tt.want) {
+				t.Errorf("nextRetryAt() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBillingAfterMissedPayment(t *testing.T) {
+	now := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name           string
+		failedAttempts int
+		wantStatus     string
+		wantRetryAt    *time.Time
+	}{
+		{name: "first miss", failedAttempts: 0, wantStatus: "past_due", wantRetryAt: timePtr(now.Add(dunningSchedule[0]))},
+		{
+			// A past-due subscription whose payment method was cleared is sent a
+			// payment link on retry and must still get a next retry
+			name:           "payment link without payment method",
+			failedAttempts: 1,
+			wantStatus:     "past_due",
+			wantRetryAt:    timePtr(now.Add(dunningSchedule[1])),
+		},
+		{name: "schedule exhausted", failedAttempts: len(dunningSchedule), wantStatus: "paused"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, attempts, retryAt := billingAfterMissedPayment(tt.failedAttempts, now)
+			if status != tt.wantStatus {
+				t.Errorf("status = %q, want %q", status, tt.wantStatus)
+			}
+			if attempts != tt.failedAttempts+1 {
+				t.Errorf("attempts = %d, want %d", attempts, tt.failedAttempts+1)
+			}
+			if (retryAt == nil) != (tt.wantRetryAt == nil) || retryAt != nil && !retryAt.Equal(*tt.wantRetryAt) {
+				t.Errorf("retryAt = %v, want %v", retryAt, tt.wantRetryAt)
+			}
+		})
+	}
+}
+
+func timePtr(t time.Time) *time.Time {
+	return &t
+}
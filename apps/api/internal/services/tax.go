@@ -0,0 +1,100 @@
+package services
+
+import (
+	"gastroshop-api/internal/models"
+)
+
+// taxCategories lists tax categories with their VAT rate in percent, in the
+// order they appear in tax breakdowns
+var taxCategories = []struct {
+	category    string
+	ratePercent int
+}{
+	{models.TaxCategoryVAT20, 20},
+	{models.TaxCategoryVAT10, 10},
+	{models.TaxCategoryVAT0, 0},
+	{models.TaxCategoryNoVAT, 0},
+}
+
+// ValidTaxCategory reports whether the tax category is known
+func ValidTaxCategory(category string) bool {
+	for _, tc := range taxCategories {
+		if tc.category == category {
+			return true
+		}
+	}
+	return false
+}
+
+// taxRatePercent returns the VAT rate of the category, 0 for unknown categories
+func taxRatePercent(category string) int {
+	for _, tc := range taxCategories {
+		if tc.category == category {
+			return tc.ratePercent
+		}
+	}
+	return 0
+}
+
+// calculateLineTax splits an order line into net, tax and gross amounts.
+// Tax-inclusive prices already contain VAT, which is extracted from the line
+// total. For tax-exclusive prices VAT is added per unit so that the gross
+// line total stays a multiple of the quantity; weighed lines priced per
+// kilogram add VAT to the line amount instead.
+func calculateLineTax(unitPriceCents, quantity int, unit, category string, pricesIncludeTax bool) (net, tax, gross int) {
+	rate := taxRatePercent(category)
+	amount := lineAmountCents(unitPriceCents, quantity, unit)
+
+	if pricesIncludeTax {
+		gross = amount
+		tax = divRound(gross*rate, 100+rate)
+		return gross - tax, tax, gross
+	}
+
+	net = amount
+	if unitScale(unit) == 1 {
+		tax = divRound(unitPriceCents*rate, 100) * quantity
+	} else {
+		tax = divRound(net*rate, 100)
+	}
+	return net, tax, net + tax
+}
+
+// applyOrderTax computes tax for every line and the per-category breakdown of
+// the order, and sets the order total to the gross amount
+func applyOrderTax(order *models.Order, pricesIncludeTax bool) {
+	totals := make(map[string]*models.TaxLine)
+	order.TaxCents = 0
+	order.AmountCents = 0
+	order.PricesIncludeTax = pricesIncludeTax
+
+	for i := range order.Items {
+		item := &order.Items[i]
+		net, tax, gross := calculateLineTax(item.PriceCents, item.Quantity, item.Unit, item.TaxCategory, pricesIncludeTax)
+		item.TaxCents = tax
+
+		line, ok := totals[item.TaxCategory]
+		if !ok {
+			line = &models.TaxLine{TaxCategory: item.TaxCategory, RatePercent: taxRatePercent(item.TaxCategory)}
+			totals[item.TaxCategory] = line
+		}
+		line.NetCents += net
+		line.TaxCents += tax
+		line.GrossCents += gross
+
+		order.TaxCents += tax
+		order.AmountCents += gross
+	}
+
+	order.TaxBreakdown = make([]models.TaxLine, 0, len(totals))
+	for _, tc := range taxCategories {
+		if line, ok := totals[tc.category]; ok {
+			order.TaxBreakdown = append(order.TaxBreakdown, *line)
+		}
+	}
+}
+
+// divRound divides non-negative integers rounding half up
+func divRound(a, b int) int {
+	return (2*a + b) / (2 * b)
+}
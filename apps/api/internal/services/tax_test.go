@@ -0,0 +1,70 @@
+package services
+
+import (
+	"testing"
+
+	"gastroshop-api/internal/models"
+)
+
+func TestCalculateLineTax(t *testing.T) {
+	tests := []struct {
+		name             string
+		unitPriceCents   int
+		quantity         int
+		unit             string
+		category         string
+		pricesIncludeTax bool
+		wantNet          int
+		wantTax          int
+		wantGross        int
+	}{
+		{name: "vat20 inclusive", unitPriceCents: 12000, quantity: 1, category: models.TaxCategoryVAT20, pricesIncludeTax: true, wantNet: 10000, wantTax: 2000, wantGross: 12000},
+		{name: "vat10 inclusive rounds", unitPriceCents: 999, quantity: 3, category: models.TaxCategoryVAT10, pricesIncludeTax: true, wantNet: 2725, wantTax: 272, wantGross: 2997},
+		{name: "vat20 exclusive per unit", unitPriceCents: 333, quantity: 3, category: models.TaxCategoryVAT20, pricesIncludeTax: false, wantNet: 999, wantTax: 201, wantGross: 1200},
+		{name: "vat0", unitPriceCents: 5000, quantity: 2, category: models.TaxCategoryVAT0, pricesIncludeTax: false, wantNet: 10000, wantTax: 0, wantGross: 10000},
+		{name: "no vat", unitPriceCents: 5000, quantity: 2, category: models.TaxCategoryNoVAT, pricesIncludeTax: true, wantNet: 10000, wantTax: 0, wantGross: 10000},
+		{name: "per kg inclusive", unitPriceCents: 250000, quantity: 350, unit: models.UnitKilogram, category: models.TaxCategoryVAT10, pricesIncludeTax: true, wantNet: 79545, wantTax: 7955, wantGross: 87500},
+		{name: "per kg exclusive", unitPriceCents: 199900, quantity: 150, unit: models.UnitKilogram, category: models.TaxCategoryVAT20, pricesIncludeTax: false, wantNet: 29985, wantTax: 5997, wantGross: 35982},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			net, tax, gross := calculateLineTax(tt.unitPriceCents, tt.quantity, tt.unit, tt.category, tt.pricesIncludeTax)
+			if net != tt.wantNet || tax != tt.wantTax || gross != tt.wantGross {
+				t.Errorf("calculateLineTax() = (%d, %d, %d), want (%d, %d, %d)",
+					net, tax, gross, tt.wantNet, tt.wantTax, tt.wantGross)
+			}
+		})
+	}
+}
+
+func TestApplyOrderTax(t *testing.T) {
+	order := &models.Order{
+		Items: []models.OrderItem{
+			{ProductID: 1, Quantity: 2, PriceCents: 1100, TaxCategory: models.TaxCategoryVAT10},
+			{ProductID: 2, Quantity: 1, PriceCents: 1200, TaxCategory: models.TaxCategoryVAT20},
+			{ProductID: 3, Quantity: 1, PriceCents: 550, TaxCategory: models.TaxCategoryVAT10},
+		},
+	}
+
+	applyOrderTax(order, true)
+
+	if order.AmountCents != 3950 {
+		t.Errorf("AmountCents = %d, want 3950", order.AmountCents)
+	}
+	if order.TaxCents != 450 {
+		t.Errorf("TaxCents = %d, want 450", order.TaxCents)
+	}
+	if order.Items[0].TaxCents != 200 {
+		t.Errorf("Items[0].TaxCents = %d, want 200", order.Items[0].TaxCents)
+	}
+	if len(order.TaxBreakdown) != 2 {
+		t.Fatalf("len(TaxBreakdown) = %d, want 2", len(order.TaxBreakdown))
+	}
+	if got := order.TaxBreakdown[0]; got.TaxCategory != models.TaxCategoryVAT20 || got.TaxCents != 200 {
+		t.Errorf("TaxBreakdown[0] = %+v, want vat20 with 200 tax", got)
+	}
+	if got := order.TaxBreakdown[1]; got.TaxCategory != models.TaxCategoryVAT10 || got.TaxCents != 250 || got.GrossCents != 2750 {
+		t.Errorf("TaxBreakdown[1] = %+v, want vat10 with 250 tax of 2750", got)
+	}
+}
@@ -0,0 +1,80 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+
+	"gastroshop-api/internal/models"
+)
+
+// unitScales lists units of measure with the quantity that makes up one
+// pricing unit: pieces are priced per piece, weighed goods are counted in
+// grams and priced per gram or per kilogram
+var unitScales = map[string]int{
+	models.UnitPiece:    1,
+	models.UnitGram:     1,
+	models.UnitKilogram: 1000,
+}
+
+// ValidUnit reports whether the unit of measure is known
+func ValidUnit(unit string) bool {
+	_, ok := unitScales[unit]
+	return ok
+}
+
+// unitScale returns the quantity in one pricing unit; lines without a unit
+// are counted in pieces
+func unitScale(unit string) int {
+	if scale, ok := unitScales[unit]; ok {
+		return scale
+	}
+	return 1
+}
+
+// lineAmountCents returns the price of a quantity of a product
+func lineAmountCents(priceCents, quantity int, unit string) int {
+	return divRound(priceCents*quantity, unitScale(unit))
+}
+
+// ValidateQuantityRules checks the unit, minimum quantity and step of a product
+func ValidateQuantityRules(unit string, minQuantity, step int) error {
+	if !ValidUnit(unit) {
+		return errors.New("unit must be piece, g or kg")
+	}
+	if minQuantity <= 0 || step <= 0 {
+		return errors.New("min quantity and quantity step must be positive")
+	}
+	return nil
+}
+
+// validateOrderQuantity checks that the quantity is at least the product's
+// minimum and a whole number of steps above it, e.g. 150 g in 50 g steps
+func validateOrderQuantity(product *models.Product, quantity int) error {
+	if quantity <= 0 {
+		return errors.New("quantity must be positive")
+	}
+
+	minQuantity, step := product.MinQuantity, product.QuantityStep
+	if minQuantity <= 0 {
+		minQuantity = 1
+	}
+	if step <= 0 {
+		step = 1
+	}
+
+	if quantity < minQuantity {
+		return fmt.Errorf("minimum quantity of %s is %s", product.Title, formatQuantity(minQuantity, product.Unit))
+	}
+	if (quantity-minQuantity)%step != 0 {
+		return fmt.Errorf("quantity of %s must be %s plus steps of %s", product.Title,
+			formatQuantity(minQuantity, product.Unit), formatQuantity(step, product.Unit))
+	}
+	return nil
+}
+
+func formatQuantity(quantity int, unit string) string {
+	if unit == models.UnitGram || unit == models.UnitKilogram {
+		return fmt.Sprintf("%d g", quantity)
+	}
+	return fmt.Sprintf("%d pcs", quantity)
+}
@@ -0,0 +1,59 @@
+package services
+
+import (
+	"testing"
+
+	"gastroshop-api/internal/models"
+)
+
+func TestValidateOrderQuantity(t *testing.T) {
+	weighed := &models.Product{Title: "Comté", Unit: models.UnitKilogram, MinQuantity: 150, QuantityStep: 50}
+	piece := &models.Product{Title: "Baguette", Unit: models.UnitPiece, MinQuantity: 1, QuantityStep: 1}
+
+	tests := []struct {
+		name     string
+		product  *models.Product
+		quantity int
+		wantErr  bool
+	}{
+		{name: "minimum", product: weighed, quantity: 150},
+		{name: "whole steps above minimum", product: weighed, quantity: 300},
+		{name: "below minimum", product: weighed, quantity: 100, wantErr: true},
+		{name: "between steps", product: weighed, quantity: 170, wantErr: true},
+		{name: "pieces", product: piece, quantity: 3},
+		{name: "zero", product: piece, quantity: 0, wantErr: true},
+		{name: "defaults without rules", product: &models.Product{}, quantity: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateOrderQuantity(tt.product, tt.quantity)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validateOrderQuantity() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLineAmountCents(t *testing.T) {
+	tests := []struct {
+		name       string
+		priceCents int
+		quantity   int
+		unit       string
+		want       int
+	}{
+		{name: "pieces", priceCents: 12000, quantity: 3, unit: models.UnitPiece, want: 36000},
+		{name: "grams", priceCents: 350, quantity: 200, unit: models.UnitGram, want: 70000},
+		{name: "kilograms rounds", priceCents: 129900, quantity: 155, unit: models.UnitKilogram, want: 20135},
+		{name: "no unit", priceCents: 500, quantity: 2, want: 1000},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := lineAmountCents(tt.priceCents, tt.quantity, tt.unit); got != tt.want {
+				t.Errorf("lineAmountCents() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,333 @@
+package services
+
+import (
+	"errors"
+	"log"
+	"strings"
+
+	"gastroshop-api/internal/models"
+	"gastroshop-api/internal/repository"
+)
+
+const (
+	// maxWishlistsPerUser limits the wishlists one customer can keep
+	maxWishlistsPerUser = 20
+	// maxWishlistNameLength matches the wishlists.name column
+	maxWishlistNameLength = 100
+)
+
+// WishlistService manages customers' named wishlists. Adding a product to a
+// wishlist is tracked as a wishlist_add event, so wishlists count toward
+// product popularity like views and purchases.
+type WishlistService struct {
+	wishlistRepo *repository.WishlistRepository
+	productRepo  *repository.ProductRepository
+	cartService  *CartService
+	eventService *EventService
+}
+
+func NewWishlistService(wishlistRepo *repository.WishlistRepository, productRepo *repository.ProductRepository, cartService *CartService, eventService *EventService) *WishlistService {
+	return &WishlistService{
+		wishlistRepo: wishlistRepo,
+		productRepo:  productRepo,
+		cartService:  cartService,
+		eventService: eventService,
+	}
+}
+
+// GetWishlists returns the wishlists of a user with their current products
+func (s *WishlistService) GetWishlists(userID int) ([]models.Wishlist, error) {
+	wishlists, err := s.wishlistRepo.GetWishlists(userID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range wishlists {
+		if err := s.hydrateItems(&wishlists[i]); err != nil {
+			return nil, err
+		}
+	}
+	return wishlists, nil
+}
+
+// GetWishlist returns a wishlist of a user with its current products
+func (s *WishlistService) GetWishlist(userID, id int) (*models.Wishlist, error) {
+	wishlist, err := s.getOwnWishlist(userID, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.hydrateItems(wishlist); err != nil {
+		return nil, err
+	}
+	return wishlist, nil
+}
+
+// GetSharedWishlist returns the wishlist shared with a link token
+func (s *WishlistService) GetSharedWishlist(token string) (*models.Wishlist, error) {
+	wishlist, err := s.wishlistRepo.GetWishlistByShareToken(token)
+	if err != nil {
+		return nil, err
+	}
+	if wishlist == nil {
+		return nil, errors.New("wishlist not found")
+	}
+	if err := s.hydrateItems(wishlist); err != nil {
+		return nil, err
+	}
+	return wishlist, nil
+}
+
+// getOwnWishlist returns a wishlist of a user without its products. Other
+// users' wishlists are reported as not found.
+func (s *WishlistService) getOwnWishlist(userID, id int) (*models.Wishlist, error) {
+	wishlist, err := s.wishlistRepo.GetWishlistByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if wishlist == nil || wishlist.UserID != userID {
+		return nil, errors.New("wishlist not found")
+	}
+	return wishlist, nil
+}
+
+// hydrateItems sets the current product and variant of the items of a
+// wishlist, leaving out items of deleted or archived products
+func (s *WishlistService) hydrateItems(wishlist *models.Wishlist) error {
+	productIDs := make([]int, 0, len(wishlist.Items))
+	for _, item := range wishlist.Items {
+		productIDs = append(productIDs, item.ProductID)
+	}
+	variants, err := s.productRepo.GetVariantsByProductIDs(productIDs)
+	if err != nil {
+		return err
+	}
+
+	items := make([]models.WishlistItem, 0, len(wishlist.Items))
+	for _, item := range wishlist.Items {
+		product, err := s.productRepo.GetProductByID(item.ProductID)
+		if err != nil {
+			return err
+		}
+		if product == nil || product.ArchivedAt != nil {
+			continue
+		}
+		item.Product = product
+
+		if item.VariantID != nil {
+			for _, v := range variants[product.ID] {
+				if v.ID == *item.VariantID {
+					variant := v
+					item.Variant = &variant
+				}
+			}
+			if item.Variant == nil {
+				continue
+			}
+		}
+
+		items = append(items, item)
+	}
+	wishlist.Items = items
+	return nil
+}
+
+// CreateWishlist creates a named wishlist for a user
+func (s *WishlistService) CreateWishlist(userID int, req *models.CreateWishlistRequest) (*models.Wishlist, error) {
+	existing, err := s.wishlistRepo.GetWishlists(userID)
+	if err != nil {
+		return nil, err
+	}
+	if len(existing) >= maxWishlistsPerUser {
+		return nil, errors.New("too many wishlists; delete some first")
+	}
+
+	name, err := validateWishlistName(req.Name, existing, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	wishlist := &models.Wishlist{UserID: userID, Name: name}
+	if err := s.wishlistRepo.CreateWishlist(wishlist); err != nil {
+		return nil, err
+	}
+	return wishlist, nil
+}
+
+// validateWishlistName trims a wishlist name and checks it is not empty,
+// fits the column and is not the name of another of the user's wishlists,
+// ignoring case. excludeID is the wishlist being renamed.
+func validateWishlistName(name string, existing []models.Wishlist, excludeID int) (string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "", errors.New("name is required")
+	}
+	if len([]rune(name)) > maxWishlistNameLength {
+		return "", errors.New("name must be at most 100 characters")
+	}
+	for _, w := range existing {
+		if w.ID != excludeID && strings.EqualFold(w.Name, name) {
+			return "", errors.New("wishlist with this name already exists")
+		}
+	}
+	return name, nil
+}
+
+// UpdateWishlist renames a wishlist and turns its share link on or off.
+// Turning sharing on makes a new link, so old links stop working once a
+// list is unshared.
+func (s *WishlistService) UpdateWishlist(userID, id int, req *models.UpdateWishlistRequest) (*models.Wishlist, error) {
+	wishlist, err := s.getOwnWishlist(userID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Name != nil {
+		existing, err := s.wishlistRepo.GetWishlists(userID)
+		if err != nil {
+			return nil, err
+		}
+		name, err := validateWishlistName(*req.Name, existing, wishlist.ID)
+		if err != nil {
+			return nil, err
+		}
+		wishlist.Name = name
+	}
+
+	if req.Shared != nil {
+		switch {
+		case *req.Shared && wishlist.ShareToken == nil:
+			token, err := GenerateVerificationToken()
+			if err != nil {
+				return nil, err
+			}
+			wishlist.ShareToken = &token
+		case !*req.Shared:
+			wishlist.ShareToken = nil
+		}
+	}
+
+	if err := s.wishlistRepo.UpdateWishlist(wishlist); err != nil {
+		return nil, err
+	}
+	if err := s.hydrateItems(wishlist); err != nil {
+		return nil, err
+	}
+	return wishlist, nil
+}
+
+func (s *WishlistService) DeleteWishlist(userID, id int) error {
+	if _, err := s.getOwnWishlist(userID, id); err != nil {
+		return err
+	}
+	return s.wishlistRepo.DeleteWishlist(id)
+}
+
+// AddItem adds a product or variant to a wishlist of a user; adding it
+// again updates its note. Products with variants can only be added as one
+// of their variants.
+func (s *WishlistService) AddItem(userID, wishlistID int, req *models.AddWishlistItemRequest) (*models.Wishlist, error) {
+	if _, err := s.getOwnWishlist(userID, wishlistID); err != nil {
+		return nil, err
+	}
+
+	line := models.OrderItem{ProductID: req.ProductID, VariantID: req.VariantID}
+	product, _, _, err := resolveOrderLine(s.productRepo, &line)
+	if err != nil {
+		return nil, err
+	}
+	if product.ArchivedAt != nil {
+		return nil, errors.New("product is no longer available")
+	}
+
+	item := &models.WishlistItem{
+		WishlistID: wishlistID,
+		ProductID:  req.ProductID,
+		VariantID:  req.VariantID,
+		Note:       strings.TrimSpace(req.Note),
+	}
+	created, err := s.wishlistRepo.AddItem(item)
+	if err != nil {
+		return nil, err
+	}
+
+	if created {
+		payload := map[string]interface{}{
+			"product_id":  req.ProductID,
+			"wishlist_id": wishlistID,
+		}
+		if req.VariantID != nil {
+			payload["variant_id"] = *req.VariantID
+		}
+		if err := s.eventService.TrackEvent(&userID, "wishlist_add", payload); err != nil {
+			log.Printf("Failed to track wishlist add of product %d: %v", req.ProductID, err)
+		}
+	}
+
+	return s.GetWishlist(userID, wishlistID)
+}
+
+// RemoveItem removes an item from a wishlist of a user
+func (s *WishlistService) RemoveItem(userID, wishlistID, itemID int) (*models.Wishlist, error) {
+	if _, err := s.getOwnWishlist(userID, wishlistID); err != nil {
+		return nil, err
+	}
+	if err := s.wishlistRepo.RemoveItem(wishlistID, itemID); err != nil {
+		return nil, errors.New("item not found in wishlist")
+	}
+	return s.GetWishlist(userID, wishlistID)
+}
+
+// MoveToCart adds a wishlist item to the user's cart and, unless keep is
+// set, removes it from the wishlist. It returns the cart.
+func (s *WishlistService) MoveToCart(userID, wishlistID, itemID int, req *models.MoveWishlistItemToCartRequest) (*models.Cart, error) {
+	wishlist, err := s.getOwnWishlist(userID, wishlistID)
+	if err != nil {
+		return nil, err
+	}
+
+	var item *models.WishlistItem
+	for i := range wishlist.Items {
+		if wishlist.Items[i].ID == itemID {
+			item = &wishlist.Items[i]
+		}
+	}
+	if item == nil {
+		return nil, errors.New("item not found in wishlist")
+	}
+
+	product, err := s.productRepo.GetProductByID(item.ProductID)
+	if err != nil {
+		return nil, err
+	}
+	if product == nil {
+		return nil, errors.New("product not found")
+	}
+
+	cart, err := s.cartService.AddItem(userID, &models.AddToCartRequest{
+		ProductID: item.ProductID,
+		VariantID: item.VariantID,
+		Quantity:  moveToCartQuantity(req.Quantity, product),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if !req.Keep {
+		if err := s.wishlistRepo.RemoveItem(wishlistID, itemID); err != nil {
+			log.Printf("Failed to remove wishlist item %d moved to cart: %v", itemID, err)
+		}
+	}
+	return cart, nil
+}
+
+// moveToCartQuantity returns the quantity a wishlist item is moved to the
+// cart with: the quantity asked for, or else one piece or the product's
+// minimum quantity
+func moveToCartQuantity(quantity int, product *models.Product) int {
+	if quantity > 0 {
+		return quantity
+	}
+	if product.MinQuantity > 1 {
+		return product.MinQuantity
+	}
+	return 1
+}
@@ -0,0 +1,70 @@
+package services
+
+import (
+	"strings"
+	"testing"
+
+	"gastroshop-api/internal/models"
+)
+
+func TestValidateWishlistName(t *testing.T) {
+	existing := []models.Wishlist{
+		{ID: 1, Name: "Birthday"},
+		{ID: 2, Name: "Pantry"},
+	}
+
+	tests := []struct {
+		name      string
+		input     string
+		excludeID int
+		want      string
+		wantErr   bool
+	}{
+		{name: "new name", input: "Christmas", want: "Christmas"},
+		{name: "trimmed", input: "  Christmas ", want: "Christmas"},
+		{name: "empty", input: "   ", wantErr: true},
+		{name: "too long", input: strings.Repeat("a", 101), wantErr: true},
+		{name: "cyrillic at limit", input: strings.Repeat("я", 100), want: strings.Repeat("я", 100)},
+		{name: "taken ignoring case", input: "birthday", wantErr: true},
+		{name: "renaming to own name", input: "BIRTHDAY", excludeID: 1, want: "BIRTHDAY"},
+		{name: "renaming to other list's name", input: "pantry", excludeID: 1, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := validateWishlistName(tt.input, existing, tt.excludeID)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("validateWishlistName() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("validateWishlistName() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMoveToCartQuantity(t *testing.T) {
+	piece := &models.Product{Unit: models.UnitPiece, MinQuantity: 1}
+	weighed := &models.Product{Unit: models.UnitKilogram, MinQuantity: 150}
+
+	tests := []struct {
+		name     string
+		quantity int
+		product  *models.Product
+		want     int
+	}{
+		{name: "default piece", product: piece, want: 1},
+		{name: "default weighed", product: weighed, want: 150},
+		{name: "given quantity", quantity: 3, product: piece, want: 3},
+		{name: "given weight", quantity: 500, product: weighed, want: 500},
+		{name: "unset minimum", product: &models.Product{}, want: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := moveToCartQuantity(tt.quantity, tt.product); got != tt.want {
+				t.Errorf("moveToCartQuantity() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
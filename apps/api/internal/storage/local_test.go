@@ -0,0 +1,40 @@
+package storage
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLocalStorage(t *testing.T) {
+	dir := t.TempDir()
+	s, err := NewLocalStorage(dir, "http://localhost:8080/uploads/")
+	if err != nil {
+		t.Fatalf("NewLocalStorage() error = %v", err)
+	}
+
+	url, err := s.Put("products/1/abc.jpg", strings.NewReader("data"), 4, "image/jpeg")
+	if err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+	if url != "http://localhost:8080/uploads/products/1/abc.jpg" {
+		t.Errorf("Put() url = %s", url)
+	}
+	if data, err := os.ReadFile(filepath.Join(dir, "products", "1", "abc.jpg")); err != nil || string(data) != "data" {
+		t.Errorf("stored file = %q, %v", data, err)
+	}
+
+	if err := s.Delete("products/1/abc.jpg"); err != nil {
+		t.Errorf("Delete() error = %v", err)
+	}
+	if err := s.Delete("products/1/abc.jpg"); err != nil {
+		t.Errorf("Delete() of a missing file error = %v", err)
+	}
+
+	for _, key := range []string{"../outside.jpg", "products/../../outside.jpg", "/etc/passwd", ""} {
+		if _, err := s.Put(key, strings.NewReader("x"), 1, "image/jpeg"); err == nil {
+			t.Errorf("Put(%q) should fail", key)
+		}
+	}
+}
@@ -0,0 +1,43 @@
+// Package storage stores uploaded files and returns their public URLs.
+package storage
+
+import (
+	"errors"
+	"io"
+)
+
+// Storage is a file store with public URLs. Keys are slash-separated paths
+// such as "products/12/3f9a.jpg".
+type Storage interface {
+	// Put stores the content under the key and returns its public URL
+	Put(key string, r io.Reader, size int64, contentType string) (string, error)
+	// Delete removes the file; deleting a missing file is not an error
+	Delete(key string) error
+}
+
+// Config selects and configures a storage backend
+type Config struct {
+	Backend string // "local" or "s3"
+
+	LocalDir     string
+	LocalBaseURL string
+
+	S3Endpoint  string
+	S3AccessKey string
+	S3SecretKey string
+	S3Bucket    string
+	S3Region    string
+	S3UseSSL    bool
+	S3PublicURL string
+}
+
+// New creates the storage backend selected by the config
+func New(cfg Config) (Storage, error) {
+	switch cfg.Backend {
+	case "", "local":
+		return NewLocalStorage(cfg.LocalDir, cfg.LocalBaseURL)
+	case "s3":
+		return NewS3Storage(cfg)
+	}
+	return nil, errors.New("unknown storage backend " + cfg.Backend)
+}
@@ -0,0 +1,10 @@
+-- Drop indexes
+DROP INDEX IF EXISTS idx_orders_delivery_slot_id;
+DROP INDEX IF EXISTS idx_delivery_slots_active;
+DROP INDEX IF EXISTS idx_delivery_slots_zone_starts_at;
+
+-- Remove slot reference from orders
+ALTER TABLE orders DROP COLUMN IF EXISTS delivery_slot_id;
+
+-- Drop tables
+DROP TABLE IF EXISTS delivery_slots;
@@ -0,0 +1,22 @@
+-- Create delivery slots table (courier time windows per zone)
+CREATE TABLE IF NOT EXISTS delivery_slots (
+    id SERIAL PRIMARY KEY,
+    zone VARCHAR(100) NOT NULL,
+    starts_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    ends_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    capacity INTEGER NOT NULL CHECK (capacity >= 0),
+    reserved INTEGER NOT NULL DEFAULT 0 CHECK (reserved >= 0),
+    active BOOLEAN DEFAULT true,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    CHECK (ends_at > starts_at),
+    UNIQUE(zone, starts_at)
+);
+
+-- Link orders to the booked slot
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS delivery_slot_id INTEGER REFERENCES delivery_slots(id);
+
+-- Create indexes
+CREATE INDEX IF NOT EXISTS idx_delivery_slots_zone_starts_at ON delivery_slots(zone, starts_at);
+CREATE INDEX IF NOT EXISTS idx_delivery_slots_active ON delivery_slots(active);
+CREATE INDEX IF NOT EXISTS idx_orders_delivery_slot_id ON orders(delivery_slot_id);
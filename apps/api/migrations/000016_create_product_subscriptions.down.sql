@@ -0,0 +1,9 @@
+-- Drop indexes
+DROP INDEX IF EXISTS idx_subscription_boxes_active;
+DROP INDEX IF EXISTS idx_product_subscriptions_next_retry_at;
+DROP INDEX IF EXISTS idx_product_subscriptions_status_next_run;
+DROP INDEX IF EXISTS idx_product_subscriptions_user_id;
+
+-- Drop tables
+DROP TABLE IF EXISTS product_subscriptions;
+DROP TABLE IF EXISTS subscription_boxes;
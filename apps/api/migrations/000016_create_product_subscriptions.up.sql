@@ -0,0 +1,38 @@
+-- Create curated subscription boxes ("cheese of the month")
+CREATE TABLE IF NOT EXISTS subscription_boxes (
+    id SERIAL PRIMARY KEY,
+    slug VARCHAR(255) UNIQUE NOT NULL,
+    title VARCHAR(255) NOT NULL,
+    description TEXT,
+    items JSONB NOT NULL DEFAULT '[]',
+    active BOOLEAN DEFAULT true,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Create recurring product subscriptions
+CREATE TABLE IF NOT EXISTS product_subscriptions (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    product_id INTEGER REFERENCES products(id) ON DELETE CASCADE,
+    box_id INTEGER REFERENCES subscription_boxes(id),
+    quantity INTEGER NOT NULL DEFAULT 1 CHECK (quantity > 0),
+    frequency VARCHAR(20) NOT NULL CHECK (frequency IN ('weekly', 'monthly')),
+    status VARCHAR(20) NOT NULL DEFAULT 'active' CHECK (status IN ('active', 'paused', 'past_due', 'canceled')),
+    shipping_address JSONB NOT NULL DEFAULT '{}',
+    payment_method_id VARCHAR(255),
+    next_run_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    skip_next BOOLEAN DEFAULT false,
+    failed_attempts INTEGER NOT NULL DEFAULT 0,
+    next_retry_at TIMESTAMP WITH TIME ZONE,
+    last_order_id INTEGER REFERENCES orders(id) ON DELETE SET NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    CHECK ((product_id IS NOT NULL) <> (box_id IS NOT NULL))
+);
+
+-- Create indexes
+CREATE INDEX IF NOT EXISTS idx_product_subscriptions_user_id ON product_subscriptions(user_id);
+CREATE INDEX IF NOT EXISTS idx_product_subscriptions_status_next_run ON product_subscriptions(status, next_run_at);
+CREATE INDEX IF NOT EXISTS idx_product_subscriptions_next_retry_at ON product_subscriptions(next_retry_at);
+CREATE INDEX IF NOT EXISTS idx_subscription_boxes_active ON subscription_boxes(active);
@@ -0,0 +1,8 @@
+-- Drop indexes
+DROP INDEX IF EXISTS idx_orders_guest_email;
+DROP INDEX IF EXISTS idx_orders_access_token;
+
+-- Remove guest columns from orders
+ALTER TABLE orders DROP COLUMN IF EXISTS access_token;
+ALTER TABLE orders DROP COLUMN IF EXISTS guest_phone;
+ALTER TABLE orders DROP COLUMN IF EXISTS guest_email;
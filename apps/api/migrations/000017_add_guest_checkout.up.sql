@@ -0,0 +1,8 @@
+-- Guest checkout: contact details and an access token for orders without an account
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS guest_email VARCHAR(255);
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS guest_phone VARCHAR(50);
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS access_token VARCHAR(64);
+
+-- Create indexes
+CREATE UNIQUE INDEX IF NOT EXISTS idx_orders_access_token ON orders(access_token);
+CREATE INDEX IF NOT EXISTS idx_orders_guest_email ON orders(LOWER(guest_email)) WHERE user_id IS NULL;
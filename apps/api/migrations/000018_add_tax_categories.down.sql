@@ -0,0 +1,7 @@
+-- Remove tax breakdown from orders
+ALTER TABLE orders DROP COLUMN IF EXISTS prices_include_tax;
+ALTER TABLE orders DROP COLUMN IF EXISTS tax_breakdown;
+ALTER TABLE orders DROP COLUMN IF EXISTS tax_cents;
+
+-- Remove tax category from products
+ALTER TABLE products DROP COLUMN IF EXISTS tax_category;
@@ -0,0 +1,8 @@
+-- Tax category of each product: VAT 0/10/20% or no VAT (small-business mode)
+ALTER TABLE products ADD COLUMN IF NOT EXISTS tax_category VARCHAR(10) NOT NULL DEFAULT 'vat20'
+    CHECK (tax_category IN ('vat0', 'vat10', 'vat20', 'no_vat'));
+
+-- Tax breakdown stored on orders at checkout
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS tax_cents INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS tax_breakdown JSONB NOT NULL DEFAULT '[]';
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS prices_include_tax BOOLEAN NOT NULL DEFAULT true;
@@ -0,0 +1,9 @@
+-- Drop indexes
+DROP INDEX IF EXISTS idx_cart_items_line;
+DROP INDEX IF EXISTS idx_product_variants_barcode;
+DROP INDEX IF EXISTS idx_product_variants_product_id;
+
+-- Drop tables and columns
+DROP TABLE IF EXISTS cart_items;
+ALTER TABLE product_subscriptions DROP COLUMN IF EXISTS variant_id;
+DROP TABLE IF EXISTS product_variants;
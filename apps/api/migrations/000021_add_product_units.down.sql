@@ -0,0 +1,5 @@
+-- Drop columns
+ALTER TABLE products
+    DROP COLUMN IF EXISTS quantity_step,
+    DROP COLUMN IF EXISTS min_quantity,
+    DROP COLUMN IF EXISTS unit;
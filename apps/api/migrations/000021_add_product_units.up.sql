@@ -0,0 +1,6 @@
+-- Add units of measure for goods sold by weight. Weighed goods are counted in
+-- grams and priced per gram ('g') or per kilogram ('kg').
+ALTER TABLE products
+    ADD COLUMN IF NOT EXISTS unit VARCHAR(10) NOT NULL DEFAULT 'piece' CHECK (unit IN ('piece', 'g', 'kg')),
+    ADD COLUMN IF NOT EXISTS min_quantity INTEGER NOT NULL DEFAULT 1 CHECK (min_quantity > 0),
+    ADD COLUMN IF NOT EXISTS quantity_step INTEGER NOT NULL DEFAULT 1 CHECK (quantity_step > 0);
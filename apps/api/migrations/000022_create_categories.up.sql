@@ -0,0 +1,56 @@
+-- Create the category tree
+CREATE TABLE IF NOT EXISTS categories (
+    id SERIAL PRIMARY KEY,
+    parent_id INTEGER REFERENCES categories(id) ON DELETE RESTRICT,
+    slug VARCHAR(255) UNIQUE NOT NULL,
+    name VARCHAR(255) NOT NULL,
+    description TEXT,
+    image_url TEXT,
+    sort_order INTEGER NOT NULL DEFAULT 0,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    CHECK (parent_id IS NULL OR parent_id <> id)
+);
+
+-- Create product-category assignments
+CREATE TABLE IF NOT EXISTS product_categories (
+    product_id INTEGER NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    category_id INTEGER NOT NULL REFERENCES categories(id) ON DELETE CASCADE,
+    PRIMARY KEY (product_id, category_id)
+);
+
+-- Create indexes
+CREATE INDEX IF NOT EXISTS idx_categories_parent_id ON categories(parent_id);
+CREATE INDEX IF NOT EXISTS idx_product_categories_category_id ON product_categories(category_id);
+
+-- Seed the categories that used to be hardcoded as tag sets
+INSERT INTO categories (slug, name, sort_order) VALUES
+    ('cheese', 'Сыры', 1),
+    ('deli', 'Мясные деликатесы', 2)
+ON CONFLICT (slug) DO NOTHING;
+
+INSERT INTO categories (parent_id, slug, name, sort_order)
+SELECT parent.id, child.slug, child.name, child.sort_order
+FROM (VALUES
+    ('soft-cheese', 'Мягкие сыры', 1),
+    ('hard-cheese', 'Твёрдые сыры', 2),
+    ('blue-cheese', 'Голубые сыры', 3),
+    ('aged-cheese', 'Выдержанные сыры', 4)
+) AS child(slug, name, sort_order)
+CROSS JOIN categories parent
+WHERE parent.slug = 'cheese'
+ON CONFLICT (slug) DO NOTHING;
+
+-- Assign products by the tags each category used to require
+INSERT INTO product_categories (product_id, category_id)
+SELECT p.id, c.id
+FROM products p
+JOIN categories c ON (
+       (c.slug = 'cheese' AND p.tags @> ARRAY['cheese'])
+    OR (c.slug = 'deli' AND p.tags @> ARRAY['ham', 'cured'])
+    OR (c.slug = 'soft-cheese' AND p.tags @> ARRAY['cheese', 'soft'])
+    OR (c.slug = 'hard-cheese' AND p.tags @> ARRAY['cheese', 'hard'])
+    OR (c.slug = 'blue-cheese' AND p.tags @> ARRAY['cheese', 'blue'])
+    OR (c.slug = 'aged-cheese' AND p.tags @> ARRAY['cheese', 'aged'])
+)
+ON CONFLICT DO NOTHING;
@@ -0,0 +1,5 @@
+-- Drop indexes
+DROP INDEX IF EXISTS idx_product_rankings_product_id;
+
+-- Drop views
+DROP MATERIALIZED VIEW IF EXISTS product_rankings;
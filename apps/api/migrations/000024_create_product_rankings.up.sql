@@ -0,0 +1,33 @@
+-- Create product rankings used for popularity and rating sorting.
+-- Popularity counts paid orders (10 points each) and product events (1 point
+-- each) of the last 90 days; rating is the average of approved reviews.
+CREATE MATERIALIZED VIEW IF NOT EXISTS product_rankings AS
+SELECT
+    p.id AS product_id,
+    COALESCE(o.order_count, 0) * 10 + COALESCE(e.event_count, 0) AS popularity,
+    COALESCE(rv.avg_rating, 0)::NUMERIC(3, 2) AS rating,
+    COALESCE(rv.review_count, 0) AS review_count
+FROM products p
+LEFT JOIN (
+    SELECT (item->>'product_id')::INTEGER AS product_id, COUNT(DISTINCT ord.id) AS order_count
+    FROM orders ord, jsonb_array_elements(ord.items) AS item
+    WHERE ord.status IN ('paid', 'shipped', 'delivered')
+      AND ord.created_at > NOW() - INTERVAL '90 days'
+    GROUP BY 1
+) o ON o.product_id = p.id
+LEFT JOIN (
+    SELECT (payload->>'product_id')::INTEGER AS product_id, COUNT(*) AS event_count
+    FROM events
+    WHERE payload->>'product_id' ~ '^[0-9]{1,9}$'
+      AND created_at > NOW() - INTERVAL '90 days'
+    GROUP BY 1
+) e ON e.product_id = p.id
+LEFT JOIN (
+    SELECT product_id, AVG(rating) AS avg_rating, COUNT(*) AS review_count
+    FROM reviews
+    WHERE approved = true
+    GROUP BY product_id
+) rv ON rv.product_id = p.id;
+
+-- Create indexes (the unique index allows concurrent refreshes)
+CREATE UNIQUE INDEX IF NOT EXISTS idx_product_rankings_product_id ON product_rankings(product_id);
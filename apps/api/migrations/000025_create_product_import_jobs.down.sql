@@ -0,0 +1,5 @@
+-- Drop indexes
+DROP INDEX IF EXISTS idx_product_import_jobs_created_at;
+
+-- Drop tables
+DROP TABLE IF EXISTS product_import_jobs;
@@ -0,0 +1,18 @@
+-- Create uploaded product images. products.images keeps the display order;
+-- this table holds the stored files and resized variants of uploaded images.
+CREATE TABLE IF NOT EXISTS product_images (
+    id SERIAL PRIMARY KEY,
+    product_id INTEGER NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    url TEXT NOT NULL,
+    storage_keys TEXT[] NOT NULL DEFAULT '{}',
+    content_type VARCHAR(50) NOT NULL,
+    width INTEGER NOT NULL,
+    height INTEGER NOT NULL,
+    size_bytes INTEGER NOT NULL,
+    variants JSONB NOT NULL DEFAULT '{}',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE(product_id, url)
+);
+
+-- Create indexes
+CREATE INDEX IF NOT EXISTS idx_product_images_product_id ON product_images(product_id);
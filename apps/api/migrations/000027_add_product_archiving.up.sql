@@ -0,0 +1,7 @@
+-- Archive products instead of deleting them. Archived products are hidden
+-- from the storefront but still resolve for orders, statistics and reviews.
+ALTER TABLE products
+    ADD COLUMN IF NOT EXISTS archived_at TIMESTAMP;
+
+-- Create indexes
+CREATE INDEX IF NOT EXISTS idx_products_archived_at ON products(archived_at);
@@ -0,0 +1,7 @@
+-- Drop indexes
+DROP INDEX IF EXISTS idx_product_attribute_values_number;
+DROP INDEX IF EXISTS idx_product_attribute_values_text;
+
+-- Drop tables
+DROP TABLE IF EXISTS product_attribute_values;
+DROP TABLE IF EXISTS product_attributes;
@@ -0,0 +1,73 @@
+-- Create product attributes: typed characteristics such as milk type or
+-- aging. Enum attributes list their options as [{"value", "label"}]; numbers
+-- carry a unit.
+CREATE TABLE IF NOT EXISTS product_attributes (
+    id SERIAL PRIMARY KEY,
+    code VARCHAR(50) UNIQUE NOT NULL,
+    name VARCHAR(255) NOT NULL,
+    type VARCHAR(20) NOT NULL CHECK (type IN ('enum', 'number', 'boolean', 'text')),
+    unit VARCHAR(20),
+    options JSONB NOT NULL DEFAULT '[]',
+    filterable BOOLEAN NOT NULL DEFAULT true,
+    sort_order INTEGER NOT NULL DEFAULT 0,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Create product attribute values; the column used depends on the attribute
+-- type (text_value holds enum and text values)
+CREATE TABLE IF NOT EXISTS product_attribute_values (
+    product_id INTEGER NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    attribute_id INTEGER NOT NULL REFERENCES product_attributes(id) ON DELETE CASCADE,
+    text_value TEXT,
+    number_value NUMERIC,
+    bool_value BOOLEAN,
+    PRIMARY KEY (product_id, attribute_id)
+);
+
+-- Create indexes
+CREATE INDEX IF NOT EXISTS idx_product_attribute_values_text ON product_attribute_values(attribute_id, text_value);
+CREATE INDEX IF NOT EXISTS idx_product_attribute_values_number ON product_attribute_values(attribute_id, number_value);
+
+-- Seed the characteristics that used to live in tags and descriptions
+INSERT INTO product_attributes (code, name, type, unit, options, sort_order) VALUES
+    ('milk', 'Молоко', 'enum', NULL, '[
+        {"value": "cow", "label": "Коровье"},
+        {"value": "goat", "label": "Козье"},
+        {"value": "sheep", "label": "Овечье"},
+        {"value": "buffalo", "label": "Буйволиное"},
+        {"value": "mixed", "label": "Смешанное"}
+    ]', 1),
+    ('aging', 'Выдержка', 'number', 'мес.', '[]', 2),
+    ('fat', 'Жирность', 'number', '%', '[]', 3),
+    ('country', 'Страна', 'enum', NULL, '[
+        {"value": "italy", "label": "Италия"},
+        {"value": "france", "label": "Франция"},
+        {"value": "spain", "label": "Испания"},
+        {"value": "netherlands", "label": "Нидерланды"},
+        {"value": "germany", "label": "Германия"},
+        {"value": "switzerland", "label": "Швейцария"},
+        {"value": "uk", "label": "Великобритания"},
+        {"value": "denmark", "label": "Дания"},
+        {"value": "russia", "label": "Россия"}
+    ]', 4),
+    ('wine_pairing', 'Вино к сыру', 'text', NULL, '[]', 5)
+ON CONFLICT (code) DO NOTHING;
+
+-- Take milk type and country from the tags they used to be kept in
+INSERT INTO product_attribute_values (product_id, attribute_id, text_value)
+SELECT p.id, a.id, m.value
+FROM products p
+JOIN (VALUES
+    ('milk', 'goat', 'goat'),
+    ('milk', 'sheep', 'sheep'),
+    ('country', 'italian', 'italy'),
+    ('country', 'french', 'france'),
+    ('country', 'spanish', 'spain'),
+    ('country', 'dutch', 'netherlands'),
+    ('country', 'german', 'germany'),
+    ('country', 'english', 'uk'),
+    ('country', 'danish', 'denmark'),
+    ('country', 'russian', 'russia')
+) AS m(code, tag, value) ON p.tags @> ARRAY[m.tag]
+JOIN product_attributes a ON a.code = m.code
+ON CONFLICT DO NOTHING;
@@ -0,0 +1,9 @@
+-- Drop indexes
+DROP INDEX IF EXISTS idx_products_dietary_flags;
+
+-- Drop columns
+ALTER TABLE users
+    DROP COLUMN IF EXISTS dietary_restrictions;
+
+ALTER TABLE products
+    DROP COLUMN IF EXISTS dietary_flags;
@@ -0,0 +1,13 @@
+-- Allergen and dietary flags of products: lactose_free, vegetarian_rennet,
+-- pasteurized, raw_milk, gluten and nuts
+ALTER TABLE products
+    ADD COLUMN IF NOT EXISTS dietary_flags TEXT[] NOT NULL DEFAULT '{}'
+    CHECK (dietary_flags <@ ARRAY['lactose_free', 'vegetarian_rennet', 'pasteurized', 'raw_milk', 'gluten', 'nuts']::TEXT[]);
+
+-- Dietary restrictions saved by users: lactose, animal_rennet, raw_milk,
+-- gluten and nuts are left out of their recommendations
+ALTER TABLE users
+    ADD COLUMN IF NOT EXISTS dietary_restrictions TEXT[] NOT NULL DEFAULT '{}';
+
+-- Create indexes
+CREATE INDEX IF NOT EXISTS idx_products_dietary_flags ON products USING GIN(dietary_flags);
@@ -0,0 +1,12 @@
+-- Drop indexes
+DROP INDEX IF EXISTS idx_product_sales_status;
+DROP INDEX IF EXISTS idx_product_sales_product_id;
+DROP INDEX IF EXISTS idx_price_history_product_id;
+
+-- Drop tables
+DROP TABLE IF EXISTS product_sales;
+DROP TABLE IF EXISTS price_history;
+
+-- Drop columns
+ALTER TABLE products
+    DROP COLUMN IF EXISTS compare_at_price_cents;
@@ -0,0 +1,40 @@
+-- Regular price of products on sale, shown struck through next to the sale price
+ALTER TABLE products
+    ADD COLUMN IF NOT EXISTS compare_at_price_cents INTEGER;
+
+-- Create price history table: every price of a product or one of its variants
+CREATE TABLE IF NOT EXISTS price_history (
+    id SERIAL PRIMARY KEY,
+    product_id INTEGER NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    variant_id INTEGER REFERENCES product_variants(id) ON DELETE CASCADE,
+    price_cents INTEGER NOT NULL,
+    previous_price_cents INTEGER,
+    reason VARCHAR(20) NOT NULL CHECK (reason IN ('initial', 'update', 'sale_start', 'sale_end')),
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+-- Current prices start the history
+INSERT INTO price_history (product_id, price_cents, reason, created_at)
+SELECT id, price_cents, 'initial', COALESCE(created_at, NOW()) FROM products;
+
+INSERT INTO price_history (product_id, variant_id, price_cents, reason, created_at)
+SELECT product_id, id, price_cents, 'initial', COALESCE(created_at, NOW()) FROM product_variants;
+
+-- Create product sales table: sale prices scheduled from starts_at to ends_at.
+-- regular_price_cents is the price the product returns to when the sale ends.
+CREATE TABLE IF NOT EXISTS product_sales (
+    id SERIAL PRIMARY KEY,
+    product_id INTEGER NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    sale_price_cents INTEGER NOT NULL CHECK (sale_price_cents > 0),
+    regular_price_cents INTEGER,
+    starts_at TIMESTAMP NOT NULL,
+    ends_at TIMESTAMP NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'scheduled' CHECK (status IN ('scheduled', 'active', 'ended', 'canceled')),
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    CHECK (ends_at > starts_at)
+);
+
+-- Create indexes
+CREATE INDEX IF NOT EXISTS idx_price_history_product_id ON price_history(product_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_product_sales_product_id ON product_sales(product_id);
+CREATE INDEX IF NOT EXISTS idx_product_sales_status ON product_sales(status, starts_at);
@@ -0,0 +1,7 @@
+-- Drop indexes
+DROP INDEX IF EXISTS idx_product_pairings_paired_product_id;
+DROP INDEX IF EXISTS idx_product_pairings_product_id;
+DROP INDEX IF EXISTS idx_product_pairings_pair;
+
+-- Drop tables
+DROP TABLE IF EXISTS product_pairings;
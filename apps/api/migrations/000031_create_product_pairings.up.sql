@@ -0,0 +1,34 @@
+-- Create product pairings: curated matches such as a wine with a cheese or a
+-- honey with a blue. A pairing works both ways, so each pair of products is
+-- stored once.
+CREATE TABLE IF NOT EXISTS product_pairings (
+    id SERIAL PRIMARY KEY,
+    product_id INTEGER NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    paired_product_id INTEGER NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    pairing_type VARCHAR(20) NOT NULL CHECK (pairing_type IN ('classic', 'complement', 'contrast', 'regional')),
+    note TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    CHECK (product_id <> paired_product_id)
+);
+
+-- Create indexes
+CREATE UNIQUE INDEX IF NOT EXISTS idx_product_pairings_pair
+    ON product_pairings(LEAST(product_id, paired_product_id), GREATEST(product_id, paired_product_id));
+CREATE INDEX IF NOT EXISTS idx_product_pairings_product_id ON product_pairings(product_id);
+CREATE INDEX IF NOT EXISTS idx_product_pairings_paired_product_id ON product_pairings(paired_product_id);
+
+-- Seed the classic pairings of the starter catalog
+INSERT INTO product_pairings (product_id, paired_product_id, pairing_type, note)
+SELECT p.id, paired.id, s.pairing_type, s.note
+FROM (VALUES
+    ('parmigiano-reggiano', 'balsamic-modena', 'classic', 'Несколько капель выдержанного бальзамика раскрывают сладость и кристаллы зрелого пармиджано'),
+    ('manchego-curado', 'iberico-ham', 'regional', 'Испанская классика для тапас: ореховый манчего и тающий жир хамона'),
+    ('feta-pdo', 'olives-kalamata', 'regional', 'Греческий салат и мезе: соленая фета и маслянистые оливки'),
+    ('feta-pdo', 'olive-oil-crete', 'regional', 'Критское масло с травами делает фету мягче и ароматнее'),
+    ('brie-de-meaux', 'truffle-oil', 'complement', 'Капля трюфельного масла подчеркивает грибные ноты корочки бри'),
+    ('gorgonzola-dolce', 'balsamic-modena', 'contrast', 'Сладкий уксус уравновешивает остроту и соль голубого сыра'),
+    ('pecorino-romano', 'pesto-genovese', 'classic', 'Пекорино - один из сыров в рецепте песто Дженовезе')
+) AS s(product_slug, paired_slug, pairing_type, note)
+JOIN products p ON p.slug = s.product_slug
+JOIN products paired ON paired.slug = s.paired_slug
+ON CONFLICT DO NOTHING;
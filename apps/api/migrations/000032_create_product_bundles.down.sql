@@ -0,0 +1,8 @@
+-- Drop indexes
+DROP INDEX IF EXISTS idx_bundle_components_variant_id;
+DROP INDEX IF EXISTS idx_bundle_components_product_id;
+DROP INDEX IF EXISTS idx_bundle_components_line;
+
+-- Drop tables
+DROP TABLE IF EXISTS bundle_components;
+DROP TABLE IF EXISTS product_bundles;
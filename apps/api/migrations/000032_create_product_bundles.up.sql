@@ -0,0 +1,26 @@
+-- Create product bundles: products such as gift boxes made of other
+-- products. A bundle is priced at its own fixed price or at a discount off
+-- the sum of its components; its stock is the number of bundles the
+-- component stock makes up and is kept up to date by the API.
+CREATE TABLE IF NOT EXISTS product_bundles (
+    product_id INTEGER PRIMARY KEY REFERENCES products(id) ON DELETE CASCADE,
+    pricing VARCHAR(20) NOT NULL CHECK (pricing IN ('fixed', 'discount')),
+    discount_percent INTEGER NOT NULL DEFAULT 0 CHECK (discount_percent >= 0 AND discount_percent < 100),
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+-- Create bundle components; variant_id names the variant of a component
+-- product with variants. Components can't be deleted while in a bundle.
+CREATE TABLE IF NOT EXISTS bundle_components (
+    id SERIAL PRIMARY KEY,
+    bundle_id INTEGER NOT NULL REFERENCES product_bundles(product_id) ON DELETE CASCADE,
+    product_id INTEGER NOT NULL REFERENCES products(id) ON DELETE RESTRICT,
+    variant_id INTEGER REFERENCES product_variants(id) ON DELETE RESTRICT,
+    quantity INTEGER NOT NULL CHECK (quantity > 0),
+    CHECK (bundle_id <> product_id)
+);
+
+-- Create indexes
+CREATE UNIQUE INDEX IF NOT EXISTS idx_bundle_components_line ON bundle_components(bundle_id, product_id, COALESCE(variant_id, 0));
+CREATE INDEX IF NOT EXISTS idx_bundle_components_product_id ON bundle_components(product_id);
+CREATE INDEX IF NOT EXISTS idx_bundle_components_variant_id ON bundle_components(variant_id) WHERE variant_id IS NOT NULL;
@@ -0,0 +1,12 @@
+-- Drop indexes
+DROP INDEX IF EXISTS idx_stock_take_counts_line;
+DROP INDEX IF EXISTS idx_inventory_movements_created_at;
+DROP INDEX IF EXISTS idx_inventory_movements_reference;
+DROP INDEX IF EXISTS idx_inventory_movements_product_id;
+
+-- Drop tables
+DROP TABLE IF EXISTS stock_take_counts;
+DROP TABLE IF EXISTS stock_takes;
+DROP TRIGGER IF EXISTS trg_inventory_movements_append_only ON inventory_movements;
+DROP FUNCTION IF EXISTS inventory_movements_forbid_update();
+DROP TABLE IF EXISTS inventory_movements;
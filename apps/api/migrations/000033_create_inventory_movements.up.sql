@@ -0,0 +1,69 @@
+-- Create inventory movements: the append-only ledger of stock changes of
+-- products and their variants. quantity_after is the stock after the
+-- movement; reference names what caused it, e.g. 'order:42'.
+CREATE TABLE IF NOT EXISTS inventory_movements (
+    id SERIAL PRIMARY KEY,
+    product_id INTEGER NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    variant_id INTEGER REFERENCES product_variants(id) ON DELETE CASCADE,
+    quantity_change INTEGER NOT NULL CHECK (quantity_change <> 0),
+    quantity_after INTEGER NOT NULL CHECK (quantity_after >= 0),
+    reason VARCHAR(20) NOT NULL CHECK (reason IN ('receipt', 'sale', 'return', 'write_off', 'correction', 'reservation')),
+    actor_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    reference VARCHAR(100) NOT NULL DEFAULT '',
+    note TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+-- Movements are never changed; stock is corrected with a new movement. Only
+-- the actor is cleared when their user is deleted.
+CREATE OR REPLACE FUNCTION inventory_movements_forbid_update() RETURNS trigger AS $$
+BEGIN
+    RAISE EXCEPTION 'inventory movements are append-only';
+END
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS trg_inventory_movements_append_only ON inventory_movements;
+CREATE TRIGGER trg_inventory_movements_append_only
+    BEFORE UPDATE OF product_id, variant_id, quantity_change, quantity_after, reason, reference, note, created_at
+    ON inventory_movements
+    FOR EACH ROW EXECUTE FUNCTION inventory_movements_forbid_update();
+
+-- Current stock opens the ledger
+INSERT INTO inventory_movements (product_id, quantity_change, quantity_after, reason, reference)
+SELECT id, quantity, quantity, 'correction', 'opening_balance'
+FROM products
+WHERE quantity > 0 AND NOT EXISTS (SELECT 1 FROM product_variants WHERE product_variants.product_id = products.id);
+
+INSERT INTO inventory_movements (product_id, variant_id, quantity_change, quantity_after, reason, reference)
+SELECT product_id, id, quantity, quantity, 'correction', 'opening_balance'
+FROM product_variants
+WHERE quantity > 0;
+
+-- Create stock takes: physical counts of stock. Completing a stock take sets
+-- the stock to the counted quantities, recording the differences.
+CREATE TABLE IF NOT EXISTS stock_takes (
+    id SERIAL PRIMARY KEY,
+    status VARCHAR(20) NOT NULL DEFAULT 'open' CHECK (status IN ('open', 'completed', 'canceled')),
+    note TEXT NOT NULL DEFAULT '',
+    created_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    completed_at TIMESTAMP
+);
+
+-- Create stock take counts; expected_quantity is the stock the count
+-- replaced, set when the stock take is completed
+CREATE TABLE IF NOT EXISTS stock_take_counts (
+    id SERIAL PRIMARY KEY,
+    stock_take_id INTEGER NOT NULL REFERENCES stock_takes(id) ON DELETE CASCADE,
+    product_id INTEGER NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    variant_id INTEGER REFERENCES product_variants(id) ON DELETE CASCADE,
+    counted_quantity INTEGER NOT NULL CHECK (counted_quantity >= 0),
+    expected_quantity INTEGER,
+    counted_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+-- Create indexes
+CREATE INDEX IF NOT EXISTS idx_inventory_movements_product_id ON inventory_movements(product_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_inventory_movements_reference ON inventory_movements(reference) WHERE reference <> '';
+CREATE INDEX IF NOT EXISTS idx_inventory_movements_created_at ON inventory_movements(created_at);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_stock_take_counts_line ON stock_take_counts(stock_take_id, product_id, COALESCE(variant_id, 0));
@@ -0,0 +1,27 @@
+-- Drop indexes
+DROP INDEX IF EXISTS idx_orders_fulfilment_location_id;
+DROP INDEX IF EXISTS idx_inventory_movements_location_id;
+DROP INDEX IF EXISTS idx_stock_transfers_created_at;
+DROP INDEX IF EXISTS idx_location_stock_product_id;
+DROP INDEX IF EXISTS idx_location_stock_line;
+DROP INDEX IF EXISTS idx_locations_default;
+
+-- Drop columns
+ALTER TABLE orders DROP COLUMN IF EXISTS fulfilment_location_id;
+ALTER TABLE stock_takes DROP COLUMN IF EXISTS location_id;
+
+DROP TRIGGER IF EXISTS trg_inventory_movements_append_only ON inventory_movements;
+DELETE FROM inventory_movements WHERE reason = 'transfer';
+ALTER TABLE inventory_movements DROP CONSTRAINT IF EXISTS inventory_movements_reason_check;
+ALTER TABLE inventory_movements ADD CONSTRAINT inventory_movements_reason_check
+    CHECK (reason IN ('receipt', 'sale', 'return', 'write_off', 'correction', 'reservation'));
+ALTER TABLE inventory_movements DROP COLUMN IF EXISTS location_id;
+CREATE TRIGGER trg_inventory_movements_append_only
+    BEFORE UPDATE OF product_id, variant_id, quantity_change, quantity_after, reason, reference, note, created_at
+    ON inventory_movements
+    FOR EACH ROW EXECUTE FUNCTION inventory_movements_forbid_update();
+
+-- Drop tables
+DROP TABLE IF EXISTS stock_transfers;
+DROP TABLE IF EXISTS location_stock;
+DROP TABLE IF EXISTS locations;
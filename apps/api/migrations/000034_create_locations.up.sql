@@ -0,0 +1,91 @@
+-- Create locations: warehouses and shops stock is kept at. zones are the
+-- delivery zones a location ships to; orders are fulfilled from the location
+-- with the lowest priority that serves their zone and has their stock.
+CREATE TABLE IF NOT EXISTS locations (
+    id SERIAL PRIMARY KEY,
+    code VARCHAR(50) UNIQUE NOT NULL,
+    name VARCHAR(255) NOT NULL,
+    city VARCHAR(100) NOT NULL DEFAULT '',
+    address TEXT NOT NULL DEFAULT '',
+    zones TEXT[] NOT NULL DEFAULT '{}',
+    is_default BOOLEAN NOT NULL DEFAULT false,
+    priority INTEGER NOT NULL DEFAULT 0,
+    active BOOLEAN NOT NULL DEFAULT true,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+-- The stock kept so far is at the main warehouse
+INSERT INTO locations (code, name, is_default)
+VALUES ('main', 'Основной склад', true)
+ON CONFLICT (code) DO NOTHING;
+
+-- Create location stock: the stock of products and their variants at each
+-- location. products.quantity and product_variants.quantity are the totals
+-- over all locations.
+CREATE TABLE IF NOT EXISTS location_stock (
+    id SERIAL PRIMARY KEY,
+    location_id INTEGER NOT NULL REFERENCES locations(id) ON DELETE CASCADE,
+    product_id INTEGER NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    variant_id INTEGER REFERENCES product_variants(id) ON DELETE CASCADE,
+    quantity INTEGER NOT NULL DEFAULT 0 CHECK (quantity >= 0)
+);
+
+INSERT INTO location_stock (location_id, product_id, quantity)
+SELECT (SELECT id FROM locations WHERE is_default), id, quantity
+FROM products
+WHERE quantity > 0
+    AND NOT EXISTS (SELECT 1 FROM product_variants WHERE product_variants.product_id = products.id)
+    AND NOT EXISTS (SELECT 1 FROM product_bundles WHERE product_bundles.product_id = products.id);
+
+INSERT INTO location_stock (location_id, product_id, variant_id, quantity)
+SELECT (SELECT id FROM locations WHERE is_default), product_id, id, quantity
+FROM product_variants
+WHERE quantity > 0;
+
+-- Create stock transfers: stock moved between locations, recorded in the
+-- ledger as a 'transfer' movement at each end
+CREATE TABLE IF NOT EXISTS stock_transfers (
+    id SERIAL PRIMARY KEY,
+    from_location_id INTEGER NOT NULL REFERENCES locations(id) ON DELETE CASCADE,
+    to_location_id INTEGER NOT NULL REFERENCES locations(id) ON DELETE CASCADE,
+    product_id INTEGER NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    variant_id INTEGER REFERENCES product_variants(id) ON DELETE CASCADE,
+    quantity INTEGER NOT NULL CHECK (quantity > 0),
+    actor_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    note TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    CHECK (from_location_id <> to_location_id)
+);
+
+-- Movements are kept per location; quantity_after is the stock at the
+-- location after the movement
+ALTER TABLE inventory_movements ADD COLUMN IF NOT EXISTS location_id INTEGER REFERENCES locations(id) ON DELETE CASCADE;
+UPDATE inventory_movements SET location_id = (SELECT id FROM locations WHERE is_default) WHERE location_id IS NULL;
+ALTER TABLE inventory_movements ALTER COLUMN location_id SET NOT NULL;
+
+ALTER TABLE inventory_movements DROP CONSTRAINT IF EXISTS inventory_movements_reason_check;
+ALTER TABLE inventory_movements ADD CONSTRAINT inventory_movements_reason_check
+    CHECK (reason IN ('receipt', 'sale', 'return', 'write_off', 'correction', 'reservation', 'transfer'));
+
+DROP TRIGGER IF EXISTS trg_inventory_movements_append_only ON inventory_movements;
+CREATE TRIGGER trg_inventory_movements_append_only
+    BEFORE UPDATE OF location_id, product_id, variant_id, quantity_change, quantity_after, reason, reference, note, created_at
+    ON inventory_movements
+    FOR EACH ROW EXECUTE FUNCTION inventory_movements_forbid_update();
+
+-- Stock takes count the stock of one location
+ALTER TABLE stock_takes ADD COLUMN IF NOT EXISTS location_id INTEGER REFERENCES locations(id) ON DELETE CASCADE;
+UPDATE stock_takes SET location_id = (SELECT id FROM locations WHERE is_default) WHERE location_id IS NULL;
+ALTER TABLE stock_takes ALTER COLUMN location_id SET NOT NULL;
+
+-- Orders are fulfilled from a location
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS fulfilment_location_id INTEGER REFERENCES locations(id) ON DELETE SET NULL;
+
+-- Create indexes
+CREATE UNIQUE INDEX IF NOT EXISTS idx_locations_default ON locations(is_default) WHERE is_default;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_location_stock_line ON location_stock(location_id, product_id, COALESCE(variant_id, 0));
+CREATE INDEX IF NOT EXISTS idx_location_stock_product_id ON location_stock(product_id);
+CREATE INDEX IF NOT EXISTS idx_stock_transfers_created_at ON stock_transfers(created_at);
+CREATE INDEX IF NOT EXISTS idx_inventory_movements_location_id ON inventory_movements(location_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_orders_fulfilment_location_id ON orders(fulfilment_location_id);
@@ -0,0 +1,10 @@
+-- Drop indexes
+DROP INDEX IF EXISTS idx_batch_movements_movement_id;
+DROP INDEX IF EXISTS idx_batch_movements_batch_id;
+DROP INDEX IF EXISTS idx_stock_batches_lot_number;
+DROP INDEX IF EXISTS idx_stock_batches_best_before;
+DROP INDEX IF EXISTS idx_stock_batches_line;
+
+-- Drop tables
+DROP TABLE IF EXISTS batch_movements;
+DROP TABLE IF EXISTS stock_batches;
@@ -0,0 +1,36 @@
+-- Create stock batches: lots of a product or variant received at a location,
+-- with their production and best-before dates. Stock is taken from batches
+-- expiring first, then from stock received without a batch. The stock of
+-- the batches at a location never exceeds the stock at the location.
+CREATE TABLE IF NOT EXISTS stock_batches (
+    id SERIAL PRIMARY KEY,
+    location_id INTEGER NOT NULL REFERENCES locations(id) ON DELETE CASCADE,
+    product_id INTEGER NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    variant_id INTEGER REFERENCES product_variants(id) ON DELETE CASCADE,
+    lot_number VARCHAR(100) NOT NULL,
+    produced_on DATE,
+    best_before DATE,
+    received_quantity INTEGER NOT NULL CHECK (received_quantity > 0),
+    quantity INTEGER NOT NULL DEFAULT 0 CHECK (quantity >= 0),
+    discount_sale_id INTEGER REFERENCES product_sales(id) ON DELETE SET NULL,
+    discounted_at TIMESTAMP,
+    written_off_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    CHECK (produced_on IS NULL OR best_before IS NULL OR produced_on <= best_before)
+);
+
+-- Create batch movements: the part of each inventory movement taken from or
+-- added to a batch, tracing a batch to the orders it was sold with
+CREATE TABLE IF NOT EXISTS batch_movements (
+    id SERIAL PRIMARY KEY,
+    batch_id INTEGER NOT NULL REFERENCES stock_batches(id) ON DELETE CASCADE,
+    movement_id INTEGER NOT NULL REFERENCES inventory_movements(id) ON DELETE CASCADE,
+    quantity_change INTEGER NOT NULL CHECK (quantity_change <> 0)
+);
+
+-- Create indexes
+CREATE INDEX IF NOT EXISTS idx_stock_batches_line ON stock_batches(location_id, product_id, COALESCE(variant_id, 0));
+CREATE INDEX IF NOT EXISTS idx_stock_batches_best_before ON stock_batches(best_before) WHERE quantity > 0;
+CREATE INDEX IF NOT EXISTS idx_stock_batches_lot_number ON stock_batches(lot_number);
+CREATE INDEX IF NOT EXISTS idx_batch_movements_batch_id ON batch_movements(batch_id);
+CREATE INDEX IF NOT EXISTS idx_batch_movements_movement_id ON batch_movements(movement_id);
@@ -0,0 +1,8 @@
+-- Drop indexes
+DROP INDEX IF EXISTS idx_product_alerts_sent_at;
+DROP INDEX IF EXISTS idx_product_alerts_user_id;
+DROP INDEX IF EXISTS idx_product_alerts_status;
+DROP INDEX IF EXISTS idx_product_alerts_active;
+
+-- Drop tables
+DROP TABLE IF EXISTS product_alerts;
@@ -0,0 +1,22 @@
+-- Create product alerts: customers ask to be emailed once a product is back
+-- in stock, or once its price is at or below target_price_cents. Alerts are
+-- sent once; unsubscribe_token unsubscribes without signing in.
+CREATE TABLE IF NOT EXISTS product_alerts (
+    id SERIAL PRIMARY KEY,
+    product_id INTEGER NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    user_id INTEGER REFERENCES users(id) ON DELETE CASCADE,
+    email VARCHAR(255) NOT NULL,
+    type VARCHAR(20) NOT NULL CHECK (type IN ('back_in_stock', 'price_drop')),
+    target_price_cents INTEGER CHECK (target_price_cents > 0),
+    status VARCHAR(20) NOT NULL DEFAULT 'active' CHECK (status IN ('active', 'sent', 'unsubscribed')),
+    unsubscribe_token VARCHAR(64) UNIQUE NOT NULL,
+    sent_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    CHECK (type <> 'price_drop' OR target_price_cents IS NOT NULL)
+);
+
+-- Create indexes
+CREATE UNIQUE INDEX IF NOT EXISTS idx_product_alerts_active ON product_alerts(product_id, LOWER(email), type) WHERE status = 'active';
+CREATE INDEX IF NOT EXISTS idx_product_alerts_status ON product_alerts(status, product_id);
+CREATE INDEX IF NOT EXISTS idx_product_alerts_user_id ON product_alerts(user_id);
+CREATE INDEX IF NOT EXISTS idx_product_alerts_sent_at ON product_alerts(LOWER(email), sent_at);
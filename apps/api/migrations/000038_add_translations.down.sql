@@ -0,0 +1,8 @@
+-- Drop tables
+DROP TABLE IF EXISTS email_templates;
+
+-- Drop columns
+ALTER TABLE users DROP COLUMN IF EXISTS locale;
+ALTER TABLE regions DROP COLUMN IF EXISTS translations;
+ALTER TABLE categories DROP COLUMN IF EXISTS translations;
+ALTER TABLE products DROP COLUMN IF EXISTS translations;
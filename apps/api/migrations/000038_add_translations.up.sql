@@ -0,0 +1,22 @@
+-- Catalog texts are written in the default locale (ru). translations holds
+-- the texts in other locales keyed by locale, e.g.
+-- {"en": {"title": "...", "description": "..."}}; missing texts fall back to
+-- the default locale.
+ALTER TABLE products ADD COLUMN IF NOT EXISTS translations JSONB NOT NULL DEFAULT '{}';
+ALTER TABLE categories ADD COLUMN IF NOT EXISTS translations JSONB NOT NULL DEFAULT '{}';
+ALTER TABLE regions ADD COLUMN IF NOT EXISTS translations JSONB NOT NULL DEFAULT '{}';
+
+-- Emails are sent in the locale of the customer's account
+ALTER TABLE users ADD COLUMN IF NOT EXISTS locale VARCHAR(5) NOT NULL DEFAULT 'ru';
+
+-- Create email templates: admins' versions of the built-in emails per
+-- locale. subject and body are Go templates over the email's data.
+CREATE TABLE IF NOT EXISTS email_templates (
+    name VARCHAR(100) NOT NULL,
+    locale VARCHAR(5) NOT NULL,
+    subject TEXT NOT NULL,
+    body TEXT NOT NULL,
+    updated_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (name, locale)
+);
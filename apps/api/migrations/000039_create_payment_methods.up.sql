@@ -0,0 +1,14 @@
+-- Create payment methods: cards and wallets customers saved with the
+-- payment provider, which subscriptions may charge without a checkout
+CREATE TABLE IF NOT EXISTS payment_methods (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    provider VARCHAR(50) NOT NULL,
+    provider_method_id VARCHAR(255) NOT NULL,
+    title VARCHAR(255) NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    UNIQUE(provider, provider_method_id)
+);
+
+-- Create indexes
+CREATE INDEX IF NOT EXISTS idx_payment_methods_user_id ON payment_methods(user_id);
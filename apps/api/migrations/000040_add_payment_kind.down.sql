@@ -0,0 +1,2 @@
+-- Drop columns
+ALTER TABLE payments DROP COLUMN IF EXISTS kind;
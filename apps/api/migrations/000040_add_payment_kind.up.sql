@@ -0,0 +1,5 @@
+-- Add payment kind: the order's primary payment, or a supplementary payment
+-- of an extra amount owed after an order edit
+ALTER TABLE payments ADD COLUMN IF NOT EXISTS kind VARCHAR(20) NOT NULL DEFAULT 'primary';
+
+UPDATE payments SET kind = 'supplementary' WHERE metadata->>'supplementary' = 'true';
@@ -0,0 +1,8 @@
+-- Drop free-from flags
+UPDATE products
+SET dietary_flags = array_remove(array_remove(dietary_flags, 'gluten_free'), 'nut_free')
+WHERE dietary_flags && ARRAY['gluten_free', 'nut_free']::TEXT[];
+
+ALTER TABLE products DROP CONSTRAINT IF EXISTS products_dietary_flags_check;
+ALTER TABLE products ADD CONSTRAINT products_dietary_flags_check
+    CHECK (dietary_flags <@ ARRAY['lactose_free', 'vegetarian_rennet', 'pasteurized', 'raw_milk', 'gluten', 'nuts']::TEXT[]);
@@ -0,0 +1,6 @@
+-- Free-from flags: gluten_free and nut_free. The gluten, nuts and raw_milk
+-- exclusions keep only products flagged gluten_free, nut_free and
+-- pasteurized, so products without allergen data are left out
+ALTER TABLE products DROP CONSTRAINT IF EXISTS products_dietary_flags_check;
+ALTER TABLE products ADD CONSTRAINT products_dietary_flags_check
+    CHECK (dietary_flags <@ ARRAY['lactose_free', 'vegetarian_rennet', 'pasteurized', 'raw_milk', 'gluten', 'gluten_free', 'nuts', 'nut_free']::TEXT[]);
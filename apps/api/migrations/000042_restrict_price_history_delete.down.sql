@@ -0,0 +1,4 @@
+-- Restore cascading deletes of price history
+ALTER TABLE price_history DROP CONSTRAINT IF EXISTS price_history_product_id_fkey;
+ALTER TABLE price_history ADD CONSTRAINT price_history_product_id_fkey
+    FOREIGN KEY (product_id) REFERENCES products(id) ON DELETE CASCADE;
@@ -0,0 +1,5 @@
+-- Keep the price history of products: deleting a product with price history
+-- fails, purging a product removes its initial price first
+ALTER TABLE price_history DROP CONSTRAINT IF EXISTS price_history_product_id_fkey;
+ALTER TABLE price_history ADD CONSTRAINT price_history_product_id_fkey
+    FOREIGN KEY (product_id) REFERENCES products(id) ON DELETE RESTRICT;
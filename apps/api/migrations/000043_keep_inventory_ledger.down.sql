@@ -0,0 +1,21 @@
+-- Drop indexes
+DROP INDEX IF EXISTS idx_product_variants_barcode;
+DROP INDEX IF EXISTS idx_product_variants_sku;
+
+DROP TRIGGER IF EXISTS trg_inventory_movements_no_delete ON inventory_movements;
+
+ALTER TABLE inventory_movements DROP CONSTRAINT IF EXISTS inventory_movements_variant_id_fkey;
+ALTER TABLE inventory_movements ADD CONSTRAINT inventory_movements_variant_id_fkey
+    FOREIGN KEY (variant_id) REFERENCES product_variants(id) ON DELETE CASCADE;
+ALTER TABLE inventory_movements DROP CONSTRAINT IF EXISTS inventory_movements_product_id_fkey;
+ALTER TABLE inventory_movements ADD CONSTRAINT inventory_movements_product_id_fkey
+    FOREIGN KEY (product_id) REFERENCES products(id) ON DELETE CASCADE;
+
+-- Drop deleted variants with their ledger
+DELETE FROM inventory_movements WHERE variant_id IN (SELECT id FROM product_variants WHERE deleted_at IS NOT NULL);
+DELETE FROM product_variants WHERE deleted_at IS NOT NULL;
+
+-- Drop columns
+ALTER TABLE product_variants DROP COLUMN IF EXISTS deleted_at;
+ALTER TABLE product_variants ADD CONSTRAINT product_variants_sku_key UNIQUE (sku);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_product_variants_barcode ON product_variants(barcode) WHERE barcode IS NOT NULL;
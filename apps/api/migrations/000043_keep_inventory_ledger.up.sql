@@ -0,0 +1,24 @@
+-- Deleted variants are kept, so their inventory movements and price history
+-- keep pointing at them; SKUs and barcodes are unique among live variants
+ALTER TABLE product_variants ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP;
+ALTER TABLE product_variants DROP CONSTRAINT IF EXISTS product_variants_sku_key;
+DROP INDEX IF EXISTS idx_product_variants_barcode;
+
+-- Products and variants with inventory movements can't be deleted
+ALTER TABLE inventory_movements DROP CONSTRAINT IF EXISTS inventory_movements_product_id_fkey;
+ALTER TABLE inventory_movements ADD CONSTRAINT inventory_movements_product_id_fkey
+    FOREIGN KEY (product_id) REFERENCES products(id) ON DELETE RESTRICT;
+ALTER TABLE inventory_movements DROP CONSTRAINT IF EXISTS inventory_movements_variant_id_fkey;
+ALTER TABLE inventory_movements ADD CONSTRAINT inventory_movements_variant_id_fkey
+    FOREIGN KEY (variant_id) REFERENCES product_variants(id) ON DELETE RESTRICT;
+
+-- Movements are never deleted either
+DROP TRIGGER IF EXISTS trg_inventory_movements_no_delete ON inventory_movements;
+CREATE TRIGGER trg_inventory_movements_no_delete
+    BEFORE DELETE ON inventory_movements
+    FOR EACH ROW EXECUTE FUNCTION inventory_movements_forbid_update();
+
+-- Create indexes
+CREATE UNIQUE INDEX IF NOT EXISTS idx_product_variants_sku ON product_variants(sku) WHERE deleted_at IS NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_product_variants_barcode ON product_variants(barcode)
+    WHERE barcode IS NOT NULL AND deleted_at IS NULL;
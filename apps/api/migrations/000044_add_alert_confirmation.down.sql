@@ -0,0 +1,10 @@
+-- Drop indexes
+DROP INDEX IF EXISTS idx_product_alerts_active;
+
+-- Drop unconfirmed alerts
+DELETE FROM product_alerts WHERE status = 'pending';
+
+ALTER TABLE product_alerts DROP CONSTRAINT IF EXISTS product_alerts_status_check;
+ALTER TABLE product_alerts ADD CONSTRAINT product_alerts_status_check
+    CHECK (status IN ('active', 'sent', 'unsubscribed'));
+CREATE UNIQUE INDEX IF NOT EXISTS idx_product_alerts_active ON product_alerts(product_id, LOWER(email), type) WHERE status = 'active';
@@ -0,0 +1,10 @@
+-- Alerts of guests are pending until their email is confirmed with the link
+-- sent to it
+ALTER TABLE product_alerts DROP CONSTRAINT IF EXISTS product_alerts_status_check;
+ALTER TABLE product_alerts ADD CONSTRAINT product_alerts_status_check
+    CHECK (status IN ('pending', 'active', 'sent', 'unsubscribed'));
+
+-- Create indexes
+DROP INDEX IF EXISTS idx_product_alerts_active;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_product_alerts_active ON product_alerts(product_id, LOWER(email), type)
+    WHERE status IN ('pending', 'active');
@@ -0,0 +1,6 @@
+DROP TRIGGER IF EXISTS trg_inventory_movements_no_delete ON inventory_movements;
+CREATE TRIGGER trg_inventory_movements_no_delete
+    BEFORE DELETE ON inventory_movements
+    FOR EACH ROW EXECUTE FUNCTION inventory_movements_forbid_update();
+
+DROP FUNCTION IF EXISTS inventory_movements_forbid_delete();
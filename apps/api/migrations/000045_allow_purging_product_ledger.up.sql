@@ -0,0 +1,17 @@
+-- Movements are still never deleted, except the receipts, write-offs,
+-- corrections and transfers of a product being purged: the purge names the
+-- product in inventory.purge_product_id for its transaction
+CREATE OR REPLACE FUNCTION inventory_movements_forbid_delete() RETURNS trigger AS $$
+BEGIN
+    IF OLD.product_id::text = current_setting('inventory.purge_product_id', true)
+        AND OLD.reason IN ('receipt', 'write_off', 'correction', 'transfer') THEN
+        RETURN OLD;
+    END IF;
+    RAISE EXCEPTION 'inventory movements are append-only';
+END
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS trg_inventory_movements_no_delete ON inventory_movements;
+CREATE TRIGGER trg_inventory_movements_no_delete
+    BEFORE DELETE ON inventory_movements
+    FOR EACH ROW EXECUTE FUNCTION inventory_movements_forbid_delete();
@@ -15,6 +15,7 @@ import (
 	"gastroshop-api/internal/models"
 	"gastroshop-api/internal/repository"
 	"gastroshop-api/internal/services"
+	"gastroshop-api/internal/storage"
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
@@ -54,6 +55,20 @@ func setupTestHandlers(t *testing.T) {
 	paymentRepo := repository.NewPaymentRepository(testDB)
 	regionRepo := repository.NewRegionRepository(testDB)
 	eventRepo := repository.NewEventRepository(testDB)
+	deliveryRepo := repository.NewDeliveryRepository(testDB)
+	subscriptionRepo := repository.NewSubscriptionRepository(testDB)
+	cartRepo := repository.NewCartRepository(testDB)
+	categoryRepo := repository.NewCategoryRepository(testDB)
+	importJobRepo := repository.NewImportJobRepository(testDB)
+	attributeRepo := repository.NewAttributeRepository(testDB)
+	priceRepo := repository.NewPriceRepository(testDB)
+	pairingRepo := repository.NewPairingRepository(testDB)
+	inventoryRepo := repository.NewInventoryRepository(testDB)
+	locationRepo := repository.NewLocationRepository(testDB)
+	batchRepo := repository.NewBatchRepository(testDB)
+	alertRepo := repository.NewAlertRepository(testDB)
+	wishlistRepo := repository.NewWishlistRepository(testDB)
+	emailTemplateRepo := repository.NewEmailTemplateRepository(testDB)
 
 	// Initialize services
 	cfg := &config.Config{
@@ -61,12 +76,40 @@ func setupTestHandlers(t *testing.T) {
 	}
 	authService := services.NewAuthService(userRepo, tokenRepo, cfg.JWTSecret)
 	productService := services.NewProductService(productRepo)
-	orderService := services.NewOrderService(orderRepo, productRepo)
+	orderService := services.NewOrderService(orderRepo, productRepo, deliveryRepo)
 	regionService := services.NewRegionService(regionRepo, productRepo)
 	recommendationService := services.NewRecommendationService(productRepo)
 	paymentService := services.NewPaymentService(cfg, paymentRepo, orderRepo)
 	eventService := services.NewEventService(eventRepo)
 	aiService := services.NewAIService(cfg, productRepo)
+	deliveryService := services.NewDeliveryService(deliveryRepo)
+	emailService := services.NewEmailService(services.EmailConfig{})
+	subscriptionService := services.NewSubscriptionService(subscriptionRepo, productRepo, userRepo, orderService, paymentService, emailService)
+	orderEditService := services.NewOrderEditService(orderRepo, productRepo, paymentService)
+	cartService := services.NewCartService(cartRepo, productRepo)
+	categoryService := services.NewCategoryService(categoryRepo, productRepo)
+	productImportService := services.NewProductImportService(importJobRepo, productRepo, regionRepo)
+	fileStorage, err := storage.NewLocalStorage(t.TempDir(), "http://localhost/uploads")
+	if err != nil {
+		t.Fatalf("Failed to create storage: %v", err)
+	}
+	productImageService := services.NewProductImageService(productRepo, fileStorage, "")
+	productService.SetImageService(productImageService)
+	attributeService := services.NewAttributeService(attributeRepo, productRepo)
+	productService.SetAttributeService(attributeService)
+	saleService := services.NewSaleService(priceRepo, productRepo)
+	pairingService := services.NewPairingService(pairingRepo, productRepo)
+	recommendationService.SetPairingService(pairingService)
+	aiService.SetPairingService(pairingService)
+	bundleService := services.NewBundleService(productRepo)
+	inventoryService := services.NewInventoryService(inventoryRepo, productRepo, locationRepo)
+	locationService := services.NewLocationService(locationRepo, productRepo, orderRepo)
+	productService.SetLocationService(locationService)
+	orderService.SetLocationService(locationService)
+	batchService := services.NewBatchService(batchRepo, productRepo, inventoryService, saleService)
+	alertService := services.NewAlertService(alertRepo, productRepo, userRepo, emailService)
+	wishlistService := services.NewWishlistService(wishlistRepo, productRepo, cartService, eventService)
+	emailTemplateService := services.NewEmailTemplateService(emailTemplateRepo)
 
 	// Initialize handlers
 	testHandlers = handlers.NewHandlers(
@@ -78,6 +121,23 @@ func setupTestHandlers(t *testing.T) {
 		paymentService,
 		eventService,
 		aiService,
+		deliveryService,
+		subscriptionService,
+		orderEditService,
+		cartService,
+		categoryService,
+		productImportService,
+		productImageService,
+		attributeService,
+		saleService,
+		pairingService,
+		bundleService,
+		inventoryService,
+		locationService,
+		batchService,
+		alertService,
+		wishlistService,
+		emailTemplateService,
 	)
 }
 
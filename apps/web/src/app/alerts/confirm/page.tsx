@@ -0,0 +1,110 @@
+'use client';
+export const dynamic = 'force-dynamic';
+
+import { useEffect, useState } from 'react';
+import Link from 'next/link';
+import { useSearchParams } from 'next/navigation';
+import { Header } from '@/components/layout/header';
+import { Footer } from '@/components/layout/footer';
+import { Button } from '@/components/ui/button';
+import { CheckCircle, Loader2, XCircle } from 'lucide-react';
+
+interface ProductAlert {
+  type: 'back_in_stock' | 'price_drop';
+  status: string;
+  title: string;
+  slug: string;
+}
+
+// Confirmation page linked from the email sent when a guest subscribes to a
+// product alert: /alerts/confirm?token=...
+export default function ConfirmAlertPage() {
+  const searchParams = useSearchParams();
+  const token = searchParams.get('token');
+  const [alert, setAlert] = useState<ProductAlert | null>(null);
+  const [status, setStatus] = useState<'loading' | 'success' | 'error'>('loading');
+  const [message, setMessage] = useState('');
+
+  useEffect(() => {
+    if (!token) {
+      setStatus('error');
+      setMessage('Ссылка для подтверждения недействительна');
+      return;
+    }
+
+    confirmAlert();
+  }, [token]);
+
+  const confirmAlert = async () => {
+    setStatus('loading');
+    try {
+      const res = await fetch(`/api/alerts/confirm/${encodeURIComponent(token!)}`, { method: 'POST' });
+      const data = await res.json();
+
+      if (res.ok) {
+        setAlert(data);
+        setStatus('success');
+      } else {
+        setStatus('error');
+        setMessage(res.status === 404 ? 'Уведомление не найдено' : data.error || 'Ошибка подтверждения');
+      }
+    } catch (error) {
+      setStatus('error');
+      setMessage('Ошибка подключения к серверу');
+    }
+  };
+
+  return (
+    <>
+      <Header />
+      <main className="min-h-screen bg-gradient-to-b from-gray-50 to-white py-16">
+        <div className="container mx-auto px-4 max-w-md">
+          <div className="bg-white rounded-lg shadow-lg p-8 text-center">
+            {status === 'loading' && (
+              <>
+                <Loader2 className="w-16 h-16 mx-auto mb-4 text-blue-600 animate-spin" />
+                <h1 className="text-2xl font-bold mb-4">Подтверждение уведомления</h1>
+                <p className="text-gray-600">Пожалуйста, подождите...</p>
+              </>
+            )}
+
+            {status === 'success' && alert && (
+              <>
+                <CheckCircle className="w-16 h-16 mx-auto mb-4 text-green-600" />
+                <h1 className="text-2xl font-bold mb-4 text-green-600">
+                  {alert.status === 'unsubscribed' ? 'Вы отписались' : 'Уведомление подтверждено'}
+                </h1>
+                <p className="text-gray-600 mb-6">
+                  {alert.status === 'unsubscribed'
+                    ? 'Это уведомление уже отменено.'
+                    : alert.type === 'price_drop'
+                      ? 'Мы напишем, как только цена снизится.'
+                      : 'Мы напишем, как только товар снова появится в наличии.'}
+                </p>
+                <Link href={`/shop/${alert.slug}`}>
+                  <Button variant="outline" className="w-full">
+                    {alert.title}
+                  </Button>
+                </Link>
+              </>
+            )}
+
+            {status === 'error' && (
+              <>
+                <XCircle className="w-16 h-16 mx-auto mb-4 text-red-600" />
+                <h1 className="text-2xl font-bold mb-4 text-red-600">Ошибка</h1>
+                <p className="text-gray-600 mb-6">{message}</p>
+                {token && (
+                  <Button onClick={confirmAlert} variant="outline">
+                    Попробовать снова
+                  </Button>
+                )}
+              </>
+            )}
+          </div>
+        </div>
+      </main>
+      <Footer />
+    </>
+  );
+}
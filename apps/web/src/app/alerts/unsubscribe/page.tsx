@@ -0,0 +1,142 @@
+'use client';
+export const dynamic = 'force-dynamic';
+
+import { useEffect, useState } from 'react';
+import Link from 'next/link';
+import { useSearchParams } from 'next/navigation';
+import { Header } from '@/components/layout/header';
+import { Footer } from '@/components/layout/footer';
+import { Button } from '@/components/ui/button';
+import { BellOff, CheckCircle, Loader2, XCircle } from 'lucide-react';
+
+interface ProductAlert {
+  email: string;
+  type: 'back_in_stock' | 'price_drop';
+  status: string;
+  title: string;
+  slug: string;
+}
+
+// Unsubscribe page linked from product alert emails: /alerts/unsubscribe?token=...
+// The alert is only unsubscribed once the customer confirms.
+export default function UnsubscribeAlertPage() {
+  const searchParams = useSearchParams();
+  const token = searchParams.get('token');
+  const [alert, setAlert] = useState<ProductAlert | null>(null);
+  const [status, setStatus] = useState<'loading' | 'confirm' | 'success' | 'error'>('loading');
+  const [message, setMessage] = useState('');
+  const [submitting, setSubmitting] = useState(false);
+
+  useEffect(() => {
+    if (!token) {
+      setStatus('error');
+      setMessage('Ссылка для отписки недействительна');
+      return;
+    }
+
+    loadAlert();
+  }, [token]);
+
+  const loadAlert = async () => {
+    setStatus('loading');
+    try {
+      const res = await fetch(`/api/alerts/unsubscribe/${encodeURIComponent(token!)}`);
+      const data = await res.json();
+
+      if (res.ok) {
+        setAlert(data);
+        setStatus('confirm');
+      } else {
+        setStatus('error');
+        setMessage(res.status === 404 ? 'Уведомление не найдено' : data.error || 'Ошибка загрузки уведомления');
+      }
+    } catch (error) {
+      setStatus('error');
+      setMessage('Ошибка подключения к серверу');
+    }
+  };
+
+  const unsubscribe = async (all: boolean) => {
+    setSubmitting(true);
+    try {
+      const res = await fetch(`/api/alerts/unsubscribe/${encodeURIComponent(token!)}${all ? '?all=true' : ''}`, {
+        method: 'POST',
+      });
+      const data = await res.json();
+
+      if (res.ok) {
+        setStatus('success');
+        setMessage(all ? 'Вы отписались от всех уведомлений о товарах' : 'Вы отписались от уведомления');
+      } else {
+        setStatus('error');
+        setMessage(data.error || 'Не удалось отписаться');
+      }
+    } catch (error) {
+      setStatus('error');
+      setMessage('Ошибка подключения к серверу');
+    }
+    setSubmitting(false);
+  };
+
+  return (
+    <>
+      <Header />
+      <main className="min-h-screen bg-gradient-to-b from-gray-50 to-white py-16">
+        <div className="container mx-auto px-4 max-w-md">
+          <div className="bg-white rounded-lg shadow-lg p-8 text-center">
+            {status === 'loading' && (
+              <>
+                <Loader2 className="w-16 h-16 mx-auto mb-4 text-blue-600 animate-spin" />
+                <p className="text-gray-600">Пожалуйста, подождите...</p>
+              </>
+            )}
+
+            {status === 'confirm' && alert && (
+              <>
+                <BellOff className="w-16 h-16 mx-auto mb-4 text-gray-600" />
+                <h1 className="text-2xl font-bold mb-4">Отписаться от уведомления?</h1>
+                <p className="text-gray-600 mb-6">
+                  {alert.type === 'price_drop' ? 'Снижение цены' : 'Поступление'} товара{' '}
+                  <Link href={`/shop/${alert.slug}`} className="text-blue-600 hover:underline">
+                    {alert.title}
+                  </Link>{' '}
+                  для {alert.email}
+                </p>
+                <div className="space-y-3">
+                  <Button onClick={() => unsubscribe(false)} disabled={submitting} className="w-full">
+                    Отписаться
+                  </Button>
+                  <Button onClick={() => unsubscribe(true)} disabled={submitting} variant="outline" className="w-full">
+                    Отписаться от всех уведомлений
+                  </Button>
+                </div>
+              </>
+            )}
+
+            {status === 'success' && (
+              <>
+                <CheckCircle className="w-16 h-16 mx-auto mb-4 text-green-600" />
+                <h1 className="text-2xl font-bold mb-4 text-green-600">Готово</h1>
+                <p className="text-gray-600">{message}</p>
+              </>
+            )}
+
+            {status === 'error' && (
+              <>
+                <XCircle className="w-16 h-16 mx-auto mb-4 text-red-600" />
+                <h1 className="text-2xl font-bold mb-4 text-red-600">Ошибка</h1>
+                <p className="text-gray-600 mb-6">{message}</p>
+                {token && (
+                  <Button onClick={loadAlert} variant="outline">
+                    Попробовать снова
+                  </Button>
+                )}
+              </>
+            )}
+          </div>
+        </div>
+      </main>
+      <Footer />
+    </>
+  );
+}
@@ -0,0 +1,166 @@
+'use client';
+export const dynamic = 'force-dynamic';
+
+import { useEffect, useState } from 'react';
+import { useSearchParams } from 'next/navigation';
+import { Header } from '@/components/layout/header';
+import { Footer } from '@/components/layout/footer';
+import { Button } from '@/components/ui/button';
+import { formatPrice } from '@/lib/utils';
+import type { Invoice, Order } from '@/types';
+import { CheckCircle, Clock, Loader2, Package, XCircle } from 'lucide-react';
+
+const statusLabels: Record<string, { label: string; color: string }> = {
+  pending: { label: 'Ожидает оплаты', color: 'text-yellow-600' },
+  paid: { label: 'Оплачен', color: 'text-green-600' },
+  shipped: { label: 'Отправлен', color: 'text-blue-600' },
+  delivered: { label: 'Доставлен', color: 'text-green-600' },
+  canceled: { label: 'Отменен', color: 'text-red-600' },
+};
+
+// Quantities of weighed goods are in grams; kilogram prices are per 1000 g
+const quantityUnitLabels: Record<string, string> = { piece: 'шт.', g: 'г', kg: 'г' };
+const priceUnitLabels: Record<string, string> = { piece: 'шт.', g: 'г', kg: 'кг' };
+
+// Guest order page linked from guest order emails: /orders/guest?token=...
+export default function GuestOrderPage() {
+  const searchParams = useSearchParams();
+  const token = searchParams.get('token');
+  const [order, setOrder] = useState<Order | null>(null);
+  const [invoice, setInvoice] = useState<Invoice | null>(null);
+  const [status, setStatus] = useState<'loading' | 'success' | 'error'>('loading');
+  const [message, setMessage] = useState('');
+  const [paying, setPaying] = useState(false);
+
+  useEffect(() => {
+    if (!token) {
+      setStatus('error');
+      setMessage('Ссылка на заказ недействительна');
+      return;
+    }
+
+    loadOrder();
+  }, [token]);
+
+  const loadOrder = async () => {
+    setStatus('loading');
+    try {
+      const [res, invoiceRes] = await Promise.all([
+        fetch(`/api/guest/orders/${encodeURIComponent(token!)}`),
+        fetch(`/api/guest/orders/${encodeURIComponent(token!)}/invoice`),
+      ]);
+      const data = await res.json();
+
+      if (res.ok && invoiceRes.ok) {
+        setOrder(data);
+        setInvoice(await invoiceRes.json());
+        setStatus('success');
+      } else {
+        setStatus('error');
+        setMessage(res.status === 404 ? 'Заказ не найден' : data.error || 'Ошибка загрузки заказа');
+      }
+    } catch (error) {
+      setStatus('error');
+      setMessage('Ошибка подключения к серверу');
+    }
+  };
+
+  const payOrder = async () => {
+    setPaying(true);
+    try {
+      const res = await fetch(`/api/guest/orders/${encodeURIComponent(token!)}/payment`, { method: 'POST' });
+      const data = await res.json();
+
+      if (res.ok && data.payment_url) {
+        window.location.href = data.payment_url;
+        return;
+      }
+      setMessage(data.error || 'Не удалось создать платеж');
+    } catch (error) {
+      setMessage('Ошибка подключения к серверу');
+    }
+    setPaying(false);
+  };
+
+  const orderStatus = order ? statusLabels[order.status] || { label: order.status, color: 'text-gray-600' } : null;
+
+  return (
+    <>
+      <Header />
+      <main className="min-h-screen bg-gradient-to-b from-gray-50 to-white py-16">
+        <div className="container mx-auto px-4 max-w-2xl">
+          <div className="bg-white rounded-lg shadow-lg p-8">
+            {status === 'loading' && (
+              <div className="text-center">
+                <Loader2 className="w-16 h-16 mx-auto mb-4 text-blue-600 animate-spin" />
+                <p className="text-gray-600">Загрузка заказа...</p>
+              </div>
+            )}
+
+            {status === 'error' && (
+              <div className="text-center">
+                <XCircle className="w-16 h-16 mx-auto mb-4 text-red-600" />
+                <h1 className="text-2xl font-bold mb-4 text-red-600">Ошибка</h1>
+                <p className="text-gray-600 mb-6">{message}</p>
+                {token && (
+                  <Button onClick={loadOrder} variant="outline">
+                    Попробовать снова
+                  </Button>
+                )}
+              </div>
+            )}
+
+            {status === 'success' && order && invoice && orderStatus && (
+              <>
+                <div className="flex items-start justify-between mb-6">
+                  <div>
+                    <h1 className="text-2xl font-bold mb-2">Заказ #{order.id}</h1>
+                    <div className={`flex items-center space-x-2 font-semibold ${orderStatus.color}`}>
+                      {order.status === 'pending' ? <Clock className="h-4 w-4" /> : <CheckCircle className="h-4 w-4" />}
+                      <span>{orderStatus.label}</span>
+                    </div>
+                  </div>
+                  <div className="text-2xl font-bold">{formatPrice(order.amount_cents)}</div>
+                </div>
+
+                <div className="border-t pt-4 space-y-2">
+                  {invoice.lines.map((line, index) => (
+                    <div key={index} className="flex items-center justify-between text-sm">
+                      <div className="flex items-center space-x-2">
+                        <Package className="h-4 w-4 text-muted-foreground" />
+                        <span>
+                          {line.title} · {line.quantity} {quantityUnitLabels[line.unit || 'piece']} ×{' '}
+                          {formatPrice(line.price_cents)}/{priceUnitLabels[line.unit || 'piece']}
+                        </span>
+                      </div>
+                      <span className="font-medium">{formatPrice(line.gross_cents)}</span>
+                    </div>
+                  ))}
+                </div>
+
+                {order.status === 'pending' && (
+                  <div className="border-t pt-6 mt-6">
+                    {message && <p className="text-sm text-red-600 mb-3">{message}</p>}
+                    <Button onClick={payOrder} disabled={paying} className="w-full">
+                      {paying ? 'Переход к оплате...' : 'Оплатить заказ'}
+                    </Button>
+                  </div>
+                )}
+
+                <div className="border-t pt-4 mt-6">
+                  <a
+                    href={`/api/guest/orders/${encodeURIComponent(token!)}/invoice`}
+                    className="text-sm text-blue-600 hover:underline"
+                  >
+                    Скачать счет
+                  </a>
+                </div>
+              </>
+            )}
+          </div>
+        </div>
+      </main>
+      <Footer />
+    </>
+  );
+}
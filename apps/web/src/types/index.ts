@@ -23,6 +23,7 @@ export interface OrderItem {
   product_id: number
   quantity: number
   price_cents: number
+  unit?: string
 }
 
 export interface Order {
@@ -37,6 +38,34 @@ export interface Order {
   created_at: string
 }
 
+export interface InvoiceLine {
+  product_id: number
+  variant_id?: number
+  sku?: string
+  title: string
+  quantity: number
+  unit?: string
+  price_cents: number
+  tax_category: string
+  rate_percent: number
+  net_cents: number
+  tax_cents: number
+  gross_cents: number
+}
+
+export interface Invoice {
+  number: string
+  order_id: number
+  issued_at: string
+  status: string
+  currency: string
+  lines: InvoiceLine[]
+  net_cents: number
+  tax_cents: number
+  total_cents: number
+  prices_include_tax: boolean
+}
+
 export interface User {
   id: number
   email: string